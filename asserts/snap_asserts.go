@@ -43,6 +43,8 @@ type SnapDeclaration struct {
 	autoAliases    []string
 	aliases        map[string]string
 	timestamp      time.Time
+
+	privilegedResourceControls bool
 }
 
 // Series returns the series for which the snap is being declared.
@@ -91,6 +93,13 @@ func (snapdcl *SnapDeclaration) AutoAliases() []string {
 	return snapdcl.autoAliases
 }
 
+// PrivilegedResourceControls returns whether the apps of the snap are
+// allowed to use privileged values for their resource controls, like a
+// negative nice value.
+func (snapdcl *SnapDeclaration) PrivilegedResourceControls() bool {
+	return snapdcl.privilegedResourceControls
+}
+
 // Aliases returns the optional explicit aliases granted to this snap.
 func (snapdcl *SnapDeclaration) Aliases() map[string]string {
 	return snapdcl.aliases
@@ -301,6 +310,11 @@ func assembleSnapDeclaration(assert assertionBase) (Assertion, error) {
 		return nil, err
 	}
 
+	privilegedResourceControls, err := checkOptionalBool(assert.headers, "privileged-resource-controls")
+	if err != nil {
+		return nil, err
+	}
+
 	return &SnapDeclaration{
 		assertionBase:  assert,
 		refreshControl: refControl,
@@ -309,6 +323,8 @@ func assembleSnapDeclaration(assert assertionBase) (Assertion, error) {
 		autoAliases:    autoAliases,
 		aliases:        aliases,
 		timestamp:      timestamp,
+
+		privilegedResourceControls: privilegedResourceControls,
 	}, nil
 }
 
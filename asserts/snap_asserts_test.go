@@ -69,6 +69,7 @@ func (sds *snapDeclSuite) TestDecodeOK(c *C) {
 		"publisher-id: dev-id1\n" +
 		"refresh-control:\n  - foo\n  - bar\n" +
 		"auto-aliases:\n  - cmd1\n  - cmd_2\n  - Cmd-3\n  - CMD.4\n" +
+		"privileged-resource-controls: true\n" +
 		sds.tsLine +
 		`aliases:
   -
@@ -106,6 +107,7 @@ func (sds *snapDeclSuite) TestDecodeOK(c *C) {
 		"Cmd-3": "cmd-3",
 		"CMD.4": "cmd-4",
 	})
+	c.Check(snapDecl.PrivilegedResourceControls(), Equals, true)
 }
 
 func (sds *snapDeclSuite) TestEmptySnapName(c *C) {
@@ -143,6 +145,7 @@ func (sds *snapDeclSuite) TestMissingRefreshControlAutoAliases(c *C) {
 	snapDecl := a.(*asserts.SnapDeclaration)
 	c.Check(snapDecl.RefreshControl(), HasLen, 0)
 	c.Check(snapDecl.AutoAliases(), HasLen, 0)
+	c.Check(snapDecl.PrivilegedResourceControls(), Equals, false)
 }
 
 const (
@@ -166,6 +169,7 @@ func (sds *snapDeclSuite) TestDecodeInvalid(c *C) {
 		aliases +
 		"plugs:\n  interface1: true\n" +
 		"slots:\n  interface2: true\n" +
+		"privileged-resource-controls: false\n" +
 		sds.tsLine +
 		"body-length: 0\n" +
 		"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" +
@@ -194,6 +198,7 @@ func (sds *snapDeclSuite) TestDecodeInvalid(c *C) {
 		{"name: cmd_1\n", "name: .cmd1\n", `"name" in "aliases" item 1 contains invalid characters: ".cmd1"`},
 		{"target: cmd-1\n", "target: -cmd-1\n", `"target" for alias "cmd_1" contains invalid characters: "-cmd-1"`},
 		{aliases, aliases + "  -\n    name: cmd_1\n    target: foo\n", `duplicated definition in "aliases" for alias "cmd_1"`},
+		{"privileged-resource-controls: false\n", "privileged-resource-controls: maybe\n", `"privileged-resource-controls" header must be 'true' or 'false'`},
 		{sds.tsLine, "", `"timestamp" header is mandatory`},
 		{sds.tsLine, "timestamp: \n", `"timestamp" header should not be empty`},
 		{sds.tsLine, "timestamp: 12:30\n", `"timestamp" header is not a RFC3339 date: .*`},
@@ -40,6 +40,10 @@ type AppActivator struct {
 	Type    string
 	Active  bool
 	Enabled bool
+	// LastTrigger and NextTrigger are the times a timer last elapsed
+	// and will elapse next, if known
+	LastTrigger time.Time
+	NextTrigger time.Time
 }
 
 // AppInfo describes a single snap application.
@@ -48,10 +52,21 @@ type AppInfo struct {
 	Name        string         `json:"name"`
 	DesktopFile string         `json:"desktop-file,omitempty"`
 	Daemon      string         `json:"daemon,omitempty"`
+	DaemonScope string         `json:"daemon-scope,omitempty"`
 	Enabled     bool           `json:"enabled,omitempty"`
 	Active      bool           `json:"active,omitempty"`
 	CommonID    string         `json:"common-id,omitempty"`
 	Activators  []AppActivator `json:"activators,omitempty"`
+
+	// The following describe the state of a service in more detail,
+	// they are zero when not known.
+	SubState   string        `json:"sub-state,omitempty"`
+	Restarts   int           `json:"restarts,omitempty"`
+	MainPID    int           `json:"main-pid,omitempty"`
+	ExitCode   string        `json:"exit-code,omitempty"`
+	ExitStatus int           `json:"exit-status,omitempty"`
+	Memory     uint64        `json:"memory,omitempty"`
+	CPUUsage   time.Duration `json:"cpu-usage,omitempty"`
 }
 
 // IsService returns true if the application is a background daemon.
@@ -66,6 +81,12 @@ func (a *AppInfo) IsService() bool {
 	return true
 }
 
+// IsUserService returns true if the application is a background daemon
+// running in the session of every user.
+func (a *AppInfo) IsUserService() bool {
+	return a.IsService() && a.DaemonScope == "user"
+}
+
 // AppOptions represent the options of the Apps call.
 type AppOptions struct {
 	// If Service is true, only return apps that are services
@@ -93,16 +114,20 @@ func (client *Client) Apps(names []string, opts AppOptions) ([]*AppInfo, error)
 
 // LogOptions represent the options of the Logs call.
 type LogOptions struct {
-	N      int  // The maximum number of log lines to retrieve initially. If <0, no limit.
-	Follow bool // Whether to continue returning new lines as they appear
+	N        int       // The maximum number of log lines to retrieve initially. If <0, no limit.
+	Follow   bool      // Whether to continue returning new lines as they appear
+	Since    time.Time // If not zero, only retrieve lines logged at or after this time
+	Until    time.Time // If not zero, only retrieve lines logged at or before this time
+	Priority string    // If set, only retrieve lines of this syslog priority or more important
 }
 
 // A Log holds the information of a single syslog entry
 type Log struct {
-	Timestamp time.Time `json:"timestamp"` // Timestamp of the event, in RFC3339 format to µs precision.
-	Message   string    `json:"message"`   // The log message itself
-	SID       string    `json:"sid"`       // The syslog identifier
-	PID       string    `json:"pid"`       // The process identifier
+	Timestamp time.Time `json:"timestamp"`          // Timestamp of the event, in RFC3339 format to µs precision.
+	Message   string    `json:"message"`            // The log message itself
+	SID       string    `json:"sid"`                // The syslog identifier
+	PID       string    `json:"pid"`                // The process identifier
+	Priority  string    `json:"priority,omitempty"` // The syslog priority level, if known
 }
 
 func (l Log) String() string {
@@ -119,6 +144,15 @@ func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error)
 	if opts.Follow {
 		query.Set("follow", strconv.FormatBool(opts.Follow))
 	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.Format(time.RFC3339))
+	}
+	if opts.Priority != "" {
+		query.Set("priority", opts.Priority)
+	}
 
 	rsp, err := client.raw(context.Background(), "GET", "/v2/logs", query, nil, nil)
 	if err != nil {
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -209,6 +210,27 @@ func (cs *clientSuite) TestClientLogsOpts(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientLogsTimeRangeAndPriority(c *check.C) {
+	since := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	until := since.Add(time.Hour)
+	ch, err := cs.cli.Logs([]string{"foo"}, client.LogOptions{N: 10, Since: since, Until: until, Priority: "err"})
+	c.Assert(err, check.IsNil)
+	for range ch {
+	}
+	query := cs.req.URL.Query()
+	c.Check(query, check.HasLen, 5)
+	c.Check(query.Get("since"), check.Equals, "2020-09-13T12:26:40Z")
+	c.Check(query.Get("until"), check.Equals, "2020-09-13T13:26:40Z")
+	c.Check(query.Get("priority"), check.Equals, "err")
+}
+
+func (cs *clientSuite) TestClientLogsPriority(c *check.C) {
+	cs.rsp = "\x1e{\"message\":\"hello\",\"priority\":\"3\"}\n"
+	logs, err := testClientLogs(cs, c)
+	c.Assert(err, check.IsNil)
+	c.Check(logs, check.DeepEquals, []client.Log{{Message: "hello", Priority: "3"}})
+}
+
 func (cs *clientSuite) TestClientLogsNotFound(c *check.C) {
 	cs.rsp = `{"type":"error","status-code":404,"status":"Not Found","result":{"message":"snap \"foo\" not found","kind":"snap-not-found","value":"foo"}}`
 	cs.status = 404
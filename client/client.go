@@ -521,6 +521,7 @@ const (
 
 	ErrorKindBadQuery           = "bad-query"
 	ErrorKindConfigNoSuchOption = "option-not-found"
+	ErrorKindConfigInvalid      = "option-invalid"
 
 	ErrorKindSystemRestart = "system-restart"
 	ErrorKindDaemonRestart = "daemon-restart"
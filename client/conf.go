@@ -22,8 +22,14 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 // SetConf requests a snap to apply the provided patch to the configuration.
@@ -50,3 +56,146 @@ func (client *Client) Conf(snapName string, keys []string) (configuration map[st
 
 	return configuration, nil
 }
+
+// ConfSchema asks for the configuration schema declared by a snap.
+func (client *Client) ConfSchema(snapName string) (schema map[string]interface{}, err error) {
+	query := url.Values{}
+	query.Set("schema", "true")
+
+	_, err = client.doSync("GET", "/v2/snaps/"+snapName+"/conf", query, nil, nil, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// ConfKeyChange describes how a single configuration option changed. A nil
+// Old or New value means the option was unset at that point.
+type ConfKeyChange struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// ConfHistoryEntry describes a change of the configuration of a snap.
+type ConfHistoryEntry struct {
+	ID      int             `json:"id"`
+	Time    time.Time       `json:"time"`
+	Origin  string          `json:"origin"`
+	Changes []ConfKeyChange `json:"changes"`
+}
+
+// ConfHistory asks for the remembered configuration changes of a snap,
+// oldest first.
+func (client *Client) ConfHistory(snapName string) (history []*ConfHistoryEntry, err error) {
+	query := url.Values{}
+	query.Set("history", "true")
+
+	_, err = client.doSync("GET", "/v2/snaps/"+snapName+"/conf", query, nil, nil, &history)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// RollbackConf requests a snap to restore its configuration to how it was
+// right after the given change from its configuration history.
+func (client *Client) RollbackConf(snapName string, id int) (changeID string, err error) {
+	query := url.Values{}
+	query.Set("rollback", strconv.Itoa(id))
+
+	return client.doAsync("PUT", "/v2/snaps/"+snapName+"/conf", query, nil, nil)
+}
+
+// ConfPatchFromDocument returns the configuration patch that merges the
+// given YAML or JSON document into the configuration of a snap, one leaf
+// option at a time. The subtrees under the keys listed in replace are set
+// as a whole instead, replacing whatever is configured there.
+func ConfPatchFromDocument(data []byte, replace []string) (map[string]interface{}, error) {
+	// YAML is a superset of JSON so this covers both
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("cannot parse configuration document: %v", err)
+	}
+	doc, err := normalizeYaml(doc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse configuration document: %v", err)
+	}
+	docm, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot parse configuration document: expected a map of options")
+	}
+
+	replaced := make(map[string]bool, len(replace))
+	for _, key := range replace {
+		replaced[key] = false
+	}
+	patch := make(map[string]interface{})
+	flattenConfDocument("", docm, replaced, patch)
+
+	keys := make([]string, 0, len(replaced))
+	for key := range replaced {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !replaced[key] {
+			return nil, fmt.Errorf("cannot replace %q: option not present in the configuration document", key)
+		}
+	}
+	return patch, nil
+}
+
+func flattenConfDocument(prefix string, doc map[string]interface{}, replaced map[string]bool, patch map[string]interface{}) {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if _, ok := replaced[key]; ok {
+			replaced[key] = true
+			patch[key] = v
+			continue
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			// merging an empty map changes nothing
+			flattenConfDocument(key, m, replaced, patch)
+			continue
+		}
+		patch[key] = v
+	}
+}
+
+// normalizeYaml turns the maps produced by the YAML decoder into maps
+// with string keys, as used by JSON.
+func normalizeYaml(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("non-string key: %v", k)
+			}
+			nv, err := normalizeYaml(vv)
+			if err != nil {
+				return nil, err
+			}
+			m[ks] = nv
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, vv := range v {
+			nv, err := normalizeYaml(vv)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = nv
+		}
+		return l, nil
+	}
+	return v, nil
+}
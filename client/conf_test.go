@@ -21,8 +21,11 @@ package client_test
 
 import (
 	"encoding/json"
+	"time"
 
 	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
 )
 
 func (cs *clientSuite) TestClientSetConfCallsEndpoint(c *check.C) {
@@ -103,3 +106,130 @@ func (cs *clientSuite) TestClientGetConfMultipleKeys(c *check.C) {
 		"test-key2": "test-value2",
 	})
 }
+
+func (cs *clientSuite) TestClientConfSchema(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"properties": {"port": {"type": "integer", "maximum": 65535}}}
+	}`
+	schema, err := cs.cli.ConfSchema("snap-name")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/conf")
+	c.Check(cs.req.URL.Query().Get("schema"), check.Equals, "true")
+	c.Check(schema, check.DeepEquals, map[string]interface{}{
+		"properties": map[string]interface{}{
+			"port": map[string]interface{}{"type": "integer", "maximum": json.Number("65535")},
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientConfSchemaMissing(c *check.C) {
+	cs.status = 404
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 404,
+		"result": {"message": "snap \"snap-name\" has no configuration schema"}
+	}`
+	_, err := cs.cli.ConfSchema("snap-name")
+	c.Assert(err, check.ErrorMatches, `snap "snap-name" has no configuration schema`)
+}
+
+func (cs *clientSuite) TestClientConfHistory(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{
+			"id": 3,
+			"time": "2019-10-01T12:00:00Z",
+			"origin": "user",
+			"changes": [{"key": "port", "old": 80, "new": 8080}, {"key": "host", "new": "localhost"}]
+		}]
+	}`
+	history, err := cs.cli.ConfHistory("snap-name")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/conf")
+	c.Check(cs.req.URL.Query().Get("history"), check.Equals, "true")
+	c.Check(history, check.DeepEquals, []*client.ConfHistoryEntry{{
+		ID:     3,
+		Time:   time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC),
+		Origin: "user",
+		Changes: []client.ConfKeyChange{
+			{Key: "port", Old: json.Number("80"), New: json.Number("8080")},
+			{Key: "host", New: "localhost"},
+		},
+	}})
+}
+
+func (cs *clientSuite) TestClientRollbackConf(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+	id, err := cs.cli.RollbackConf("snap-name", 2)
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "PUT")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/conf")
+	c.Check(cs.req.URL.Query().Get("rollback"), check.Equals, "2")
+}
+
+func (cs *clientSuite) TestConfPatchFromDocument(c *check.C) {
+	doc := []byte(`
+server:
+  port: 8080
+  tls:
+    cert: /path/to/cert
+    key: null
+  empty: {}
+mode: fast
+tags: [a, b]
+`)
+	patch, err := client.ConfPatchFromDocument(doc, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(patch, check.DeepEquals, map[string]interface{}{
+		"server.port":     8080,
+		"server.tls.cert": "/path/to/cert",
+		"server.tls.key":  nil,
+		"mode":            "fast",
+		"tags":            []interface{}{"a", "b"},
+	})
+
+	patch, err = client.ConfPatchFromDocument(doc, []string{"server.tls", "server.empty"})
+	c.Assert(err, check.IsNil)
+	c.Check(patch, check.DeepEquals, map[string]interface{}{
+		"server.port":  8080,
+		"server.tls":   map[string]interface{}{"cert": "/path/to/cert", "key": nil},
+		"server.empty": map[string]interface{}{},
+		"mode":         "fast",
+		"tags":         []interface{}{"a", "b"},
+	})
+
+	// JSON works too
+	patch, err = client.ConfPatchFromDocument([]byte(`{"a": {"b": 1.5}}`), []string{"a"})
+	c.Assert(err, check.IsNil)
+	c.Check(patch, check.DeepEquals, map[string]interface{}{
+		"a": map[string]interface{}{"b": 1.5},
+	})
+}
+
+func (cs *clientSuite) TestConfPatchFromDocumentErrors(c *check.C) {
+	for _, t := range []struct {
+		doc     string
+		replace []string
+		err     string
+	}{
+		{"- a\n- b\n", nil, "cannot parse configuration document: expected a map of options"},
+		{"", nil, "cannot parse configuration document: expected a map of options"},
+		{"a: [\n", nil, "cannot parse configuration document: .*"},
+		{"1: a\n", nil, "cannot parse configuration document: non-string key: 1"},
+		{"a: 1\n", []string{"b"}, `cannot replace "b": option not present in the configuration document`},
+	} {
+		_, err := client.ConfPatchFromDocument([]byte(t.doc), t.replace)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf(t.doc))
+	}
+}
@@ -60,6 +60,9 @@ type ConnectionOptions struct {
 	// All when true, selects established and undesired connections as well
 	// as all disconnected plugs and slots.
 	All bool
+	// Hotplug when true, selects only the slots created by hotplug and
+	// their connections, including the ones of unplugged devices.
+	Hotplug bool
 }
 
 // Connections returns matching plugs, slots and their connections. Unless
@@ -76,6 +79,9 @@ func (client *Client) Connections(opts *ConnectionOptions) (Connections, error)
 	if opts != nil && opts.All {
 		query.Set("select", "all")
 	}
+	if opts != nil && opts.Hotplug {
+		query.Set("select", "hotplug")
+	}
 	_, err := client.doSync("GET", "/v2/connections", query, nil, nil, &conns)
 	return conns, err
 }
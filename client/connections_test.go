@@ -267,4 +267,62 @@ func (cs *clientSuite) TestClientConnectionsFilter(c *check.C) {
 		"interface": []string{"test"},
 		"snap":      []string{"foo"},
 	})
+	_, err = cs.cli.Connections(&client.ConnectionOptions{Hotplug: true})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/connections")
+	c.Check(cs.req.URL.RawQuery, check.Equals, "select=hotplug")
+}
+
+func (cs *clientSuite) TestClientConnectionsHotplug(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"established": [
+				{
+					"slot": {"snap": "core", "slot": "webcam"},
+					"plug": {"snap": "vlc", "plug": "camera"},
+					"interface": "camera"
+				}
+			],
+			"plugs": [],
+			"slots": [
+				{
+					"snap": "core",
+					"slot": "webcam",
+					"interface": "camera",
+					"attrs": {"path": "/dev/video0"},
+					"hotplug-key": "1234",
+					"connections": [
+						{"snap": "vlc", "plug": "camera"}
+					]
+				},
+				{
+					"snap": "core",
+					"slot": "dongle",
+					"interface": "hidraw",
+					"hotplug-key": "5678",
+					"hotplug-gone": true
+				}
+			]
+		}
+	}`
+	conns, err := cs.cli.Connections(&client.ConnectionOptions{Hotplug: true})
+	c.Assert(err, check.IsNil)
+	c.Check(conns.Slots, check.DeepEquals, []client.Slot{
+		{
+			Snap:        "core",
+			Name:        "webcam",
+			Interface:   "camera",
+			Attrs:       map[string]interface{}{"path": "/dev/video0"},
+			HotplugKey:  "1234",
+			Connections: []client.PlugRef{{Snap: "vlc", Name: "camera"}},
+		},
+		{
+			Snap:        "core",
+			Name:        "dongle",
+			Interface:   "hidraw",
+			HotplugKey:  "5678",
+			HotplugGone: true,
+		},
+	})
 }
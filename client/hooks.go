@@ -0,0 +1,51 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"time"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+// HookRun describes a past run of a hook of a snap.
+type HookRun struct {
+	Hook     string        `json:"hook"`
+	Revision snap.Revision `json:"revision"`
+	// ChangeID is the ID of the change that triggered the hook.
+	ChangeID string    `json:"change-id,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	// ExitStatus is the exit status of the hook, or -1 if the hook did
+	// not exit on its own, e.g. because it was killed on timeout.
+	ExitStatus int    `json:"exit-status"`
+	Error      string `json:"error,omitempty"`
+	// Output is the tail of the combined stdout and stderr of the hook.
+	Output string `json:"output,omitempty"`
+}
+
+// SnapHookRuns returns the recent hook runs of the given snap, oldest first.
+func (client *Client) SnapHookRuns(snapName string) ([]*HookRun, error) {
+	var runs []*HookRun
+	if _, err := client.doSync("GET", "/v2/snaps/"+snapName+"/hooks", nil, nil, nil, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,78 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (cs *clientSuite) TestClientSnapHookRuns(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{
+			"hook": "install",
+			"revision": "3",
+			"change-id": "12",
+			"start": "2019-10-01T12:00:00Z",
+			"end": "2019-10-01T12:01:00Z",
+			"exit-status": 1,
+			"error": "exit status 1",
+			"output": "cannot frobnicate\n"
+		}, {
+			"hook": "configure",
+			"revision": "3",
+			"start": "2019-10-01T12:02:00Z",
+			"end": "2019-10-01T12:02:01Z",
+			"exit-status": 0
+		}]
+	}`
+	runs, err := cs.cli.SnapHookRuns("snap-name")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/snap-name/hooks")
+	start := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	c.Check(runs, check.DeepEquals, []*client.HookRun{{
+		Hook:       "install",
+		Revision:   snap.R(3),
+		ChangeID:   "12",
+		Start:      start,
+		End:        start.Add(time.Minute),
+		ExitStatus: 1,
+		Error:      "exit status 1",
+		Output:     "cannot frobnicate\n",
+	}, {
+		Hook:     "configure",
+		Revision: snap.R(3),
+		Start:    start.Add(2 * time.Minute),
+		End:      start.Add(2*time.Minute + time.Second),
+	}})
+}
+
+func (cs *clientSuite) TestClientSnapHookRunsError(c *check.C) {
+	cs.rsp = `{"type": "error", "status-code": 500, "result": {"message": "boom"}}`
+	_, err := cs.cli.SnapHookRuns("snap-name")
+	c.Check(err, check.ErrorMatches, "boom")
+}
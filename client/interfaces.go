@@ -52,6 +52,11 @@ type Slot struct {
 	Apps        []string               `json:"apps,omitempty"`
 	Label       string                 `json:"label,omitempty"`
 	Connections []PlugRef              `json:"connections,omitempty"`
+	// HotplugKey identifies the device of slots created by hotplug.
+	HotplugKey string `json:"hotplug-key,omitempty"`
+	// HotplugGone indicates that the device of the hotplug slot is
+	// unplugged.
+	HotplugGone bool `json:"hotplug-gone,omitempty"`
 }
 
 // SlotRef is a reference to a slot.
@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// QuotaValues are the limits of a quota group.
+type QuotaValues struct {
+	// Memory is the memory limit in bytes.
+	Memory uint64 `json:"memory,omitempty"`
+	// CPU is the CPU limit in percent of a single CPU.
+	CPU int `json:"cpu,omitempty"`
+}
+
+// QuotaUsage is the current resource usage of a quota group.
+type QuotaUsage struct {
+	// Memory is the memory currently used in bytes.
+	Memory uint64 `json:"memory"`
+	// CPUTime is the CPU time used since the group was started.
+	CPUTime time.Duration `json:"cpu-time"`
+}
+
+// QuotaGroupResult describes a quota group.
+type QuotaGroupResult struct {
+	GroupName   string       `json:"group-name"`
+	Parent      string       `json:"parent,omitempty"`
+	Subgroups   []string     `json:"subgroups,omitempty"`
+	Snaps       []string     `json:"snaps,omitempty"`
+	Constraints *QuotaValues `json:"constraints,omitempty"`
+	Current     *QuotaUsage  `json:"current,omitempty"`
+}
+
+type postQuotaData struct {
+	Action      string       `json:"action"`
+	GroupName   string       `json:"group-name"`
+	Parent      string       `json:"parent,omitempty"`
+	Snaps       []string     `json:"snaps,omitempty"`
+	Constraints *QuotaValues `json:"constraints,omitempty"`
+}
+
+func (client *Client) postQuota(data *postQuotaData) (changeID string, err error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return "", err
+	}
+	return client.doAsync("POST", "/v2/quotas", nil, nil, &body)
+}
+
+// EnsureQuota creates the quota group with the given name if it doesn't
+// exist yet, or updates it otherwise. The given snaps are added to the
+// group, zero limits leave the current ones alone. It returns the ID of
+// the change doing it.
+func (client *Client) EnsureQuota(groupName string, parent string, snaps []string, limits *QuotaValues) (changeID string, err error) {
+	if groupName == "" {
+		return "", fmt.Errorf("cannot create or update quota group without a name")
+	}
+	return client.postQuota(&postQuotaData{
+		Action:      "ensure",
+		GroupName:   groupName,
+		Parent:      parent,
+		Snaps:       snaps,
+		Constraints: limits,
+	})
+}
+
+// RemoveQuotaGroup removes the quota group with the given name. It
+// returns the ID of the change doing it.
+func (client *Client) RemoveQuotaGroup(groupName string) (changeID string, err error) {
+	if groupName == "" {
+		return "", fmt.Errorf("cannot remove quota group without a name")
+	}
+	return client.postQuota(&postQuotaData{
+		Action:    "remove",
+		GroupName: groupName,
+	})
+}
+
+// GetQuotaGroup returns the quota group with the given name, including
+// its current resource usage.
+func (client *Client) GetQuotaGroup(groupName string) (*QuotaGroupResult, error) {
+	if groupName == "" {
+		return nil, fmt.Errorf("cannot get quota group without a name")
+	}
+	var res *QuotaGroupResult
+	if _, err := client.doSync("GET", "/v2/quotas/"+url.PathEscape(groupName), nil, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Quotas returns all the quota groups, including their current resource
+// usage.
+func (client *Client) Quotas() ([]*QuotaGroupResult, error) {
+	var res []*QuotaGroupResult
+	if _, err := client.doSync("GET", "/v2/quotas", nil, nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
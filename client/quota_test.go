@@ -0,0 +1,125 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestEnsureQuota(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{"type": "async", "status-code": 202, "change": "42"}`
+	chgID, err := cs.cli.EnsureQuota("foo", "bar", []string{"snap-a", "snap-b"}, &client.QuotaValues{Memory: 1024, CPU: 50})
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action":      "ensure",
+		"group-name":  "foo",
+		"parent":      "bar",
+		"snaps":       []interface{}{"snap-a", "snap-b"},
+		"constraints": map[string]interface{}{"memory": 1024.0, "cpu": 50.0},
+	})
+}
+
+func (cs *clientSuite) TestEnsureQuotaNoName(c *check.C) {
+	_, err := cs.cli.EnsureQuota("", "", nil, nil)
+	c.Check(err, check.ErrorMatches, "cannot create or update quota group without a name")
+}
+
+func (cs *clientSuite) TestRemoveQuotaGroup(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{"type": "async", "status-code": 202, "change": "42"}`
+	chgID, err := cs.cli.RemoveQuotaGroup("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action":     "remove",
+		"group-name": "foo",
+	})
+}
+
+func (cs *clientSuite) TestGetQuotaGroup(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"group-name": "foo",
+			"parent": "bar",
+			"subgroups": ["baz"],
+			"snaps": ["snap-a"],
+			"constraints": {"memory": 1024, "cpu": 50},
+			"current": {"memory": 512, "cpu-time": 1500000000}
+		}
+	}`
+	grp, err := cs.cli.GetQuotaGroup("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas/foo")
+	c.Check(grp, check.DeepEquals, &client.QuotaGroupResult{
+		GroupName:   "foo",
+		Parent:      "bar",
+		Subgroups:   []string{"baz"},
+		Snaps:       []string{"snap-a"},
+		Constraints: &client.QuotaValues{Memory: 1024, CPU: 50},
+		Current:     &client.QuotaUsage{Memory: 512, CPUTime: 1500 * time.Millisecond},
+	})
+}
+
+func (cs *clientSuite) TestQuotas(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{"group-name": "foo"}, {"group-name": "bar", "parent": "foo"}]
+	}`
+	grps, err := cs.cli.Quotas()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas")
+	c.Check(grps, check.DeepEquals, []*client.QuotaGroupResult{
+		{GroupName: "foo"},
+		{GroupName: "bar", Parent: "foo"},
+	})
+}
+
+func (cs *clientSuite) TestQuotasError(c *check.C) {
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "cannot find quota group \"foo\""}}`
+	_, err := cs.cli.GetQuotaGroup("foo")
+	c.Check(err, check.ErrorMatches, `cannot find quota group "foo"`)
+}
@@ -33,6 +33,10 @@ type SnapCtlOptions struct {
 
 	// Args contains a list of parameters to use for this invocation.
 	Args []string `json:"args"`
+
+	// Stdin contains data read by snapctl on behalf of the invoked
+	// command, such as the file given to set --file.
+	Stdin []byte `json:"stdin,omitempty"`
 }
 
 type snapctlOutput struct {
@@ -38,12 +38,37 @@ static void sc_context_free(context_t *ctx) {
     }
 }
 
+/**
+ * Compute the SELinux domain of the given security tag.
+ *
+ * This must match DomainType in interfaces/selinux: dots become two
+ * underscores, dashes become three and the _t suffix is appended.
+ **/
+static void sc_selinux_domain_type(const char *security_tag, char *buf, size_t buf_size) {
+    buf[0] = '\0';
+    for (const char *c = security_tag; *c != '\0'; c++) {
+        switch (*c) {
+            case '.':
+                sc_string_append(buf, buf_size, "__");
+                break;
+            case '-':
+                sc_string_append(buf, buf_size, "___");
+                break;
+            default:
+                sc_string_append_char(buf, buf_size, *c);
+                break;
+        }
+    }
+    sc_string_append(buf, buf_size, "_t");
+}
+
 /**
  * Set security context for the snap.
  *
- * Sets up SELinux context transition to unconfined_service_t.
+ * Sets up SELinux context transition to the domain of the security tag, when
+ * the policy module of the snap is loaded, or to unconfined_service_t.
  **/
-int sc_selinux_set_snap_execcon(void) {
+int sc_selinux_set_snap_execcon(const char *security_tag) {
     if (is_selinux_enabled() < 1) {
         debug("SELinux not enabled");
         return 0;
@@ -70,22 +95,35 @@ int sc_selinux_set_snap_execcon(void) {
     if (sc_streq(ctx_type, "snappy_confine_t")) {
         /* We are running under a targeted policy which ended up transitioning
          * to snappy_confine_t domain, at this point we are right before
-         * executing snap-exec. However we do not have a full SELinux support
-         * for services running in snaps, only the snapd bits and helpers are
-         * covered by the policy.
+         * executing snap-exec.
          *
-         * At this point transition to the unconfined_service_t domain (allowed
-         * by snap_confine_t policy) upon the next exec() call.
+         * Transition to the domain of the application or hook upon the next
+         * exec() call. The domain is defined by the policy module that snapd
+         * generates for the snap, when the module is not loaded, e.g. because
+         * the policy development tools are missing, transition to the
+         * unconfined_service_t domain (allowed by snap_confine_t policy)
+         * instead.
          */
-        if (context_type_set(ctx, "unconfined_service_t") != 0) {
-            die("cannot update SELinux context %s type to unconfined_service_t", ctx_str);
+        char domain[1024] = {0};
+        sc_selinux_domain_type(security_tag, domain, sizeof domain);
+        if (context_type_set(ctx, domain) != 0) {
+            die("cannot update SELinux context %s type to %s", ctx_str, domain);
         }
-
         /* freed by context_free(ctx) */
         char *new_ctx_str = context_str(ctx);
         if (new_ctx_str == NULL) {
             die("cannot obtain updated SELinux context string");
         }
+        if (security_check_context(new_ctx_str) != 0) {
+            debug("SELinux domain %s is not defined by the loaded policy", domain);
+            if (context_type_set(ctx, "unconfined_service_t") != 0) {
+                die("cannot update SELinux context %s type to unconfined_service_t", ctx_str);
+            }
+            new_ctx_str = context_str(ctx);
+            if (new_ctx_str == NULL) {
+                die("cannot obtain updated SELinux context string");
+            }
+        }
         if (setexeccon(new_ctx_str) < 0) {
             die("cannot set SELinux exec context to %s", new_ctx_str);
         }
@@ -20,8 +20,9 @@
 /**
  * Set security context for the snap
  *
- * Sets up SELinux context transition to unconfined_service_t.
+ * Sets up SELinux context transition to the domain of the security tag, when
+ * the policy module of the snap is loaded, or to unconfined_service_t.
  **/
-int sc_selinux_set_snap_execcon(void);
+int sc_selinux_set_snap_execcon(const char *security_tag);
 
 #endif /* SNAP_CONFINE_SELINUX_SUPPORT_H */
@@ -455,7 +455,7 @@ int main(int argc, char **argv)
 	sc_maybe_aa_change_onexec(&apparmor, invocation.security_tag);
 #ifdef HAVE_SELINUX
 	// For classic and confined snaps
-	sc_selinux_set_snap_execcon();
+	sc_selinux_set_snap_execcon(invocation.security_tag);
 #endif
 	if (snap_context != NULL) {
 		setenv("SNAP_COOKIE", snap_context, 1);
@@ -0,0 +1,230 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces/denials"
+)
+
+var denialsSuggest = denials.Suggest
+
+type cmdDebugDenials struct {
+	clientMixin
+	File       flags.Filename `long:"file"`
+	Positional struct {
+		Snap installedSnapName
+	} `positional-args:"yes"`
+}
+
+var longDebugDenialsHelp = i18n.G(`
+The denials command lists the accesses that the apparmor and seccomp
+sandboxes denied to the applications and hooks of snaps since boot, grouped
+by snap, along with the interfaces that would allow them when connected.
+
+The denials are collected from the audit log, or from the kernel and audit
+messages of the journal, which requires root. With --file, the denials are
+collected from the given log instead, for example one copied from another
+system, without asking snapd; use - to read it from the standard input.
+`)
+
+func init() {
+	addDebugCommand("denials",
+		i18n.G("List the sandbox denials of snaps"),
+		longDebugDenialsHelp,
+		func() flags.Commander {
+			return &cmdDebugDenials{}
+		}, map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"file": i18n.G("Collect the denials from the given log"),
+		}, []argDesc{{
+			name: "<snap>",
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Only list the denials of the given snap"),
+		}})
+}
+
+type sandboxDenial struct {
+	Kind          string   `json:"kind"`
+	Snap          string   `json:"snap"`
+	SecurityTag   string   `json:"security-tag"`
+	Operation     string   `json:"operation"`
+	Name          string   `json:"name"`
+	Mask          string   `json:"mask"`
+	Capability    string   `json:"capability"`
+	Family        string   `json:"family"`
+	SockType      string   `json:"sock-type"`
+	DBusBus       string   `json:"dbus-bus"`
+	DBusPath      string   `json:"dbus-path"`
+	DBusInterface string   `json:"dbus-interface"`
+	DBusMember    string   `json:"dbus-member"`
+	Syscall       string   `json:"syscall"`
+	Arch          string   `json:"arch"`
+	Count         int      `json:"count"`
+	Suggested     []string `json:"suggested-interfaces"`
+}
+
+func (d *sandboxDenial) description() string {
+	var desc string
+	switch {
+	case d.Kind == "seccomp":
+		desc = fmt.Sprintf("syscall %s", d.Syscall)
+		if d.Arch != "" {
+			desc += fmt.Sprintf(" (%s)", d.Arch)
+		}
+	case d.Capability != "":
+		desc = fmt.Sprintf("capability %s", d.Capability)
+	case d.Family != "":
+		desc = strings.TrimSpace(fmt.Sprintf("network %s %s", d.Family, d.SockType))
+	case strings.HasPrefix(d.Operation, "dbus"):
+		desc = fmt.Sprintf("dbus %s", d.Mask)
+		for _, field := range []struct{ key, value string }{
+			{"bus", d.DBusBus},
+			{"path", d.DBusPath},
+			{"interface", d.DBusInterface},
+			{"member", d.DBusMember},
+			{"name", d.Name},
+		} {
+			if field.value != "" {
+				desc += fmt.Sprintf(" %s=%s", field.key, field.value)
+			}
+		}
+	default:
+		desc = d.Operation
+		if d.Name != "" {
+			desc += fmt.Sprintf(" %q", d.Name)
+		}
+		if d.Mask != "" {
+			desc += fmt.Sprintf(" (%s)", d.Mask)
+		}
+	}
+	return fmt.Sprintf("%s: %s", d.Kind, desc)
+}
+
+// denialsFromFile collects the denials from the given log, and suggests
+// the interfaces that would allow them.
+func (x *cmdDebugDenials) denialsFromFile(snapName string) ([]*sandboxDenial, error) {
+	var r io.Reader = Stdin
+	if x.File != "-" {
+		f, err := os.Open(string(x.File))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	ds, err := denials.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	var result []*sandboxDenial
+	for _, d := range ds {
+		if snapName != "" && d.Snap != snapName {
+			continue
+		}
+		result = append(result, &sandboxDenial{
+			Kind:          d.Kind,
+			Snap:          d.Snap,
+			SecurityTag:   d.SecurityTag,
+			Operation:     d.Operation,
+			Name:          d.Name,
+			Mask:          d.Mask,
+			Capability:    d.Capability,
+			Family:        d.Family,
+			SockType:      d.SockType,
+			DBusBus:       d.DBusBus,
+			DBusPath:      d.DBusPath,
+			DBusInterface: d.DBusInterface,
+			DBusMember:    d.DBusMember,
+			Syscall:       d.Syscall,
+			Arch:          d.Arch,
+			Count:         d.Count,
+			Suggested:     denialsSuggest(d),
+		})
+	}
+	return result, nil
+}
+
+func (x *cmdDebugDenials) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	snapName := string(x.Positional.Snap)
+	var found []*sandboxDenial
+	if x.File != "" {
+		var err error
+		found, err = x.denialsFromFile(snapName)
+		if err != nil {
+			return err
+		}
+	} else {
+		params := map[string]string{"snap": snapName}
+		if err := x.client.DebugGet("denials", &found, params); err != nil {
+			return err
+		}
+	}
+
+	if len(found) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No denials found."))
+		return nil
+	}
+
+	// group the denials by snap and by application, keeping them in the
+	// order they were first recorded otherwise
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Snap != found[j].Snap {
+			return found[i].Snap < found[j].Snap
+		}
+		return found[i].SecurityTag < found[j].SecurityTag
+	})
+
+	w := Stdout
+	var lastSnap string
+	for _, d := range found {
+		if d.Snap != lastSnap {
+			fmt.Fprintf(w, "%s:\n", d.Snap)
+			lastSnap = d.Snap
+		}
+		tag := d.SecurityTag
+		if tag == "" {
+			tag = i18n.G("unknown application")
+		}
+		desc := d.description()
+		if d.Count > 1 {
+			desc += fmt.Sprintf(i18n.G(", %d times"), d.Count)
+		}
+		fmt.Fprintf(w, "  %s: %s\n", tag, desc)
+		if len(d.Suggested) > 0 {
+			fmt.Fprintf(w, "    %s %s\n", i18n.G("suggested interfaces:"), strings.Join(d.Suggested, ", "))
+		} else {
+			fmt.Fprintf(w, "    %s\n", i18n.G("no suggested interfaces"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/interfaces/denials"
+)
+
+const denialsJSON = `{"type": "sync", "result": [
+	{"kind": "apparmor", "snap": "foo", "security-tag": "snap.foo.app", "operation": "open", "name": "/etc/shadow", "mask": "r", "count": 2},
+	{"kind": "seccomp", "snap": "bar", "security-tag": "snap.bar.app", "syscall": "mount", "arch": "x86_64", "count": 1, "suggested-interfaces": ["mount-control"]},
+	{"kind": "apparmor", "snap": "foo", "security-tag": "snap.foo.app", "operation": "capable", "capability": "net_admin", "count": 1, "suggested-interfaces": ["firewall-control", "network-control"]},
+	{"kind": "apparmor", "snap": "foo", "security-tag": "snap.foo.app", "operation": "create", "family": "bluetooth", "sock-type": "raw", "mask": "create", "count": 1, "suggested-interfaces": ["bluetooth-control"]},
+	{"kind": "apparmor", "snap": "foo", "security-tag": "snap.foo.app", "operation": "dbus_method_call", "name": "org.freedesktop.NetworkManager", "mask": "send", "dbus-bus": "system", "dbus-path": "/org/freedesktop/NetworkManager", "dbus-interface": "org.freedesktop.DBus.Properties", "dbus-member": "GetAll", "count": 1, "suggested-interfaces": ["network-manager"]},
+	{"kind": "seccomp", "snap": "baz", "syscall": "999", "arch": "x86_64", "count": 1}
+]}`
+
+const denialsOutput = `bar:
+  snap.bar.app: seccomp: syscall mount (x86_64)
+    suggested interfaces: mount-control
+baz:
+  unknown application: seccomp: syscall 999 (x86_64)
+    no suggested interfaces
+foo:
+  snap.foo.app: apparmor: open "/etc/shadow" (r), 2 times
+    no suggested interfaces
+  snap.foo.app: apparmor: capability net_admin
+    suggested interfaces: firewall-control, network-control
+  snap.foo.app: apparmor: network bluetooth raw
+    suggested interfaces: bluetooth-control
+  snap.foo.app: apparmor: dbus send bus=system path=/org/freedesktop/NetworkManager interface=org.freedesktop.DBus.Properties member=GetAll name=org.freedesktop.NetworkManager
+    suggested interfaces: network-manager
+`
+
+func (s *SnapSuite) TestDebugDenials(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query().Get("aspect"), check.Equals, "denials")
+			c.Check(r.URL.Query().Get("snap"), check.Equals, "")
+			fmt.Fprintln(w, denialsJSON)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, denialsOutput)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugDenialsSnap(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Query().Get("aspect"), check.Equals, "denials")
+		c.Check(r.URL.Query().Get("snap"), check.Equals, "foo")
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No denials found.\n")
+}
+
+func (s *SnapSuite) TestDebugDenialsFile(c *check.C) {
+	const log = `audit: type=1326 audit(1611232546.106:1239): subj=snap.bar.app syscall=mount
+audit: type=1400 audit(1611232542.106:1234): apparmor="DENIED" operation="open" profile="snap.foo.app" name="/etc/shadow" requested_mask="r" denied_mask="r"
+`
+	logFile := filepath.Join(c.MkDir(), "audit.log")
+	c.Assert(ioutil.WriteFile(logFile, []byte(log), 0644), check.IsNil)
+
+	restore := snap.MockDenialsSuggest(func(d *denials.Denial) []string {
+		if d.Kind == denials.KindSecComp {
+			return []string{"mount-control"}
+		}
+		return nil
+	})
+	defer restore()
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("the log should not be sent to snapd")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "--file", logFile, "bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `bar:
+  snap.bar.app: seccomp: syscall mount
+    suggested interfaces: mount-control
+`)
+
+	s.ResetStdStreams()
+	s.stdin.WriteString(log)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "--file", "-"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `bar:
+  snap.bar.app: seccomp: syscall mount
+    suggested interfaces: mount-control
+foo:
+  snap.foo.app: apparmor: open "/etc/shadow" (r)
+    no suggested interfaces
+`)
+}
+
+func (s *SnapSuite) TestDebugDenialsErrors(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "foo", "extra"})
+	c.Assert(err, check.ErrorMatches, "too many arguments for command")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "denials", "--file", "/does/not/exist"})
+	c.Assert(err, check.ErrorMatches, "open /does/not/exist: no such file or directory")
+}
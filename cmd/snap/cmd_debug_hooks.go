@@ -0,0 +1,143 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugHooks struct {
+	clientMixin
+	timeMixin
+	Hook       string `long:"hook"`
+	Verbose    bool   `long:"verbose"`
+	Positional struct {
+		Snap installedSnapName `required:"yes"`
+	} `positional-args:"yes"`
+}
+
+var longDebugHooksHelp = i18n.G(`
+The hooks command lists the recent runs of the hooks of a snap, including
+when they ran, for how long, how they finished and which change triggered
+them. With --verbose the captured output of every run is shown as well.
+
+Runs are forgotten when the snap is removed. The runs of a failed install
+are kept, so that it can be looked into after the fact.
+`)
+
+func init() {
+	addDebugCommand("hooks",
+		i18n.G("List the recent hook runs of a snap"),
+		longDebugHooksHelp,
+		func() flags.Commander {
+			return &cmdDebugHooks{}
+		}, timeDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"hook": i18n.G("Only list the runs of the given hook"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"verbose": i18n.G("Show the captured output of every run"),
+		}), []argDesc{{
+			name: "<snap>",
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The snap whose hook runs are being requested"),
+		}})
+}
+
+func hookRunStatus(run *client.HookRun) string {
+	if run.Error != "" {
+		return run.Error
+	}
+	return "ok"
+}
+
+func (x *cmdDebugHooks) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	snapName := string(x.Positional.Snap)
+	allRuns, err := x.client.SnapHookRuns(snapName)
+	if err != nil {
+		return err
+	}
+	runs := make([]*client.HookRun, 0, len(allRuns))
+	for _, run := range allRuns {
+		if x.Hook == "" || run.Hook == x.Hook {
+			runs = append(runs, run)
+		}
+	}
+	if len(runs) == 0 {
+		if x.Hook != "" {
+			fmt.Fprintf(Stderr, i18n.G("No runs of hook %q of snap %q are recorded.\n"), x.Hook, snapName)
+		} else {
+			fmt.Fprintf(Stderr, i18n.G("No hook runs of snap %q are recorded.\n"), snapName)
+		}
+		return nil
+	}
+
+	if x.Verbose {
+		x.showVerbose(runs)
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Hook\tRev\tStarted\tDuration\tStatus\tChange"))
+	for _, run := range runs {
+		changeID := run.ChangeID
+		if changeID == "" {
+			changeID = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", run.Hook, run.Revision, x.fmtTime(run.Start),
+			run.End.Sub(run.Start).Round(time.Millisecond), hookRunStatus(run), changeID)
+	}
+	return nil
+}
+
+func (x *cmdDebugHooks) showVerbose(runs []*client.HookRun) {
+	for i, run := range runs {
+		if i > 0 {
+			fmt.Fprintln(Stdout, "---")
+		}
+		fmt.Fprintf(Stdout, "hook:      %s\n", run.Hook)
+		fmt.Fprintf(Stdout, "revision:  %s\n", run.Revision)
+		if run.ChangeID != "" {
+			fmt.Fprintf(Stdout, "change:    %s\n", run.ChangeID)
+		}
+		fmt.Fprintf(Stdout, "started:   %s\n", x.fmtTime(run.Start))
+		fmt.Fprintf(Stdout, "duration:  %s\n", run.End.Sub(run.Start).Round(time.Millisecond))
+		fmt.Fprintf(Stdout, "status:    %s\n", hookRunStatus(run))
+		if run.Output == "" {
+			continue
+		}
+		fmt.Fprintln(Stdout, "output: |")
+		for _, line := range strings.Split(strings.TrimRight(run.Output, "\n"), "\n") {
+			fmt.Fprintf(Stdout, "  %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const hookRunsJSON = `{"type": "sync", "result": [{
+	"hook": "install",
+	"revision": "3",
+	"change-id": "12",
+	"start": "2019-10-01T12:00:00Z",
+	"end": "2019-10-01T12:01:00Z",
+	"exit-status": 1,
+	"error": "exit status 1",
+	"output": "setting up\ncannot frobnicate\n"
+}, {
+	"hook": "configure",
+	"revision": "3",
+	"start": "2019-10-01T12:02:00Z",
+	"end": "2019-10-01T12:02:01.5Z",
+	"exit-status": 0
+}]}`
+
+func (s *SnapSuite) mockHookRuns(c *check.C, result string) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo/hooks")
+			fmt.Fprintln(w, result)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestDebugHooks(c *check.C) {
+	n := s.mockHookRuns(c, hookRunsJSON)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "hooks", "--abs-time", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `Hook       Rev  Started               Duration  Status         Change
+install    3    2019-10-01T12:00:00Z  1m0s      exit status 1  12
+configure  3    2019-10-01T12:02:00Z  1.5s      ok             -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugHooksVerbose(c *check.C) {
+	s.mockHookRuns(c, hookRunsJSON)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "hooks", "--abs-time", "--verbose", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `hook:      install
+revision:  3
+change:    12
+started:   2019-10-01T12:00:00Z
+duration:  1m0s
+status:    exit status 1
+output: |
+  setting up
+  cannot frobnicate
+---
+hook:      configure
+revision:  3
+started:   2019-10-01T12:02:00Z
+duration:  1.5s
+status:    ok
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugHooksFiltered(c *check.C) {
+	s.mockHookRuns(c, hookRunsJSON)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "hooks", "--abs-time", "--hook=configure", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `Hook       Rev  Started               Duration  Status  Change
+configure  3    2019-10-01T12:02:00Z  1.5s      ok      -
+`)
+}
+
+func (s *SnapSuite) TestDebugHooksNone(c *check.C) {
+	s.mockHookRuns(c, `{"type": "sync", "result": []}`)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "hooks", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No hook runs of snap \"foo\" are recorded.\n")
+}
@@ -0,0 +1,156 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugPolicy struct {
+	clientMixin
+	Interface  string `long:"interface"`
+	Positional struct {
+		Snap installedSnapName `required:"yes"`
+	} `positional-args:"yes"`
+}
+
+var longDebugPolicyHelp = i18n.G(`
+The policy command explains the outcome of the declaration based policy
+checks for the plugs and slots of a snap: whether their installation is
+allowed, and whether they can be connected, manually and automatically, to
+the slots and plugs of the same interface of all the installed snaps.
+
+For every check the evaluated rules of the base-declaration and of the
+snap-declarations are listed in order, with the reason why their
+constraints did not match.
+`)
+
+func init() {
+	addDebugCommand("policy",
+		i18n.G("Explain the policy checks for the interfaces of a snap"),
+		longDebugPolicyHelp,
+		func() flags.Commander {
+			return &cmdDebugPolicy{}
+		}, map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"interface": i18n.G("Only show the checks for the given interface"),
+		}, []argDesc{{
+			name: "<snap>",
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The snap whose policy checks are being explained"),
+		}})
+}
+
+type policyTraceStep struct {
+	Declaration string `json:"declaration"`
+	Snap        string `json:"snap"`
+	Side        string `json:"side"`
+	Name        string `json:"name"`
+	Interface   string `json:"interface"`
+	Constraints string `json:"constraints"`
+	Matched     bool   `json:"matched"`
+	Reason      string `json:"reason"`
+}
+
+type policyCheck struct {
+	Allowed bool              `json:"allowed"`
+	Error   string            `json:"error"`
+	Skipped string            `json:"skipped"`
+	Trace   []policyTraceStep `json:"trace"`
+}
+
+type snapPolicy struct {
+	Snap         string `json:"snap"`
+	Installation []struct {
+		Side      string      `json:"side"`
+		Name      string      `json:"name"`
+		Interface string      `json:"interface"`
+		Check     policyCheck `json:"check"`
+	} `json:"installation"`
+	Connections []struct {
+		Plug           string      `json:"plug"`
+		Slot           string      `json:"slot"`
+		Interface      string      `json:"interface"`
+		Connection     policyCheck `json:"connection"`
+		AutoConnection policyCheck `json:"auto-connection"`
+	} `json:"connections"`
+}
+
+func printPolicyCheck(w io.Writer, indent, what string, check *policyCheck) {
+	switch {
+	case check.Skipped != "":
+		fmt.Fprintf(w, "%s%s: allowed, not checked: %s\n", indent, what, check.Skipped)
+	case check.Allowed:
+		fmt.Fprintf(w, "%s%s: allowed\n", indent, what)
+	default:
+		fmt.Fprintf(w, "%s%s: not allowed: %s\n", indent, what, check.Error)
+	}
+	if len(check.Trace) == 0 && check.Skipped == "" && check.Allowed {
+		fmt.Fprintf(w, "%s  - no rules for the interface\n", indent)
+	}
+	for _, step := range check.Trace {
+		decl := step.Declaration
+		if step.Snap != "" {
+			decl = fmt.Sprintf("%s of %q", step.Declaration, step.Snap)
+		}
+		outcome := "matched"
+		if !step.Matched {
+			outcome = "not matched: " + step.Reason
+		}
+		fmt.Fprintf(w, "%s  - %s, %s rule, %s: %s\n", indent, decl, step.Side, step.Constraints, outcome)
+	}
+}
+
+func (x *cmdDebugPolicy) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var sp snapPolicy
+	params := map[string]string{"snap": string(x.Positional.Snap)}
+	if err := x.client.DebugGet("policy", &sp, params); err != nil {
+		return err
+	}
+
+	w := Stdout
+	fmt.Fprintf(w, "installation:\n")
+	for _, ip := range sp.Installation {
+		if x.Interface != "" && ip.Interface != x.Interface {
+			continue
+		}
+		what := fmt.Sprintf("%s %s:%s (%s)", ip.Side, sp.Snap, ip.Name, ip.Interface)
+		printPolicyCheck(w, "  ", what, &ip.Check)
+	}
+	fmt.Fprintf(w, "connections:\n")
+	for _, cp := range sp.Connections {
+		if x.Interface != "" && cp.Interface != x.Interface {
+			continue
+		}
+		fmt.Fprintf(w, "  %s -> %s (%s):\n", cp.Plug, cp.Slot, cp.Interface)
+		printPolicyCheck(w, "    ", "connection", &cp.Connection)
+		printPolicyCheck(w, "    ", "auto-connection", &cp.AutoConnection)
+	}
+	return nil
+}
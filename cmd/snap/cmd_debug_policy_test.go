@@ -0,0 +1,130 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const policyJSON = `{"type": "sync", "result": {
+	"snap": "consumer",
+	"installation": [
+		{"side": "plug", "name": "plug", "interface": "test", "check": {"allowed": true}},
+		{"side": "slot", "name": "feed", "interface": "other", "check": {
+			"allowed": false,
+			"error": "installation not allowed by \"feed\" slot rule of interface \"other\"",
+			"trace": [
+				{"declaration": "base-declaration", "side": "slot", "name": "feed", "interface": "other", "constraints": "deny-installation", "matched": false, "reason": "on-classic mismatch"},
+				{"declaration": "base-declaration", "side": "slot", "name": "feed", "interface": "other", "constraints": "allow-installation", "matched": false, "reason": "snap type does not match"}
+			]
+		}}
+	],
+	"connections": [
+		{"plug": "consumer:plug", "slot": "producer:slot", "interface": "test",
+		 "connection": {
+			"allowed": false,
+			"error": "connection not allowed by slot rule of interface \"test\" for \"producer\" snap",
+			"trace": [
+				{"declaration": "snap-declaration", "snap": "producer", "side": "slot", "name": "slot", "interface": "test", "constraints": "deny-connection", "matched": false, "reason": "publisher id does not match"},
+				{"declaration": "snap-declaration", "snap": "producer", "side": "slot", "name": "slot", "interface": "test", "constraints": "allow-connection", "matched": false, "reason": "on-store mismatch"}
+			]
+		 },
+		 "auto-connection": {
+			"allowed": true,
+			"trace": [
+				{"declaration": "base-declaration", "side": "slot", "name": "slot", "interface": "test", "constraints": "deny-auto-connection", "matched": false, "reason": "slot attribute \"x\" not found"},
+				{"declaration": "base-declaration", "side": "slot", "name": "slot", "interface": "test", "constraints": "allow-auto-connection", "matched": true}
+			]
+		 }
+		},
+		{"plug": "consumer:plug", "slot": "dangerous:slot", "interface": "test",
+		 "connection": {"allowed": true, "skipped": "snap \"dangerous\" has no snap-declaration, it was installed with --dangerous"},
+		 "auto-connection": {"allowed": true}
+		}
+	]
+}}`
+
+func (s *SnapSuite) mockDebugPolicy(c *check.C) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query().Get("aspect"), check.Equals, "policy")
+			c.Check(r.URL.Query().Get("snap"), check.Equals, "consumer")
+			fmt.Fprintln(w, policyJSON)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestDebugPolicy(c *check.C) {
+	n := s.mockDebugPolicy(c)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "policy", "consumer"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `installation:
+  plug consumer:plug (test): allowed
+    - no rules for the interface
+  slot consumer:feed (other): not allowed: installation not allowed by "feed" slot rule of interface "other"
+    - base-declaration, slot rule, deny-installation: not matched: on-classic mismatch
+    - base-declaration, slot rule, allow-installation: not matched: snap type does not match
+connections:
+  consumer:plug -> producer:slot (test):
+    connection: not allowed: connection not allowed by slot rule of interface "test" for "producer" snap
+      - snap-declaration of "producer", slot rule, deny-connection: not matched: publisher id does not match
+      - snap-declaration of "producer", slot rule, allow-connection: not matched: on-store mismatch
+    auto-connection: allowed
+      - base-declaration, slot rule, deny-auto-connection: not matched: slot attribute "x" not found
+      - base-declaration, slot rule, allow-auto-connection: matched
+  consumer:plug -> dangerous:slot (test):
+    connection: allowed, not checked: snap "dangerous" has no snap-declaration, it was installed with --dangerous
+    auto-connection: allowed
+      - no rules for the interface
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugPolicyInterface(c *check.C) {
+	s.mockDebugPolicy(c)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "policy", "--interface", "other", "consumer"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `installation:
+  slot consumer:feed (other): not allowed: installation not allowed by "feed" slot rule of interface "other"
+    - base-declaration, slot rule, deny-installation: not matched: on-classic mismatch
+    - base-declaration, slot rule, allow-installation: not matched: snap type does not match
+connections:
+`)
+}
+
+func (s *SnapSuite) TestDebugPolicyExtraArgs(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "policy", "consumer", "extra"})
+	c.Assert(err, check.ErrorMatches, "too many arguments for command")
+}
@@ -0,0 +1,163 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
+)
+
+type cmdDebugSandbox struct {
+	clientMixin
+	Hook       string `long:"hook"`
+	Backend    string `long:"backend"`
+	Positional struct {
+		SnapApp installedSnapName `required:"yes"`
+	} `positional-args:"yes"`
+}
+
+var longDebugSandboxHelp = i18n.G(`
+The sandbox command shows the security profiles generated for a snap:
+apparmor and seccomp profiles, D-Bus policy, udev rules, mount profiles,
+kernel modules and systemd services.
+
+Every profile starts with the snippets that the interfaces contribute to it,
+each annotated with the plug or slot, and the connection if any, whose
+interface contributed it. The complete profile, as written to disk, follows.
+When an application or a hook is given only its profiles and the profiles of
+the whole snap are shown.
+`)
+
+func init() {
+	addDebugCommand("sandbox",
+		i18n.G("Show the security profiles of a snap"),
+		longDebugSandboxHelp,
+		func() flags.Commander {
+			return &cmdDebugSandbox{}
+		}, map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"hook": i18n.G("Only show the profiles of the given hook"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"backend": i18n.G("Only show the profiles of the given security backend"),
+		}, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<snap>.<app>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("The snap or application whose profiles are shown"),
+		}})
+}
+
+type snapSandbox struct {
+	Snap     string `json:"snap"`
+	Profiles []struct {
+		Backend     string `json:"backend"`
+		Name        string `json:"name"`
+		SecurityTag string `json:"security-tag"`
+		Path        string `json:"path"`
+		Content     string `json:"content"`
+		Snippets    []struct {
+			Interface string `json:"interface"`
+			Side      string `json:"side"`
+			Plug      string `json:"plug"`
+			Slot      string `json:"slot"`
+			Snippet   string `json:"snippet"`
+		} `json:"snippets"`
+	} `json:"profiles"`
+}
+
+func snippetSource(iface, side, plug, slot string) string {
+	if plug != "" && slot != "" {
+		if side == "plug" {
+			return fmt.Sprintf("connected plug %s (slot %s), interface %s", plug, slot, iface)
+		}
+		return fmt.Sprintf("connected slot %s (plug %s), interface %s", slot, plug, iface)
+	}
+	if side == "plug" {
+		return fmt.Sprintf("permanent plug %s, interface %s", plug, iface)
+	}
+	return fmt.Sprintf("permanent slot %s, interface %s", slot, iface)
+}
+
+func (x *cmdDebugSandbox) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	snapName := string(x.Positional.SnapApp)
+	var securityTag string
+	if l := strings.SplitN(snapName, ".", 2); len(l) == 2 {
+		snapName = l[0]
+		securityTag = snap.AppSecurityTag(l[0], l[1])
+	}
+	if x.Hook != "" {
+		if securityTag != "" {
+			return errors.New(i18n.G("cannot show the profiles of an application and of a hook at the same time"))
+		}
+		securityTag = snap.HookSecurityTag(snapName, x.Hook)
+	}
+
+	var sb snapSandbox
+	params := map[string]string{"snap": snapName}
+	if err := x.client.DebugGet("sandbox", &sb, params); err != nil {
+		return err
+	}
+
+	w := Stdout
+	for _, profile := range sb.Profiles {
+		if x.Backend != "" && profile.Backend != x.Backend {
+			continue
+		}
+		if securityTag != "" && profile.SecurityTag != "" && profile.SecurityTag != securityTag {
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s\n", profile.Backend, profile.Name)
+		var lastSource string
+		for _, snippet := range profile.Snippets {
+			source := snippetSource(snippet.Interface, snippet.Side, snippet.Plug, snippet.Slot)
+			if source != lastSource {
+				fmt.Fprintf(w, "  %s:\n", source)
+				lastSource = source
+			}
+			printIndented(w, snippet.Snippet)
+		}
+		switch {
+		case profile.Path == "":
+		case profile.Content == "":
+			fmt.Fprintf(w, "  %s: not present\n", profile.Path)
+		default:
+			fmt.Fprintf(w, "  %s:\n", profile.Path)
+			printIndented(w, profile.Content)
+		}
+	}
+	return nil
+}
+
+func printIndented(w io.Writer, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+}
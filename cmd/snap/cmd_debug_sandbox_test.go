@@ -0,0 +1,127 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const sandboxJSON = `{"type": "sync", "result": {
+	"snap": "consumer",
+	"profiles": [
+		{"backend": "apparmor", "name": "snap.consumer.app", "security-tag": "snap.consumer.app", "path": "/var/lib/snapd/apparmor/profiles/snap.consumer.app", "content": "profile \"snap.consumer.app\" {\n  /run/producer/** rw,\n}\n", "snippets": [
+			{"interface": "test", "side": "plug", "plug": "consumer:plug", "slot": "producer:slot", "snippet": "# access to producer:slot\n/run/producer/** rw,\n"},
+			{"interface": "test", "side": "plug", "plug": "consumer:plug", "slot": "producer:slot", "snippet": "/dev/test rw,"}
+		]},
+		{"backend": "apparmor", "name": "snap.consumer.hook.configure", "security-tag": "snap.consumer.hook.configure", "path": "/var/lib/snapd/apparmor/profiles/snap.consumer.hook.configure", "content": "", "snippets": [
+			{"interface": "test", "side": "plug", "plug": "consumer:plug", "slot": "producer:slot", "snippet": "# access to producer:slot"}
+		]},
+		{"backend": "kmod", "name": "snap.consumer.conf", "snippets": [
+			{"interface": "other", "side": "slot", "slot": "consumer:feed", "snippet": "test-module"}
+		]}
+	]
+}}`
+
+func (s *SnapSuite) mockDebugSandbox(c *check.C) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/debug")
+			c.Check(r.URL.Query().Get("aspect"), check.Equals, "sandbox")
+			c.Check(r.URL.Query().Get("snap"), check.Equals, "consumer")
+			fmt.Fprintln(w, sandboxJSON)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestDebugSandbox(c *check.C) {
+	n := s.mockDebugSandbox(c)
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox", "consumer"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `apparmor: snap.consumer.app
+  connected plug consumer:plug (slot producer:slot), interface test:
+    # access to producer:slot
+    /run/producer/** rw,
+    /dev/test rw,
+  /var/lib/snapd/apparmor/profiles/snap.consumer.app:
+    profile "snap.consumer.app" {
+      /run/producer/** rw,
+    }
+apparmor: snap.consumer.hook.configure
+  connected plug consumer:plug (slot producer:slot), interface test:
+    # access to producer:slot
+  /var/lib/snapd/apparmor/profiles/snap.consumer.hook.configure: not present
+kmod: snap.consumer.conf
+  permanent slot consumer:feed, interface other:
+    test-module
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugSandboxApp(c *check.C) {
+	s.mockDebugSandbox(c)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox", "consumer.app"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `apparmor: snap.consumer.app
+  connected plug consumer:plug (slot producer:slot), interface test:
+    # access to producer:slot
+    /run/producer/** rw,
+    /dev/test rw,
+  /var/lib/snapd/apparmor/profiles/snap.consumer.app:
+    profile "snap.consumer.app" {
+      /run/producer/** rw,
+    }
+kmod: snap.consumer.conf
+  permanent slot consumer:feed, interface other:
+    test-module
+`)
+}
+
+func (s *SnapSuite) TestDebugSandboxHookAndBackend(c *check.C) {
+	s.mockDebugSandbox(c)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox", "--hook", "configure", "--backend", "apparmor", "consumer"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `apparmor: snap.consumer.hook.configure
+  connected plug consumer:plug (slot producer:slot), interface test:
+    # access to producer:slot
+  /var/lib/snapd/apparmor/profiles/snap.consumer.hook.configure: not present
+`)
+}
+
+func (s *SnapSuite) TestDebugSandboxErrors(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox", "consumer", "extra"})
+	c.Assert(err, check.ErrorMatches, "too many arguments for command")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "sandbox", "--hook", "configure", "consumer.app"})
+	c.Assert(err, check.ErrorMatches, "cannot show the profiles of an application and of a hook at the same time")
+}
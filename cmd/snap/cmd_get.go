@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
 
 	"github.com/snapcore/snapd/i18n"
 )
@@ -48,10 +49,31 @@ Nested values may be retrieved via a dotted path:
 
     $ snap get snap-name author.name
     frank
+
+The document printed with -d may be formatted as YAML instead of JSON with
+--yaml, in which form it can be passed back to 'snap set --file'.
+
+The configuration schema declared by the snap, if any, is printed with
+--schema.
+
+The recent configuration changes of the snap are listed with --history;
+they can be reverted with 'snap set --rollback'.
+
+System options are available via the "system" snap. For instance,
+journal.persistent tells whether the logs are kept across reboots in
+/var/log/journal, when unset the journal is left as the image set it up,
+and journal.max-size how much disk space the journal may use at most:
+
+    $ snap set system journal.persistent=true journal.max-size=100M
+    $ snap get system journal
+    Key                 Value
+    journal.max-size    100M
+    journal.persistent  true
 `)
 
 type cmdGet struct {
 	clientMixin
+	timeMixin
 	Positional struct {
 		Snap installedSnapName `required:"yes"`
 		Keys []string
@@ -60,18 +82,27 @@ type cmdGet struct {
 	Typed    bool `short:"t"`
 	Document bool `short:"d"`
 	List     bool `short:"l"`
+	Schema   bool `long:"schema"`
+	History  bool `long:"history"`
+	Yaml     bool `long:"yaml"`
 }
 
 func init() {
 	addCommand("get", shortGetHelp, longGetHelp, func() flags.Commander { return &cmdGet{} },
-		map[string]string{
+		timeDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"d": i18n.G("Always return document, even with single key"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"l": i18n.G("Always return list, even with single key"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"t": i18n.G("Strict typing with nulls and quoted strings"),
-		}, []argDesc{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"schema": i18n.G("Print the configuration schema of the snap"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"history": i18n.G("Print the recent configuration changes of the snap"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"yaml": i18n.G("Print the document as YAML rather than JSON"),
+		}), []argDesc{
 			{
 				name: "<snap>",
 				// TRANSLATORS: This should not start with a lowercase letter.
@@ -159,6 +190,46 @@ func (c *cmdGet) outputJson(conf interface{}) error {
 	return nil
 }
 
+// yamlValue converts the numbers decoded from JSON so that they are
+// not marshalled as strings in YAML.
+func yamlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return string(v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, vv := range v {
+			m[k] = yamlValue(vv)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, vv := range v {
+			l[i] = yamlValue(vv)
+		}
+		return l
+	}
+	return v
+}
+
+// outputYaml will be used when the user requested "document" output
+// as YAML via the "--yaml" commandline switch.
+func (c *cmdGet) outputYaml(conf interface{}) error {
+	bytes, err := yaml.Marshal(yamlValue(conf))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(Stdout, string(bytes))
+	return nil
+}
+
 // outputList will be used when the user requested list output via the
 // "-l" commandline switch.
 func (x *cmdGet) outputList(conf map[string]interface{}) error {
@@ -225,6 +296,68 @@ func (x *cmdGet) outputDefault(conf map[string]interface{}, snapName string, con
 
 }
 
+// outputSchema will be used when the user requested the configuration
+// schema via the "--schema" commandline switch.
+func (x *cmdGet) outputSchema(snapName string, confKeys []string) error {
+	if len(confKeys) > 0 {
+		return fmt.Errorf("cannot use --schema with configuration keys")
+	}
+	if x.Typed || x.List {
+		return fmt.Errorf("cannot use --schema together with -t or -l")
+	}
+
+	schema, err := x.client.ConfSchema(snapName)
+	if err != nil {
+		return err
+	}
+	return x.outputJson(schema)
+}
+
+func fmtConfValue(v interface{}) string {
+	if v == nil {
+		return "-"
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bytes)
+}
+
+// outputHistory will be used when the user requested the configuration
+// history via the "--history" commandline switch.
+func (x *cmdGet) outputHistory(snapName string, confKeys []string) error {
+	if len(confKeys) > 0 {
+		return fmt.Errorf("cannot use --history with configuration keys")
+	}
+	if x.Typed || x.List || x.Document || x.Yaml || x.Schema {
+		return fmt.Errorf("cannot use --history together with -d, -t, -l, --yaml or --schema")
+	}
+
+	history, err := x.client.ConfHistory(snapName)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return fmt.Errorf(i18n.G("snap %q has no configuration history"), snapName)
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintf(w, i18n.G("ID\tTime\tOrigin\tKey\tOld\tNew\n"))
+	for _, entry := range history {
+		for _, change := range entry.Changes {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", entry.ID, x.fmtTime(entry.Time), entry.Origin,
+				change.Key, fmtConfValue(change.Old), fmtConfValue(change.New))
+		}
+	}
+	return nil
+}
+
 func (x *cmdGet) Execute(args []string) error {
 	if len(args) > 0 {
 		// TRANSLATORS: the %s is the list of extra arguments
@@ -239,15 +372,29 @@ func (x *cmdGet) Execute(args []string) error {
 		return fmt.Errorf("cannot use -d and -l together")
 	}
 
+	if x.Yaml && (x.Typed || x.List) {
+		return fmt.Errorf("cannot use --yaml together with -t or -l")
+	}
+
 	snapName := string(x.Positional.Snap)
 	confKeys := x.Positional.Keys
 
+	if x.History {
+		return x.outputHistory(snapName, confKeys)
+	}
+
+	if x.Schema {
+		return x.outputSchema(snapName, confKeys)
+	}
+
 	conf, err := x.client.Conf(snapName, confKeys)
 	if err != nil {
 		return err
 	}
 
 	switch {
+	case x.Yaml:
+		return x.outputYaml(conf)
 	case x.Document:
 		return x.outputJson(conf)
 	case x.List:
@@ -90,6 +90,15 @@ var getTests = []getCmdArgs{{
 }, {
 	args:   "get -d snapname",
 	stdout: "{\n\t\"bar\": 100,\n\t\"foo\": {\n\t\t\"key1\": \"value1\",\n\t\t\"key2\": \"value2\"\n\t}\n}\n",
+}, {
+	args:   "get -d --yaml snapname",
+	stdout: "bar: 100\nfoo:\n  key1: value1\n  key2: value2\n",
+}, {
+	args:   "get --yaml snapname test-key1 test-key2",
+	stdout: "test-key1: test-value1\ntest-key2: 2\n",
+}, {
+	args:  "get --yaml -l snapname",
+	error: "cannot use --yaml together with -t or -l",
 }, {
 	isTerminal: true,
 	args:       "get snapname  test-key1 test-key2",
@@ -144,6 +153,80 @@ func (s *SnapSuite) TestSnapGetNoConfiguration(c *C) {
 	s.runTests(getNoConfigTests, c)
 }
 
+var getSchemaTests = []getCmdArgs{{
+	args:   "get --schema snapname",
+	stdout: "{\n\t\"properties\": {\n\t\t\"port\": {\n\t\t\t\"type\": \"integer\"\n\t\t}\n\t}\n}\n",
+}, {
+	args:   "get -d --schema snapname",
+	stdout: "{\n\t\"properties\": {\n\t\t\"port\": {\n\t\t\t\"type\": \"integer\"\n\t\t}\n\t}\n}\n",
+}, {
+	args:  "get --schema snapname port",
+	error: "cannot use --schema with configuration keys",
+}, {
+	args:  "get -l --schema snapname",
+	error: "cannot use --schema together with -t or -l",
+}, {
+	args:  "get --schema other-snap",
+	error: `snap "other-snap" has no configuration schema`,
+}}
+
+func (s *SnapSuite) TestSnapGetSchema(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Query().Get("schema"), Equals, "true")
+
+		switch r.URL.Path {
+		case "/v2/snaps/snapname/conf":
+			fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": {"properties": {"port": {"type": "integer"}}}}`)
+		case "/v2/snaps/other-snap/conf":
+			w.WriteHeader(404)
+			fmt.Fprintln(w, `{"type":"error", "status-code": 404, "result": {"message": "snap \"other-snap\" has no configuration schema"}}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+	s.runTests(getSchemaTests, c)
+}
+
+var getHistoryTests = []getCmdArgs{{
+	args: "get --history --abs-time snapname",
+	stdout: "ID   Time                  Origin  Key       Old    New\n" +
+		"1    2019-10-01T12:00:00Z  user    port      -      80\n" +
+		"1    2019-10-01T12:00:00Z  user    host.aux  -      {\"a\":1}\n" +
+		"2    2019-10-02T12:00:00Z  hook    port      80     8080\n" +
+		"3    2019-10-03T12:00:00Z  user    name      frank  -\n",
+}, {
+	args:  "get --history snapname port",
+	error: "cannot use --history with configuration keys",
+}, {
+	args:  "get --history -d snapname",
+	error: "cannot use --history together with -d, -t, -l, --yaml or --schema",
+}, {
+	args:  "get --history other-snap",
+	error: `snap "other-snap" has no configuration history`,
+}}
+
+func (s *SnapSuite) TestSnapGetHistory(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Query().Get("history"), Equals, "true")
+
+		switch r.URL.Path {
+		case "/v2/snaps/snapname/conf":
+			fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": [
+{"id": 1, "time": "2019-10-01T12:00:00Z", "origin": "user", "changes": [{"key": "port", "new": 80}, {"key": "host.aux", "new": {"a": 1}}]},
+{"id": 2, "time": "2019-10-02T12:00:00Z", "origin": "hook", "changes": [{"key": "port", "old": 80, "new": 8080}]},
+{"id": 3, "time": "2019-10-03T12:00:00Z", "origin": "user", "changes": [{"key": "name", "old": "frank"}]}
+]}`)
+		case "/v2/snaps/other-snap/conf":
+			fmt.Fprintln(w, `{"type":"sync", "status-code": 200, "result": []}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+	s.runTests(getHistoryTests, c)
+}
+
 func (s *SnapSuite) TestSortByPath(c *C) {
 	values := []snapset.ConfigValue{
 		{Path: "test-key3.b"},
@@ -193,6 +193,10 @@ var helpCategories = []helpCategory{
 		Label:       i18n.G("Daemons"),
 		Description: i18n.G("manage services"),
 		Commands:    []string{"services", "start", "stop", "restart", "logs"},
+	}, {
+		Label:       i18n.G("Quotas"),
+		Description: i18n.G("manage resource quota groups of snaps"),
+		Commands:    []string{"set-quota", "remove-quota", "quotas", "quota"},
 	}, {
 		Label:       i18n.G("Commands"),
 		Description: i18n.G("manage aliases"),
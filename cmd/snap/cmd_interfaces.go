@@ -21,9 +21,11 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
 
 	"github.com/jessevdk/go-flags"
 )
@@ -31,6 +33,7 @@ import (
 type cmdInterfaces struct {
 	clientMixin
 	Interface   string `short:"i"`
+	Hotplug     bool   `long:"hotplug"`
 	Positionals struct {
 		Query interfacesSlotOrPlugSpec `skip-help:"true"`
 	} `positional-args:"true"`
@@ -55,6 +58,11 @@ $ snap interfaces -i=<interface> [<snap>]
 Filters the complete output so only plugs and/or slots matching the provided
 details are listed.
 
+$ snap interfaces --hotplug
+
+Lists the slots created for hotplugged devices, including the slots of
+unplugged devices whose connections are restored when they re-appear.
+
 NOTE this command is deprecated and has been replaced with the 'connections'
      command.
 `)
@@ -65,6 +73,8 @@ func init() {
 	}, map[string]string{
 		// TRANSLATORS: This should not start with a lowercase letter.
 		"i": i18n.G("Constrain listing to specific interfaces"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"hotplug": i18n.G("List the slots of hotplugged devices"),
 	}, []argDesc{{
 		// TRANSLATORS: This needs to begin with < and end with >
 		name: i18n.G("<snap>:<slot or plug>"),
@@ -81,6 +91,10 @@ func (x *cmdInterfaces) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 
+	if x.Hotplug {
+		return x.showHotplug()
+	}
+
 	opts := client.ConnectionOptions{
 		All:  true,
 		Snap: x.Positionals.Query.Snap,
@@ -179,3 +193,42 @@ func (x *cmdInterfaces) Execute(args []string) error {
 	}
 	return nil
 }
+
+func (x *cmdInterfaces) showHotplug() error {
+	opts := client.ConnectionOptions{
+		Hotplug:   true,
+		Snap:      x.Positionals.Query.Snap,
+		Interface: x.Interface,
+	}
+	ifaces, err := x.client.Connections(&opts)
+	if err != nil {
+		return err
+	}
+	if len(ifaces.Slots) == 0 {
+		return fmt.Errorf(i18n.G("no hotplugged devices found"))
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+	fmt.Fprintln(w, i18n.G("Slot\tInterface\tHotplug key\tPlug\tNotes"))
+
+	for _, slot := range ifaces.Slots {
+		if x.Positionals.Query.Name != "" && x.Positionals.Query.Name != slot.Name {
+			continue
+		}
+		plugs := "-"
+		if len(slot.Connections) > 0 {
+			refs := make([]string, len(slot.Connections))
+			for i, plug := range slot.Connections {
+				refs[i] = fmt.Sprintf("%s:%s", plug.Snap, plug.Name)
+			}
+			plugs = strings.Join(refs, ",")
+		}
+		notes := "-"
+		if slot.HotplugGone {
+			notes = i18n.G("gone")
+		}
+		fmt.Fprintf(w, ":%s\t%s\t%s\t%s\t%s\n", slot.Name, slot.Interface, snap.HotplugKey(slot.HotplugKey).ShortString(), plugs, notes)
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ package main_test
 import (
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 
 	"github.com/jessevdk/go-flags"
@@ -673,3 +674,71 @@ func (s *SnapSuite) checkConnectionsSystemCoreRemapping(c *C, apiSnapName, cliSn
 	c.Assert(s.Stdout(), Equals, expectedStdout)
 	c.Assert(s.Stderr(), testutil.EqualsWrapped, InterfacesDeprecationNotice)
 }
+
+func (s *SnapSuite) TestInterfacesHotplug(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/connections")
+		c.Check(r.URL.Query(), DeepEquals, url.Values{
+			"select": []string{"hotplug"},
+		})
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type": "sync",
+			"result": client.Connections{
+				Slots: []client.Slot{
+					{
+						Snap:       "core",
+						Name:       "webcam",
+						Interface:  "camera",
+						HotplugKey: "0123456789abcdef0123456789abcdef",
+						Connections: []client.PlugRef{
+							{Snap: "vlc", Name: "camera"},
+							{Snap: "zoom", Name: "camera"},
+						},
+					},
+					{
+						Snap:        "core",
+						Name:        "dongle",
+						Interface:   "hidraw",
+						HotplugKey:  "fedcba9876543210fedcba9876543210",
+						HotplugGone: true,
+						Connections: []client.PlugRef{
+							{Snap: "keys", Name: "hidraw"},
+						},
+					},
+					{
+						Snap:       "core",
+						Name:       "usb-disk",
+						Interface:  "block-devices",
+						HotplugKey: "00112233445566778899aabbccddeeff",
+					},
+				},
+			},
+		})
+	})
+	rest, err := Parser(Client()).ParseArgs([]string{"interfaces", "--hotplug"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := "" +
+		"Slot       Interface      Hotplug key    Plug                    Notes\n" +
+		":webcam    camera         0123456789ab…  vlc:camera,zoom:camera  -\n" +
+		":dongle    hidraw         fedcba987654…  keys:hidraw             gone\n" +
+		":usb-disk  block-devices  001122334455…  -                       -\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestInterfacesHotplugNoDevices(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query(), DeepEquals, url.Values{
+			"select":    []string{"hotplug"},
+			"interface": []string{"camera"},
+		})
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type":   "sync",
+			"result": client.Connections{},
+		})
+	})
+	_, err := Parser(Client()).ParseArgs([]string{"interfaces", "--hotplug", "-i", "camera"})
+	c.Assert(err, ErrorMatches, "no hotplugged devices found")
+}
@@ -0,0 +1,296 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var shortSetQuotaHelp = i18n.G("Create or update a quota group")
+var longSetQuotaHelp = i18n.G(`
+The set-quota command creates a quota group with the given resource limits
+and adds the given snaps to it, or updates an existing quota group.
+
+Memory limits take a size like 512M or 2G, CPU limits a percentage of a
+single CPU like 50%. The services of all the snaps in a group, and of all
+the snaps in its sub-groups, share the resources of the group.
+
+The limits of an existing group can be raised but not removed, and its
+parent group can't be changed.
+`)
+
+var shortRemoveQuotaHelp = i18n.G("Remove a quota group")
+var longRemoveQuotaHelp = i18n.G(`
+The remove-quota command removes the given quota group. The snaps in the
+group are not removed, but their services are no longer restricted by it.
+`)
+
+var shortQuotaHelp = i18n.G("Show the details of a quota group")
+var longQuotaHelp = i18n.G(`
+The quota command shows the limits, the current resource usage, the snaps
+and the sub-groups of the given quota group.
+`)
+
+var shortQuotasHelp = i18n.G("List quota groups")
+var longQuotasHelp = i18n.G(`
+The quotas command lists all the quota groups along with their limits and
+current resource usage.
+`)
+
+type cmdSetQuota struct {
+	waitMixin
+
+	MemoryMax  string `long:"memory" optional:"true"`
+	CPUMax     string `long:"cpu" optional:"true"`
+	Parent     string `long:"parent" optional:"true"`
+	Positional struct {
+		GroupName string              `positional-arg-name:"<group-name>" required:"true"`
+		Snaps     []installedSnapName `positional-arg-name:"<snap>" optional:"true"`
+	} `positional-args:"yes"`
+}
+
+type cmdRemoveQuota struct {
+	waitMixin
+
+	Positional struct {
+		GroupName string `positional-arg-name:"<group-name>" required:"true"`
+	} `positional-args:"yes"`
+}
+
+type cmdQuota struct {
+	clientMixin
+
+	Positional struct {
+		GroupName string `positional-arg-name:"<group-name>" required:"true"`
+	} `positional-args:"yes"`
+}
+
+type cmdQuotas struct {
+	clientMixin
+}
+
+func init() {
+	addCommand("set-quota", shortSetQuotaHelp, longSetQuotaHelp,
+		func() flags.Commander { return &cmdSetQuota{} },
+		waitDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"memory": i18n.G("Memory limit for the quota group"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"cpu": i18n.G("CPU limit for the quota group, in percent of a single CPU"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"parent": i18n.G("Parent quota group of a newly created quota group"),
+		}), nil)
+	addCommand("remove-quota", shortRemoveQuotaHelp, longRemoveQuotaHelp,
+		func() flags.Commander { return &cmdRemoveQuota{} }, waitDescs, nil)
+	addCommand("quota", shortQuotaHelp, longQuotaHelp,
+		func() flags.Commander { return &cmdQuota{} }, nil, nil)
+	addCommand("quotas", shortQuotasHelp, longQuotasHelp,
+		func() flags.Commander { return &cmdQuotas{} }, nil, nil)
+}
+
+// binaryUnits are the single letter units accepted for memory limits,
+// which are powers of 1024 as is customary for memory sizes.
+var binaryUnits = map[string]uint64{
+	"K": 1024,
+	"M": 1024 * 1024,
+	"G": 1024 * 1024 * 1024,
+	"T": 1024 * 1024 * 1024 * 1024,
+}
+
+func parseQuotaMemory(inp string) (uint64, error) {
+	val, unit, err := strutil.SplitUnit(inp)
+	if err != nil {
+		return 0, fmt.Errorf(i18n.G("cannot parse memory limit %q: %v"), inp, err)
+	}
+	if val <= 0 {
+		return 0, fmt.Errorf(i18n.G("cannot parse memory limit %q: limit must be positive"), inp)
+	}
+	if unit == "" {
+		return uint64(val), nil
+	}
+	if mul, ok := binaryUnits[strings.ToUpper(unit)]; ok {
+		return uint64(val) * mul, nil
+	}
+	size, err := strutil.ParseByteSize(inp)
+	if err != nil {
+		return 0, fmt.Errorf(i18n.G("cannot parse memory limit %q: try 512M or 2G"), inp)
+	}
+	return uint64(size), nil
+}
+
+func parseQuotaCPU(inp string) (int, error) {
+	val, err := strconv.Atoi(strings.TrimSuffix(inp, "%"))
+	if err != nil || val <= 0 {
+		return 0, fmt.Errorf(i18n.G("cannot parse CPU limit %q: expected a positive percentage like 50%%"), inp)
+	}
+	return val, nil
+}
+
+func (x *cmdSetQuota) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var limits *client.QuotaValues
+	if x.MemoryMax != "" || x.CPUMax != "" {
+		limits = &client.QuotaValues{}
+	}
+	if x.MemoryMax != "" {
+		mem, err := parseQuotaMemory(x.MemoryMax)
+		if err != nil {
+			return err
+		}
+		limits.Memory = mem
+	}
+	if x.CPUMax != "" {
+		cpu, err := parseQuotaCPU(x.CPUMax)
+		if err != nil {
+			return err
+		}
+		limits.CPU = cpu
+	}
+
+	changeID, err := x.client.EnsureQuota(x.Positional.GroupName, x.Parent, installedSnapNames(x.Positional.Snaps), limits)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil && err != noWait {
+		return err
+	}
+	return nil
+}
+
+func (x *cmdRemoveQuota) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	changeID, err := x.client.RemoveQuotaGroup(x.Positional.GroupName)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil && err != noWait {
+		return err
+	}
+	return nil
+}
+
+func fmtQuotaConstraints(constraints *client.QuotaValues) string {
+	if constraints == nil {
+		return "-"
+	}
+	var parts []string
+	if constraints.Memory != 0 {
+		parts = append(parts, "memory="+strutil.SizeToStr(int64(constraints.Memory)))
+	}
+	if constraints.CPU != 0 {
+		parts = append(parts, fmt.Sprintf("cpu=%d%%", constraints.CPU))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ",")
+}
+
+func fmtQuotaUsage(current *client.QuotaUsage) string {
+	if current == nil || (current.Memory == 0 && current.CPUTime == 0) {
+		return "-"
+	}
+	return fmt.Sprintf("memory=%s,cpu-time=%s", strutil.SizeToStr(int64(current.Memory)), current.CPUTime)
+}
+
+func (x *cmdQuota) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	grp, err := x.client.GetQuotaGroup(x.Positional.GroupName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, "name: %s\n", grp.GroupName)
+	if grp.Parent != "" {
+		fmt.Fprintf(Stdout, "parent: %s\n", grp.Parent)
+	}
+	if grp.Constraints != nil && (grp.Constraints.Memory != 0 || grp.Constraints.CPU != 0) {
+		fmt.Fprintln(Stdout, "constraints:")
+		if grp.Constraints.Memory != 0 {
+			fmt.Fprintf(Stdout, "  memory: %s\n", strutil.SizeToStr(int64(grp.Constraints.Memory)))
+		}
+		if grp.Constraints.CPU != 0 {
+			fmt.Fprintf(Stdout, "  cpu: %d%%\n", grp.Constraints.CPU)
+		}
+	}
+	if grp.Current != nil {
+		fmt.Fprintln(Stdout, "current:")
+		fmt.Fprintf(Stdout, "  memory: %s\n", strutil.SizeToStr(int64(grp.Current.Memory)))
+		fmt.Fprintf(Stdout, "  cpu-time: %s\n", grp.Current.CPUTime)
+	}
+	if len(grp.Subgroups) > 0 {
+		fmt.Fprintln(Stdout, "subgroups:")
+		for _, name := range grp.Subgroups {
+			fmt.Fprintf(Stdout, "  - %s\n", name)
+		}
+	}
+	if len(grp.Snaps) > 0 {
+		fmt.Fprintln(Stdout, "snaps:")
+		for _, name := range grp.Snaps {
+			fmt.Fprintf(Stdout, "  - %s\n", name)
+		}
+	}
+	return nil
+}
+
+func (x *cmdQuotas) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	groups, err := x.client.Quotas()
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No quota groups defined."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Quota\tParent\tConstraints\tCurrent"))
+	for _, grp := range groups {
+		parent := grp.Parent
+		if parent == "" {
+			parent = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", grp.GroupName, parent, fmtQuotaConstraints(grp.Constraints), fmtQuotaUsage(grp.Current))
+	}
+	return nil
+}
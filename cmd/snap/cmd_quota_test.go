@@ -0,0 +1,213 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) mockQuotaPost(c *check.C, expected map[string]interface{}) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/quotas")
+			var body map[string]interface{}
+			c.Assert(json.NewDecoder(r.Body).Decode(&body), check.IsNil)
+			c.Check(body, check.DeepEquals, expected)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "status-code": 202, "change": "42"}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	return &n
+}
+
+func (s *SnapSuite) TestSetQuota(c *check.C) {
+	n := s.mockQuotaPost(c, map[string]interface{}{
+		"action":      "ensure",
+		"group-name":  "foo",
+		"parent":      "bar",
+		"snaps":       []interface{}{"snap-a", "snap-b"},
+		"constraints": map[string]interface{}{"memory": 512.0 * 1024 * 1024, "cpu": 50.0},
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"set-quota", "--memory=512M", "--cpu=50%", "--parent=bar", "foo", "snap-a", "snap-b"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(*n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestSetQuotaNoLimits(c *check.C) {
+	n := s.mockQuotaPost(c, map[string]interface{}{
+		"action":     "ensure",
+		"group-name": "foo",
+		"snaps":      []interface{}{"snap-a"},
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"set-quota", "foo", "snap-a"})
+	c.Assert(err, check.IsNil)
+	c.Check(*n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestSetQuotaMemoryUnits(c *check.C) {
+	for _, t := range []struct {
+		memory   string
+		expected float64
+	}{
+		{"4096", 4096},
+		{"2G", 2 * 1024 * 1024 * 1024},
+		{"100kB", 100 * 1000},
+		{"1MB", 1000 * 1000},
+	} {
+		s.mockQuotaPost(c, map[string]interface{}{
+			"action":      "ensure",
+			"group-name":  "foo",
+			"constraints": map[string]interface{}{"memory": t.expected},
+		})
+		_, err := snap.Parser(snap.Client()).ParseArgs([]string{"set-quota", "--memory=" + t.memory, "foo"})
+		c.Check(err, check.IsNil, check.Commentf(t.memory))
+	}
+}
+
+func (s *SnapSuite) TestSetQuotaInvalid(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"set-quota", "--memory=lots", "foo"}, `cannot parse memory limit "lots": .*`},
+		{[]string{"set-quota", "--memory=0", "foo"}, `cannot parse memory limit "0": limit must be positive`},
+		{[]string{"set-quota", "--memory=12X", "foo"}, `cannot parse memory limit "12X": try 512M or 2G`},
+		{[]string{"set-quota", "--cpu=half", "foo"}, `cannot parse CPU limit "half": expected a positive percentage like 50%`},
+		{[]string{"set-quota", "--cpu=0%", "foo"}, `cannot parse CPU limit "0%": expected a positive percentage like 50%`},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(t.args)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
+func (s *SnapSuite) TestRemoveQuota(c *check.C) {
+	n := s.mockQuotaPost(c, map[string]interface{}{
+		"action":     "remove",
+		"group-name": "foo",
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(*n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestRemoveQuotaNoWait(c *check.C) {
+	n := s.mockQuotaPost(c, map[string]interface{}{
+		"action":     "remove",
+		"group-name": "foo",
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remove-quota", "--no-wait", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(*n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "42\n")
+}
+
+func (s *SnapSuite) mockQuotaGet(c *check.C, path, result string) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, path)
+			fmt.Fprintln(w, result)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+		n++
+	})
+}
+
+func (s *SnapSuite) TestQuota(c *check.C) {
+	s.mockQuotaGet(c, "/v2/quotas/foo", `{"type": "sync", "result": {
+		"group-name": "foo",
+		"parent": "bar",
+		"subgroups": ["baz"],
+		"snaps": ["snap-a", "snap-b"],
+		"constraints": {"memory": 536870912, "cpu": 50},
+		"current": {"memory": 1048576, "cpu-time": 2500000000}
+	}}`)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `name: foo
+parent: bar
+constraints:
+  memory: 536MB
+  cpu: 50%
+current:
+  memory: 1MB
+  cpu-time: 2.5s
+subgroups:
+  - baz
+snaps:
+  - snap-a
+  - snap-b
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestQuotas(c *check.C) {
+	s.mockQuotaGet(c, "/v2/quotas", `{"type": "sync", "result": [{
+		"group-name": "bar",
+		"subgroups": ["foo"],
+		"constraints": {"memory": 1073741824},
+		"current": {"memory": 0, "cpu-time": 0}
+	}, {
+		"group-name": "foo",
+		"parent": "bar",
+		"snaps": ["snap-a"],
+		"constraints": {"memory": 536870912, "cpu": 50},
+		"current": {"memory": 1048576, "cpu-time": 2500000000}
+	}]}`)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"quotas"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `Quota  Parent  Constraints           Current
+bar    -       memory=1GB            -
+foo    bar     memory=536MB,cpu=50%  memory=1MB,cpu-time=2.5s
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestQuotasNone(c *check.C) {
+	s.mockQuotaGet(c, "/v2/quotas", `{"type": "sync", "result": []}`)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"quotas"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No quota groups defined.\n")
+}
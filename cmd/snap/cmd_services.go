@@ -20,18 +20,28 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/cmd"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
+	usclient "github.com/snapcore/snapd/usersession/client"
 )
 
 type svcStatus struct {
 	clientMixin
+	timeMixin
+	User       bool `long:"user"`
+	Verbose    bool `long:"verbose" short:"v"`
 	Positional struct {
 		ServiceNames []serviceName
 	} `positional-args:"yes"`
@@ -41,6 +51,10 @@ type svcLogs struct {
 	clientMixin
 	N          string `short:"n" default:"10"`
 	Follow     bool   `short:"f"`
+	Since      string `long:"since"`
+	Until      string `long:"until"`
+	Priority   string `long:"priority"`
+	Format     string `long:"format" default:"text" choice:"text" choice:"json"`
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -51,19 +65,39 @@ var (
 	longServicesHelp  = i18n.G(`
 The services command lists information about the services specified, or about
 the services in all currently installed snaps.
+
+If the --user option is given, the user services running in the session of
+the current user are listed instead.
+
+If the --verbose option is given, the state of the services is shown in more
+detail: their sub-state, number of automatic restarts, main process, last exit
+and resource usage, and when their timers were last and will be next
+triggered.
 `)
 	shortLogsHelp = i18n.G("Retrieve logs for services")
 	longLogsHelp  = i18n.G(`
 The logs command fetches logs of the given services and displays them in
 chronological order.
+
+The --since and --until options take either a time relative to now, like
+"1h30m" or "2d" for that long ago, "today" or "yesterday", or a local date and
+time like "2006-01-02 15:04:05", with the time being optional.
+
+With --format=json every log entry is output as a JSON object on its own line.
 `)
 	shortStartHelp = i18n.G("Start services")
 	longStartHelp  = i18n.G(`
 The start command starts, and optionally enables, the given services.
+
+If the --user option is given, the user services in the session of the
+current user are started instead.
 `)
 	shortStopHelp = i18n.G("Stop services")
 	longStopHelp  = i18n.G(`
 The stop command stops, and optionally disables, the given services.
+
+If the --user option is given, the user services in the session of the
+current user are stopped instead.
 `)
 	shortRestartHelp = i18n.G("Restart services")
 	longRestartHelp  = i18n.G(`
@@ -71,6 +105,9 @@ The restart command restarts the given services.
 
 If the --reload option is given, for each service whose app has a reload
 command, a reload is performed instead of a restart.
+
+If the --user option is given, the user services in the session of the
+current user are restarted instead.
 `)
 )
 
@@ -81,29 +118,47 @@ func init() {
 		// TRANSLATORS: This should not start with a lowercase letter.
 		desc: i18n.G("A service specification, which can be just a snap name (for all services in the snap), or <snap>.<app> for a single service."),
 	}}
-	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &svcStatus{} }, nil, argdescs)
+	// TRANSLATORS: This should not start with a lowercase letter.
+	userDesc := i18n.G("Operate on the user services in the session of the current user.")
+	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &svcStatus{} },
+		timeDescs.also(map[string]string{
+			"user": userDesc,
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"verbose": i18n.G("Show more details about the state of the services."),
+		}), argdescs)
 	addCommand("logs", shortLogsHelp, longLogsHelp, func() flags.Commander { return &svcLogs{} },
 		map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"n": i18n.G("Show only the given number of lines, or 'all'."),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"f": i18n.G("Wait for new lines and print them as they come in."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"since": i18n.G("Show only lines logged at or after the given time."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"until": i18n.G("Show only lines logged at or before the given time."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"priority": i18n.G("Show only lines of the given syslog priority (emerg, alert, crit, err, warning, notice, info, debug) or more important."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"format": i18n.G("Output format: text (default) or json."),
 		}, argdescs)
 
 	addCommand("start", shortStartHelp, longStartHelp, func() flags.Commander { return &svcStart{} },
 		waitDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"enable": i18n.G("As well as starting the service now, arrange for it to be started on boot."),
+			"user":   userDesc,
 		}), argdescs)
 	addCommand("stop", shortStopHelp, longStopHelp, func() flags.Commander { return &svcStop{} },
 		waitDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"disable": i18n.G("As well as stopping the service now, arrange for it to no longer be started on boot."),
+			"user":    userDesc,
 		}), argdescs)
 	addCommand("restart", shortRestartHelp, longRestartHelp, func() flags.Commander { return &svcRestart{} },
 		waitDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"reload": i18n.G("If the service has a reload command, use it instead of restarting."),
+			"user":   userDesc,
 		}), argdescs)
 }
 
@@ -115,11 +170,55 @@ func svcNames(s []serviceName) []string {
 	return svcNames
 }
 
+// userServices returns the user services among the given services, which
+// are not managed by snapd but by the session agent of every user.
+func userServices(cli *client.Client, names []string) ([]*client.AppInfo, error) {
+	services, err := cli.Apps(names, client.AppOptions{Service: true})
+	if err != nil {
+		return nil, err
+	}
+	userServices := make([]*client.AppInfo, 0, len(services))
+	for _, svc := range services {
+		if svc.IsUserService() {
+			userServices = append(userServices, svc)
+		}
+	}
+	return userServices, nil
+}
+
+func userServiceUnits(services []*client.AppInfo) []string {
+	units := make([]string, len(services))
+	for i, svc := range services {
+		units[i] = fmt.Sprintf("snap.%s.%s.service", svc.Snap, svc.Name)
+	}
+	return units
+}
+
+var errNoUserServices = errors.New(i18n.G("no user services found"))
+
+// userServicesAction performs the given action on the user services among
+// the given services, in the session of the current user.
+func userServicesAction(cli *client.Client, names []string, inst *usclient.ServiceInstruction) error {
+	services, err := userServices(cli, names)
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		return errNoUserServices
+	}
+	inst.Services = userServiceUnits(services)
+	return usclient.New().ServicesAction(context.Background(), inst)
+}
+
 func (s *svcStatus) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
 
+	if s.User {
+		return s.showUserServices()
+	}
+
 	services, err := s.client.Apps(svcNames(s.Positional.ServiceNames), client.AppOptions{Service: true})
 	if err != nil {
 		return err
@@ -133,9 +232,18 @@ func (s *svcStatus) Execute(args []string) error {
 	w := tabWriter()
 	defer w.Flush()
 
-	fmt.Fprintln(w, i18n.G("Service\tStartup\tCurrent\tNotes"))
+	if s.Verbose {
+		fmt.Fprintln(w, i18n.G("Service\tStartup\tCurrent\tState\tRestarts\tPID\tLast-exit\tMemory\tCPU\tNotes"))
+	} else {
+		fmt.Fprintln(w, i18n.G("Service\tStartup\tCurrent\tNotes"))
+	}
 
 	for _, svc := range services {
+		if svc.IsUserService() {
+			// their status is only known to the session
+			// agents, see --user
+			continue
+		}
 		startup := i18n.G("disabled")
 		if svc.Enabled {
 			startup = i18n.G("enabled")
@@ -144,6 +252,116 @@ func (s *svcStatus) Execute(args []string) error {
 		if svc.Active {
 			current = i18n.G("active")
 		}
+		if s.Verbose {
+			fmt.Fprintf(w, "%s.%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", svc.Snap, svc.Name, startup, current,
+				dashIfEmpty(svc.SubState), svc.Restarts, pidOrDash(svc.MainPID), lastExit(svc),
+				memoryOrDash(svc.Memory), cpuOrDash(svc.CPUUsage), s.verboseNotes(svc))
+			continue
+		}
+		fmt.Fprintf(w, "%s.%s\t%s\t%s\t%s\n", svc.Snap, svc.Name, startup, current, cmd.ClientAppInfoNotes(svc))
+	}
+
+	return nil
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func pidOrDash(pid int) string {
+	if pid == 0 {
+		return "-"
+	}
+	return strconv.Itoa(pid)
+}
+
+func memoryOrDash(mem uint64) string {
+	if mem == 0 {
+		return "-"
+	}
+	return strutil.SizeToStr(int64(mem))
+}
+
+func cpuOrDash(cpu time.Duration) string {
+	if cpu == 0 {
+		return "-"
+	}
+	return cpu.Round(time.Millisecond).String()
+}
+
+// lastExit describes how the main process of the service last exited.
+func lastExit(svc *client.AppInfo) string {
+	switch svc.ExitCode {
+	case "":
+		return "-"
+	case "exited":
+		return fmt.Sprintf("status=%d", svc.ExitStatus)
+	}
+	// killed or dumped, the status is the signal number
+	return fmt.Sprintf("%s(signal=%d)", svc.ExitCode, svc.ExitStatus)
+}
+
+// verboseNotes extends the notes of the service with the trigger times
+// of its timer.
+func (s *svcStatus) verboseNotes(svc *client.AppInfo) string {
+	notes := cmd.ClientAppInfoNotes(svc)
+	for _, act := range svc.Activators {
+		if act.Type != "timer" {
+			continue
+		}
+		var times []string
+		if !act.LastTrigger.IsZero() {
+			times = append(times, fmt.Sprintf(i18n.G("last: %s"), s.fmtTime(act.LastTrigger)))
+		}
+		if !act.NextTrigger.IsZero() {
+			times = append(times, fmt.Sprintf(i18n.G("next: %s"), s.fmtTime(act.NextTrigger)))
+		}
+		if len(times) > 0 {
+			notes += " (" + strings.Join(times, ", ") + ")"
+		}
+	}
+	return notes
+}
+
+func (s *svcStatus) showUserServices() error {
+	services, err := userServices(s.client, svcNames(s.Positional.ServiceNames))
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("There are no user services provided by installed snaps."))
+		return nil
+	}
+
+	sts, err := usclient.New().ServicesStatus(context.Background(), userServiceUnits(services))
+	if err != nil {
+		return err
+	}
+	status := make(map[string]*usclient.ServiceStatus, len(sts))
+	for _, st := range sts {
+		status[st.Unit] = st
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Service\tStartup\tCurrent\tNotes"))
+
+	units := userServiceUnits(services)
+	for i, svc := range services {
+		startup := i18n.G("disabled")
+		current := i18n.G("inactive")
+		if st := status[units[i]]; st != nil {
+			if st.Enabled {
+				startup = i18n.G("enabled")
+			}
+			if st.Active {
+				current = i18n.G("active")
+			}
+		}
 		fmt.Fprintf(w, "%s.%s\t%s\t%s\t%s\n", svc.Snap, svc.Name, startup, current, cmd.ClientAppInfoNotes(svc))
 	}
 
@@ -164,24 +382,89 @@ func (s *svcLogs) Execute(args []string) error {
 		sN = int(n)
 	}
 
-	logs, err := s.client.Logs(svcNames(s.Positional.ServiceNames), client.LogOptions{N: sN, Follow: s.Follow})
+	opts := client.LogOptions{N: sN, Follow: s.Follow, Priority: s.Priority}
+	if s.Since != "" {
+		t, err := parseLogTime(s.Since, timeNow())
+		if err != nil {
+			return fmt.Errorf(i18n.G("invalid argument for flag ‘--since’: %v"), err)
+		}
+		opts.Since = t
+	}
+	if s.Until != "" {
+		t, err := parseLogTime(s.Until, timeNow())
+		if err != nil {
+			return fmt.Errorf(i18n.G("invalid argument for flag ‘--until’: %v"), err)
+		}
+		opts.Until = t
+	}
+
+	logs, err := s.client.Logs(svcNames(s.Positional.ServiceNames), opts)
 	if err != nil {
 		return err
 	}
 
+	enc := json.NewEncoder(Stdout)
 	for log := range logs {
+		if s.Format == "json" {
+			if err := enc.Encode(log); err != nil {
+				return err
+			}
+			continue
+		}
 		fmt.Fprintln(Stdout, log)
 	}
 
 	return nil
 }
 
+var logTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseLogTime parses the time given to logs --since and --until, which is
+// either relative to now or an absolute local time.
+func parseLogTime(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	rel := strings.TrimPrefix(s, "-")
+	if strings.HasSuffix(rel, "d") {
+		if days, err := strconv.ParseUint(strings.TrimSuffix(rel, "d"), 10, 16); err == nil {
+			return now.AddDate(0, 0, -int(days)), nil
+		}
+	}
+	if d, err := time.ParseDuration(rel); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range logTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(i18n.G("cannot parse time %q: expected a time relative to now like \"1h30m\", \"today\" or \"yesterday\", or a date like \"2006-01-02 15:04:05\""), s)
+}
+
 type svcStart struct {
 	waitMixin
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
 	Enable bool `long:"enable"`
+	User   bool `long:"user"`
 }
 
 func (s *svcStart) Execute(args []string) error {
@@ -189,6 +472,14 @@ func (s *svcStart) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 	names := svcNames(s.Positional.ServiceNames)
+	if s.User {
+		inst := &usclient.ServiceInstruction{Action: "start", Enable: s.Enable}
+		if err := userServicesAction(s.client, names, inst); err != nil {
+			return err
+		}
+		fmt.Fprintf(Stdout, i18n.G("Started.\n"))
+		return nil
+	}
 	changeID, err := s.client.Start(names, client.StartOptions{Enable: s.Enable})
 	if err != nil {
 		return err
@@ -211,6 +502,7 @@ type svcStop struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
 	Disable bool `long:"disable"`
+	User    bool `long:"user"`
 }
 
 func (s *svcStop) Execute(args []string) error {
@@ -218,6 +510,14 @@ func (s *svcStop) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 	names := svcNames(s.Positional.ServiceNames)
+	if s.User {
+		inst := &usclient.ServiceInstruction{Action: "stop", Disable: s.Disable}
+		if err := userServicesAction(s.client, names, inst); err != nil {
+			return err
+		}
+		fmt.Fprintf(Stdout, i18n.G("Stopped.\n"))
+		return nil
+	}
 	changeID, err := s.client.Stop(names, client.StopOptions{Disable: s.Disable})
 	if err != nil {
 		return err
@@ -240,6 +540,7 @@ type svcRestart struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
 	Reload bool `long:"reload"`
+	User   bool `long:"user"`
 }
 
 func (s *svcRestart) Execute(args []string) error {
@@ -247,6 +548,17 @@ func (s *svcRestart) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 	names := svcNames(s.Positional.ServiceNames)
+	if s.User {
+		if s.Reload {
+			return fmt.Errorf(i18n.G("cannot use --reload with --user"))
+		}
+		inst := &usclient.ServiceInstruction{Action: "restart"}
+		if err := userServicesAction(s.client, names, inst); err != nil {
+			return err
+		}
+		fmt.Fprintf(Stdout, i18n.G("Restarted.\n"))
+		return nil
+	}
 	changeID, err := s.client.Restart(names, client.RestartOptions{Reload: s.Reload})
 	if err != nil {
 		return err
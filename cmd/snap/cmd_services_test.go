@@ -22,7 +22,10 @@ package main_test
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -31,6 +34,7 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/dirs"
 )
 
 type appOpSuite struct {
@@ -219,6 +223,58 @@ foo.zed  enabled  active    -
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *appOpSuite) TestAppStatusVerbose(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/apps")
+			c.Check(r.URL.Query().Get("select"), check.Equals, "service")
+			c.Check(r.Method, check.Equals, "GET")
+			w.WriteHeader(200)
+			enc := json.NewEncoder(w)
+			enc.Encode(map[string]interface{}{
+				"type": "sync",
+				"result": []map[string]interface{}{
+					{"snap": "foo", "name": "bar", "daemon": "oneshot",
+						"active": false, "enabled": true,
+						"sub-state": "dead", "exit-code": "exited", "exit-status": 1,
+						"activators": []map[string]interface{}{
+							{"Name": "bar", "Type": "timer", "Active": true, "Enabled": true,
+								"LastTrigger": "2021-04-01T10:00:00Z", "NextTrigger": "2021-04-02T10:00:00Z"},
+						},
+					}, {"snap": "foo", "name": "baz", "daemon": "simple",
+						"active": false, "enabled": true,
+						"sub-state": "auto-restart", "restarts": 3,
+						"exit-code": "killed", "exit-status": 9,
+					}, {"snap": "foo", "name": "zed", "daemon": "simple",
+						"active": true, "enabled": true,
+						"sub-state": "running", "main-pid": 1234,
+						"memory": 2048 * 1024, "cpu-usage": int64(1500 * time.Millisecond),
+					},
+				},
+				"status":      "OK",
+				"status-code": 200,
+			})
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--verbose", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `Service  Startup  Current   State         Restarts  PID   Last-exit         Memory  CPU   Notes
+foo.bar  enabled  inactive  dead          0         -     status=1          -       -     timer-activated (last: 2021-04-01T10:00:00Z, next: 2021-04-02T10:00:00Z)
+foo.baz  enabled  inactive  auto-restart  3         -     killed(signal=9)  -       -     -
+foo.zed  enabled  active    running       0         1234  -                 2MB     1.5s  -
+`)
+	// ensure that the fake server api was actually hit
+	c.Check(n, check.Equals, 1)
+}
+
 func (s *appOpSuite) TestServiceCompletion(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -294,3 +350,185 @@ func (s *appOpSuite) TestAppStatusNoServices(c *check.C) {
 	// ensure that the fake server api was actually hit
 	c.Check(n, check.Equals, 1)
 }
+
+// mockSessionAgent serves the given handler on the session agent socket of
+// the current user.
+func (s *appOpSuite) mockSessionAgent(c *check.C, handler http.HandlerFunc) {
+	socket := fmt.Sprintf("%s/%d/snapd-session-agent.socket", dirs.XdgRuntimeDirBase, os.Getuid())
+	c.Assert(os.MkdirAll(filepath.Dir(socket), 0700), check.IsNil)
+	l, err := net.Listen("unix", socket)
+	c.Assert(err, check.IsNil)
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(l)
+	s.AddCleanup(func() { srv.Close() })
+}
+
+func (s *appOpSuite) mockUserServicesApps(c *check.C, w http.ResponseWriter, r *http.Request) {
+	c.Check(r.Method, check.Equals, "GET")
+	c.Check(r.URL.Path, check.Equals, "/v2/apps")
+	c.Check(r.URL.Query().Get("select"), check.Equals, "service")
+	c.Check(r.URL.Query().Get("names"), check.Equals, "foo")
+	fmt.Fprintln(w, `{"type": "sync", "result": [
+{"snap": "foo", "name": "sys", "daemon": "simple", "active": true, "enabled": true},
+{"snap": "foo", "name": "usr", "daemon": "simple", "daemon-scope": "user"}
+]}`)
+}
+
+func (s *appOpSuite) TestAppStatusUser(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		s.mockUserServicesApps(c, w, r)
+	})
+	s.mockSessionAgent(c, func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v1/services")
+		c.Check(r.URL.Query().Get("names"), check.Equals, "snap.foo.usr.service")
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"unit": "snap.foo.usr.service", "enabled": true, "active": true}]}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "--user", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `Service  Startup  Current  Notes
+foo.usr  enabled  active   -
+`)
+}
+
+func (s *appOpSuite) TestAppStatusHidesUserServices(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		s.mockUserServicesApps(c, w, r)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"services", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `Service  Startup  Current  Notes
+foo.sys  enabled  active   -
+`)
+}
+
+func (s *appOpSuite) TestAppOpsUser(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		s.mockUserServicesApps(c, w, r)
+	})
+	var inst map[string]interface{}
+	s.mockSessionAgent(c, func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v1/services")
+		inst = DecodedRequestBody(c, r)
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+
+	for _, t := range []struct {
+		args    []string
+		summary string
+		inst    map[string]interface{}
+	}{
+		{[]string{"start", "--user", "--enable", "foo"}, "Started.", map[string]interface{}{
+			"action": "start", "services": []interface{}{"snap.foo.usr.service"}, "enable": true,
+		}},
+		{[]string{"stop", "--user", "foo"}, "Stopped.", map[string]interface{}{
+			"action": "stop", "services": []interface{}{"snap.foo.usr.service"},
+		}},
+		{[]string{"restart", "--user", "foo"}, "Restarted.", map[string]interface{}{
+			"action": "restart", "services": []interface{}{"snap.foo.usr.service"},
+		}},
+	} {
+		s.ResetStdStreams()
+		inst = nil
+		rest, err := snap.Parser(snap.Client()).ParseArgs(t.args)
+		c.Assert(err, check.IsNil)
+		c.Assert(rest, check.HasLen, 0)
+		c.Check(s.Stdout(), check.Equals, t.summary+"\n")
+		c.Check(inst, check.DeepEquals, t.inst)
+	}
+}
+
+func (s *appOpSuite) TestAppOpsUserErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"snap": "foo", "name": "sys", "daemon": "simple"}]}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"start", "--user", "foo"})
+	c.Check(err, check.ErrorMatches, "no user services found")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"restart", "--user", "--reload", "foo"})
+	c.Check(err, check.ErrorMatches, "cannot use --reload with --user")
+}
+
+func (s *appOpSuite) TestParseLogTime(c *check.C) {
+	loc := time.FixedZone("test", 2*60*60)
+	now := time.Date(2020, 9, 13, 14, 26, 40, 0, loc)
+	for _, t := range []struct {
+		in  string
+		out time.Time
+	}{
+		{"now", now},
+		{"today", time.Date(2020, 9, 13, 0, 0, 0, 0, loc)},
+		{"yesterday", time.Date(2020, 9, 12, 0, 0, 0, 0, loc)},
+		{"1h30m", now.Add(-90 * time.Minute)},
+		{"-10m", now.Add(-10 * time.Minute)},
+		{"2d", time.Date(2020, 9, 11, 14, 26, 40, 0, loc)},
+		{"2020-09-01", time.Date(2020, 9, 1, 0, 0, 0, 0, loc)},
+		{"2020-09-01 10:00", time.Date(2020, 9, 1, 10, 0, 0, 0, loc)},
+		{"2020-09-01 10:00:05", time.Date(2020, 9, 1, 10, 0, 5, 0, loc)},
+		{"2020-09-01T10:00:05Z", time.Date(2020, 9, 1, 10, 0, 5, 0, time.UTC)},
+	} {
+		out, err := snap.ParseLogTime(t.in, now)
+		c.Assert(err, check.IsNil, check.Commentf(t.in))
+		c.Check(out.Equal(t.out), check.Equals, true, check.Commentf("%s: %s != %s", t.in, out, t.out))
+	}
+
+	for _, in := range []string{"", "soon", "1y", "2020-13-01", "d"} {
+		_, err := snap.ParseLogTime(in, now)
+		c.Check(err, check.ErrorMatches, `cannot parse time ".*": expected a time relative to now like "1h30m", "today" or "yesterday", or a date like "2006-01-02 15:04:05"`, check.Commentf(in))
+	}
+}
+
+func (s *appOpSuite) TestLogsOptions(c *check.C) {
+	now := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	s.AddCleanup(snap.MockTimeNow(func() time.Time { return now }))
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/logs")
+		q := r.URL.Query()
+		c.Check(q.Get("names"), check.Equals, "foo")
+		c.Check(q.Get("n"), check.Equals, "-1")
+		c.Check(q.Get("since"), check.Equals, "2020-09-13T11:26:40Z")
+		c.Check(q.Get("until"), check.Equals, "2020-09-13T12:16:40Z")
+		c.Check(q.Get("priority"), check.Equals, "warning")
+		w.Header().Set("Content-Type", "application/json-seq")
+		fmt.Fprint(w, "\x1e"+`{"timestamp":"2020-09-13T11:30:00Z","message":"hello","sid":"foo.svc","pid":"42","priority":"4"}`+"\n")
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "-n", "all", "--since", "1h", "--until", "10m", "--priority", "warning", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "2020-09-13T11:30:00Z foo.svc[42]: hello\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *appOpSuite) TestLogsJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json-seq")
+		fmt.Fprint(w, "\x1e"+`{"timestamp":"2020-09-13T11:30:00Z","message":"hello","sid":"foo.svc","pid":"42","priority":"4"}`+"\n")
+		fmt.Fprint(w, "\x1e"+`{"timestamp":"2020-09-13T11:30:01Z","message":"bye","sid":"foo.svc","pid":"42"}`+"\n")
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--format=json", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `{"timestamp":"2020-09-13T11:30:00Z","message":"hello","sid":"foo.svc","pid":"42","priority":"4"}
+{"timestamp":"2020-09-13T11:30:01Z","message":"bye","sid":"foo.svc","pid":"42"}
+`)
+}
+
+func (s *appOpSuite) TestLogsBadTime(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--since", "soon", "foo"})
+	c.Check(err, check.ErrorMatches, `invalid argument for flag ‘--since’: cannot parse time "soon": .*`)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"logs", "--until", "later", "foo"})
+	c.Check(err, check.ErrorMatches, `invalid argument for flag ‘--until’: cannot parse time "later": .*`)
+}
@@ -20,11 +20,15 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"strconv"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/jsonutil"
 )
@@ -44,18 +48,40 @@ Nested values may be modified via a dotted path:
 
 Configuration option may be unset with exclamation mark:
     $ snap set snap-name author!
+
+Larger configurations may be read from a YAML or JSON file, or from
+standard input when the file is "-". The options in the file are merged
+into the existing configuration, except under the keys given with --replace,
+which are replaced as a whole:
+
+    $ snap set snap-name --file=config.yaml --replace=server.tls
+
+The configuration may be restored to how it was right after a change listed
+by 'snap get --history', running the snap's configuration hook again:
+
+    $ snap set --rollback=3 snap-name
 `)
 
 type cmdSet struct {
 	waitMixin
+	Rollback   string         `long:"rollback"`
+	File       flags.Filename `long:"file"`
+	Replace    []string       `long:"replace"`
 	Positional struct {
 		Snap       installedSnapName
-		ConfValues []string `required:"1"`
+		ConfValues []string
 	} `positional-args:"yes" required:"yes"`
 }
 
 func init() {
-	addCommand("set", shortSetHelp, longSetHelp, func() flags.Commander { return &cmdSet{} }, waitDescs, []argDesc{
+	addCommand("set", shortSetHelp, longSetHelp, func() flags.Commander { return &cmdSet{} }, waitDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"rollback": i18n.G("Restore the configuration to how it was right after the given change"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"file": i18n.G("Read configuration options from the given YAML or JSON file"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"replace": i18n.G("Replace rather than merge the given option with the content of the file"),
+	}), []argDesc{
 		{
 			name: "<snap>",
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -70,7 +96,24 @@ func init() {
 }
 
 func (x *cmdSet) Execute(args []string) error {
+	if x.Rollback != "" {
+		return x.rollback()
+	}
+	if len(x.Replace) > 0 && x.File == "" {
+		return errors.New(i18n.G("cannot use --replace without --file"))
+	}
+	if len(x.Positional.ConfValues) == 0 && x.File == "" {
+		return errors.New(i18n.G("missing configuration values (want key=value)"))
+	}
+
 	patchValues := make(map[string]interface{})
+	if x.File != "" {
+		var err error
+		patchValues, err = x.patchFromFile()
+		if err != nil {
+			return err
+		}
+	}
 	for _, patchValue := range x.Positional.ConfValues {
 		parts := strings.SplitN(patchValue, "=", 2)
 		if len(parts) == 1 && strings.HasSuffix(patchValue, "!") {
@@ -95,6 +138,44 @@ func (x *cmdSet) Execute(args []string) error {
 		return err
 	}
 
+	return x.waitConf(id)
+}
+
+func (x *cmdSet) patchFromFile() (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if x.File == "-" {
+		data, err = ioutil.ReadAll(Stdin)
+	} else {
+		data, err = ioutil.ReadFile(string(x.File))
+	}
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot read configuration file: %v"), err)
+	}
+	return client.ConfPatchFromDocument(data, x.Replace)
+}
+
+func (x *cmdSet) rollback() error {
+	if x.File != "" {
+		return errors.New(i18n.G("cannot use --rollback together with --file"))
+	}
+	if len(x.Positional.ConfValues) > 0 {
+		return errors.New(i18n.G("cannot use --rollback together with configuration values"))
+	}
+	changeID, err := strconv.Atoi(x.Rollback)
+	if err != nil {
+		return fmt.Errorf(i18n.G("invalid --rollback change %q"), x.Rollback)
+	}
+
+	id, err := x.client.RollbackConf(string(x.Positional.Snap), changeID)
+	if err != nil {
+		return err
+	}
+
+	return x.waitConf(id)
+}
+
+func (x *cmdSet) waitConf(id string) error {
 	if _, err := x.wait(id); err != nil {
 		if err == noWait {
 			return nil
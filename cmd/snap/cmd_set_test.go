@@ -22,7 +22,9 @@ package main_test
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"path/filepath"
 
 	"gopkg.in/check.v1"
 
@@ -49,6 +51,98 @@ func (s *snapSetSuite) TestInvalidSetParameters(c *check.C) {
 	c.Check(s.setConfApiCalls, check.Equals, 0)
 }
 
+func (s *snapSetSuite) TestSetMissingValues(c *check.C) {
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "snap-name"})
+	c.Check(err, check.ErrorMatches, `missing configuration values \(want key=value\)`)
+	c.Check(s.setConfApiCalls, check.Equals, 0)
+}
+
+func (s *snapSetSuite) mockSetConfigServerPatch(c *check.C, expected map[string]interface{}) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snaps/snapname/conf":
+			c.Check(r.Method, check.Equals, "PUT")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, expected)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "zzz"}`)
+			s.setConfApiCalls += 1
+		case "/v2/changes/zzz":
+			c.Check(r.Method, check.Equals, "GET")
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	})
+}
+
+func (s *snapSetSuite) TestSnapSetFile(c *check.C) {
+	fn := filepath.Join(c.MkDir(), "config.yaml")
+	err := ioutil.WriteFile(fn, []byte("server:\n  port: 8080\n  tls:\n    cert: foo\nmode: fast\n"), 0644)
+	c.Assert(err, check.IsNil)
+
+	s.mockSetConfigServerPatch(c, map[string]interface{}{
+		"server.port":     json.Number("8080"),
+		"server.tls.cert": "foo",
+		"mode":            "slow",
+	})
+	// values on the command line win over the file
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--file", fn, "snapname", "mode=slow"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.setConfApiCalls, check.Equals, 1)
+}
+
+func (s *snapSetSuite) TestSnapSetFileReplace(c *check.C) {
+	s.stdin.WriteString(`{"server": {"port": 8080, "tls": {"cert": "foo"}}}`)
+
+	s.mockSetConfigServerPatch(c, map[string]interface{}{
+		"server.port": json.Number("8080"),
+		"server.tls":  map[string]interface{}{"cert": "foo"},
+	})
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--file=-", "--replace=server.tls", "snapname"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.setConfApiCalls, check.Equals, 1)
+}
+
+func (s *snapSetSuite) TestSnapSetFileErrors(c *check.C) {
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--file=/does/not/exist", "snapname"})
+	c.Check(err, check.ErrorMatches, "cannot read configuration file: .* no such file or directory")
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--replace=foo", "snapname", "foo=bar"})
+	c.Check(err, check.ErrorMatches, "cannot use --replace without --file")
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--rollback=1", "--file=-", "snapname"})
+	c.Check(err, check.ErrorMatches, "cannot use --rollback together with --file")
+	c.Check(s.setConfApiCalls, check.Equals, 0)
+}
+
+func (s *snapSetSuite) TestSnapSetRollback(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snaps/snapname/conf":
+			c.Check(r.Method, check.Equals, "PUT")
+			c.Check(r.URL.Query().Get("rollback"), check.Equals, "3")
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "zzz"}`)
+			s.setConfApiCalls += 1
+		case "/v2/changes/zzz":
+			c.Check(r.Method, check.Equals, "GET")
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--rollback=3", "snapname"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.setConfApiCalls, check.Equals, 1)
+}
+
+func (s *snapSetSuite) TestSnapSetRollbackErrors(c *check.C) {
+	_, err := snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--rollback=3", "snapname", "key=value"})
+	c.Check(err, check.ErrorMatches, "cannot use --rollback together with configuration values")
+	_, err = snapset.Parser(snapset.Client()).ParseArgs([]string{"set", "--rollback=x", "snapname"})
+	c.Check(err, check.ErrorMatches, `invalid --rollback change "x"`)
+	c.Check(s.setConfApiCalls, check.Equals, 0)
+}
+
 func (s *snapSetSuite) TestSnapSetIntegrationString(c *check.C) {
 	// and mock the server
 	s.mockSetConfigServer(c, "value")
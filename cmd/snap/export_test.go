@@ -28,6 +28,7 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/image"
+	"github.com/snapcore/snapd/interfaces/denials"
 	"github.com/snapcore/snapd/sandbox/selinux"
 	"github.com/snapcore/snapd/store"
 )
@@ -86,6 +87,8 @@ var (
 	SortTimingsTasks = sortTimingsTasks
 
 	PrintInstallHint = printInstallHint
+
+	ParseLogTime = parseLogTime
 )
 
 func HiddenCmd(descr string, completeHidden bool) *cmdInfo {
@@ -314,6 +317,14 @@ func MockImagePrepare(newImagePrepare func(*image.Options) error) (restore func(
 	}
 }
 
+func MockDenialsSuggest(f func(d *denials.Denial) []string) (restore func()) {
+	old := denialsSuggest
+	denialsSuggest = f
+	return func() {
+		denialsSuggest = old
+	}
+}
+
 func MockSignalNotify(newSignalNotify func(sig ...os.Signal) (chan os.Signal, func())) (restore func()) {
 	old := signalNotify
 	signalNotify = newSignalNotify
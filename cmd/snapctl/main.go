@@ -21,7 +21,10 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
@@ -71,9 +74,47 @@ func main() {
 	}
 }
 
+var stdin io.Reader = os.Stdin
+
+// fileArg returns the file given with --file to the set command, if any.
+func fileArg(args []string) string {
+	if len(args) == 0 || args[0] != "set" {
+		return ""
+	}
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			return ""
+		case strings.HasPrefix(args[i], "--file="):
+			return strings.TrimPrefix(args[i], "--file=")
+		case args[i] == "--file" && i+1 < len(args):
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// readFileArg reads the file given with --file to the set command; the
+// command itself runs inside snapd which cannot see the files of the snap.
+func readFileArg(args []string) ([]byte, error) {
+	switch fn := fileArg(args); fn {
+	case "":
+		return nil, nil
+	case "-":
+		return ioutil.ReadAll(stdin)
+	default:
+		return ioutil.ReadFile(fn)
+	}
+}
+
 func run() (stdout, stderr []byte, err error) {
 	cli := client.New(&clientConfig)
 
+	data, err := readFileArg(os.Args[1:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read configuration file: %v", err)
+	}
+
 	cookie := os.Getenv("SNAP_COOKIE")
 	// for compatibility, if re-exec is not enabled and facing older snapd.
 	if cookie == "" {
@@ -82,5 +123,6 @@ func run() (stdout, stderr []byte, err error) {
 	return cli.RunSnapctl(&client.SnapCtlOptions{
 		ContextID: cookie,
 		Args:      os.Args[1:],
+		Stdin:     data,
 	})
 }
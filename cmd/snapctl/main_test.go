@@ -20,6 +20,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -41,6 +42,7 @@ type snapctlSuite struct {
 	oldArgs           []string
 	expectedContextID string
 	expectedArgs      []string
+	expectedStdin     []byte
 }
 
 var _ = Suite(&snapctlSuite{})
@@ -60,6 +62,7 @@ func (s *snapctlSuite) SetUpTest(c *C) {
 			c.Assert(decoder.Decode(&snapctlOptions), IsNil)
 			c.Assert(snapctlOptions.ContextID, Equals, s.expectedContextID)
 			c.Assert(snapctlOptions.Args, DeepEquals, s.expectedArgs)
+			c.Assert(snapctlOptions.Stdin, DeepEquals, s.expectedStdin)
 
 			fmt.Fprintln(w, `{"type": "sync", "result": {"stdout": "test stdout", "stderr": "test stderr"}}`)
 		default:
@@ -73,6 +76,7 @@ func (s *snapctlSuite) SetUpTest(c *C) {
 	os.Args = []string{"snapctl"}
 	s.expectedContextID = "snap-context-test"
 	s.expectedArgs = []string{}
+	s.expectedStdin = nil
 
 	fakeAuthPath := filepath.Join(c.MkDir(), "auth.json")
 	os.Setenv("SNAPD_AUTH_DATA_FILENAME", fakeAuthPath)
@@ -115,3 +119,50 @@ func (s *snapctlSuite) TestSnapctlHelp(c *C) {
 	_, _, err := run()
 	c.Check(err, IsNil)
 }
+
+func (s *snapctlSuite) TestSnapctlSetFile(c *C) {
+	fn := filepath.Join(c.MkDir(), "config.yaml")
+	c.Assert(ioutil.WriteFile(fn, []byte("foo: bar\n"), 0644), IsNil)
+
+	os.Args = []string{"snapctl", "set", "--file", fn, "--replace=foo"}
+	s.expectedArgs = os.Args[1:]
+	s.expectedStdin = []byte("foo: bar\n")
+
+	_, _, err := run()
+	c.Check(err, IsNil)
+}
+
+func (s *snapctlSuite) TestSnapctlSetFileStdin(c *C) {
+	stdin = bytes.NewBufferString(`{"foo": "bar"}`)
+	defer func() { stdin = os.Stdin }()
+
+	os.Args = []string{"snapctl", "set", "--file=-"}
+	s.expectedArgs = os.Args[1:]
+	s.expectedStdin = []byte(`{"foo": "bar"}`)
+
+	_, _, err := run()
+	c.Check(err, IsNil)
+}
+
+func (s *snapctlSuite) TestSnapctlSetFileError(c *C) {
+	os.Args = []string{"snapctl", "set", "--file=/does/not/exist"}
+
+	_, _, err := run()
+	c.Check(err, ErrorMatches, "cannot read configuration file: .* no such file or directory")
+}
+
+func (s *snapctlSuite) TestFileArg(c *C) {
+	for _, t := range []struct {
+		args []string
+		file string
+	}{
+		{[]string{"set", "--file=foo"}, "foo"},
+		{[]string{"set", "a=b", "--file", "foo"}, "foo"},
+		{[]string{"set", "--", "--file=foo"}, ""},
+		{[]string{"get", "--file=foo"}, ""},
+		{[]string{"set", "--file"}, ""},
+		{nil, ""},
+	} {
+		c.Check(fileArg(t.args), Equals, t.file, Commentf("%q", t.args))
+	}
+}
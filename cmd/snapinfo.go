@@ -143,7 +143,10 @@ func ClientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) ([]client.AppInfo, err
 		}
 
 		appInfo.Daemon = app.Daemon
-		if !app.IsService() || !app.Snap.IsActive() {
+		appInfo.DaemonScope = string(app.DaemonScope)
+		// the status of user services is specific to every user
+		// session, and only known to their session agents
+		if !app.IsService() || app.IsUserService() || !app.Snap.IsActive() {
 			out = append(out, appInfo)
 			continue
 		}
@@ -177,12 +180,21 @@ func ClientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) ([]client.AppInfo, err
 			case ".service":
 				appInfo.Enabled = st.Enabled
 				appInfo.Active = st.Active
+				appInfo.SubState = st.SubState
+				appInfo.Restarts = st.NRestarts
+				appInfo.MainPID = st.MainPID
+				appInfo.ExitCode = st.ExitCode
+				appInfo.ExitStatus = st.ExitStatus
+				appInfo.Memory = st.Memory
+				appInfo.CPUUsage = st.CPUUsage
 			case ".timer":
 				appInfo.Activators = append(appInfo.Activators, client.AppActivator{
-					Name:    app.Name,
-					Enabled: st.Enabled,
-					Active:  st.Active,
-					Type:    "timer",
+					Name:        app.Name,
+					Enabled:     st.Enabled,
+					Active:      st.Active,
+					Type:        "timer",
+					LastTrigger: st.LastTrigger,
+					NextTrigger: st.NextTrigger,
 				})
 			case ".socket":
 				appInfo.Activators = append(appInfo.Activators, client.AppActivator{
@@ -59,6 +59,7 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/schema"
 	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/servicestate"
@@ -86,6 +87,7 @@ var api = []*Command{
 	snapFileCmd,
 	snapDownloadCmd,
 	snapConfCmd,
+	snapHooksCmd,
 	interfacesCmd,
 	assertsCmd,
 	assertsFindManyCmd,
@@ -107,6 +109,8 @@ var api = []*Command{
 	connectionsCmd,
 	modelCmd,
 	cohortsCmd,
+	quotaGroupsCmd,
+	quotaGroupInfoCmd,
 	serialModelCmd,
 }
 
@@ -1586,9 +1590,25 @@ func getSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	vars := muxVars(r)
 	snapName := configstate.RemapSnapFromRequest(vars["name"])
 
-	keys := strutil.CommaSeparatedList(r.URL.Query().Get("keys"))
+	query := r.URL.Query()
+	keys := strutil.CommaSeparatedList(query.Get("keys"))
 
 	s := c.d.overlord.State()
+
+	if query.Get("schema") == "true" {
+		if len(keys) > 0 {
+			return BadRequest("cannot use keys together with schema")
+		}
+		return getSnapConfSchema(s, snapName)
+	}
+
+	if query.Get("history") == "true" {
+		if len(keys) > 0 {
+			return BadRequest("cannot use keys together with history")
+		}
+		return getSnapConfHistory(s, snapName)
+	}
+
 	s.Lock()
 	tr := config.NewTransaction(s)
 	s.Unlock()
@@ -1633,10 +1653,56 @@ func getSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(currentConfValues, nil)
 }
 
+func getSnapConfSchema(st *state.State, snapName string) Response {
+	st.Lock()
+	defer st.Unlock()
+
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(st, snapName, &snapst); err != nil && err != state.ErrNoState {
+		return InternalError("%v", err)
+	}
+	if snapName != "core" && !snapst.IsInstalled() {
+		return SnapNotFound(snapName, &snap.NotInstalledError{Snap: snapName})
+	}
+
+	sch, err := configstate.SnapConfigSchema(st, snapName)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	if sch == nil {
+		return NotFound("snap %q has no configuration schema", configstate.RemapSnapToResponse(snapName))
+	}
+
+	return SyncResponse(sch, nil)
+}
+
+func getSnapConfHistory(st *state.State, snapName string) Response {
+	st.Lock()
+	defer st.Unlock()
+
+	history, err := config.History(st, snapName)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	if history == nil {
+		history = []*config.HistoryEntry{}
+	}
+
+	return SyncResponse(history, nil)
+}
+
 func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	vars := muxVars(r)
 	snapName := configstate.RemapSnapFromRequest(vars["name"])
 
+	if rollback := r.URL.Query().Get("rollback"); rollback != "" {
+		id, err := strconv.Atoi(rollback)
+		if err != nil {
+			return BadRequest("cannot parse rollback change %q: %v", rollback, err)
+		}
+		return rollbackSnapConf(c.d.overlord.State(), snapName, id)
+	}
+
 	var patchValues map[string]interface{}
 	if err := jsonutil.DecodeWithNumber(r.Body, &patchValues); err != nil {
 		return BadRequest("cannot decode request body into patch values: %v", err)
@@ -1652,6 +1718,17 @@ func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 		if _, ok := err.(*snap.NotInstalledError); ok {
 			return SnapNotFound(snapName, err)
 		}
+		if verr, ok := err.(*schema.ValidationError); ok {
+			return SyncResponse(&resp{
+				Type: ResponseTypeError,
+				Result: &errorResult{
+					Message: err.Error(),
+					Kind:    errorKindConfigInvalid,
+					Value:   verr,
+				},
+				Status: 400,
+			}, nil)
+		}
 		return errToResponse(err, []string{snapName}, InternalError, "%v")
 	}
 
@@ -1663,6 +1740,37 @@ func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	return AsyncResponse(nil, &Meta{Change: change.ID()})
 }
 
+func rollbackSnapConf(st *state.State, snapName string, id int) Response {
+	st.Lock()
+	defer st.Unlock()
+
+	taskset, err := configstate.RollbackConfig(st, snapName, id, 0)
+	if err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(snapName, err)
+		}
+		if verr, ok := err.(*schema.ValidationError); ok {
+			return SyncResponse(&resp{
+				Type: ResponseTypeError,
+				Result: &errorResult{
+					Message: err.Error(),
+					Kind:    errorKindConfigInvalid,
+					Value:   verr,
+				},
+				Status: 400,
+			}, nil)
+		}
+		return errToResponse(err, []string{snapName}, BadRequest, "%v")
+	}
+
+	summary := fmt.Sprintf("Roll back configuration of %q snap to change %d", snapName, id)
+	change := newChange(st, "configure-snap", summary, []*state.TaskSet{taskset}, []string{snapName})
+
+	st.EnsureBefore(0)
+
+	return AsyncResponse(nil, &Meta{Change: change.ID()})
+}
+
 // interfacesConnectionsMultiplexer multiplexes to either legacy (connection) or modern behavior (interfaces).
 func interfacesConnectionsMultiplexer(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
@@ -2135,6 +2243,11 @@ func runSnapctl(c *Command, r *http.Request, user *auth.UserState) Response {
 	// Ignore missing context error to allow 'snapctl -h' without a context;
 	// Actual context is validated later by get/set.
 	context, _ := c.d.overlord.HookManager().Context(snapctlOptions.ContextID)
+	if context != nil {
+		context.Lock()
+		ctlcmd.SetStdin(context, snapctlOptions.Stdin)
+		context.Unlock()
+	}
 	stdout, stderr, err := ctlcmdRun(context, snapctlOptions.Args, uid)
 	if err != nil {
 		if e, ok := err.(*ctlcmd.ForbiddenCommandError); ok {
@@ -2337,6 +2450,30 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 		}
 		follow = f
 	}
+	var since, until time.Time
+	if s := query.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequest(`invalid value for since: %q: %v`, s, err)
+		}
+		since = t
+	}
+	if s := query.Get("until"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequest(`invalid value for until: %q: %v`, s, err)
+		}
+		until = t
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return BadRequest(`invalid time range: until is before since`)
+	}
+	priority := query.Get("priority")
+	if priority != "" {
+		if err := systemd.ValidateLogPriority(priority); err != nil {
+			return BadRequest("%v", err)
+		}
+	}
 
 	// only services have logs for now
 	opts := appInfoOptions{service: true}
@@ -2354,7 +2491,13 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, progress.Null)
-	reader, err := sysd.LogReader(serviceNames, n, follow)
+	reader, err := sysd.LogReader(serviceNames, systemd.LogOptions{
+		N:        n,
+		Follow:   follow,
+		Since:    since,
+		Until:    until,
+		Priority: priority,
+	})
 	if err != nil {
 		return InternalError("cannot get logs: %v", err)
 	}
@@ -2387,6 +2530,18 @@ func postApps(c *Command, r *http.Request, user *auth.UserState) Response {
 		// shouldn't ever return an empty appInfos with no error response
 		return InternalError("no services found")
 	}
+	// user services are controlled through the session agents of the
+	// users, not snapd
+	sysAppInfos := make([]*snap.AppInfo, 0, len(appInfos))
+	for _, app := range appInfos {
+		if !app.IsUserService() {
+			sysAppInfos = append(sysAppInfos, app)
+		}
+	}
+	if len(sysAppInfos) == 0 {
+		return BadRequest("cannot perform operation on user services via snapd, use --user")
+	}
+	appInfos = sysAppInfos
 
 	tss, err := servicestate.Control(st, appInfos, &inst, nil)
 	if err != nil {
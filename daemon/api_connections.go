@@ -40,6 +40,9 @@ type collectFilter struct {
 	snapName  string
 	ifaceName string
 	connected bool
+	// hotplug selects only hotplug slots and their connections, including
+	// the ones of unplugged devices
+	hotplug bool
 }
 
 func (c *collectFilter) plugOrConnectedSlotMatches(plug *interfaces.PlugRef, connectedSlots []interfaces.SlotRef) bool {
@@ -116,6 +119,16 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 	if err != nil {
 		return nil, err
 	}
+	var hotplugSlots map[string]*ifacestate.HotplugSlotInfo
+	if filter.hotplug {
+		hotplugSlots, err = ifaceMgr.HotplugSlots()
+		if err != nil {
+			return nil, err
+		}
+	}
+	isHotplugSlot := func(slotRef *interfaces.SlotRef) bool {
+		return slotRef.Snap == ifacestate.SystemSnapName() && hotplugSlots[slotRef.Name] != nil
+	}
 
 	connsjson.Established = make([]connectionJSON, 0, len(connStates))
 	connsjson.Plugs = make([]*plugJSON, 0, len(ifaces.Plugs))
@@ -125,7 +138,7 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 		if cstate.Undesired && filter.connected {
 			continue
 		}
-		if cstate.HotplugGone {
+		if cstate.HotplugGone && !filter.hotplug {
 			// XXX: hotplug connection - the device and slot are gone
 			continue
 		}
@@ -134,6 +147,9 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 		if err != nil {
 			return nil, err
 		}
+		if filter.hotplug && !isHotplugSlot(&cref.SlotRef) {
+			continue
+		}
 		if !filter.plugOrConnectedSlotMatches(&cref.PlugRef, nil) && !filter.slotOrConnectedPlugMatches(&cref.SlotRef, nil) {
 			continue
 		}
@@ -167,6 +183,9 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 	}
 
 	for _, plug := range ifaces.Plugs {
+		if filter.hotplug {
+			break
+		}
 		plugRef := interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name}
 		connectedSlots, connected := plugConns[plugRef.String()]
 		if !connected && filter.connected {
@@ -197,6 +216,9 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 		if !connected && filter.connected {
 			continue
 		}
+		if filter.hotplug && !isHotplugSlot(&slotRef) {
+			continue
+		}
 		if !filter.ifaceMatches(slot.Interface) || !filter.slotOrConnectedPlugMatches(&slotRef, connectedPlugs) {
 			continue
 		}
@@ -213,6 +235,34 @@ func collectConnections(ifaceMgr *ifacestate.InterfaceManager, filter collectFil
 			Apps:        apps,
 			Label:       slot.Label,
 			Connections: connectedPlugs,
+			HotplugKey:  slot.HotplugKey,
+		}
+		connsjson.Slots = append(connsjson.Slots, sj)
+	}
+	// the slots of unplugged devices are not in the repository
+	goneSlotNames := make([]string, 0, len(hotplugSlots))
+	for name, hotplugSlot := range hotplugSlots {
+		if hotplugSlot.HotplugGone && repo.Slot(ifacestate.SystemSnapName(), name) == nil {
+			goneSlotNames = append(goneSlotNames, name)
+		}
+	}
+	sort.Strings(goneSlotNames)
+	for _, name := range goneSlotNames {
+		hotplugSlot := hotplugSlots[name]
+		slotRef := interfaces.SlotRef{Snap: ifacestate.SystemSnapName(), Name: name}
+		connectedPlugs := slotConns[slotRef.String()]
+		if !filter.ifaceMatches(hotplugSlot.Interface) || !filter.slotOrConnectedPlugMatches(&slotRef, connectedPlugs) {
+			continue
+		}
+		sort.Sort(byPlugRef(connectedPlugs))
+		sj := &slotJSON{
+			Snap:        slotRef.Snap,
+			Name:        slotRef.Name,
+			Interface:   hotplugSlot.Interface,
+			Attrs:       hotplugSlot.StaticAttrs,
+			Connections: connectedPlugs,
+			HotplugKey:  hotplugSlot.HotplugKey,
+			HotplugGone: true,
 		}
 		connsjson.Slots = append(connsjson.Slots, sj)
 	}
@@ -245,7 +295,7 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 	snapName := query.Get("snap")
 	ifaceName := query.Get("interface")
 	qselect := query.Get("select")
-	if qselect != "all" && qselect != "" {
+	if qselect != "all" && qselect != "hotplug" && qselect != "" {
 		return BadRequest("unsupported select qualifier")
 	}
 	onlyConnected := qselect == ""
@@ -264,6 +314,7 @@ func getConnections(c *Command, r *http.Request, user *auth.UserState) Response
 		snapName:  snapName,
 		ifaceName: ifaceName,
 		connected: onlyConnected,
+		hotplug:   qselect == "hotplug",
 	})
 	if err != nil {
 		return InternalError("collecting connection information failed: %v", err)
@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/snap"
 )
 
 // Tests for GET /v2/connections
@@ -820,6 +821,112 @@ func (s *apiSuite) TestConnectionsHotplugGone(c *check.C) {
 	})
 }
 
+func (s *apiSuite) TestConnectionsHotplug(c *check.C) {
+	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
+	defer restore()
+
+	s.daemon(c)
+
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	coreInfo := s.mockSnap(c, coreProducerYaml)
+
+	repo := s.d.overlord.InterfaceManager().Repository()
+	err := repo.AddSlot(&snap.SlotInfo{
+		Snap:       coreInfo,
+		Name:       "webcam",
+		Interface:  "test",
+		Attrs:      map[string]interface{}{"path": "/dev/video0"},
+		HotplugKey: "1234",
+	})
+	c.Assert(err, check.IsNil)
+	cref := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "core", Name: "webcam"},
+	}
+	_, err = repo.Connect(cref, nil, nil, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	st := s.d.overlord.State()
+	st.Lock()
+	st.Set("conns", map[string]interface{}{
+		"consumer:plug core:webcam": map[string]interface{}{
+			"interface":   "test",
+			"hotplug-key": "1234",
+		},
+		"consumer:plug core:dongle": map[string]interface{}{
+			"interface":    "test",
+			"hotplug-key":  "5678",
+			"hotplug-gone": true,
+		},
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test",
+		},
+	})
+	st.Set("hotplug-slots", map[string]interface{}{
+		"webcam": map[string]interface{}{
+			"name":         "webcam",
+			"interface":    "test",
+			"hotplug-key":  "1234",
+			"static-attrs": map[string]interface{}{"path": "/dev/video0"},
+		},
+		"dongle": map[string]interface{}{
+			"name":         "dongle",
+			"interface":    "test",
+			"hotplug-key":  "5678",
+			"static-attrs": map[string]interface{}{"path": "/dev/hidraw0"},
+			"hotplug-gone": true,
+		},
+	})
+	st.Unlock()
+
+	s.testConnections(c, "/v2/connections?select=hotplug", map[string]interface{}{
+		"result": map[string]interface{}{
+			"established": []interface{}{
+				map[string]interface{}{
+					"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					"slot":      map[string]interface{}{"snap": "core", "slot": "dongle"},
+					"manual":    true,
+					"interface": "test",
+				},
+				map[string]interface{}{
+					"plug":      map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					"slot":      map[string]interface{}{"snap": "core", "slot": "webcam"},
+					"manual":    true,
+					"interface": "test",
+				},
+			},
+			"plugs": []interface{}{},
+			"slots": []interface{}{
+				map[string]interface{}{
+					"snap":        "core",
+					"slot":        "webcam",
+					"interface":   "test",
+					"attrs":       map[string]interface{}{"path": "/dev/video0"},
+					"hotplug-key": "1234",
+					"connections": []interface{}{
+						map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					},
+				},
+				map[string]interface{}{
+					"snap":         "core",
+					"slot":         "dongle",
+					"interface":    "test",
+					"attrs":        map[string]interface{}{"path": "/dev/hidraw0"},
+					"hotplug-key":  "5678",
+					"hotplug-gone": true,
+					"connections": []interface{}{
+						map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					},
+				},
+			},
+		},
+		"status":      "OK",
+		"status-code": 200.0,
+		"type":        "sync",
+	})
+}
+
 func (s *apiSuite) TestConnectionsSorted(c *check.C) {
 	restore := builtin.MockInterface(&ifacetest.TestInterface{InterfaceName: "test"})
 	defer restore()
@@ -266,6 +266,10 @@ func getChangeTimings(st *state.State, changeID, ensureTag, startupTag string, a
 func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 	aspect := query.Get("aspect")
+	if aspect == "denials" {
+		// collected from the system log, the state is not needed
+		return getDenials(r, query.Get("snap"))
+	}
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
@@ -288,6 +292,10 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		startupTag := query.Get("startup")
 		all := query.Get("all")
 		return getChangeTimings(st, chgID, ensureTag, startupTag, all == "true")
+	case "policy":
+		return getPolicyTrace(st, query.Get("snap"))
+	case "sandbox":
+		return getSandboxProfiles(st, query.Get("snap"))
 	default:
 		return BadRequest("unknown debug aspect %q", aspect)
 	}
@@ -0,0 +1,110 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/snapcore/snapd/interfaces/denials"
+)
+
+var (
+	denialsSystemLog = denials.SystemLog
+	denialsSuggest   = denials.Suggest
+)
+
+type denialJSON struct {
+	Kind          string   `json:"kind"`
+	Snap          string   `json:"snap"`
+	SecurityTag   string   `json:"security-tag,omitempty"`
+	Operation     string   `json:"operation,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	Mask          string   `json:"mask,omitempty"`
+	Capability    string   `json:"capability,omitempty"`
+	Family        string   `json:"family,omitempty"`
+	SockType      string   `json:"sock-type,omitempty"`
+	DBusBus       string   `json:"dbus-bus,omitempty"`
+	DBusPath      string   `json:"dbus-path,omitempty"`
+	DBusInterface string   `json:"dbus-interface,omitempty"`
+	DBusMember    string   `json:"dbus-member,omitempty"`
+	DBusPeerLabel string   `json:"dbus-peer-label,omitempty"`
+	Syscall       string   `json:"syscall,omitempty"`
+	Arch          string   `json:"arch,omitempty"`
+	Count         int      `json:"count"`
+	Suggested     []string `json:"suggested-interfaces,omitempty"`
+}
+
+func denialsFromLog(r io.Reader, snapName string) ([]*denialJSON, error) {
+	ds, err := denials.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	result := []*denialJSON{}
+	for _, d := range ds {
+		if snapName != "" && d.Snap != snapName {
+			continue
+		}
+		result = append(result, &denialJSON{
+			Kind:          d.Kind,
+			Snap:          d.Snap,
+			SecurityTag:   d.SecurityTag,
+			Operation:     d.Operation,
+			Name:          d.Name,
+			Mask:          d.Mask,
+			Capability:    d.Capability,
+			Family:        d.Family,
+			SockType:      d.SockType,
+			DBusBus:       d.DBusBus,
+			DBusPath:      d.DBusPath,
+			DBusInterface: d.DBusInterface,
+			DBusMember:    d.DBusMember,
+			DBusPeerLabel: d.DBusPeerLabel,
+			Syscall:       d.Syscall,
+			Arch:          d.Arch,
+			Count:         d.Count,
+			Suggested:     denialsSuggest(d),
+		})
+	}
+	return result, nil
+}
+
+func getDenials(r *http.Request, snapName string) Response {
+	// the system log has the denials of all the users and more
+	_, uid, _, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return Forbidden("cannot get remote user: %s", err)
+	}
+	if uid != 0 {
+		return Forbidden("cannot read the system log as a non-root user")
+	}
+
+	log, err := denialsSystemLog()
+	if err != nil {
+		return InternalError("cannot open the system log: %v", err)
+	}
+	defer log.Close()
+
+	result, err := denialsFromLog(log, snapName)
+	if err != nil {
+		return InternalError("cannot collect the denials: %v", err)
+	}
+	return SyncResponse(result, nil)
+}
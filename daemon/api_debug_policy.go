@@ -0,0 +1,116 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var ifacestateTraceSnapPolicy = ifacestate.TraceSnapPolicy
+
+type policyTraceStepJSON struct {
+	Declaration string `json:"declaration"`
+	Snap        string `json:"snap,omitempty"`
+	Side        string `json:"side"`
+	Name        string `json:"name"`
+	Interface   string `json:"interface"`
+	Constraints string `json:"constraints"`
+	Matched     bool   `json:"matched"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type policyCheckJSON struct {
+	Allowed bool                  `json:"allowed"`
+	Error   string                `json:"error,omitempty"`
+	Skipped string                `json:"skipped,omitempty"`
+	Trace   []policyTraceStepJSON `json:"trace,omitempty"`
+}
+
+type installationPolicyJSON struct {
+	Side      string          `json:"side"`
+	Name      string          `json:"name"`
+	Interface string          `json:"interface"`
+	Check     policyCheckJSON `json:"check"`
+}
+
+type connectionPolicyJSON struct {
+	Plug           string          `json:"plug"`
+	Slot           string          `json:"slot"`
+	Interface      string          `json:"interface"`
+	Connection     policyCheckJSON `json:"connection"`
+	AutoConnection policyCheckJSON `json:"auto-connection"`
+}
+
+type snapPolicyJSON struct {
+	Snap         string                    `json:"snap"`
+	Installation []*installationPolicyJSON `json:"installation"`
+	Connections  []*connectionPolicyJSON   `json:"connections"`
+}
+
+func policyCheckToJSON(check *ifacestate.PolicyCheck) policyCheckJSON {
+	j := policyCheckJSON{
+		Allowed: check.Allowed,
+		Error:   check.Error,
+		Skipped: check.Skipped,
+	}
+	for _, step := range check.Trace {
+		j.Trace = append(j.Trace, policyTraceStepJSON(step))
+	}
+	return j
+}
+
+func getPolicyTrace(st *state.State, snapName string) Response {
+	if snapName == "" {
+		return BadRequest("cannot trace the policy checks without a snap name")
+	}
+	sp, err := ifacestateTraceSnapPolicy(st, snapName)
+	if err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(snapName, err)
+		}
+		return InternalError("cannot trace the policy checks of snap %q: %v", snapName, err)
+	}
+
+	result := &snapPolicyJSON{
+		Snap:         sp.Snap,
+		Installation: []*installationPolicyJSON{},
+		Connections:  []*connectionPolicyJSON{},
+	}
+	for _, ip := range sp.Installation {
+		result.Installation = append(result.Installation, &installationPolicyJSON{
+			Side:      ip.Side,
+			Name:      ip.Name,
+			Interface: ip.Interface,
+			Check:     policyCheckToJSON(&ip.Check),
+		})
+	}
+	for _, cp := range sp.Connections {
+		result.Connections = append(result.Connections, &connectionPolicyJSON{
+			Plug:           cp.Plug.String(),
+			Slot:           cp.Slot.String(),
+			Interface:      cp.Interface,
+			Connection:     policyCheckToJSON(&cp.Connection),
+			AutoConnection: policyCheckToJSON(&cp.AutoConnection),
+		})
+	}
+	return SyncResponse(result, nil)
+}
@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var ifacestateSnapSandboxProfiles = ifacestate.SnapSandboxProfiles
+
+type sandboxSnippetJSON struct {
+	Interface string `json:"interface"`
+	Side      string `json:"side"`
+	Plug      string `json:"plug,omitempty"`
+	Slot      string `json:"slot,omitempty"`
+	Snippet   string `json:"snippet"`
+}
+
+type sandboxProfileJSON struct {
+	Backend     string                `json:"backend"`
+	Name        string                `json:"name"`
+	SecurityTag string                `json:"security-tag,omitempty"`
+	Path        string                `json:"path,omitempty"`
+	Content     string                `json:"content"`
+	Snippets    []*sandboxSnippetJSON `json:"snippets"`
+}
+
+type snapSandboxJSON struct {
+	Snap     string                `json:"snap"`
+	Profiles []*sandboxProfileJSON `json:"profiles"`
+}
+
+func getSandboxProfiles(st *state.State, snapName string) Response {
+	if snapName == "" {
+		return BadRequest("cannot show the sandbox profiles without a snap name")
+	}
+	sb, err := ifacestateSnapSandboxProfiles(st, snapName)
+	if err != nil {
+		if _, ok := err.(*snap.NotInstalledError); ok {
+			return SnapNotFound(snapName, err)
+		}
+		return InternalError("cannot obtain the sandbox profiles of snap %q: %v", snapName, err)
+	}
+
+	result := &snapSandboxJSON{
+		Snap:     sb.Snap,
+		Profiles: []*sandboxProfileJSON{},
+	}
+	for _, profile := range sb.Profiles {
+		pj := &sandboxProfileJSON{
+			Backend:     string(profile.Backend),
+			Name:        profile.Name,
+			SecurityTag: profile.SecurityTag,
+			Path:        profile.Path,
+			Content:     profile.Content,
+			Snippets:    []*sandboxSnippetJSON{},
+		}
+		for _, snippet := range profile.Snippets {
+			sj := &sandboxSnippetJSON{
+				Interface: snippet.Source.Interface,
+				Side:      snippet.Source.Side,
+				Snippet:   snippet.Snippet,
+			}
+			if snippet.Source.Plug.Snap != "" {
+				sj.Plug = snippet.Source.Plug.String()
+			}
+			if snippet.Source.Slot.Snap != "" {
+				sj.Slot = snippet.Source.Slot.String()
+			}
+			pj.Snippets = append(pj.Snippets, sj)
+		}
+		result.Profiles = append(result.Profiles, pj)
+	}
+	return SyncResponse(result, nil)
+}
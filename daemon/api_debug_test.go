@@ -22,11 +22,20 @@ package daemon
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/denials"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
 )
@@ -157,6 +166,318 @@ func (s *postDebugSuite) TestGetDebugBaseDeclaration(c *check.C) {
 		testutil.Contains, "type: base-declaration")
 }
 
+func (s *postDebugSuite) TestGetDebugPolicy(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	var tracedSnap string
+	restore := MockIfacestateTraceSnapPolicy(func(st *state.State, instanceName string) (*ifacestate.SnapPolicy, error) {
+		tracedSnap = instanceName
+		return &ifacestate.SnapPolicy{
+			Snap: instanceName,
+			Installation: []*ifacestate.InstallationPolicy{
+				{Side: "plug", Name: "plug", Interface: "test", Check: ifacestate.PolicyCheck{Allowed: true}},
+			},
+			Connections: []*ifacestate.ConnectionPolicy{{
+				Plug:      interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+				Slot:      interfaces.SlotRef{Snap: "producer", Name: "slot"},
+				Interface: "test",
+				Connection: ifacestate.PolicyCheck{
+					Error: `connection not allowed by slot rule of interface "test"`,
+					Trace: []policy.TraceStep{{
+						Declaration: "snap-declaration",
+						Snap:        "producer",
+						Side:        "slot",
+						Name:        "slot",
+						Interface:   "test",
+						Constraints: "allow-connection",
+						Reason:      "publisher id does not match",
+					}},
+				},
+				AutoConnection: ifacestate.PolicyCheck{Allowed: true, Skipped: "no declaration"},
+			}},
+		}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=policy&snap=consumer", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(tracedSnap, check.Equals, "consumer")
+
+	data, err := json.Marshal(rsp.Result)
+	c.Assert(err, check.IsNil)
+	var result map[string]interface{}
+	c.Assert(json.Unmarshal(data, &result), check.IsNil)
+	c.Check(result, check.DeepEquals, map[string]interface{}{
+		"snap": "consumer",
+		"installation": []interface{}{
+			map[string]interface{}{
+				"side": "plug", "name": "plug", "interface": "test",
+				"check": map[string]interface{}{"allowed": true},
+			},
+		},
+		"connections": []interface{}{
+			map[string]interface{}{
+				"plug":      "consumer:plug",
+				"slot":      "producer:slot",
+				"interface": "test",
+				"connection": map[string]interface{}{
+					"allowed": false,
+					"error":   `connection not allowed by slot rule of interface "test"`,
+					"trace": []interface{}{
+						map[string]interface{}{
+							"declaration": "snap-declaration",
+							"snap":        "producer",
+							"side":        "slot",
+							"name":        "slot",
+							"interface":   "test",
+							"constraints": "allow-connection",
+							"matched":     false,
+							"reason":      "publisher id does not match",
+						},
+					},
+				},
+				"auto-connection": map[string]interface{}{
+					"allowed": true,
+					"skipped": "no declaration",
+				},
+			},
+		},
+	})
+}
+
+func (s *postDebugSuite) TestGetDebugPolicyErrors(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=policy", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot trace the policy checks without a snap name")
+
+	restore := MockIfacestateTraceSnapPolicy(func(st *state.State, instanceName string) (*ifacestate.SnapPolicy, error) {
+		return nil, &snap.NotInstalledError{Snap: instanceName}
+	})
+	defer restore()
+	req, err = http.NewRequest("GET", "/v2/debug?aspect=policy&snap=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+
+	restore = MockIfacestateTraceSnapPolicy(func(st *state.State, instanceName string) (*ifacestate.SnapPolicy, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot trace the policy checks of snap "foo": boom`)
+}
+
+func (s *postDebugSuite) TestGetDebugSandbox(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	var dumpedSnap string
+	restore := MockIfacestateSnapSandboxProfiles(func(st *state.State, instanceName string) (*ifacestate.SnapSandbox, error) {
+		dumpedSnap = instanceName
+		return &ifacestate.SnapSandbox{
+			Snap: instanceName,
+			Profiles: []*ifacestate.SandboxProfile{{
+				Backend:     "apparmor",
+				Name:        "snap.consumer.app",
+				SecurityTag: "snap.consumer.app",
+				Path:        "/var/lib/snapd/apparmor/profiles/snap.consumer.app",
+				Content:     "profile \"snap.consumer.app\" {}\n",
+				Snippets: []*ifacestate.SandboxSnippet{{
+					Source: interfaces.SpecificationSource{
+						Interface: "test",
+						Side:      "plug",
+						Plug:      interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+						Slot:      interfaces.SlotRef{Snap: "producer", Name: "slot"},
+					},
+					Snippet: "# access to producer:slot",
+				}},
+			}, {
+				Backend: "kmod",
+				Name:    "snap.consumer.conf",
+				Snippets: []*ifacestate.SandboxSnippet{{
+					Source: interfaces.SpecificationSource{
+						Interface: "test",
+						Side:      "plug",
+						Plug:      interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+					},
+					Snippet: "test-module",
+				}},
+			}},
+		}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=sandbox&snap=consumer", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(dumpedSnap, check.Equals, "consumer")
+
+	data, err := json.Marshal(rsp.Result)
+	c.Assert(err, check.IsNil)
+	var result map[string]interface{}
+	c.Assert(json.Unmarshal(data, &result), check.IsNil)
+	c.Check(result, check.DeepEquals, map[string]interface{}{
+		"snap": "consumer",
+		"profiles": []interface{}{
+			map[string]interface{}{
+				"backend":      "apparmor",
+				"name":         "snap.consumer.app",
+				"security-tag": "snap.consumer.app",
+				"path":         "/var/lib/snapd/apparmor/profiles/snap.consumer.app",
+				"content":      "profile \"snap.consumer.app\" {}\n",
+				"snippets": []interface{}{
+					map[string]interface{}{
+						"interface": "test",
+						"side":      "plug",
+						"plug":      "consumer:plug",
+						"slot":      "producer:slot",
+						"snippet":   "# access to producer:slot",
+					},
+				},
+			},
+			map[string]interface{}{
+				"backend": "kmod",
+				"name":    "snap.consumer.conf",
+				"content": "",
+				"snippets": []interface{}{
+					map[string]interface{}{
+						"interface": "test",
+						"side":      "plug",
+						"plug":      "consumer:plug",
+						"snippet":   "test-module",
+					},
+				},
+			},
+		},
+	})
+}
+
+func (s *postDebugSuite) TestGetDebugSandboxErrors(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=sandbox", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot show the sandbox profiles without a snap name")
+
+	restore := MockIfacestateSnapSandboxProfiles(func(st *state.State, instanceName string) (*ifacestate.SnapSandbox, error) {
+		return nil, &snap.NotInstalledError{Snap: instanceName}
+	})
+	defer restore()
+	req, err = http.NewRequest("GET", "/v2/debug?aspect=sandbox&snap=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+
+	restore = MockIfacestateSnapSandboxProfiles(func(st *state.State, instanceName string) (*ifacestate.SnapSandbox, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot obtain the sandbox profiles of snap "foo": boom`)
+}
+
+const denialsLog = `audit: type=1400 audit(1611232542.106:1234): apparmor="DENIED" operation="open" profile="snap.foo.app" name="/etc/shadow" pid=1234 comm="app" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0
+audit: type=1400 audit(1611232542.107:1235): apparmor="DENIED" operation="open" profile="snap.foo.app" name="/etc/shadow" pid=1234 comm="app" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0
+audit: type=1326 audit(1611232546.106:1239): auid=1000 uid=1000 gid=1000 ses=2 subj=snap.bar.app pid=1236 comm="app" exe="/snap/bar/1/bin/app" sig=0 arch=x86_64 syscall=mount compat=0 ip=0x7f0000000000 code=0x50000
+`
+
+func mockDenialsSuggest() (restore func()) {
+	return MockDenialsSuggest(func(d *denials.Denial) []string {
+		if d.Kind == denials.KindSecComp {
+			return []string{"mount-control"}
+		}
+		return nil
+	})
+}
+
+func (s *postDebugSuite) TestGetDebugDenials(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	defer mockDenialsSuggest()()
+	restore := MockDenialsSystemLog(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(denialsLog)), nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=denials", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []*denialJSON{{
+		Kind:        "apparmor",
+		Snap:        "foo",
+		SecurityTag: "snap.foo.app",
+		Operation:   "open",
+		Name:        "/etc/shadow",
+		Mask:        "r",
+		Count:       2,
+	}, {
+		Kind:        "seccomp",
+		Snap:        "bar",
+		SecurityTag: "snap.bar.app",
+		Syscall:     "mount",
+		Arch:        "x86_64",
+		Count:       1,
+		Suggested:   []string{"mount-control"},
+	}})
+
+	req, err = http.NewRequest("GET", "/v2/debug?aspect=denials&snap=bar", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Assert(rsp.Result, check.HasLen, 1)
+	c.Check(rsp.Result.([]*denialJSON)[0].Snap, check.Equals, "bar")
+
+	req, err = http.NewRequest("GET", "/v2/debug?aspect=denials&snap=baz", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+	rsp = getDebug(debugCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []*denialJSON{})
+}
+
+func (s *postDebugSuite) TestGetDebugDenialsError(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	restore := MockDenialsSystemLog(func() (io.ReadCloser, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=denials", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot open the system log: boom")
+}
+
+func (s *postDebugSuite) TestGetDebugDenialsNonRoot(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	restore := MockDenialsSystemLog(func() (io.ReadCloser, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=denials", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rsp := getDebug(debugCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 403)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot read the system log as a non-root user")
+}
+
 func mockDurationThreshold() func() {
 	oldDurationThreshold := timings.DurationThreshold
 	restore := func() {
@@ -0,0 +1,54 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/hookstate"
+)
+
+var snapHooksCmd = &Command{
+	Path: "/v2/snaps/{name}/hooks",
+	GET:  getSnapHooks,
+}
+
+// getSnapHooks returns the recent hook runs of a snap. The runs are
+// forgotten when the snap is removed, but the snap does not need to be
+// installed, so that failed installs can be looked into.
+func getSnapHooks(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	name := vars["name"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	runs, err := hookstate.HookRuns(st, name)
+	if err != nil {
+		return InternalError("cannot get hook runs of snap %q: %v", name, err)
+	}
+	if runs == nil {
+		runs = []*hookstate.HookRun{}
+	}
+
+	return SyncResponse(runs, nil)
+}
@@ -0,0 +1,75 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+var _ = check.Suite(&snapHooksSuite{})
+
+type snapHooksSuite struct{}
+
+func (s *snapHooksSuite) TestGetHooks(c *check.C) {
+	defer daemon.MockMuxVars(func(*http.Request) map[string]string {
+		return map[string]string{"name": "foo"}
+	})()
+
+	c.Check(daemon.SnapHooksCmd.Path, check.Equals, "/v2/snaps/{name}/hooks")
+
+	o := overlord.Mock()
+	daemon.NewWithOverlord(o)
+	st := o.State()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/hooks", nil)
+	c.Assert(err, check.IsNil)
+
+	// no runs yet, and the snap need not be installed
+	rsp := daemon.GetSnapHooks(daemon.SnapHooksCmd, req, nil).(*daemon.Resp)
+	c.Assert(rsp.Type, check.Equals, daemon.ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []*hookstate.HookRun{})
+
+	start := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	run := &hookstate.HookRun{
+		Hook:       "install",
+		Revision:   snap.R(3),
+		ChangeID:   "12",
+		Start:      start,
+		End:        start.Add(time.Minute),
+		ExitStatus: 1,
+		Error:      "exit status 1",
+		Output:     "cannot frobnicate\n",
+	}
+	st.Lock()
+	st.Set("hook-runs", map[string][]*hookstate.HookRun{"foo": {run}})
+	st.Unlock()
+
+	rsp = daemon.GetSnapHooks(daemon.SnapHooksCmd, req, nil).(*daemon.Resp)
+	c.Assert(rsp.Type, check.Equals, daemon.ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []*hookstate.HookRun{run})
+}
@@ -21,6 +21,7 @@ package daemon
 
 import (
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
 )
 
 // plugJSON aids in marshaling snap.PlugInfo into JSON.
@@ -45,6 +46,11 @@ type slotJSON struct {
 	Label     string                 `json:"label,omitempty"`
 	// Connections are synthesized, they are not on the original type.
 	Connections []interfaces.PlugRef `json:"connections,omitempty"`
+	// HotplugKey identifies the device of slots created by hotplug.
+	HotplugKey snap.HotplugKey `json:"hotplug-key,omitempty"`
+	// HotplugGone indicates that the device of the hotplug slot is
+	// unplugged, its connections are restored when it re-appears.
+	HotplugGone bool `json:"hotplug-gone,omitempty"`
 }
 
 // interfaceJSON aids in marshaling interfaces.Info into JSON.
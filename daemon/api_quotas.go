@@ -0,0 +1,183 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+var (
+	quotaGroupsCmd = &Command{
+		Path: "/v2/quotas",
+		GET:  getQuotaGroups,
+		POST: postQuotaGroup,
+	}
+	quotaGroupInfoCmd = &Command{
+		Path: "/v2/quotas/{group}",
+		GET:  getQuotaGroupInfo,
+	}
+)
+
+type postQuotaGroupData struct {
+	Action      string             `json:"action"`
+	GroupName   string             `json:"group-name"`
+	Parent      string             `json:"parent,omitempty"`
+	Snaps       []string           `json:"snaps,omitempty"`
+	Constraints client.QuotaValues `json:"constraints,omitempty"`
+}
+
+func quotaGroupResult(grp *quota.Group) (*client.QuotaGroupResult, error) {
+	res := &client.QuotaGroupResult{
+		GroupName: grp.Name,
+		Parent:    grp.ParentGroup,
+		Subgroups: grp.SubGroups,
+		Snaps:     grp.Snaps,
+	}
+	if grp.MemoryLimit != 0 || grp.CPULimit != 0 {
+		res.Constraints = &client.QuotaValues{
+			Memory: grp.MemoryLimit,
+			CPU:    grp.CPULimit,
+		}
+	}
+
+	mem, err := grp.CurrentMemoryUsage()
+	if err != nil {
+		return nil, err
+	}
+	cpu, err := grp.CurrentCPUUsage()
+	if err != nil {
+		return nil, err
+	}
+	res.Current = &client.QuotaUsage{
+		Memory:  mem,
+		CPUTime: cpu,
+	}
+	return res, nil
+}
+
+// getQuotaGroups returns all the quota groups, sorted by name with
+// parent groups before their sub-groups.
+func getQuotaGroups(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	grps, err := servicestate.AllQuotas(st)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+
+	results := make([]*client.QuotaGroupResult, 0, len(grps))
+	for _, grp := range quota.SortedGroups(grps) {
+		res, err := quotaGroupResult(grp)
+		if err != nil {
+			return InternalError(err.Error())
+		}
+		results = append(results, res)
+	}
+	return SyncResponse(results, nil)
+}
+
+func getQuotaGroupInfo(c *Command, r *http.Request, user *auth.UserState) Response {
+	name := muxVars(r)["group"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	grp, err := servicestate.GetQuota(st, name)
+	if err == servicestate.ErrQuotaNotFound {
+		return NotFound("cannot find quota group %q", name)
+	}
+	if err != nil {
+		return InternalError(err.Error())
+	}
+
+	res, err := quotaGroupResult(grp)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+	return SyncResponse(res, nil)
+}
+
+// postQuotaGroup creates, updates or removes a quota group.
+func postQuotaGroup(c *Command, r *http.Request, user *auth.UserState) Response {
+	var data postQuotaGroupData
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return BadRequest("cannot decode quota action from request body: %v", err)
+	}
+	if err := quota.ValidateGroupName(data.GroupName); err != nil {
+		return BadRequest(err.Error())
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var ts *state.TaskSet
+	var err error
+	switch data.Action {
+	case "ensure":
+		var grp *quota.Group
+		grp, err = servicestate.GetQuota(st, data.GroupName)
+		switch {
+		case err == servicestate.ErrQuotaNotFound:
+			ts, err = servicestate.CreateQuota(st, data.GroupName, data.Parent, data.Snaps, data.Constraints.Memory, data.Constraints.CPU)
+		case err != nil:
+			return InternalError(err.Error())
+		case data.Parent != "" && data.Parent != grp.ParentGroup:
+			return BadRequest("cannot change the parent group of quota group %q", data.GroupName)
+		default:
+			ts, err = servicestate.UpdateQuota(st, data.GroupName, servicestate.QuotaGroupUpdate{
+				AddSnaps:       data.Snaps,
+				NewMemoryLimit: data.Constraints.Memory,
+				NewCPULimit:    data.Constraints.CPU,
+			})
+		}
+	case "remove":
+		ts, err = servicestate.RemoveQuota(st, data.GroupName)
+	default:
+		return BadRequest("unknown quota action %q", data.Action)
+	}
+
+	if err == servicestate.ErrQuotaNotFound {
+		return NotFound("cannot find quota group %q", data.GroupName)
+	}
+	if cce, ok := err.(*snapstate.ChangeConflictError); ok {
+		return SnapChangeConflict(cce)
+	}
+	if err != nil {
+		return BadRequest(err.Error())
+	}
+
+	chg := newChange(st, "quota-control", ts.Tasks()[0].Summary(), []*state.TaskSet{ts}, data.Snaps)
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
@@ -0,0 +1,266 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var _ = check.Suite(&quotaSuite{})
+
+type quotaSuite struct {
+	testutil.BaseTest
+	o  *overlord.Overlord
+	st *state.State
+}
+
+func (s *quotaSuite) SetUpTest(c *check.C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+	s.AddCleanup(snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {}))
+	s.AddCleanup(systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		return []byte("ActiveState=inactive"), nil
+	}))
+	s.AddCleanup(systemd.MockStopDelays(time.Millisecond, 25*time.Second))
+	s.AddCleanup(cgroup.MockVersion(cgroup.V2, nil))
+
+	s.o = overlord.Mock()
+	daemon.NewWithOverlord(s.o)
+	s.st = s.o.State()
+	runner := s.o.TaskRunner()
+	s.o.AddManager(servicestate.Manager(s.st, runner))
+	s.o.AddManager(runner)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	si := &snap.SideInfo{RealName: "test-snap", Revision: snap.R(1)}
+	snaptest.MockSnapCurrent(c, "name: test-snap\nversion: 1\napps:\n  svc:\n    command: bin/svc\n    daemon: simple\n", si)
+	snapstate.Set(s.st, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		SnapType: "app",
+	})
+}
+
+func (s *quotaSuite) post(c *check.C, body string) *daemon.Resp {
+	req, err := http.NewRequest("POST", "/v2/quotas", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	return daemon.QuotaGroupsCmd.POST(daemon.QuotaGroupsCmd, req, nil).(*daemon.Resp)
+}
+
+// postAndSettle posts the given quota action and runs the resulting
+// change to completion.
+func (s *quotaSuite) postAndSettle(c *check.C, body string) *state.Change {
+	rsp := s.post(c, body)
+	c.Assert(rsp.Type, check.Equals, daemon.ResponseTypeAsync, check.Commentf("%v", rsp.Result))
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	chg := s.st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "quota-control")
+
+	s.st.Unlock()
+	err := s.o.Settle(5 * time.Second)
+	s.st.Lock()
+	c.Assert(err, check.IsNil)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus, check.Commentf("%v", chg.Err()))
+	return chg
+}
+
+// createQuota creates a quota group via its change. The state must be
+// locked.
+func (s *quotaSuite) createQuota(c *check.C, name, parent string, snaps []string, memory uint64) {
+	ts, err := servicestate.CreateQuota(s.st, name, parent, snaps, memory, 0)
+	c.Assert(err, check.IsNil)
+	chg := s.st.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+
+	s.st.Unlock()
+	err = s.o.Settle(5 * time.Second)
+	s.st.Lock()
+	c.Assert(err, check.IsNil)
+	c.Assert(chg.Err(), check.IsNil)
+}
+
+func (s *quotaSuite) TestEnsureAndRemoveQuota(c *check.C) {
+	chg := s.postAndSettle(c, `{"action": "ensure", "group-name": "foo", "snaps": ["test-snap"], "constraints": {"memory": 1048576}}`)
+	s.st.Lock()
+	c.Check(chg.Summary(), check.Equals, `Create quota group "foo"`)
+	var snapNames []string
+	c.Check(chg.Get("snap-names", &snapNames), check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"test-snap"})
+	grp, err := servicestate.GetQuota(s.st, "foo")
+	s.st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(grp.Snaps, check.DeepEquals, []string{"test-snap"})
+	c.Check(grp.MemoryLimit, check.Equals, uint64(1048576))
+
+	// ensuring again updates the group
+	chg = s.postAndSettle(c, `{"action": "ensure", "group-name": "foo", "constraints": {"memory": 2097152, "cpu": 50}}`)
+	s.st.Lock()
+	c.Check(chg.Summary(), check.Equals, `Update quota group "foo"`)
+	grp, err = servicestate.GetQuota(s.st, "foo")
+	s.st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(grp.MemoryLimit, check.Equals, uint64(2097152))
+	c.Check(grp.CPULimit, check.Equals, 50)
+
+	rsp := s.post(c, `{"action": "ensure", "group-name": "foo", "parent": "bar"}`)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, `cannot change the parent group of quota group "foo"`)
+
+	chg = s.postAndSettle(c, `{"action": "remove", "group-name": "foo"}`)
+	s.st.Lock()
+	c.Check(chg.Summary(), check.Equals, `Remove quota group "foo"`)
+	_, err = servicestate.GetQuota(s.st, "foo")
+	s.st.Unlock()
+	c.Check(err, check.Equals, servicestate.ErrQuotaNotFound)
+
+	rsp = s.post(c, `{"action": "remove", "group-name": "foo"}`)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, `cannot find quota group "foo"`)
+}
+
+func (s *quotaSuite) TestPostQuotaErrors(c *check.C) {
+	for _, t := range []struct {
+		body   string
+		status int
+		msg    string
+	}{
+		{`garbage`, 400, `cannot decode quota action from request body: .*`},
+		{`{"action": "ensure", "group-name": "Foo"}`, 400, `invalid group name "Foo": .*`},
+		{`{"action": "frobnicate", "group-name": "foo"}`, 400, `unknown quota action "frobnicate"`},
+		{`{"action": "ensure", "group-name": "foo", "snaps": ["missing"]}`, 400, `cannot add snap "missing" to quota group "foo": snap is not installed`},
+	} {
+		rsp := s.post(c, t.body)
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Matches, t.msg, check.Commentf(t.body))
+	}
+}
+
+func (s *quotaSuite) TestPostQuotaConflict(c *check.C) {
+	s.st.Lock()
+	chg := s.st.NewChange("refresh", "...")
+	t := s.st.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "test-snap"}})
+	chg.AddTask(t)
+	s.st.Unlock()
+
+	rsp := s.post(c, `{"action": "ensure", "group-name": "foo", "snaps": ["test-snap"]}`)
+	c.Check(rsp.Status, check.Equals, 409)
+	c.Check(string(rsp.Result.(*daemon.ErrorResult).Kind), check.Equals, "snap-change-conflict")
+}
+
+func (s *quotaSuite) TestPostQuotaGroupConflict(c *check.C) {
+	rsp := s.post(c, `{"action": "ensure", "group-name": "foo"}`)
+	c.Assert(rsp.Type, check.Equals, daemon.ResponseTypeAsync, check.Commentf("%v", rsp.Result))
+
+	// the group is being created still
+	for _, body := range []string{
+		`{"action": "ensure", "group-name": "foo"}`,
+		`{"action": "remove", "group-name": "foo"}`,
+	} {
+		rsp = s.post(c, body)
+		c.Check(rsp.Status, check.Equals, 409)
+		c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, `quota group "foo" has "quota-control" change in progress`)
+	}
+}
+
+func (s *quotaSuite) TestGetQuotas(c *check.C) {
+	s.st.Lock()
+	s.createQuota(c, "foo", "", nil, 2097152)
+	s.createQuota(c, "bar", "foo", []string{"test-snap"}, 1048576)
+	s.st.Unlock()
+
+	cgroupDir := filepath.Join(dirs.GlobalRootDir, "/sys/fs/cgroup/snap.foo.slice")
+	c.Assert(os.MkdirAll(cgroupDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(cgroupDir, "memory.current"), []byte("4096\n"), 0644), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(cgroupDir, "cpu.stat"), []byte("usage_usec 2000000\n"), 0644), check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/quotas", nil)
+	c.Assert(err, check.IsNil)
+	rsp := daemon.QuotaGroupsCmd.GET(daemon.QuotaGroupsCmd, req, nil).(*daemon.Resp)
+	c.Assert(rsp.Type, check.Equals, daemon.ResponseTypeSync, check.Commentf("%v", rsp.Result))
+	c.Check(rsp.Result, check.DeepEquals, []*client.QuotaGroupResult{{
+		GroupName:   "foo",
+		Subgroups:   []string{"bar"},
+		Constraints: &client.QuotaValues{Memory: 2097152},
+		Current:     &client.QuotaUsage{Memory: 4096, CPUTime: 2 * time.Second},
+	}, {
+		GroupName:   "bar",
+		Parent:      "foo",
+		Snaps:       []string{"test-snap"},
+		Constraints: &client.QuotaValues{Memory: 1048576},
+		Current:     &client.QuotaUsage{},
+	}})
+}
+
+func (s *quotaSuite) TestGetQuotaGroupInfo(c *check.C) {
+	s.st.Lock()
+	s.createQuota(c, "foo", "", []string{"test-snap"}, 0)
+	s.st.Unlock()
+
+	c.Check(daemon.QuotaGroupInfoCmd.Path, check.Equals, "/v2/quotas/{group}")
+	for _, name := range []string{"foo", "bar"} {
+		name := name
+		restore := daemon.MockMuxVars(func(*http.Request) map[string]string {
+			return map[string]string{"group": name}
+		})
+		req, err := http.NewRequest("GET", "/v2/quotas/"+name, nil)
+		c.Assert(err, check.IsNil)
+		rsp := daemon.QuotaGroupInfoCmd.GET(daemon.QuotaGroupInfoCmd, req, nil).(*daemon.Resp)
+		restore()
+
+		if name == "bar" {
+			c.Check(rsp.Status, check.Equals, 404)
+			c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, `cannot find quota group "bar"`)
+			continue
+		}
+		c.Assert(rsp.Type, check.Equals, daemon.ResponseTypeSync)
+		c.Check(rsp.Result, check.DeepEquals, &client.QuotaGroupResult{
+			GroupName: "foo",
+			Snaps:     []string{"test-snap"},
+			Current:   &client.QuotaUsage{},
+		})
+	}
+}
@@ -110,6 +110,7 @@ type apiBaseSuite struct {
 	jctlSvcses         [][]string
 	jctlNs             []int
 	jctlFollows        []bool
+	jctlOpts           []systemd.LogOptions
 	jctlRCs            []io.ReadCloser
 	jctlErrs           []error
 
@@ -249,10 +250,11 @@ func (s *apiBaseSuite) systemctl(args ...string) (buf []byte, err error) {
 	return buf, err
 }
 
-func (s *apiBaseSuite) journalctl(svcs []string, n int, follow bool) (rc io.ReadCloser, err error) {
+func (s *apiBaseSuite) journalctl(svcs []string, opts systemd.LogOptions) (rc io.ReadCloser, err error) {
 	s.jctlSvcses = append(s.jctlSvcses, svcs)
-	s.jctlNs = append(s.jctlNs, n)
-	s.jctlFollows = append(s.jctlFollows, follow)
+	s.jctlNs = append(s.jctlNs, opts.N)
+	s.jctlFollows = append(s.jctlFollows, opts.Follow)
+	s.jctlOpts = append(s.jctlOpts, opts)
 
 	if len(s.jctlErrs) > 0 {
 		err, s.jctlErrs = s.jctlErrs[0], s.jctlErrs[1:]
@@ -271,6 +273,7 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 	s.jctlSvcses = nil
 	s.jctlNs = nil
 	s.jctlFollows = nil
+	s.jctlOpts = nil
 	s.jctlRCs = nil
 	s.jctlErrs = nil
 
@@ -3606,6 +3609,206 @@ func (s *apiSuite) TestSetConfChangeConflict(c *check.C) {
 		"type": "error"})
 }
 
+func (s *apiSuite) TestSetConfInvalid(c *check.C) {
+	s.daemon(c)
+	info := s.mockSnap(c, configYaml)
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(`{
+  "properties": {"port": {"type": "integer"}}
+}`), 0644)
+	c.Assert(err, check.IsNil)
+
+	text, err := json.Marshal(map[string]interface{}{"port": "eighty"})
+	c.Assert(err, check.IsNil)
+
+	buffer := bytes.NewBuffer(text)
+	req, err := http.NewRequest("PUT", "/v2/snaps/config-snap/conf", buffer)
+	c.Assert(err, check.IsNil)
+
+	s.vars = map[string]string{"name": "config-snap"}
+
+	rec := httptest.NewRecorder()
+	snapConfCmd.PUT(snapConfCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 400)
+
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Assert(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, map[string]interface{}{
+		"message": `invalid option "port": expected integer, got string`,
+		"kind":    "option-invalid",
+		"value": map[string]interface{}{
+			"path":    "port",
+			"message": "expected integer, got string",
+		},
+	})
+}
+
+func (s *apiSuite) mockConfHistory(c *check.C, st *state.State, values ...interface{}) {
+	st.Lock()
+	defer st.Unlock()
+	for _, value := range values {
+		tr := config.NewTransaction(st)
+		tr.SetOrigin(config.OriginUser)
+		c.Assert(tr.Set("config-snap", "key", value), check.IsNil)
+		tr.Commit()
+	}
+}
+
+func (s *apiSuite) TestGetConfHistory(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	s.mockConfHistory(c, d.overlord.State(), "foo", "bar")
+
+	s.vars = map[string]string{"name": "config-snap"}
+	req, err := http.NewRequest("GET", "/v2/snaps/config-snap/conf?history=true", nil)
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	snapConfCmd.GET(snapConfCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var body map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &body), check.IsNil)
+	entries := body["result"].([]interface{})
+	c.Assert(entries, check.HasLen, 2)
+	entry := entries[1].(map[string]interface{})
+	c.Check(entry["id"], check.Equals, 2.)
+	c.Check(entry["origin"], check.Equals, "user")
+	c.Check(entry["changes"], check.DeepEquals, []interface{}{
+		map[string]interface{}{"key": "key", "old": "foo", "new": "bar"},
+	})
+
+	// no history
+	s.vars = map[string]string{"name": "other-snap"}
+	req, err = http.NewRequest("GET", "/v2/snaps/other-snap/conf?history=true", nil)
+	c.Assert(err, check.IsNil)
+	rec = httptest.NewRecorder()
+	snapConfCmd.GET(snapConfCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &body), check.IsNil)
+	c.Check(body["result"], check.DeepEquals, []interface{}{})
+}
+
+func (s *apiSuite) TestSetConfRollback(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	s.mockConfHistory(c, d.overlord.State(), "foo", "bar")
+
+	// Mock the hook runner
+	hookRunner := testutil.MockCommand(c, "snap", "")
+	defer hookRunner.Restore()
+
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	req, err := http.NewRequest("PUT", "/v2/snaps/config-snap/conf?rollback=1", nil)
+	c.Assert(err, check.IsNil)
+	s.vars = map[string]string{"name": "config-snap"}
+
+	rec := httptest.NewRecorder()
+	snapConfCmd.PUT(snapConfCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 202)
+
+	var body map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &body), check.IsNil)
+	id := body["change"].(string)
+
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.Change(id)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+
+	<-chg.Ready()
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(chg.Summary(), check.Equals, `Roll back configuration of "config-snap" snap to change 1`)
+	c.Check(chg.Status(), check.Equals, state.DoneStatus)
+	tr := config.NewTransaction(st)
+	var value string
+	c.Assert(tr.Get("config-snap", "key", &value), check.IsNil)
+	c.Check(value, check.Equals, "foo")
+
+	history, err := config.History(st, "config-snap")
+	c.Assert(err, check.IsNil)
+	c.Assert(history, check.HasLen, 3)
+	c.Check(history[2].Origin, check.Equals, config.OriginUser)
+}
+
+func (s *apiSuite) TestSetConfRollbackErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+	s.mockConfHistory(c, d.overlord.State(), "foo", "bar")
+	s.vars = map[string]string{"name": "config-snap"}
+
+	for _, t := range []struct {
+		rollback string
+		message  string
+	}{
+		{"x", `cannot parse rollback change "x": .*`},
+		{"2", `configuration of snap "config-snap" is already at change 2`},
+		{"7", `cannot roll back configuration of snap "config-snap" to change 7: available changes are 0 to 1`},
+	} {
+		req, err := http.NewRequest("PUT", "/v2/snaps/config-snap/conf?rollback="+t.rollback, nil)
+		c.Assert(err, check.IsNil)
+		rec := httptest.NewRecorder()
+		snapConfCmd.PUT(snapConfCmd, req, nil).ServeHTTP(rec, req)
+		c.Check(rec.Code, check.Equals, 400)
+
+		var body map[string]interface{}
+		c.Assert(json.Unmarshal(rec.Body.Bytes(), &body), check.IsNil)
+		c.Check(body["result"].(map[string]interface{})["message"], check.Matches, t.message)
+	}
+}
+
+func (s *apiSuite) runGetConfSchema(c *check.C, snapName string, statusCode int) interface{} {
+	s.vars = map[string]string{"name": snapName}
+	req, err := http.NewRequest("GET", "/v2/snaps/"+snapName+"/conf?schema=true", nil)
+	c.Check(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	snapConfCmd.GET(snapConfCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, statusCode)
+
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	return body["result"]
+}
+
+func (s *apiSuite) TestGetConfSchema(c *check.C) {
+	s.daemon(c)
+	info := s.mockSnap(c, configYaml)
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(`{
+  "properties": {"port": {"type": "integer"}}
+}`), 0644)
+	c.Assert(err, check.IsNil)
+
+	result := s.runGetConfSchema(c, "config-snap", 200)
+	c.Check(result, check.DeepEquals, map[string]interface{}{
+		"properties": map[string]interface{}{
+			"port": map[string]interface{}{"type": "integer"},
+		},
+	})
+}
+
+func (s *apiSuite) TestGetConfSchemaMissing(c *check.C) {
+	s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	result := s.runGetConfSchema(c, "config-snap", 404)
+	c.Check(result, check.DeepEquals, map[string]interface{}{
+		"message": `snap "config-snap" has no configuration schema`,
+	})
+
+	result = s.runGetConfSchema(c, "system", 404)
+	c.Check(result, check.DeepEquals, map[string]interface{}{
+		"message": `snap "system" has no configuration schema`,
+	})
+
+	result = s.runGetConfSchema(c, "other-snap", 404)
+	c.Check(result.(map[string]interface{})["kind"], check.Equals, "snap-not-found")
+}
+
 func (s *apiSuite) TestAppIconGet(c *check.C) {
 	d := s.daemon(c)
 
@@ -6586,6 +6789,38 @@ func (s *apiSuite) TestSnapctlForbiddenError(c *check.C) {
 	c.Assert(rsp.Status, check.Equals, 403)
 }
 
+func (s *apiSuite) TestSnapctlSetFile(c *check.C) {
+	d := s.daemon(c)
+
+	runSnapctlUcrednetGet = func(string) (int32, uint32, string, error) {
+		return 100, 0, dirs.SnapSocket, nil
+	}
+	defer func() { runSnapctlUcrednetGet = ucrednetGet }()
+
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("snap-cookies", map[string]string{"some-cookie": "some-snap"})
+	st.Unlock()
+
+	body, err := json.Marshal(&client.SnapCtlOptions{
+		ContextID: "some-cookie",
+		Args:      []string{"set", "--file=config.yaml"},
+		Stdin:     []byte("foo:\n  bar: baz\n"),
+	})
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/snapctl", bytes.NewBuffer(body))
+	c.Assert(err, check.IsNil)
+	rsp := runSnapctl(snapctlCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+
+	st.Lock()
+	defer st.Unlock()
+	tr := config.NewTransaction(st)
+	var value string
+	c.Assert(tr.Get("some-snap", "foo.bar", &value), check.IsNil)
+	c.Check(value, check.Equals, "baz")
+}
+
 type appSuite struct {
 	apiBaseSuite
 	cmd *testutil.MockCmd
@@ -6682,6 +6917,41 @@ UnitFileState=enabled
 	c.Check(sort.StringsAreSorted(appNames), check.Equals, true)
 }
 
+func (s *appSuite) TestGetAppsInfoServiceDetails(c *check.C) {
+	s.sysctlBufs = append(s.sysctlBufs, []byte(`
+Id=snap.snap-a.svc1.service
+Type=simple
+ActiveState=activating
+UnitFileState=enabled
+SubState=auto-restart
+NRestarts=3
+MainPID=0
+ExecMainCode=2
+ExecMainStatus=9
+MemoryCurrent=[not set]
+CPUUsageNSec=1500000000
+`[1:]))
+
+	req, err := http.NewRequest("GET", "/v2/apps?names=snap-a.svc1", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getAppsInfo(appsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	apps := rsp.Result.([]client.AppInfo)
+	c.Assert(apps, check.HasLen, 1)
+	c.Check(apps[0], check.DeepEquals, client.AppInfo{
+		Snap:       "snap-a",
+		Name:       "svc1",
+		Daemon:     "simple",
+		Enabled:    true,
+		SubState:   "auto-restart",
+		Restarts:   3,
+		ExitCode:   "killed",
+		ExitStatus: 9,
+		CPUUsage:   1500 * time.Millisecond,
+	})
+}
+
 func (s *appSuite) TestGetAppsInfoNames(c *check.C) {
 
 	req, err := http.NewRequest("GET", "/v2/apps?names=snap-d", nil)
@@ -6916,7 +7186,7 @@ func (s *appSuite) TestLogs(c *check.C) {
 {"MESSAGE": "hello2", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "44"}
 {"MESSAGE": "hello3", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "46"}
 {"MESSAGE": "hello4", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "48"}
-{"MESSAGE": "hello5", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "50"}
+{"MESSAGE": "hello5", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "50", "PRIORITY": "3"}
 	`))}
 
 	req, err := http.NewRequest("GET", "/v2/logs?names=snap-a.svc2&n=42&follow=false", nil)
@@ -6936,7 +7206,7 @@ func (s *appSuite) TestLogs(c *check.C) {
 {"timestamp":"1970-01-01T00:00:00.000044Z","message":"hello2","sid":"xyzzy","pid":"42"}
 {"timestamp":"1970-01-01T00:00:00.000046Z","message":"hello3","sid":"xyzzy","pid":"42"}
 {"timestamp":"1970-01-01T00:00:00.000048Z","message":"hello4","sid":"xyzzy","pid":"42"}
-{"timestamp":"1970-01-01T00:00:00.00005Z","message":"hello5","sid":"xyzzy","pid":"42"}
+{"timestamp":"1970-01-01T00:00:00.00005Z","message":"hello5","sid":"xyzzy","pid":"42","priority":"3"}
 `[1:])
 }
 
@@ -7007,6 +7277,44 @@ func (s *appSuite) TestLogsBadFollow(c *check.C) {
 	c.Assert(rsp.Type, check.Equals, ResponseTypeError)
 }
 
+func (s *appSuite) TestLogsTimeRangeAndPriority(c *check.C) {
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(""))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?since=2020-09-13T12:26:40Z&until=2020-09-13T13:26:40%2B01:00&priority=warning", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	c.Assert(s.jctlOpts, check.HasLen, 1)
+	opts := s.jctlOpts[0]
+	c.Check(opts.Since.Equal(time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)), check.Equals, true)
+	c.Check(opts.Until.Equal(time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)), check.Equals, true)
+	c.Check(opts.Priority, check.Equals, "warning")
+}
+
+func (s *appSuite) TestLogsBadTimeRangeAndPriority(c *check.C) {
+	for _, t := range []struct {
+		query string
+		err   string
+	}{
+		{"since=yesterday", `invalid value for since: "yesterday": .*`},
+		{"until=1h", `invalid value for until: "1h": .*`},
+		{"since=2020-09-13T12:00:00Z&until=2020-09-13T11:00:00Z", `invalid time range: until is before since`},
+		{"priority=loud", `invalid log priority "loud", expected one of: .*`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/logs?"+t.query, nil)
+		c.Assert(err, check.IsNil)
+
+		rsp := getLogs(logsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, 400, check.Commentf(t.query))
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.err)
+	}
+	c.Check(s.jctlOpts, check.HasLen, 0)
+}
+
 func (s *appSuite) TestLogsBadName(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/logs?names=hello", nil)
 	c.Assert(err, check.IsNil)
@@ -7150,6 +7458,17 @@ func (s *appSuite) TestPostAppsBadApp(c *check.C) {
 	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `snap "snap-a" has no service "what"`)
 }
 
+func (s *appSuite) TestPostAppsUserServices(c *check.C) {
+	s.mkInstalledInState(c, s.d, "snap-e", "dev", "v1", snap.R(1), true, "apps: {usvc: {daemon: simple, daemon-scope: user}}")
+
+	req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBufferString(`{"action": "stop", "names": ["snap-e"]}`))
+	c.Assert(err, check.IsNil)
+	rsp := postApps(appsCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot perform operation on user services via snapd, use --user`)
+}
+
 func (s *appSuite) TestPostAppsBadAction(c *check.C) {
 	req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBufferString(`{"action": "discombobulate", "names": ["snap-a.svc1"]}`))
 	c.Assert(err, check.IsNil)
@@ -20,9 +20,13 @@
 package daemon
 
 import (
+	"io"
 	"net/http"
 
+	"github.com/snapcore/snapd/interfaces/denials"
 	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
 )
 
 type Resp = resp
@@ -51,3 +55,35 @@ func MockBuildID(mock string) (restore func()) {
 		buildID = old
 	}
 }
+
+func MockIfacestateTraceSnapPolicy(f func(st *state.State, instanceName string) (*ifacestate.SnapPolicy, error)) (restore func()) {
+	old := ifacestateTraceSnapPolicy
+	ifacestateTraceSnapPolicy = f
+	return func() {
+		ifacestateTraceSnapPolicy = old
+	}
+}
+
+func MockIfacestateSnapSandboxProfiles(f func(st *state.State, instanceName string) (*ifacestate.SnapSandbox, error)) (restore func()) {
+	old := ifacestateSnapSandboxProfiles
+	ifacestateSnapSandboxProfiles = f
+	return func() {
+		ifacestateSnapSandboxProfiles = old
+	}
+}
+
+func MockDenialsSystemLog(f func() (io.ReadCloser, error)) (restore func()) {
+	old := denialsSystemLog
+	denialsSystemLog = f
+	return func() {
+		denialsSystemLog = old
+	}
+}
+
+func MockDenialsSuggest(f func(d *denials.Denial) []string) (restore func()) {
+	old := denialsSuggest
+	denialsSuggest = f
+	return func() {
+		denialsSuggest = old
+	}
+}
@@ -185,6 +185,7 @@ const (
 	errorKindInterfacesUnchanged = errorKind("interfaces-unchanged")
 
 	errorKindConfigNoSuchOption = errorKind("option-not-found")
+	errorKindConfigInvalid      = errorKind("option-invalid")
 
 	errorKindDaemonRestart = errorKind("daemon-restart")
 	errorKindSystemRestart = errorKind("system-restart")
@@ -323,11 +324,16 @@ func (rr *journalLineReaderSeqResponse) ServeHTTP(w http.ResponseWriter, r *http
 
 		// ignore the error...
 		t, _ := log.Time()
+		priority := log.Priority()
+		if priority == "-" {
+			priority = ""
+		}
 		if err = enc.Encode(client.Log{
 			Timestamp: t,
 			Message:   log.Message(),
 			SID:       log.SID(),
 			PID:       log.PID(),
+			Priority:  priority,
 		}); err != nil {
 			break
 		}
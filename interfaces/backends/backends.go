@@ -28,9 +28,11 @@ import (
 	"github.com/snapcore/snapd/interfaces/kmod"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
 	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	selinux_sandbox "github.com/snapcore/snapd/sandbox/selinux"
 )
 
 var All []interfaces.SecurityBackend = backends()
@@ -73,5 +75,12 @@ func backends() []interfaces.SecurityBackend {
 	case apparmor_sandbox.Partial, apparmor_sandbox.Full:
 		all = append(all, &apparmor.Backend{})
 	}
+
+	// Enable the SELinux backend only when the policy is enforced. In
+	// permissive mode snaps keep running in the domains of the snapd policy
+	// and there is nothing to gain from building per-snap policy modules.
+	if selinux_sandbox.ProbedLevel() == selinux_sandbox.Enforcing {
+		all = append(all, &selinux.Backend{})
+	}
 	return all
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces/backends"
 	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
+	selinux_sandbox "github.com/snapcore/snapd/sandbox/selinux"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -55,6 +56,33 @@ func (s *backendsSuite) TestIsAppArmorEnabled(c *C) {
 	}
 }
 
+func (s *backendsSuite) TestIsSELinuxEnabled(c *C) {
+	for _, t := range []struct {
+		enabled, enforcing bool
+		expected           bool
+	}{
+		{false, false, false},
+		{true, false, false},
+		{true, true, true},
+	} {
+		restore := selinux_sandbox.MockIsEnabled(func() (bool, error) { return t.enabled, nil })
+		defer restore()
+		restore = selinux_sandbox.MockIsEnforcing(func() (bool, error) { return t.enforcing, nil })
+		defer restore()
+
+		all := backends.Backends()
+		names := make([]string, len(all))
+		for i, backend := range all {
+			names[i] = string(backend.Name())
+		}
+		if t.expected {
+			c.Check(names, testutil.Contains, "selinux")
+		} else {
+			c.Check(names, Not(testutil.Contains), "selinux")
+		}
+	}
+}
+
 func (s *backendsSuite) TestEssentialOrdering(c *C) {
 	restore := apparmor_sandbox.MockLevel(apparmor_sandbox.Full)
 	defer restore()
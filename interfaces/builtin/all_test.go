@@ -32,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/kmod"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
@@ -114,6 +115,19 @@ type seccompDefiner4 interface {
 	SecCompPermanentSlot(spec *seccomp.Specification, slot *snap.SlotInfo) error
 }
 
+type selinuxDefiner1 interface {
+	SELinuxConnectedPlug(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type selinuxDefiner2 interface {
+	SELinuxConnectedSlot(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+}
+type selinuxDefiner3 interface {
+	SELinuxPermanentPlug(spec *selinux.Specification, plug *snap.PlugInfo) error
+}
+type selinuxDefiner4 interface {
+	SELinuxPermanentSlot(spec *selinux.Specification, slot *snap.SlotInfo) error
+}
+
 type systemdDefiner1 interface {
 	SystemdConnectedPlug(spec *systemd.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 }
@@ -167,6 +181,11 @@ var allGoodDefiners = []reflect.Type{
 	reflect.TypeOf((*seccompDefiner2)(nil)).Elem(),
 	reflect.TypeOf((*seccompDefiner3)(nil)).Elem(),
 	reflect.TypeOf((*seccompDefiner4)(nil)).Elem(),
+	// selinux
+	reflect.TypeOf((*selinuxDefiner1)(nil)).Elem(),
+	reflect.TypeOf((*selinuxDefiner2)(nil)).Elem(),
+	reflect.TypeOf((*selinuxDefiner3)(nil)).Elem(),
+	reflect.TypeOf((*selinuxDefiner4)(nil)).Elem(),
 	// systemd
 	reflect.TypeOf((*systemdDefiner1)(nil)).Elem(),
 	reflect.TypeOf((*systemdDefiner2)(nil)).Elem(),
@@ -19,6 +19,17 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 // Only allow raw disk devices; not loop, ram, CDROM, generic SCSI, network,
 // tape, raid, etc devices or disk partitions
 const blockDevicesSummary = `allows access to disk block devices`
@@ -79,10 +90,76 @@ var blockDevicesConnectedPlugUDev = []string{
 	`KERNEL=="megaraid_sas_ioctl_node"`,
 }
 
+// blockDevicesInterface gives access to all raw disks through the implicit
+// slot and to a single removable disk through the slots created when such a
+// disk is hotplugged, along with its partitions.
 type blockDevicesInterface struct {
 	commonInterface
 }
 
+// Pattern to match the device nodes of removable disks, this must match the
+// AppArmor rules above
+var blockDevicesRemovableNodePattern = regexp.MustCompile("^/dev/(sd[a-h]?[a-z]|mmcblk[0-9]{1,3})$")
+
+// BeforePrepareSlot checks validity of the defined slot
+func (iface *blockDevicesInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	path, ok := slot.Attrs["path"]
+	if !ok {
+		return nil
+	}
+	if p, ok := path.(string); !ok || !blockDevicesRemovableNodePattern.MatchString(p) {
+		return fmt.Errorf("block-devices path attribute must be a valid device node")
+	}
+	return nil
+}
+
+// blockDevicesDiskRules returns the udev rules matching the given disk and its
+// partitions. The partitions of disks whose names end with a digit, such as
+// mmcblk0, have a "p" between the name of the disk and their number.
+func blockDevicesDiskRules(path string) []string {
+	disk := strings.TrimPrefix(path, "/dev/")
+	partitions := disk + "[0-9]*"
+	if last := disk[len(disk)-1]; last >= '0' && last <= '9' {
+		partitions = disk + "p[0-9]*"
+	}
+	return []string{
+		fmt.Sprintf(`SUBSYSTEM=="block", ENV{DEVTYPE}=="disk", KERNEL=="%s"`, disk),
+		fmt.Sprintf(`SUBSYSTEM=="block", ENV{DEVTYPE}=="partition", KERNEL=="%s"`, partitions),
+	}
+}
+
+func (iface *blockDevicesInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	return hotplugUDevConnectedPlug(&iface.commonInterface, spec, plug, slot, blockDevicesDiskRules)
+}
+
+func (iface *blockDevicesInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.Subsystem() != "block" || di.DeviceType() != "disk" || !blockDevicesRemovableNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	// only consider USB disks and SD cards as removable
+	bus, _ := di.Attribute("ID_BUS")
+	sd, _ := di.Attribute("ID_DRIVE_FLASH_SD")
+	if bus != "usb" && sd != "1" {
+		return nil, nil
+	}
+	slot := hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	}
+	return &slot, nil
+}
+
+// HotplugKey identifies removable disks by their serial, SD cards often lack
+// the vendor and model attributes the default key relies on.
+func (iface *blockDevicesInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	serial, ok := di.Attribute("ID_SERIAL")
+	if !ok || serial == "" {
+		return "", nil
+	}
+	return hotplugKeyFromValues(iface.Name(), serial), nil
+}
+
 func init() {
 	registerIface(&blockDevicesInterface{commonInterface{
 		name:                  "block-devices",
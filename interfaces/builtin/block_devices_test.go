@@ -20,11 +20,14 @@
 package builtin_test
 
 import (
+	"strings"
+
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -109,3 +112,97 @@ func (s *blockDevicesInterfaceSuite) TestAutoConnect(c *C) {
 func (s *blockDevicesInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+const blockDevicesHotplugCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  usb-disk:
+    interface: block-devices
+    path: /dev/sdb
+`
+
+func (s *blockDevicesInterfaceSuite) TestSanitizeHotplugSlot(c *C) {
+	_, slotInfo := MockConnectedSlot(c, blockDevicesHotplugCoreYaml, nil, "usb-disk")
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+
+	slotInfo.Attrs["path"] = "/dev/sdb1"
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, "block-devices path attribute must be a valid device node")
+}
+
+func (s *blockDevicesInterfaceSuite) TestUDevSpecHotplugSlot(c *C) {
+	slot, _ := MockConnectedSlot(c, blockDevicesHotplugCoreYaml, nil, "usb-disk")
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 3)
+	c.Assert(spec.Snippets(), testutil.Contains, `# block-devices
+SUBSYSTEM=="block", ENV{DEVTYPE}=="disk", KERNEL=="sdb", TAG+="snap_consumer_app"`)
+	c.Assert(spec.Snippets(), testutil.Contains, `# block-devices
+SUBSYSTEM=="block", ENV{DEVTYPE}=="partition", KERNEL=="sdb[0-9]*", TAG+="snap_consumer_app"`)
+}
+
+func (s *blockDevicesInterfaceSuite) TestUDevSpecHotplugSlotSDCard(c *C) {
+	yaml := strings.Replace(blockDevicesHotplugCoreYaml, "/dev/sdb", "/dev/mmcblk1", 1)
+	slot, _ := MockConnectedSlot(c, yaml, nil, "usb-disk")
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 3)
+	c.Assert(spec.Snippets(), testutil.Contains, `# block-devices
+SUBSYSTEM=="block", ENV{DEVTYPE}=="disk", KERNEL=="mmcblk1", TAG+="snap_consumer_app"`)
+	c.Assert(spec.Snippets(), testutil.Contains, `# block-devices
+SUBSYSTEM=="block", ENV{DEVTYPE}=="partition", KERNEL=="mmcblk1p[0-9]*", TAG+="snap_consumer_app"`)
+}
+
+func (s *blockDevicesInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	for _, env := range []map[string]string{
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/sdb", "DEVTYPE": "disk", "ID_BUS": "usb", "ACTION": "add", "SUBSYSTEM": "block"},
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/mmcblk1", "DEVTYPE": "disk", "ID_DRIVE_FLASH_SD": "1", "ACTION": "add", "SUBSYSTEM": "block"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": env["DEVNAME"]}})
+	}
+}
+
+func (s *blockDevicesInterfaceSuite) TestHotplugDeviceDetectedNotRemovable(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	for _, env := range []map[string]string{
+		// internal disk
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/sda", "DEVTYPE": "disk", "ID_BUS": "ata", "ACTION": "add", "SUBSYSTEM": "block"},
+		// partition
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/sdb1", "DEVTYPE": "partition", "ID_BUS": "usb", "ACTION": "add", "SUBSYSTEM": "block"},
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/loop0", "DEVTYPE": "disk", "ACTION": "add", "SUBSYSTEM": "block"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, IsNil)
+	}
+}
+
+func (s *blockDevicesInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/mmcblk1", "DEVTYPE": "disk", "ID_SERIAL": "0x1234abcd", "ACTION": "add", "SUBSYSTEM": "block"})
+	c.Assert(err, IsNil)
+	key, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, HasLen, 64)
+
+	// the same card in another reader gets the same key
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/other", "DEVNAME": "/dev/mmcblk2", "DEVTYPE": "disk", "ID_SERIAL": "0x1234abcd", "ACTION": "add", "SUBSYSTEM": "block"})
+	c.Assert(err, IsNil)
+	otherKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(otherKey, Equals, key)
+
+	// without a serial the default key is used
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/sdb", "DEVTYPE": "disk", "ACTION": "add", "SUBSYSTEM": "block"})
+	c.Assert(err, IsNil)
+	key, err = keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, Equals, snap.HotplugKey(""))
+}
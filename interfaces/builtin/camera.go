@@ -19,6 +19,17 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 const cameraSummary = `allows access to all cameras`
 
 const cameraBaseDeclarationSlots = `
@@ -56,8 +67,54 @@ var cameraConnectedPlugUDev = []string{
 	`KERNEL=="vchiq"`,
 }
 
+// cameraInterface gives access to all cameras through the implicit slot and
+// to a single camera through the slots created when a camera is hotplugged.
+type cameraInterface struct {
+	commonInterface
+}
+
+// Pattern to match the video4linux device nodes of cameras
+var cameraDeviceNodePattern = regexp.MustCompile("^/dev/video[0-9]{1,3}$")
+
+// BeforePrepareSlot checks validity of the defined slot
+func (iface *cameraInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	path, ok := slot.Attrs["path"]
+	if !ok {
+		return nil
+	}
+	if p, ok := path.(string); !ok || !cameraDeviceNodePattern.MatchString(p) {
+		return fmt.Errorf("camera path attribute must be a valid device node")
+	}
+	return nil
+}
+
+func (iface *cameraInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	return hotplugUDevConnectedPlug(&iface.commonInterface, spec, plug, slot, func(path string) []string {
+		return []string{fmt.Sprintf(`SUBSYSTEM=="video4linux", KERNEL=="%s"`, strings.TrimPrefix(path, "/dev/"))}
+	})
+}
+
+func (iface *cameraInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.Subsystem() != "video4linux" || !cameraDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	// only video capture devices are cameras, not metadata or output nodes
+	if caps, ok := di.Attribute("ID_V4L_CAPABILITIES"); ok && !strings.Contains(caps, ":capture:") {
+		return nil, nil
+	}
+	slot := hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	}
+	if product, ok := di.Attribute("ID_V4L_PRODUCT"); ok {
+		slot.Label = product
+	}
+	return &slot, nil
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&cameraInterface{commonInterface{
 		name:                  "camera",
 		summary:               cameraSummary,
 		implicitOnCore:        true,
@@ -65,5 +122,5 @@ func init() {
 		baseDeclarationSlots:  cameraBaseDeclarationSlots,
 		connectedPlugAppArmor: cameraConnectedPlugAppArmor,
 		connectedPlugUDev:     cameraConnectedPlugUDev,
-	})
+	}})
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -106,3 +107,53 @@ func (s *CameraInterfaceSuite) TestAutoConnect(c *C) {
 func (s *CameraInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+const cameraHotplugCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  webcam:
+    interface: camera
+    path: /dev/video1
+`
+
+func (s *CameraInterfaceSuite) TestSanitizeHotplugSlot(c *C) {
+	_, slotInfo := MockConnectedSlot(c, cameraHotplugCoreYaml, nil, "webcam")
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+
+	slotInfo.Attrs["path"] = "/dev/ttyS0"
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, "camera path attribute must be a valid device node")
+}
+
+func (s *CameraInterfaceSuite) TestUDevSpecHotplugSlot(c *C) {
+	slot, _ := MockConnectedSlot(c, cameraHotplugCoreYaml, nil, "webcam")
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Assert(spec.Snippets(), testutil.Contains, `# camera
+SUBSYSTEM=="video4linux", KERNEL=="video1", TAG+="snap_consumer_app"`)
+}
+
+func (s *CameraInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-5/1-5:1.0/video4linux/video0", "DEVNAME": "/dev/video0", "ID_V4L_PRODUCT": "Integrated Camera", "ID_V4L_CAPABILITIES": ":capture:", "ACTION": "add", "SUBSYSTEM": "video4linux"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Label: "Integrated Camera", Attrs: map[string]interface{}{"path": "/dev/video0"}})
+}
+
+func (s *CameraInterfaceSuite) TestHotplugDeviceDetectedNotCamera(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	for _, env := range []map[string]string{
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/ttyUSB0", "ACTION": "add", "SUBSYSTEM": "tty"},
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/vbi0", "ACTION": "add", "SUBSYSTEM": "video4linux"},
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/video1", "ID_V4L_CAPABILITIES": ":", "ACTION": "add", "SUBSYSTEM": "video4linux"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, IsNil)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/kmod"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 )
@@ -52,6 +53,7 @@ type commonInterface struct {
 
 	connectedPlugAppArmor  string
 	connectedPlugSecComp   string
+	connectedPlugSELinux   string
 	connectedPlugUDev      []string
 	rejectAutoConnectPairs bool
 
@@ -150,6 +152,13 @@ func (iface *commonInterface) SecCompConnectedPlug(spec *seccomp.Specification,
 	return nil
 }
 
+func (iface *commonInterface) SELinuxConnectedPlug(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if iface.connectedPlugSELinux != "" {
+		spec.AddSnippet(iface.connectedPlugSELinux)
+	}
+	return nil
+}
+
 func (iface *commonInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
 	// don't tag devices if the interface controls it's own device cgroup
 	if iface.controlsDeviceCgroup {
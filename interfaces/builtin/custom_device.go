@@ -0,0 +1,423 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
+const customDeviceSummary = `provides access to custom devices specified via the gadget`
+
+// The slots of the custom-device interface describe the device they give
+// access to, therefore only gadget and kernel snaps may declare them and
+// each connection needs to be allowed by a snap declaration.
+const customDeviceBaseDeclarationSlots = `
+  custom-device:
+    allow-installation:
+      slot-snap-type:
+        - gadget
+        - kernel
+    deny-connection: true
+    deny-auto-connection: true
+`
+
+var (
+	// customDeviceNamePattern matches the names of custom devices.
+	customDeviceNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// customDevicePathPattern matches the device nodes, possibly using
+	// globs, custom devices may give access to.
+	customDevicePathPattern = regexp.MustCompile(`^/dev/[-_.:A-Za-z0-9*?\[\]{},/]+$`)
+	// customDeviceFilePattern matches the sysfs and procfs files, possibly
+	// using globs, custom devices may give access to.
+	customDeviceFilePattern = regexp.MustCompile(`^/(sys|proc)/[-_.:A-Za-z0-9*?\[\]{},/]+$`)
+	// customDeviceKernelPattern matches the kernel names of devices, using
+	// udev globs.
+	customDeviceKernelPattern = regexp.MustCompile(`^[-_.:A-Za-z0-9*?\[\]|]+$`)
+	// customDeviceSubsystemPattern matches udev subsystems.
+	customDeviceSubsystemPattern = regexp.MustCompile(`^[-_a-z0-9]+$`)
+	// customDeviceUDevKeyPattern matches the names of udev attributes and
+	// properties.
+	customDeviceUDevKeyPattern = regexp.MustCompile(`^[-_.A-Za-z0-9/]+$`)
+	// customDeviceModulePattern matches kernel module names.
+	customDeviceModulePattern = regexp.MustCompile(`^[-_A-Za-z0-9]+$`)
+)
+
+// customDeviceInterface gives access to devices described by the
+// attributes of its slot, which the gadget or kernel snap declares:
+//
+//	slots:
+//	  dual-sd:
+//	    interface: custom-device
+//	    custom-device: dual-sd   # defaults to the name of the slot
+//	    devices: [/dev/dualSD[0-9]]
+//	    read-devices: [/dev/dualSD-ctl]
+//	    files:
+//	      read: [/sys/class/dualsd/*/status]
+//	      write: [/sys/class/dualsd/*/mode]
+//	    udev-tagging:
+//	      - kernel: dualSD[0-9]
+//	        subsystem: block
+//	        attributes: {idVendor: "1234"}
+//	        environment: {ID_BUS: usb}
+//	    kernel-modules: [dualsd]
+//
+// Plugs are matched to slots through their custom-device attribute, which
+// also defaults to the name of the plug.
+type customDeviceInterface struct {
+	commonInterface
+}
+
+func (iface *customDeviceInterface) validateName(attrs map[string]interface{}, defaultName string) error {
+	name, ok := attrs["custom-device"]
+	if !ok {
+		attrs["custom-device"] = defaultName
+		name = defaultName
+	}
+	nameStr, ok := name.(string)
+	if !ok {
+		return fmt.Errorf(`custom-device "custom-device" attribute must be a string, not %v`, name)
+	}
+	if !customDeviceNamePattern.MatchString(nameStr) {
+		return fmt.Errorf(`custom-device "custom-device" attribute must be a valid device name, not %q`, nameStr)
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	if plug.Attrs == nil {
+		plug.Attrs = make(map[string]interface{})
+	}
+	return iface.validateName(plug.Attrs, plug.Name)
+}
+
+// validateCustomDevicePattern checks that the given path is clean, matches the given
+// pattern and uses balanced brackets and braces, so that it can be used as
+// an AppArmor glob.
+func validateCustomDevicePattern(path string, pattern *regexp.Regexp) error {
+	if filepath.Clean(path) != path || strings.Contains(path, "..") {
+		return fmt.Errorf("%q is not clean", path)
+	}
+	if !pattern.MatchString(path) {
+		return fmt.Errorf("%q contains invalid characters or lies outside of the allowed directories", path)
+	}
+	var brackets, braces int
+	for _, r := range path {
+		switch r {
+		case '[':
+			if brackets > 0 {
+				return fmt.Errorf("%q contains nested brackets", path)
+			}
+			brackets++
+		case ']':
+			brackets--
+		case '{':
+			braces++
+		case '}':
+			braces--
+		}
+		if brackets < 0 || braces < 0 {
+			return fmt.Errorf("%q contains unbalanced brackets or braces", path)
+		}
+	}
+	if brackets != 0 || braces != 0 {
+		return fmt.Errorf("%q contains unbalanced brackets or braces", path)
+	}
+	return nil
+}
+
+// customDeviceStrings returns the list of strings of the given attribute.
+func customDeviceStrings(attrs interfaces.Attrer, name string) ([]string, error) {
+	value, ok := attrs.Lookup(name)
+	if !ok {
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q attribute must be a list of strings", name)
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q attribute must be a list of strings", name)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// customDeviceStringMap returns the given map as a map of strings.
+func customDeviceStringMap(value interface{}, name string) (map[string]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q must be a map of strings", name)
+	}
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a map of strings", name)
+		}
+		if !customDeviceUDevKeyPattern.MatchString(k) {
+			return nil, fmt.Errorf("%q contains invalid key %q", name, k)
+		}
+		if strings.ContainsAny(s, "\"\n\\") {
+			return nil, fmt.Errorf("%q contains invalid value %q for key %q", name, s, k)
+		}
+		result[k] = s
+	}
+	return result, nil
+}
+
+// customDeviceUDevRules returns the udev match rules of the devices of the
+// custom device: one rule per entry of udev-tagging, and one rule matching
+// the kernel name of each device not covered by those.
+func customDeviceUDevRules(attrs interfaces.Attrer) ([]string, error) {
+	var rules []string
+	tagged := make(map[string]bool)
+
+	value, ok := attrs.Lookup("udev-tagging")
+	if ok {
+		entries, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`"udev-tagging" attribute must be a list of maps`)
+		}
+		for _, e := range entries {
+			entry, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf(`"udev-tagging" attribute must be a list of maps`)
+			}
+			for key := range entry {
+				switch key {
+				case "kernel", "subsystem", "attributes", "environment":
+				default:
+					return nil, fmt.Errorf(`"udev-tagging" entry contains unknown key %q`, key)
+				}
+			}
+			kernel, ok := entry["kernel"].(string)
+			if !ok || !customDeviceKernelPattern.MatchString(kernel) {
+				return nil, fmt.Errorf(`"udev-tagging" entry must have a valid "kernel" key, not %v`, entry["kernel"])
+			}
+			rule := []string{fmt.Sprintf(`KERNEL=="%s"`, kernel)}
+			if subsystem, ok := entry["subsystem"]; ok {
+				s, ok := subsystem.(string)
+				if !ok || !customDeviceSubsystemPattern.MatchString(s) {
+					return nil, fmt.Errorf(`"udev-tagging" entry must have a valid "subsystem" key, not %v`, subsystem)
+				}
+				rule = append(rule, fmt.Sprintf(`SUBSYSTEM=="%s"`, s))
+			}
+			attributes, err := customDeviceStringMap(entry["attributes"], "attributes")
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range sortedStringKeys(attributes) {
+				rule = append(rule, fmt.Sprintf(`ATTRS{%s}=="%s"`, k, attributes[k]))
+			}
+			environment, err := customDeviceStringMap(entry["environment"], "environment")
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range sortedStringKeys(environment) {
+				rule = append(rule, fmt.Sprintf(`ENV{%s}=="%s"`, k, environment[k]))
+			}
+			rules = append(rules, strings.Join(rule, ", "))
+			tagged[kernel] = true
+		}
+	}
+
+	for _, attr := range []string{"devices", "read-devices"} {
+		devices, err := customDeviceStrings(attrs, attr)
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range devices {
+			kernel := strings.TrimPrefix(device, "/dev/")
+			if tagged[kernel] || tagged[filepath.Base(device)] {
+				continue
+			}
+			if !customDeviceKernelPattern.MatchString(kernel) {
+				return nil, fmt.Errorf(`cannot derive udev rule for device %q, use "udev-tagging"`, device)
+			}
+			rules = append(rules, fmt.Sprintf(`KERNEL=="%s"`, kernel))
+			tagged[kernel] = true
+		}
+	}
+	return rules, nil
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (iface *customDeviceInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	if slot.Attrs == nil {
+		slot.Attrs = make(map[string]interface{})
+	}
+	if err := iface.validateName(slot.Attrs, slot.Name); err != nil {
+		return err
+	}
+	for key := range slot.Attrs {
+		switch key {
+		case "custom-device", "devices", "read-devices", "files", "udev-tagging", "kernel-modules":
+		default:
+			return fmt.Errorf("custom-device slot has unknown attribute %q", key)
+		}
+	}
+
+	var numDevices int
+	for _, attr := range []string{"devices", "read-devices"} {
+		devices, err := customDeviceStrings(slot, attr)
+		if err != nil {
+			return fmt.Errorf("custom-device %s", err)
+		}
+		for _, device := range devices {
+			if err := validateCustomDevicePattern(device, customDevicePathPattern); err != nil {
+				return fmt.Errorf("custom-device %q attribute is invalid: %v", attr, err)
+			}
+		}
+		numDevices += len(devices)
+	}
+	if numDevices == 0 {
+		return fmt.Errorf(`custom-device slot must have at least one device in "devices" or "read-devices"`)
+	}
+
+	if files, ok := slot.Attrs["files"]; ok {
+		m, ok := files.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf(`custom-device "files" attribute must be a map with "read" and "write" lists`)
+		}
+		for key := range m {
+			if key != "read" && key != "write" {
+				return fmt.Errorf(`custom-device "files" attribute contains unknown key %q`, key)
+			}
+			paths, err := customDeviceStrings(slot, "files."+key)
+			if err != nil {
+				return fmt.Errorf("custom-device %s", err)
+			}
+			for _, path := range paths {
+				if err := validateCustomDevicePattern(path, customDeviceFilePattern); err != nil {
+					return fmt.Errorf("custom-device \"files.%s\" attribute is invalid: %v", key, err)
+				}
+			}
+		}
+	}
+
+	if _, err := customDeviceUDevRules(slot); err != nil {
+		return fmt.Errorf("custom-device %v", err)
+	}
+
+	modules, err := customDeviceStrings(slot, "kernel-modules")
+	if err != nil {
+		return fmt.Errorf("custom-device %s", err)
+	}
+	for _, module := range modules {
+		if !customDeviceModulePattern.MatchString(module) {
+			return fmt.Errorf(`custom-device "kernel-modules" attribute contains invalid module name %q`, module)
+		}
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	var name string
+	if err := slot.Attr("custom-device", &name); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n# Description: Allow access to the custom device %q provided by %s\n", name, slot.Ref())
+	for _, attr := range []struct {
+		name  string
+		perms string
+	}{
+		{"devices", "rwk"},
+		{"read-devices", "r"},
+		{"files.read", "r"},
+		{"files.write", "rw"},
+	} {
+		paths, err := customDeviceStrings(slot, attr.name)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Fprintf(&buf, "%q %s,\n", path, attr.perms)
+		}
+	}
+	spec.AddSnippet(buf.String())
+	return nil
+}
+
+func (iface *customDeviceInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	rules, err := customDeviceUDevRules(slot)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		spec.TagDevice(rule)
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) KModConnectedPlug(spec *kmod.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	modules, err := customDeviceStrings(slot, "kernel-modules")
+	if err != nil {
+		return err
+	}
+	for _, module := range modules {
+		if err := spec.AddModule(module); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (iface *customDeviceInterface) AutoConnect(plug *snap.PlugInfo, slot *snap.SlotInfo) bool {
+	// only connect plugs and slots of the same custom device
+	plugName, _ := plug.Attrs["custom-device"].(string)
+	slotName, _ := slot.Attrs["custom-device"].(string)
+	return plugName != "" && plugName == slotName
+}
+
+func init() {
+	registerIface(&customDeviceInterface{commonInterface{
+		name:                 "custom-device",
+		summary:              customDeviceSummary,
+		baseDeclarationSlots: customDeviceBaseDeclarationSlots,
+	}})
+}
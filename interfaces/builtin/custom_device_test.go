@@ -0,0 +1,216 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type customDeviceInterfaceSuite struct {
+	testutil.BaseTest
+
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&customDeviceInterfaceSuite{
+	iface: builtin.MustInterface("custom-device"),
+})
+
+const customDeviceConsumerYaml = `name: consumer
+version: 0
+plugs:
+  dual-sd:
+    interface: custom-device
+apps:
+  app:
+    plugs: [dual-sd]
+`
+
+const customDeviceGadgetYaml = `name: gadget
+version: 0
+type: gadget
+slots:
+  dual-sd:
+    interface: custom-device
+    devices:
+      - /dev/dualSD[0-9]
+    read-devices:
+      - /dev/dualSD-ctl
+    files:
+      read: [/sys/class/dualsd/*/status]
+      write: [/sys/class/dualsd/*/mode]
+    udev-tagging:
+      - kernel: dualSD[0-9]
+        subsystem: block
+        attributes:
+          idVendor: "1234"
+          idProduct: "5678"
+        environment:
+          ID_BUS: usb
+    kernel-modules: [dualsd]
+`
+
+func (s *customDeviceInterfaceSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.plug, s.plugInfo = MockConnectedPlug(c, customDeviceConsumerYaml, nil, "dual-sd")
+	s.slot, s.slotInfo = MockConnectedSlot(c, customDeviceGadgetYaml, nil, "dual-sd")
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+	s.plug = interfaces.NewConnectedPlug(s.plugInfo, nil, nil)
+	s.slot = interfaces.NewConnectedSlot(s.slotInfo, nil, nil)
+}
+
+func (s *customDeviceInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "custom-device")
+}
+
+func (s *customDeviceInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Check(s.slotInfo.Attrs["custom-device"], Equals, "dual-sd")
+}
+
+func (s *customDeviceInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Check(s.plugInfo.Attrs["custom-device"], Equals, "dual-sd")
+
+	const plugYaml = `name: consumer
+version: 0
+plugs:
+  sd:
+    interface: custom-device
+    custom-device: Bad_Name
+`
+	info := snaptest.MockInfo(c, plugYaml, nil)
+	c.Check(interfaces.BeforePreparePlug(s.iface, info.Plugs["sd"]), ErrorMatches,
+		`custom-device "custom-device" attribute must be a valid device name, not "Bad_Name"`)
+}
+
+func (s *customDeviceInterfaceSuite) TestSanitizeSlotErrors(c *C) {
+	const slotYaml = `name: gadget
+version: 0
+type: gadget
+slots:
+  sd:
+    interface: custom-device
+%s
+`
+	for _, t := range []struct {
+		attrs string
+		err   string
+	}{
+		{"", `custom-device slot must have at least one device in "devices" or "read-devices"`},
+		{"    custom-device: 42\n    devices: [/dev/foo]", `custom-device "custom-device" attribute must be a string, not 42`},
+		{"    devices: [/dev/foo]\n    unknown: 1", `custom-device slot has unknown attribute "unknown"`},
+		{"    devices: /dev/foo", `custom-device "devices" attribute must be a list of strings`},
+		{"    devices: [1]", `custom-device "devices" attribute must be a list of strings`},
+		{"    devices: [/dev/../etc/passwd]", `custom-device "devices" attribute is invalid: "/dev/../etc/passwd" is not clean`},
+		{"    devices: [/etc/passwd]", `custom-device "devices" attribute is invalid: "/etc/passwd" contains invalid characters or lies outside of the allowed directories`},
+		{`    read-devices: ["/dev/foo\"bar"]`, `custom-device "read-devices" attribute is invalid: .* contains invalid characters .*`},
+		{`    devices: ["/dev/foo[0-9"]`, `custom-device "devices" attribute is invalid: "/dev/foo\[0-9" contains unbalanced brackets or braces`},
+		{`    devices: ["/dev/foo[[0-9]]"]`, `custom-device "devices" attribute is invalid: "/dev/foo\[\[0-9\]\]" contains nested brackets`},
+		{"    devices: [/dev/foo]\n    files: [/sys/foo]", `custom-device "files" attribute must be a map with "read" and "write" lists`},
+		{"    devices: [/dev/foo]\n    files: {exec: [/sys/foo]}", `custom-device "files" attribute contains unknown key "exec"`},
+		{"    devices: [/dev/foo]\n    files: {read: [/etc/shadow]}", `custom-device "files.read" attribute is invalid: "/etc/shadow" contains invalid characters or lies outside of the allowed directories`},
+		{"    devices: [/dev/foo]\n    udev-tagging: foo", `custom-device "udev-tagging" attribute must be a list of maps`},
+		{"    devices: [/dev/foo]\n    udev-tagging: [{subsystem: usb}]", `custom-device "udev-tagging" entry must have a valid "kernel" key, not <nil>`},
+		{"    devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, run: /bin/sh}]", `custom-device "udev-tagging" entry contains unknown key "run"`},
+		{"    devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, subsystem: \"usb\\\", RUN+=\\\"/bin/sh\"}]", `custom-device "udev-tagging" entry must have a valid "subsystem" key, .*`},
+		{"    devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, attributes: {\"a}\": b}}]", `custom-device "attributes" contains invalid key "a}"`},
+		{"    devices: [/dev/foo]\n    udev-tagging: [{kernel: foo, environment: {a: \"b\\\"\"}}]", `custom-device "environment" contains invalid value "b\\"" for key "a"`},
+		{"    devices: [\"/dev/{foo,bar}\"]", `custom-device cannot derive udev rule for device "/dev/{foo,bar}", use "udev-tagging"`},
+		{"    devices: [/dev/foo]\n    kernel-modules: [\"foo bar\"]", `custom-device "kernel-modules" attribute contains invalid module name "foo bar"`},
+	} {
+		info := snaptest.MockInfo(c, fmt.Sprintf(slotYaml, t.attrs), nil)
+		c.Check(interfaces.BeforePrepareSlot(s.iface, info.Slots["sd"]), ErrorMatches, t.err, Commentf(t.attrs))
+	}
+}
+
+func (s *customDeviceInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), Equals, `
+# Description: Allow access to the custom device "dual-sd" provided by gadget:dual-sd
+"/dev/dualSD[0-9]" rwk,
+"/dev/dualSD-ctl" r,
+"/sys/class/dualsd/*/status" r,
+"/sys/class/dualsd/*/mode" rw,
+`)
+}
+
+func (s *customDeviceInterfaceSuite) TestUDevSpec(c *C) {
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 3)
+	c.Assert(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="dualSD[0-9]", SUBSYSTEM=="block", ATTRS{idProduct}=="5678", ATTRS{idVendor}=="1234", ENV{ID_BUS}=="usb", TAG+="snap_consumer_app"`)
+	c.Assert(spec.Snippets(), testutil.Contains, `# custom-device
+KERNEL=="dualSD-ctl", TAG+="snap_consumer_app"`)
+	c.Assert(spec.Snippets(), testutil.Contains, `TAG=="snap_consumer_app", RUN+="/usr/lib/snapd/snap-device-helper $env{ACTION} snap_consumer_app $devpath $major:$minor"`)
+}
+
+func (s *customDeviceInterfaceSuite) TestKModSpec(c *C) {
+	spec := &kmod.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.Modules(), DeepEquals, map[string]bool{
+		"dualsd": true,
+	})
+}
+
+func (s *customDeviceInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, false)
+	c.Assert(si.ImplicitOnClassic, Equals, false)
+	c.Assert(si.Summary, Equals, `provides access to custom devices specified via the gadget`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "custom-device")
+}
+
+func (s *customDeviceInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+
+	const plugYaml = `name: consumer
+version: 0
+plugs:
+  other:
+    interface: custom-device
+`
+	info := snaptest.MockInfo(c, plugYaml, nil)
+	plugInfo := info.Plugs["other"]
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plugInfo), IsNil)
+	c.Check(s.iface.AutoConnect(plugInfo, s.slotInfo), Equals, false)
+}
+
+func (s *customDeviceInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 )
@@ -190,6 +191,60 @@ func (iface *hidrawInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
 	return true
 }
 
+// Pattern to match the sysfs path of hidraw devices, the first group is the
+// path of the HID device without its instance number, which changes every
+// time the device is plugged in
+var hidrawDevicePathPattern = regexp.MustCompile(`^(/.*/[0-9A-Fa-f]{4}:[0-9A-Fa-f]{4}:[0-9A-Fa-f]{4})\.[0-9A-Fa-f]+/hidraw/hidraw[0-9]+$`)
+
+func (iface *hidrawInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.Subsystem() != "hidraw" || !hidrawDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	slot := hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	}
+	return &slot, nil
+}
+
+// HotplugKey identifies hidraw devices by the path of their HID device, as
+// hidraw events rarely carry the vendor and model attributes the default key
+// relies on. The path depends on the port the device is plugged into, the
+// serial is added when known to tell apart identical devices.
+func (iface *hidrawInterface) HotplugKey(di *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
+	if _, ok := di.Attribute("ID_VENDOR_ID"); ok {
+		if _, ok := di.Attribute("ID_MODEL_ID"); ok {
+			// the default key works
+			return "", nil
+		}
+	}
+	devPath, _ := di.Attribute("DEVPATH")
+	match := hidrawDevicePathPattern.FindStringSubmatch(devPath)
+	if match == nil {
+		return "", nil
+	}
+	serial, _ := di.Attribute("ID_SERIAL")
+	return hotplugKeyFromValues(iface.Name(), match[1], serial), nil
+}
+
+func (iface *hidrawInterface) HandledByGadget(di *hotplug.HotplugDeviceInfo, slot *snap.SlotInfo) bool {
+	// if the slot has vendor and product set, check if they match
+	var usbVendor, usbProduct int64
+	if err := slot.Attr("usb-vendor", &usbVendor); err == nil {
+		if err := slot.Attr("usb-product", &usbProduct); err != nil {
+			return false
+		}
+		return slotDeviceAttrEqual(di, "ID_VENDOR_ID", usbVendor) && slotDeviceAttrEqual(di, "ID_MODEL_ID", usbProduct)
+	}
+
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
+		return false
+	}
+	return di.DeviceName() == path
+}
+
 func (iface *hidrawInterface) hasUsbAttrs(attrs interfaces.Attrer) bool {
 	var v int64
 	if err := attrs.Attr("usb-vendor", &v); err == nil {
@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -353,3 +354,68 @@ SUBSYSTEM=="hidraw", SUBSYSTEMS=="usb", ATTRS{idVendor}=="ffff", ATTRS{idProduct
 func (s *HidrawInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+func (s *HidrawInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/0003:046D:C52B.0001/hidraw/hidraw0", "DEVNAME": "/dev/hidraw0", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/hidraw0"}})
+
+	// the proposed slot is valid
+	slotInfo := &snap.SlotInfo{Snap: &snap.Info{SuggestedName: "core"}, Interface: "hidraw", Attrs: proposedSlot.Attrs}
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+}
+
+func (s *HidrawInterfaceSuite) TestHotplugDeviceDetectedNotHidraw(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/ttyUSB0", "ACTION": "add", "SUBSYSTEM": "tty"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, IsNil)
+}
+
+func (s *HidrawInterfaceSuite) TestHotplugKey(c *C) {
+	keyHandler := s.iface.(hotplug.HotplugKeyHandler)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/0003:046D:C52B.0001/hidraw/hidraw0", "DEVNAME": "/dev/hidraw0", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	key, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, HasLen, 64)
+
+	// the same device plugged in again gets the same key
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/0003:046D:C52B.0002/hidraw/hidraw1", "DEVNAME": "/dev/hidraw1", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	otherKey, err := keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(otherKey, Equals, key)
+
+	// but not when plugged into another port
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-3/1-3:1.0/0003:046D:C52B.0003/hidraw/hidraw1", "DEVNAME": "/dev/hidraw1", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	otherKey, err = keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(otherKey, Not(Equals), key)
+
+	// the default key is used when vendor and model are known
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/0003:046D:C52B.0001/hidraw/hidraw0", "DEVNAME": "/dev/hidraw0", "ID_VENDOR_ID": "046d", "ID_MODEL_ID": "c52b", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	key, err = keyHandler.HotplugKey(di)
+	c.Assert(err, IsNil)
+	c.Check(key, Equals, snap.HotplugKey(""))
+}
+
+func (s *HidrawInterfaceSuite) TestHotplugHandledByGadget(c *C) {
+	byGadgetPred := s.iface.(hotplug.HandledByGadgetPredicate)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/hidraw0", "ID_VENDOR_ID": "0001", "ID_MODEL_ID": "0001", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	c.Assert(byGadgetPred.HandledByGadget(di, s.testUDev1Info), Equals, true)
+	c.Assert(byGadgetPred.HandledByGadget(di, s.testUDev2Info), Equals, false)
+
+	di, err = hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/hidraw0", "ACTION": "add", "SUBSYSTEM": "hidraw"})
+	c.Assert(err, IsNil)
+	c.Assert(byGadgetPred.HandledByGadget(di, s.testSlot1Info), Equals, true)
+	c.Assert(byGadgetPred.HandledByGadget(di, s.testSlot2Info), Equals, false)
+}
@@ -92,6 +92,19 @@ capability dac_read_search,
 @{HOME}/{s,sn,sna}{,/} r,
 `
 
+const homeConnectedPlugSELinux = `
+# Description: Can access files in user's $HOME. Unlike with AppArmor, hidden
+# files cannot be told apart from other files as they share the same type.
+userdom_search_user_home_dirs(###DOMAIN###)
+userdom_list_user_home_content(###DOMAIN###)
+userdom_manage_user_home_content_dirs(###DOMAIN###)
+userdom_manage_user_home_content_files(###DOMAIN###)
+userdom_manage_user_home_content_symlinks(###DOMAIN###)
+
+# Allow access to @{HOME}/snap/ to allow directory traversals
+snappy_read_user_home_files(###DOMAIN###)
+`
+
 type homeInterface struct {
 	commonInterface
 }
@@ -127,5 +140,6 @@ func init() {
 		implicitOnCore:       true,
 		implicitOnClassic:    true,
 		baseDeclarationSlots: homeBaseDeclarationSlots,
+		connectedPlugSELinux: homeConnectedPlugSELinux,
 	}})
 }
@@ -0,0 +1,305 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+const mountControlSummary = `allows mounting and unmounting specific filesystems`
+
+const mountControlBaseDeclarationPlugs = `
+  mount-control:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const mountControlBaseDeclarationSlots = `
+  mount-control:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const mountControlConnectedPlugAppArmor = `
+# Description: Allow mounting and unmounting the filesystems listed in the
+# "mount" attribute of the plug.
+
+# Required for mounts and unmounts
+capability sys_admin,
+`
+
+const mountControlConnectedPlugSecComp = `
+# Description: Allow mount and umount syscall access.
+mount
+umount
+umount2
+`
+
+const (
+	// mountControlNamespaceSnap keeps mounts inside the mount namespace of
+	// the snap.
+	mountControlNamespaceSnap = "snap"
+	// mountControlNamespaceHost shares mounts with the host, this is only
+	// possible below /media, which snap-confine shares in both directions.
+	mountControlNamespaceHost = "host"
+)
+
+var (
+	// mountControlWhatPattern matches the sources of mounts, possibly
+	// using globs.
+	mountControlWhatPattern = regexp.MustCompile(`^(none|/[-_.:@A-Za-z0-9*/]*)$`)
+	// mountControlWherePattern matches the mount points, possibly using
+	// globs, after their variables are expanded.
+	mountControlWherePattern = regexp.MustCompile(`^/[-_.:@A-Za-z0-9*/]+$`)
+
+	// mountControlFSTypes lists the filesystems which can be mounted.
+	mountControlFSTypes = []string{
+		"aufs", "autofs", "btrfs", "cifs", "ext2", "ext3", "ext4",
+		"hfs", "iso9660", "jfs", "msdos", "nfs", "nfs4", "ntfs",
+		"ramfs", "reiserfs", "squashfs", "tmpfs", "ubifs", "udf",
+		"ufs", "vfat", "xfs", "zfs",
+	}
+	// mountControlOptions lists the mount options which can be used,
+	// options like suid or dev, and the ones changing the propagation of
+	// mounts, are left out on purpose.
+	mountControlOptions = []string{
+		"async", "atime", "diratime", "dirsync", "iversion", "lazytime",
+		"noatime", "nodev", "nodiratime", "noexec", "noiversion",
+		"nolazytime", "norelatime", "nostrictatime", "nosuid", "relatime",
+		"ro", "rw", "silent", "strictatime", "sync",
+	}
+)
+
+// mountControlEntry describes one entry of the "mount" attribute:
+//
+//	plugs:
+//	  mnt:
+//	    interface: mount-control
+//	    mount:
+//	      - what: /dev/sd*
+//	        where: $SNAP_COMMON/backup/*
+//	        type: [ext4, vfat]
+//	        options: [rw, nosuid, nodev]
+//	      - what: /dev/sdb1
+//	        where: /media/backup
+//	        type: [ext4]
+//	        options: [ro]
+//	        namespace: host
+//
+// Mount points can use $SNAP_DATA or $SNAP_COMMON, lie below /mnt or, for
+// mounts shared with the host, below /media. The mounts may use any subset
+// of the listed options.
+//
+// The namespace is enforced by the propagation of the mount namespace of
+// the snap: snap-confine shares /media with the host in both directions
+// while the rest of the filesystem, /mnt and /var/snap included, only
+// receives the mounts of the host. The propagation options are not among
+// the options which can be used, so the snap cannot change that.
+type mountControlEntry struct {
+	What      string
+	Where     string
+	Types     []string
+	Options   []string
+	Namespace string
+}
+
+// mountControlStrings returns the list of strings of the given key.
+func mountControlStrings(entry map[string]interface{}, key string) ([]string, error) {
+	list, ok := entry[key].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%q must be a non-empty list of strings", key)
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a non-empty list of strings", key)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// expandMountControlWhere expands the variables of the given mount point
+// into AppArmor variables. For parallel installs SNAP_{DATA,COMMON} are
+// remapped, so both SNAP_NAME and SNAP_INSTANCE_NAME are allowed.
+func expandMountControlWhere(where string) string {
+	r := strings.NewReplacer(
+		"$SNAP_COMMON", "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/common",
+		"$SNAP_DATA", "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/@{SNAP_REVISION}")
+	return r.Replace(where)
+}
+
+func validateMountControlWhere(where, namespace string) error {
+	if filepath.Clean(where) != where || strings.Contains(where, "..") {
+		return fmt.Errorf("mount point %q is not clean", where)
+	}
+	var base string
+	for _, prefix := range []string{"$SNAP_COMMON", "$SNAP_DATA", "/mnt", "/media"} {
+		if where == prefix || strings.HasPrefix(where, prefix+"/") {
+			base = prefix
+			break
+		}
+	}
+	if base == "" {
+		return fmt.Errorf("mount point %q must be below $SNAP_DATA, $SNAP_COMMON, /mnt or /media", where)
+	}
+	if where == base {
+		return fmt.Errorf("mount point %q must not be %s itself", where, base)
+	}
+	if !mountControlWherePattern.MatchString(strings.TrimPrefix(where, base)) {
+		return fmt.Errorf("mount point %q contains invalid characters", where)
+	}
+	switch {
+	case namespace == mountControlNamespaceHost && base != "/media":
+		return fmt.Errorf("mount point %q is not shared with the host, use a location below /media", where)
+	case namespace == mountControlNamespaceSnap && base == "/media":
+		return fmt.Errorf("mount point %q is shared with the host, use namespace %q", where, mountControlNamespaceHost)
+	}
+	return nil
+}
+
+func parseMountControlEntry(value interface{}) (*mountControlEntry, error) {
+	entry, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`"mount" must be a list of maps`)
+	}
+	for key := range entry {
+		switch key {
+		case "what", "where", "type", "options", "namespace":
+		default:
+			return nil, fmt.Errorf("mount entry contains unknown key %q", key)
+		}
+	}
+
+	e := &mountControlEntry{Namespace: mountControlNamespaceSnap}
+	if namespace, ok := entry["namespace"]; ok {
+		e.Namespace, _ = namespace.(string)
+		if e.Namespace != mountControlNamespaceSnap && e.Namespace != mountControlNamespaceHost {
+			return nil, fmt.Errorf(`mount namespace must be %q or %q, not %v`, mountControlNamespaceSnap, mountControlNamespaceHost, namespace)
+		}
+	}
+
+	e.What, _ = entry["what"].(string)
+	if e.What == "" {
+		return nil, fmt.Errorf(`mount entry must have a "what" string`)
+	}
+	if (e.What != "none" && filepath.Clean(e.What) != e.What) || strings.Contains(e.What, "..") || !mountControlWhatPattern.MatchString(e.What) {
+		return nil, fmt.Errorf("mount source %q is invalid", e.What)
+	}
+
+	e.Where, _ = entry["where"].(string)
+	if e.Where == "" {
+		return nil, fmt.Errorf(`mount entry must have a "where" string`)
+	}
+	if err := validateMountControlWhere(e.Where, e.Namespace); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if e.Types, err = mountControlStrings(entry, "type"); err != nil {
+		return nil, fmt.Errorf("mount entry %s", err)
+	}
+	for _, t := range e.Types {
+		if !strutil.ListContains(mountControlFSTypes, t) {
+			return nil, fmt.Errorf("mount filesystem type %q is not supported", t)
+		}
+	}
+	if e.Options, err = mountControlStrings(entry, "options"); err != nil {
+		return nil, fmt.Errorf("mount entry %s", err)
+	}
+	for _, o := range e.Options {
+		if !strutil.ListContains(mountControlOptions, o) {
+			return nil, fmt.Errorf("mount option %q is not supported", o)
+		}
+	}
+	return e, nil
+}
+
+func parseMountControlEntries(attrs interfaces.Attrer) ([]*mountControlEntry, error) {
+	value, ok := attrs.Lookup("mount")
+	if !ok {
+		return nil, fmt.Errorf(`needs valid "mount" attribute`)
+	}
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf(`"mount" must be a non-empty list of maps`)
+	}
+	entries := make([]*mountControlEntry, 0, len(list))
+	for _, item := range list {
+		e, err := parseMountControlEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+type mountControlInterface struct {
+	commonInterface
+}
+
+func (iface *mountControlInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	if _, err := parseMountControlEntries(plug); err != nil {
+		return fmt.Errorf("cannot add mount-control plug: %v", err)
+	}
+	return nil
+}
+
+func (iface *mountControlInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	entries, err := parseMountControlEntries(plug)
+	if err != nil {
+		return fmt.Errorf("cannot connect plug %s: %v", plug.Name(), err)
+	}
+
+	buf := bytes.NewBufferString(mountControlConnectedPlugAppArmor)
+	for _, e := range entries {
+		where := expandMountControlWhere(e.Where)
+		fmt.Fprintf(buf, "mount fstype=(%s) options in (%s) %q -> %q,\n",
+			strings.Join(e.Types, ","), strings.Join(e.Options, ","), e.What, where+"{,/}")
+		fmt.Fprintf(buf, "umount %q,\n", where+"{,/}")
+	}
+	spec.AddSnippet(buf.String())
+	return nil
+}
+
+func init() {
+	registerIface(&mountControlInterface{commonInterface{
+		name:                 "mount-control",
+		summary:              mountControlSummary,
+		implicitOnCore:       true,
+		implicitOnClassic:    true,
+		baseDeclarationPlugs: mountControlBaseDeclarationPlugs,
+		baseDeclarationSlots: mountControlBaseDeclarationSlots,
+		connectedPlugSecComp: mountControlConnectedPlugSecComp,
+	}})
+}
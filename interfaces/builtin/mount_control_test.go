@@ -0,0 +1,168 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type mountControlInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&mountControlInterfaceSuite{
+	iface: builtin.MustInterface("mount-control"),
+})
+
+const mountControlConsumerYaml = `name: consumer
+version: 0
+plugs:
+  mnt:
+    interface: mount-control
+    mount:
+      - what: /dev/sd*
+        where: $SNAP_COMMON/backup/*
+        type: [ext4, vfat]
+        options: [rw, nosuid, nodev]
+      - what: /dev/sdb1
+        where: /media/backup
+        type: [ext4]
+        options: [ro]
+        namespace: host
+apps:
+  app:
+    plugs: [mnt]
+`
+
+const mountControlCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  mount-control:
+`
+
+func (s *mountControlInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, mountControlConsumerYaml, nil, "mnt")
+	s.slot, s.slotInfo = MockConnectedSlot(c, mountControlCoreYaml, nil, "mount-control")
+}
+
+func (s *mountControlInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "mount-control")
+}
+
+func (s *mountControlInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *mountControlInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *mountControlInterfaceSuite) TestSanitizePlugErrors(c *C) {
+	const plugYaml = `name: consumer
+version: 0
+plugs:
+  mnt:
+    interface: mount-control
+%s
+`
+	const valid = "{what: /dev/sda1, where: /mnt/foo, type: [ext4], options: [rw]}"
+	for _, t := range []struct {
+		attrs string
+		err   string
+	}{
+		{"", `cannot add mount-control plug: needs valid "mount" attribute`},
+		{"    mount: []", `cannot add mount-control plug: "mount" must be a non-empty list of maps`},
+		{"    mount: [foo]", `cannot add mount-control plug: "mount" must be a list of maps`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, type: [ext4], options: [rw], persistent: true}]", `cannot add mount-control plug: mount entry contains unknown key "persistent"`},
+		{"    mount: [{where: /mnt/foo, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount entry must have a "what" string`},
+		{"    mount: [{what: /dev/../etc, where: /mnt/foo, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount source "/dev/../etc" is invalid`},
+		{`    mount: [{what: "/dev/sd[ab]", where: /mnt/foo, type: [ext4], options: [rw]}]`, `cannot add mount-control plug: mount source "/dev/sd\[ab\]" is invalid`},
+		{"    mount: [{what: /dev/sda1, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount entry must have a "where" string`},
+		{"    mount: [{what: /dev/sda1, where: /etc, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount point "/etc" must be below \$SNAP_DATA, \$SNAP_COMMON, /mnt or /media`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount point "/mnt/" is not clean`},
+		{"    mount: [{what: /dev/sda1, where: /mnt, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount point "/mnt" must not be /mnt itself`},
+		{"    mount: [{what: /dev/sda1, where: $SNAP_COMMONS, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount point "\$SNAP_COMMONS" must be below .*`},
+		{`    mount: [{what: /dev/sda1, where: "/mnt/{a,b}", type: [ext4], options: [rw]}]`, `cannot add mount-control plug: mount point "/mnt/{a,b}" contains invalid characters`},
+		{"    mount: [{what: /dev/sda1, where: /media/foo, type: [ext4], options: [rw]}]", `cannot add mount-control plug: mount point "/media/foo" is shared with the host, use namespace "host"`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, type: [ext4], options: [rw], namespace: host}]", `cannot add mount-control plug: mount point "/mnt/foo" is not shared with the host, use a location below /media`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, type: [ext4], options: [rw], namespace: other}]", `cannot add mount-control plug: mount namespace must be "snap" or "host", not other`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, options: [rw]}]", `cannot add mount-control plug: mount entry "type" must be a non-empty list of strings`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, type: [proc], options: [rw]}]", `cannot add mount-control plug: mount filesystem type "proc" is not supported`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, type: [ext4]}]", `cannot add mount-control plug: mount entry "options" must be a non-empty list of strings`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, type: [ext4], options: [rshared]}]", `cannot add mount-control plug: mount option "rshared" is not supported`},
+		{"    mount: [{what: /dev/sda1, where: /mnt/foo, type: [ext4], options: [suid]}]", `cannot add mount-control plug: mount option "suid" is not supported`},
+		{"    mount: [" + valid + ", {what: /dev/sda1, where: /mnt/foo, type: [ext4], options: [bind]}]", `cannot add mount-control plug: mount option "bind" is not supported`},
+	} {
+		info := snaptest.MockInfo(c, fmt.Sprintf(plugYaml, t.attrs), nil)
+		c.Check(interfaces.BeforePreparePlug(s.iface, info.Plugs["mnt"]), ErrorMatches, t.err, Commentf(t.attrs))
+	}
+}
+
+func (s *mountControlInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "capability sys_admin,\n")
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `
+mount fstype=(ext4,vfat) options in (rw,nosuid,nodev) "/dev/sd*" -> "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/common/backup/*{,/}",
+umount "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/common/backup/*{,/}",
+mount fstype=(ext4) options in (ro) "/dev/sdb1" -> "/media/backup{,/}",
+umount "/media/backup{,/}",
+`)
+}
+
+func (s *mountControlInterfaceSuite) TestSecCompSpec(c *C) {
+	spec := &seccomp.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "mount\numount\numount2\n")
+}
+
+func (s *mountControlInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, true)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, `allows mounting and unmounting specific filesystems`)
+	c.Assert(si.BaseDeclarationPlugs, testutil.Contains, "mount-control")
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "mount-control")
+}
+
+func (s *mountControlInterfaceSuite) TestAutoConnect(c *C) {
+	c.Assert(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
+}
+
+func (s *mountControlInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -78,6 +78,21 @@ socket AF_NETLINK - NETLINK_ROUTE
 socket AF_CONN
 `
 
+const networkConnectedPlugSELinux = `
+# Description: Can access the network as a client.
+sysnet_dns_name_resolve(###DOMAIN###)
+sysnet_read_config(###DOMAIN###)
+miscfiles_read_generic_certs(###DOMAIN###)
+
+allow ###DOMAIN### self:tcp_socket create_stream_socket_perms;
+allow ###DOMAIN### self:udp_socket create_socket_perms;
+corenet_tcp_sendrecv_generic_if(###DOMAIN###)
+corenet_udp_sendrecv_generic_if(###DOMAIN###)
+corenet_tcp_sendrecv_generic_node(###DOMAIN###)
+corenet_udp_sendrecv_generic_node(###DOMAIN###)
+corenet_tcp_connect_all_ports(###DOMAIN###)
+`
+
 func init() {
 	registerIface(&commonInterface{
 		name:                  "network",
@@ -87,5 +102,6 @@ func init() {
 		baseDeclarationSlots:  networkBaseDeclarationSlots,
 		connectedPlugAppArmor: networkConnectedPlugAppArmor,
 		connectedPlugSecComp:  networkConnectedPlugSecComp,
+		connectedPlugSELinux:  networkConnectedPlugSELinux,
 	})
 }
@@ -19,6 +19,17 @@
 
 package builtin
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/snap"
+)
+
 const rawusbSummary = `allows raw access to all USB devices`
 
 const rawusbBaseDeclarationSlots = `
@@ -61,8 +72,58 @@ var rawusbConnectedPlugUDev = []string{
 	`SUBSYSTEM=="tty", ENV{ID_BUS}=="usb"`,
 }
 
+// rawUsbInterface gives raw access to all USB devices through the implicit
+// slot and to a single device through the slots created when a USB device is
+// hotplugged.
+type rawUsbInterface struct {
+	commonInterface
+}
+
+// Pattern to match the device nodes of USB devices
+var rawusbDeviceNodePattern = regexp.MustCompile("^/dev/bus/usb/[0-9]{3}/[0-9]{3}$")
+
+// BeforePrepareSlot checks validity of the defined slot
+func (iface *rawUsbInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	path, ok := slot.Attrs["path"]
+	if !ok {
+		return nil
+	}
+	if p, ok := path.(string); !ok || !rawusbDeviceNodePattern.MatchString(p) {
+		return fmt.Errorf("raw-usb path attribute must be a valid device node")
+	}
+	return nil
+}
+
+func (iface *rawUsbInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	return hotplugUDevConnectedPlug(&iface.commonInterface, spec, plug, slot, func(path string) []string {
+		return []string{fmt.Sprintf(`SUBSYSTEM=="usb", ENV{DEVNAME}=="%s"`, path)}
+	})
+}
+
+func (iface *rawUsbInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo) (*hotplug.ProposedSlot, error) {
+	if di.Subsystem() != "usb" || di.DeviceType() != "usb_device" || !rawusbDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil, nil
+	}
+	// hubs, including the root hubs, are not interesting on their own
+	if ifaces, ok := di.Attribute("ID_USB_INTERFACES"); ok && strings.HasPrefix(ifaces, ":09") {
+		return nil, nil
+	}
+	slot := hotplug.ProposedSlot{
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	}
+	if vendor, ok := di.Attribute("ID_VENDOR_ID"); ok {
+		slot.Attrs["usb-vendor"] = vendor
+	}
+	if product, ok := di.Attribute("ID_MODEL_ID"); ok {
+		slot.Attrs["usb-product"] = product
+	}
+	return &slot, nil
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&rawUsbInterface{commonInterface{
 		name:                  "raw-usb",
 		summary:               rawusbSummary,
 		implicitOnCore:        true,
@@ -71,5 +132,5 @@ func init() {
 		connectedPlugAppArmor: rawusbConnectedPlugAppArmor,
 		connectedPlugSecComp:  rawusbConnectedPlugSecComp,
 		connectedPlugUDev:     rawusbConnectedPlugUDev,
-	})
+	}})
 }
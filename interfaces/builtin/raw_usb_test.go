@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
@@ -114,3 +115,57 @@ func (s *RawUsbInterfaceSuite) TestAutoConnect(c *C) {
 func (s *RawUsbInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
+
+const rawusbHotplugCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  usb-device:
+    interface: raw-usb
+    path: /dev/bus/usb/001/004
+    usb-vendor: "1234"
+    usb-product: "5678"
+`
+
+func (s *RawUsbInterfaceSuite) TestSanitizeHotplugSlot(c *C) {
+	_, slotInfo := MockConnectedSlot(c, rawusbHotplugCoreYaml, nil, "usb-device")
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+
+	slotInfo.Attrs["path"] = "/dev/bus/usb/001/../004"
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, "raw-usb path attribute must be a valid device node")
+}
+
+func (s *RawUsbInterfaceSuite) TestUDevSpecHotplugSlot(c *C) {
+	slot, _ := MockConnectedSlot(c, rawusbHotplugCoreYaml, nil, "usb-device")
+	spec := &udev.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, slot), IsNil)
+	c.Assert(spec.Snippets(), HasLen, 2)
+	c.Assert(spec.Snippets(), testutil.Contains, `# raw-usb
+SUBSYSTEM=="usb", ENV{DEVNAME}=="/dev/bus/usb/001/004", TAG+="snap_consumer_app"`)
+}
+
+func (s *RawUsbInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/pci0000:00/0000:00:14.0/usb1/1-2", "DEVNAME": "/dev/bus/usb/001/004", "DEVTYPE": "usb_device", "ID_VENDOR_ID": "1234", "ID_MODEL_ID": "5678", "ID_USB_INTERFACES": ":ff0000:", "ACTION": "add", "SUBSYSTEM": "usb"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/bus/usb/001/004", "usb-vendor": "1234", "usb-product": "5678"}})
+}
+
+func (s *RawUsbInterfaceSuite) TestHotplugDeviceDetectedNotUsbDevice(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	for _, env := range []map[string]string{
+		// USB interface of a device
+		{"DEVPATH": "/sys/foo/bar", "DEVTYPE": "usb_interface", "ACTION": "add", "SUBSYSTEM": "usb"},
+		// USB hub
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/bus/usb/001/001", "DEVTYPE": "usb_device", "ID_USB_INTERFACES": ":090000:", "ACTION": "add", "SUBSYSTEM": "usb"},
+		{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/ttyUSB0", "ACTION": "add", "SUBSYSTEM": "tty"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+		c.Assert(err, IsNil)
+		c.Check(proposedSlot, IsNil)
+	}
+}
@@ -49,6 +49,25 @@ const removableMediaConnectedPlugAppArmor = `
 /mnt/** rwkl,
 `
 
+const removableMediaConnectedPlugSELinux = `
+# Description: Can access removable storage filesystems
+
+# Allow navigating to the mount points in /media, /run/media and /mnt
+files_list_mnt(###DOMAIN###)
+fs_getattr_all_fs(###DOMAIN###)
+
+# Allow access to the filesystems commonly found on removable storage
+fs_manage_dos_files(###DOMAIN###)
+fs_manage_dos_dirs(###DOMAIN###)
+fs_manage_fusefs_files(###DOMAIN###)
+fs_manage_fusefs_dirs(###DOMAIN###)
+fs_read_iso9660_files(###DOMAIN###)
+
+# Allow write access to anything under /mnt
+files_manage_mnt_files(###DOMAIN###)
+files_manage_mnt_dirs(###DOMAIN###)
+`
+
 func init() {
 	registerIface(&commonInterface{
 		name:                  "removable-media",
@@ -57,5 +76,6 @@ func init() {
 		implicitOnClassic:     true,
 		baseDeclarationSlots:  removableMediaBaseDeclarationSlots,
 		connectedPlugAppArmor: removableMediaConnectedPlugAppArmor,
+		connectedPlugSELinux:  removableMediaConnectedPlugSELinux,
 	})
 }
@@ -21,10 +21,12 @@ package builtin
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"sort"
 
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 )
@@ -96,3 +98,31 @@ func implicitSystemConnectedSlot(slot *interfaces.ConnectedSlot) bool {
 	}
 	return false
 }
+
+// hotplugKeyFromValues returns a hotplug key for a device identified by the
+// given values, for interfaces whose devices lack the attributes needed by
+// the default key. The key is a sha256 checksum, like the default keys.
+func hotplugKeyFromValues(values ...string) snap.HotplugKey {
+	key := sha256.New()
+	for _, v := range values {
+		key.Write([]byte(v))
+		key.Write([]byte{0})
+	}
+	return snap.HotplugKey(fmt.Sprintf("%x", key.Sum(nil)))
+}
+
+// hotplugUDevConnectedPlug tags the devices an interface gives access to when
+// the plug is connected to the given slot. The slots of hotplugged devices
+// only tag the device node in their "path" attribute, using the rules
+// returned for it by pathRules, so that the device cgroup restricts access
+// to that device. The other slots tag all the devices of the interface.
+func hotplugUDevConnectedPlug(iface *commonInterface, spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot, pathRules func(path string) []string) error {
+	var path string
+	if err := slot.Attr("path", &path); err != nil {
+		return iface.UDevConnectedPlug(spec, plug, slot)
+	}
+	for _, rule := range pathRules(path) {
+		spec.TagDevice(rule)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package denials finds the denials of the sandbox of snaps in the audit
+// and kernel logs, and suggests the interfaces that would allow the denied
+// accesses.
+package denials
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+)
+
+const (
+	// KindAppArmor identifies denials of apparmor.
+	KindAppArmor = "apparmor"
+	// KindSecComp identifies denials of seccomp.
+	KindSecComp = "seccomp"
+)
+
+// Denial describes a denied access of an application or hook of a snap.
+type Denial struct {
+	// Kind is either KindAppArmor or KindSecComp.
+	Kind string
+	Snap string
+	// SecurityTag identifies the application or hook that was denied.
+	SecurityTag string
+
+	// Operation is the apparmor operation, for example "open",
+	// "capable" or "dbus_method_call".
+	Operation string
+	// Name is the path of a file or the D-Bus name the operation
+	// applied to.
+	Name string
+	// Mask is the denied access, for example "r" or "send".
+	Mask string
+	// Capability is set for denials of capabilities.
+	Capability string
+	// Family and SockType are set for denials of network access.
+	Family   string
+	SockType string
+	// DBusBus, DBusPath, DBusInterface, DBusMember and DBusPeerLabel are
+	// set for denials of D-Bus messages.
+	DBusBus       string
+	DBusPath      string
+	DBusInterface string
+	DBusMember    string
+	DBusPeerLabel string
+
+	// Syscall is the name of the denied system call, or its number when
+	// it cannot be resolved.
+	Syscall string
+	// Arch is the architecture of the denied system call.
+	Arch string
+
+	// Count is the number of times the denial was recorded.
+	Count int
+}
+
+// auditArchs maps the audit architecture identifiers to the names used by
+// libseccomp.
+var auditArchs = map[string]string{
+	"c000003e": "x86_64",
+	"40000003": "x86",
+	"c00000b7": "aarch64",
+	"40000028": "arm",
+	"c0000015": "ppc64le",
+	"80000015": "ppc64",
+	"80000016": "s390x",
+	"c00000f3": "riscv64",
+}
+
+// resolveSyscall returns the name of the system call with the given number
+// on the given architecture.
+var resolveSyscall = func(arch, nr string) (string, error) {
+	output, err := exec.Command("scmp_sys_resolver", "-a", arch, nr).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+var fieldRx = regexp.MustCompile(`([a-z_]+)=("[^"]*"|\S+)`)
+
+// parseFields returns the key=value fields of a log record, the first
+// occurrence of a key wins.
+func parseFields(line string) map[string]string {
+	fields := make(map[string]string)
+	// messages of user space, like the ones of D-Bus, are quoted as
+	// msg='...'
+	line = strings.Replace(line, "'", " ", -1)
+	for _, m := range fieldRx.FindAllStringSubmatch(line, -1) {
+		if _, ok := fields[m[1]]; ok {
+			continue
+		}
+		fields[m[1]] = strings.Trim(m[2], `"`)
+	}
+	return fields
+}
+
+// snapOfSecurityTag returns the instance name of the snap of the given
+// security tag of an application or hook.
+func snapOfSecurityTag(tag string) (string, bool) {
+	l := strings.Split(tag, ".")
+	if len(l) < 3 || l[0] != "snap" || l[1] == "" {
+		return "", false
+	}
+	return l[1], true
+}
+
+func isSecCompRecord(line string, fields map[string]string) bool {
+	if strings.Contains(line, "type=1326") || strings.Contains(line, "type=SECCOMP") {
+		return true
+	}
+	// kernel messages without the audit type
+	_, ok := fields["syscall"]
+	return ok && fields["sig"] != "" && strings.Contains(line, "audit")
+}
+
+type parser struct {
+	denials  []*Denial
+	seen     map[Denial]*Denial
+	syscalls map[string]string
+}
+
+func (p *parser) add(d *Denial) {
+	if prev := p.seen[*d]; prev != nil {
+		prev.Count++
+		return
+	}
+	p.seen[*d] = d
+	d.Count = 1
+	p.denials = append(p.denials, d)
+}
+
+func (p *parser) syscallName(arch, nr string) string {
+	key := arch + "/" + nr
+	if name, ok := p.syscalls[key]; ok {
+		return name
+	}
+	name, err := resolveSyscall(arch, nr)
+	if err != nil || name == "" || name == "UNKNOWN" {
+		name = nr
+	}
+	p.syscalls[key] = name
+	return name
+}
+
+func (p *parser) parseAppArmor(fields map[string]string) *Denial {
+	// D-Bus mediation records the label of the process as label
+	tag := fields["profile"]
+	if tag == "" {
+		tag = fields["label"]
+	}
+	snapName, ok := snapOfSecurityTag(tag)
+	if !ok {
+		return nil
+	}
+	mask := fields["denied_mask"]
+	if mask == "" {
+		mask = fields["mask"]
+	}
+	return &Denial{
+		Kind:          KindAppArmor,
+		Snap:          snapName,
+		SecurityTag:   tag,
+		Operation:     fields["operation"],
+		Name:          fields["name"],
+		Mask:          mask,
+		Capability:    fields["capname"],
+		Family:        fields["family"],
+		SockType:      fields["sock_type"],
+		DBusBus:       fields["bus"],
+		DBusPath:      fields["path"],
+		DBusInterface: fields["interface"],
+		DBusMember:    fields["member"],
+		DBusPeerLabel: fields["peer_label"],
+	}
+}
+
+func (p *parser) parseSecComp(fields map[string]string) *Denial {
+	tag := fields["subj"]
+	snapName, ok := snapOfSecurityTag(tag)
+	if !ok {
+		// without apparmor the process is attributed by its executable
+		exe := strings.Split(strings.TrimPrefix(fields["exe"], "/snap/"), "/")
+		if !strings.HasPrefix(fields["exe"], "/snap/") || exe[0] == "" {
+			return nil
+		}
+		snapName, tag = exe[0], ""
+	}
+	arch := fields["arch"]
+	if name, ok := auditArchs[arch]; ok {
+		arch = name
+	}
+	syscall := fields["syscall"]
+	if _, err := fmt.Sscanf(syscall, "%d", new(int)); err == nil {
+		syscall = p.syscallName(arch, syscall)
+	}
+	return &Denial{
+		Kind:        KindSecComp,
+		Snap:        snapName,
+		SecurityTag: tag,
+		Syscall:     syscall,
+		Arch:        arch,
+	}
+}
+
+// Parse finds the denials of the sandbox of snaps in the given audit or
+// kernel log. Identical denials are reported once, in the order in which
+// they were first recorded.
+func Parse(r io.Reader) ([]*Denial, error) {
+	p := &parser{
+		seen:     make(map[Denial]*Denial),
+		syscalls: make(map[string]string),
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "snap") {
+			continue
+		}
+		fields := parseFields(line)
+		var d *Denial
+		switch {
+		case fields["apparmor"] == "DENIED":
+			d = p.parseAppArmor(fields)
+		case isSecCompRecord(line, fields):
+			d = p.parseSecComp(fields)
+		}
+		if d != nil {
+			p.add(d)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read the log: %v", err)
+	}
+	return p.denials, nil
+}
+
+var osutilStreamCommand = osutil.StreamCommand
+
+var auditTimeRx = regexp.MustCompile(`audit\(([0-9]+)\.[0-9]+:[0-9]+\)`)
+
+// bootTime returns the time of the current boot, in seconds since the
+// epoch.
+func bootTime() (int64, error) {
+	f, err := os.Open(filepath.Join(dirs.GlobalRootDir, "/proc/stat"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		l := strings.Fields(scanner.Text())
+		if len(l) == 2 && l[0] == "btime" {
+			return strconv.ParseInt(l[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("cannot find the boot time in /proc/stat")
+}
+
+// currentBootRecords returns the records of the given audit log that were
+// recorded since the given boot time.
+func currentBootRecords(f *os.File, btime int64) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if m := auditTimeRx.FindStringSubmatch(line); m != nil {
+				t, err := strconv.ParseInt(m[1], 10, 64)
+				if err == nil && t < btime {
+					continue
+				}
+			}
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				// the reader went away
+				return
+			}
+		}
+		w.CloseWithError(scanner.Err())
+	}()
+	return r
+}
+
+// SystemLog returns the log where the denials of the current boot are
+// recorded: the records of the current boot in the log of auditd when it is
+// running, or the audit and kernel messages collected by journald otherwise.
+func SystemLog() (io.ReadCloser, error) {
+	auditLog := filepath.Join(dirs.GlobalRootDir, "/var/log/audit/audit.log")
+	if osutil.FileExists(auditLog) {
+		btime, err := bootTime()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the boot time: %v", err)
+		}
+		f, err := os.Open(auditLog)
+		if err != nil {
+			return nil, err
+		}
+		return currentBootRecords(f, btime), nil
+	}
+	return osutilStreamCommand("journalctl", "-b", "-o", "cat", "--no-pager", "_TRANSPORT=audit", "+", "_TRANSPORT=kernel")
+}
@@ -0,0 +1,203 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/denials"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type denialsSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&denialsSuite{})
+
+func (s *denialsSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+	s.AddCleanup(denials.MockResolveSyscall(func(arch, nr string) (string, error) {
+		return "", errors.New("unexpected call")
+	}))
+}
+
+const kernelLog = `Jan 21 13:55:42 host kernel: audit: type=1400 audit(1611232542.106:1234): apparmor="DENIED" operation="open" profile="snap.foo.app" name="/etc/shadow" pid=1234 comm="app" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0
+Jan 21 13:55:42 host kernel: audit: type=1400 audit(1611232542.107:1235): apparmor="DENIED" operation="open" profile="/usr/sbin/cupsd" name="/etc/shadow" pid=1 comm="cupsd" requested_mask="r" denied_mask="r" fsuid=0 ouid=0
+Jan 21 13:55:43 host kernel: audit: type=1400 audit(1611232543.106:1236): apparmor="DENIED" operation="capable" profile="snap.foo.hook.configure" pid=1235 comm="configure" capability=12 capname="net_admin"
+Jan 21 13:55:44 host kernel: audit: type=1400 audit(1611232544.106:1237): apparmor="DENIED" operation="open" profile="snap.foo.app" name="/etc/shadow" pid=1234 comm="app" requested_mask="r" denied_mask="r" fsuid=1000 ouid=0
+Jan 21 13:55:45 host kernel: audit: type=1400 audit(1611232545.106:1238): apparmor="DENIED" operation="create" profile="snap.bar_instance.daemon" pid=1236 comm="daemon" family="bluetooth" sock_type="raw" protocol=1 requested_mask="create" denied_mask="create"
+Jan 21 13:55:46 host kernel: audit: type=1326 audit(1611232546.106:1239): auid=1000 uid=1000 gid=1000 ses=2 subj=snap.foo.app pid=1234 comm="app" exe="/snap/foo/1/bin/app" sig=0 arch=c000003e syscall=165 compat=0 ip=0x7f0000000000 code=0x50000
+Jan 21 13:55:47 host kernel: audit: type=1326 audit(1611232547.106:1240): auid=1000 uid=1000 gid=1000 ses=2 subj=? pid=1237 comm="tool" exe="/snap/baz/x1/bin/tool" sig=31 arch=c000003e syscall=999 compat=0 ip=0x7f0000000000 code=0x0
+Jan 21 13:55:48 host kernel: unrelated message about snap.foo.app
+`
+
+const auditLog = `type=USER_AVC msg=audit(1611232542.106:1234): pid=700 uid=103 auid=4294967295 ses=4294967295 msg='apparmor="DENIED" operation="dbus_method_call"  bus="system" path="/org/freedesktop/NetworkManager" interface="org.freedesktop.DBus.Properties" member="GetAll" mask="send" name="org.freedesktop.NetworkManager" pid=1234 label="snap.foo.app" peer_pid=800 peer_label="unconfined" exe="/usr/bin/dbus-daemon" sauid=103 hostname=? addr=? terminal=?'
+type=SECCOMP msg=audit(1611232546.106:1239): auid=1000 uid=1000 gid=1000 ses=2 subj=snap.foo.app pid=1234 comm="app" exe="/snap/foo/1/bin/app" sig=0 arch=x86_64 syscall=mount compat=0 ip=0x7f0000000000 code=0x50000
+`
+
+func (s *denialsSuite) TestParseKernelLog(c *C) {
+	var resolved []string
+	restore := denials.MockResolveSyscall(func(arch, nr string) (string, error) {
+		resolved = append(resolved, arch+"/"+nr)
+		if nr == "165" {
+			return "mount", nil
+		}
+		return "UNKNOWN", nil
+	})
+	defer restore()
+
+	ds, err := denials.Parse(strings.NewReader(kernelLog))
+	c.Assert(err, IsNil)
+	c.Check(ds, DeepEquals, []*denials.Denial{{
+		Kind:        denials.KindAppArmor,
+		Snap:        "foo",
+		SecurityTag: "snap.foo.app",
+		Operation:   "open",
+		Name:        "/etc/shadow",
+		Mask:        "r",
+		Count:       2,
+	}, {
+		Kind:        denials.KindAppArmor,
+		Snap:        "foo",
+		SecurityTag: "snap.foo.hook.configure",
+		Operation:   "capable",
+		Capability:  "net_admin",
+		Count:       1,
+	}, {
+		Kind:        denials.KindAppArmor,
+		Snap:        "bar_instance",
+		SecurityTag: "snap.bar_instance.daemon",
+		Operation:   "create",
+		Mask:        "create",
+		Family:      "bluetooth",
+		SockType:    "raw",
+		Count:       1,
+	}, {
+		Kind:        denials.KindSecComp,
+		Snap:        "foo",
+		SecurityTag: "snap.foo.app",
+		Syscall:     "mount",
+		Arch:        "x86_64",
+		Count:       1,
+	}, {
+		Kind:    denials.KindSecComp,
+		Snap:    "baz",
+		Syscall: "999",
+		Arch:    "x86_64",
+		Count:   1,
+	}})
+	c.Check(resolved, DeepEquals, []string{"x86_64/165", "x86_64/999"})
+}
+
+func (s *denialsSuite) TestParseAuditLog(c *C) {
+	ds, err := denials.Parse(strings.NewReader(auditLog))
+	c.Assert(err, IsNil)
+	c.Check(ds, DeepEquals, []*denials.Denial{{
+		Kind:          denials.KindAppArmor,
+		Snap:          "foo",
+		SecurityTag:   "snap.foo.app",
+		Operation:     "dbus_method_call",
+		Name:          "org.freedesktop.NetworkManager",
+		Mask:          "send",
+		DBusBus:       "system",
+		DBusPath:      "/org/freedesktop/NetworkManager",
+		DBusInterface: "org.freedesktop.DBus.Properties",
+		DBusMember:    "GetAll",
+		DBusPeerLabel: "unconfined",
+		Count:         1,
+	}, {
+		Kind:        denials.KindSecComp,
+		Snap:        "foo",
+		SecurityTag: "snap.foo.app",
+		Syscall:     "mount",
+		Arch:        "x86_64",
+		Count:       1,
+	}})
+}
+
+func (s *denialsSuite) TestParseNothing(c *C) {
+	ds, err := denials.Parse(strings.NewReader("nothing to see here\n"))
+	c.Assert(err, IsNil)
+	c.Check(ds, HasLen, 0)
+}
+
+func (s *denialsSuite) TestSystemLogAudit(c *C) {
+	restore := denials.MockStreamCommand(func(name string, args ...string) (io.ReadCloser, error) {
+		c.Fatalf("unexpected call")
+		return nil, nil
+	})
+	defer restore()
+
+	auditLogPath := filepath.Join(dirs.GlobalRootDir, "/var/log/audit/audit.log")
+	c.Assert(os.MkdirAll(filepath.Dir(auditLogPath), 0755), IsNil)
+	previousBoot := `type=SECCOMP msg=audit(1611132546.106:1200): auid=1000 uid=1000 gid=1000 ses=2 subj=snap.old.app pid=1234 comm="app" exe="/snap/old/1/bin/app" sig=0 arch=x86_64 syscall=mount compat=0 ip=0x7f0000000000 code=0x50000
+`
+	c.Assert(ioutil.WriteFile(auditLogPath, []byte(previousBoot+auditLog), 0600), IsNil)
+	procStat := filepath.Join(dirs.GlobalRootDir, "/proc/stat")
+	c.Assert(os.MkdirAll(filepath.Dir(procStat), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(procStat, []byte("cpu  1 2 3 4\nbtime 1611232500\nprocesses 1234\n"), 0644), IsNil)
+
+	r, err := denials.SystemLog()
+	c.Assert(err, IsNil)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, auditLog)
+}
+
+func (s *denialsSuite) TestSystemLogAuditNoBootTime(c *C) {
+	auditLogPath := filepath.Join(dirs.GlobalRootDir, "/var/log/audit/audit.log")
+	c.Assert(os.MkdirAll(filepath.Dir(auditLogPath), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(auditLogPath, []byte(auditLog), 0600), IsNil)
+	procStat := filepath.Join(dirs.GlobalRootDir, "/proc/stat")
+	c.Assert(os.MkdirAll(filepath.Dir(procStat), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(procStat, []byte("cpu  1 2 3 4\n"), 0644), IsNil)
+
+	_, err := denials.SystemLog()
+	c.Assert(err, ErrorMatches, "cannot get the boot time: cannot find the boot time in /proc/stat")
+}
+
+func (s *denialsSuite) TestSystemLogJournal(c *C) {
+	var cmd []string
+	restore := denials.MockStreamCommand(func(name string, args ...string) (io.ReadCloser, error) {
+		cmd = append([]string{name}, args...)
+		return ioutil.NopCloser(strings.NewReader(kernelLog)), nil
+	})
+	defer restore()
+
+	r, err := denials.SystemLog()
+	c.Assert(err, IsNil)
+	defer r.Close()
+	c.Check(cmd, DeepEquals, []string{"journalctl", "-b", "-o", "cat", "--no-pager", "_TRANSPORT=audit", "+", "_TRANSPORT=kernel"})
+}
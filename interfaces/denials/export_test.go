@@ -0,0 +1,59 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials
+
+import (
+	"io"
+	"sync"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+func MockResolveSyscall(f func(arch, nr string) (string, error)) (restore func()) {
+	old := resolveSyscall
+	resolveSyscall = f
+	return func() {
+		resolveSyscall = old
+	}
+}
+
+func MockStreamCommand(f func(name string, args ...string) (io.ReadCloser, error)) (restore func()) {
+	old := osutilStreamCommand
+	osutilStreamCommand = f
+	return func() {
+		osutilStreamCommand = old
+	}
+}
+
+// MockInterfaces makes the suggestions consider the given interfaces
+// instead of the builtin ones, nil resets to the builtin interfaces.
+func MockInterfaces(ifaces []interfaces.Interface) (restore func()) {
+	reset := func(ifaces []interfaces.Interface) {
+		mockedInterfaces = ifaces
+		ifaceRulesOnce = sync.Once{}
+		ifaceRules = nil
+	}
+	reset(ifaces)
+	return func() {
+		reset(nil)
+	}
+}
+
+var GlobToRegexp = globToRegexp
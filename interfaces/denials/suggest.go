@@ -0,0 +1,389 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+type fileRule struct {
+	path  *regexp.Regexp
+	perms string
+}
+
+type dbusRule struct {
+	// access is empty when the rule allows any access
+	access                                []string
+	bus, path, iface, member, name, label *regexp.Regexp
+}
+
+// rules collects what the apparmor and seccomp snippets that an interface
+// generates for a connected plug allow.
+type rules struct {
+	iface        string
+	files        []fileRule
+	capabilities map[string]bool
+	network      [][]string
+	dbus         []dbusRule
+	syscalls     map[string]bool
+}
+
+var (
+	ifaceRulesOnce sync.Once
+	ifaceRules     []*rules
+)
+
+// mockedInterfaces, when set, are used instead of the builtin interfaces.
+var mockedInterfaces []interfaces.Interface
+
+const suggestedPlugYaml = `name: consumer
+version: 1
+apps:
+  app:
+    command: foo
+plugs:
+  plug:
+    interface: %s
+`
+
+const suggestedSlotYaml = `name: core
+version: 1
+type: os
+slots:
+  slot:
+    interface: %s
+`
+
+// plugSnippets returns the apparmor and seccomp snippets generated by the
+// given interface for an application with a connected plug.
+func plugSnippets(iface interfaces.Interface) (aa, sc []string, err error) {
+	// interfaces expect the plugs and slots to be sanitized, be defensive
+	// with the ones that require attributes
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot obtain the snippets of interface %q: %v", iface.Name(), r)
+		}
+	}()
+	plugSnap, err := snap.InfoFromSnapYaml([]byte(fmt.Sprintf(suggestedPlugYaml, iface.Name())))
+	if err != nil {
+		return nil, nil, err
+	}
+	slotSnap, err := snap.InfoFromSnapYaml([]byte(fmt.Sprintf(suggestedSlotYaml, iface.Name())))
+	if err != nil {
+		return nil, nil, err
+	}
+	plugInfo, slotInfo := plugSnap.Plugs["plug"], slotSnap.Slots["slot"]
+	if plugInfo == nil || slotInfo == nil {
+		// dropped when sanitized, the interface requires attributes
+		return nil, nil, fmt.Errorf("cannot use interface %q without attributes", iface.Name())
+	}
+	plug := interfaces.NewConnectedPlug(plugInfo, nil, nil)
+	slot := interfaces.NewConnectedSlot(slotInfo, nil, nil)
+	tag := plugSnap.Apps["app"].SecurityTag()
+
+	aaSpec := &apparmor.Specification{}
+	// errors only mean that some snippets are missing
+	aaSpec.AddPermanentPlug(iface, plugInfo)
+	aaSpec.AddConnectedPlug(iface, plug, slot)
+	scSpec := &seccomp.Specification{}
+	scSpec.AddPermanentPlug(iface, plugInfo)
+	scSpec.AddConnectedPlug(iface, plug, slot)
+	return aaSpec.Snippets()[tag], scSpec.Snippets()[tag], nil
+}
+
+func allInterfaceRules() []*rules {
+	ifaceRulesOnce.Do(func() {
+		ifaces := mockedInterfaces
+		if ifaces == nil {
+			ifaces = builtin.Interfaces()
+		}
+		for _, iface := range ifaces {
+			aa, sc, err := plugSnippets(iface)
+			if err != nil {
+				continue
+			}
+			r := &rules{
+				iface:        iface.Name(),
+				capabilities: make(map[string]bool),
+				syscalls:     make(map[string]bool),
+			}
+			for _, snippet := range aa {
+				r.addAppArmor(snippet)
+			}
+			for _, snippet := range sc {
+				r.addSecComp(snippet)
+			}
+			ifaceRules = append(ifaceRules, r)
+		}
+	})
+	return ifaceRules
+}
+
+// apparmorVariables maps the variables used by the snippets to regular
+// expressions matching their values.
+var apparmorVariables = map[string]string{
+	"PROC":        "/proc",
+	"HOME":        "(/home/[^/]+|/root)",
+	"HOMEDIRS":    "/home",
+	"pid":         "[0-9]+",
+	"pids":        "[0-9]+",
+	"INSTALL_DIR": "(/snap|/var/lib/snapd/snap)",
+}
+
+var variableRx = regexp.MustCompile(`^(@\{([A-Za-z_]+)\}|###[A-Z_]+###)`)
+
+// globToRegexp converts an apparmor glob into an equivalent regular
+// expression.
+func globToRegexp(glob string) string {
+	var buf strings.Builder
+	for i := 0; i < len(glob); i++ {
+		if m := variableRx.FindStringSubmatch(glob[i:]); m != nil {
+			if value, ok := apparmorVariables[m[2]]; ok {
+				buf.WriteString(value)
+			} else {
+				buf.WriteString("[^/]+")
+			}
+			i += len(m[0]) - 1
+			continue
+		}
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				buf.WriteString(".*")
+				i++
+			} else {
+				buf.WriteString("[^/]*")
+			}
+		case '?':
+			buf.WriteString("[^/]")
+		case '{':
+			buf.WriteString("(")
+		case '}':
+			buf.WriteString(")")
+		case ',':
+			// only meaningful within alternations
+			buf.WriteString("|")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			buf.WriteString(glob[i : i+end+1])
+			i += end
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return buf.String()
+}
+
+func compileGlob(glob string) *regexp.Regexp {
+	rx, err := regexp.Compile("^" + globToRegexp(strings.Trim(glob, `"`)) + "$")
+	if err != nil {
+		return nil
+	}
+	return rx
+}
+
+var (
+	filePermsRx  = regexp.MustCompile(`^[rwaklmixuUpPcCbB]+$`)
+	dbusFieldRx  = regexp.MustCompile(`([a-z_]+)=("[^"]*"|(?:\{[^}]*\}|[^\s,(){])+)`)
+	dbusAccessRx = regexp.MustCompile(`^dbus\s*\(([^)]*)\)`)
+)
+
+// apparmorRules splits an apparmor snippet into its rules, without the
+// comments, the qualifiers and the trailing comma.
+func apparmorRules(snippet string) []string {
+	var result []string
+	var rule string
+	for _, line := range strings.Split(snippet, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "}" || strings.HasSuffix(line, " {") {
+			// blocks, like the ones of child profiles, are not rules
+			rule = ""
+			continue
+		}
+		rule = strings.TrimSpace(rule + " " + line)
+		if !strings.HasSuffix(rule, ",") {
+			// multi-line rules, dbus ones in particular
+			continue
+		}
+		result = append(result, strings.TrimSuffix(rule, ","))
+		rule = ""
+	}
+	return result
+}
+
+func (r *rules) addAppArmor(snippet string) {
+	for _, rule := range apparmorRules(snippet) {
+		words := strings.Fields(rule)
+		for len(words) > 0 && (words[0] == "audit" || words[0] == "owner" || words[0] == "allow") {
+			words = words[1:]
+		}
+		if len(words) == 0 || words[0] == "deny" {
+			continue
+		}
+		switch {
+		case words[0] == "capability":
+			for _, capname := range words[1:] {
+				r.capabilities[strings.Trim(capname, ",")] = true
+			}
+		case words[0] == "network":
+			r.network = append(r.network, words[1:])
+		case words[0] == "dbus":
+			var dr dbusRule
+			if m := dbusAccessRx.FindStringSubmatch(rule); m != nil {
+				dr.access = strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == ' ' })
+			}
+			for _, m := range dbusFieldRx.FindAllStringSubmatch(rule, -1) {
+				switch m[1] {
+				case "bus":
+					dr.bus = compileGlob(m[2])
+				case "path":
+					dr.path = compileGlob(m[2])
+				case "interface":
+					dr.iface = compileGlob(m[2])
+				case "member":
+					dr.member = compileGlob(m[2])
+				case "name":
+					// the name to bind or the name of the peer
+					dr.name = compileGlob(m[2])
+				case "label":
+					dr.label = compileGlob(m[2])
+				}
+			}
+			r.dbus = append(r.dbus, dr)
+		case len(words) >= 2:
+			path, perms := words[0], words[1]
+			if filePermsRx.MatchString(path) {
+				path, perms = perms, path
+			}
+			if !strings.HasPrefix(strings.Trim(path, `"`), "/") && !strings.HasPrefix(path, "@{") {
+				continue
+			}
+			if rx := compileGlob(path); rx != nil && filePermsRx.MatchString(perms) {
+				r.files = append(r.files, fileRule{path: rx, perms: perms})
+			}
+		}
+	}
+}
+
+func (r *rules) addSecComp(snippet string) {
+	for _, line := range strings.Split(snippet, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 || strings.HasPrefix(words[0], "#") || strings.HasPrefix(words[0], "~") {
+			continue
+		}
+		r.syscalls[words[0]] = true
+	}
+}
+
+// permsAllow returns whether the apparmor file permissions allow the given
+// access mask.
+func permsAllow(perms, mask string) bool {
+	for _, c := range mask {
+		var allowed bool
+		switch c {
+		case 'r', 'm', 'k', 'l':
+			allowed = strings.ContainsRune(perms, c)
+		case 'w', 'c', 'd':
+			allowed = strings.ContainsRune(perms, 'w')
+		case 'a':
+			allowed = strings.ContainsAny(perms, "aw")
+		case 'x':
+			allowed = strings.ContainsAny(perms, "xX")
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(rx *regexp.Regexp, value string) bool {
+	// unset fields of a rule match anything
+	return rx == nil || rx.MatchString(value)
+}
+
+func (r *rules) allows(d *Denial) bool {
+	switch {
+	case d.Kind == KindSecComp:
+		return r.syscalls[d.Syscall]
+	case d.Capability != "":
+		return r.capabilities[d.Capability]
+	case d.Family != "":
+		for _, words := range r.network {
+			if len(words) == 0 {
+				return true
+			}
+			if words[0] == d.Family && (len(words) == 1 || strutil.ListContains(words[1:], d.SockType)) {
+				return true
+			}
+		}
+	case strings.HasPrefix(d.Operation, "dbus"):
+		for _, dr := range r.dbus {
+			if len(dr.access) > 0 && !strutil.ListContains(dr.access, d.Mask) {
+				continue
+			}
+			if matches(dr.bus, d.DBusBus) && matches(dr.path, d.DBusPath) && matches(dr.iface, d.DBusInterface) && matches(dr.member, d.DBusMember) && matches(dr.name, d.Name) && matches(dr.label, d.DBusPeerLabel) {
+				return true
+			}
+		}
+	case strings.HasPrefix(d.Name, "/"):
+		for _, fr := range r.files {
+			if fr.path.MatchString(d.Name) && permsAllow(fr.perms, d.Mask) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Suggest returns the names of the builtin interfaces whose apparmor or
+// seccomp snippets, generated for an application with a connected plug of
+// the interface, would allow the denied access.
+func Suggest(d *Denial) []string {
+	var suggested []string
+	for _, r := range allInterfaceRules() {
+		if r.allows(d) {
+			suggested = append(suggested, r.iface)
+		}
+	}
+	sort.Strings(suggested)
+	return suggested
+}
@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package denials_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/denials"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type suggestSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&suggestSuite{})
+
+func (s *suggestSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.AddCleanup(snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {}))
+
+	files := &ifacetest.TestInterface{
+		InterfaceName: "files",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet(`
+# Description: access to some files
+/etc/shadow r,
+owner @{HOME}/.config/{foo,bar}/** rw,
+/dev/tty[0-9]* rw,
+deny /etc/gshadow r,
+`)
+			return nil
+		},
+	}
+	system := &ifacetest.TestInterface{
+		InterfaceName: "system",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet(`
+capability net_admin,
+network bluetooth raw,
+dbus (send)
+    bus=system
+    path=/org/freedesktop/NetworkManager{,/**}
+    interface=org.freedesktop.DBus.Properties
+    member=Get{,All}
+    peer=(label=unconfined),
+`)
+			return nil
+		},
+		SecCompConnectedPlugCallback: func(spec *seccomp.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			spec.AddSnippet("# mounting\nmount\numount2\nsocket AF_BLUETOOTH\n")
+			return nil
+		},
+	}
+	broken := &ifacetest.TestInterface{
+		InterfaceName: "broken",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			return errors.New("missing attribute")
+		},
+	}
+	panicky := &ifacetest.TestInterface{
+		InterfaceName: "panicky",
+		AppArmorConnectedPlugCallback: func(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+			panic("missing attribute")
+		},
+	}
+	s.AddCleanup(denials.MockInterfaces([]interfaces.Interface{files, system, broken, panicky}))
+}
+
+func (s *suggestSuite) TestSuggestFiles(c *C) {
+	for _, t := range []struct {
+		name, mask string
+		suggested  []string
+	}{
+		{"/etc/shadow", "r", []string{"files"}},
+		{"/etc/shadow", "w", nil},
+		{"/etc/gshadow", "r", nil},
+		{"/home/user/.config/foo/settings", "rw", []string{"files"}},
+		{"/home/user/.config/foo/settings", "c", []string{"files"}},
+		{"/home/user/.config/baz/settings", "r", nil},
+		{"/dev/tty12", "wr", []string{"files"}},
+		{"/dev/ttyUSB0", "r", nil},
+	} {
+		d := &denials.Denial{Kind: denials.KindAppArmor, Operation: "open", Name: t.name, Mask: t.mask}
+		c.Check(denials.Suggest(d), DeepEquals, t.suggested, Commentf("%s %s", t.name, t.mask))
+	}
+}
+
+func (s *suggestSuite) TestSuggestCapabilityAndNetwork(c *C) {
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "capable", Capability: "net_admin"}), DeepEquals, []string{"system"})
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "capable", Capability: "sys_admin"}), IsNil)
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "create", Family: "bluetooth", SockType: "raw"}), DeepEquals, []string{"system"})
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "create", Family: "bluetooth", SockType: "stream"}), IsNil)
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "create", Family: "netlink", SockType: "raw"}), IsNil)
+}
+
+func (s *suggestSuite) TestSuggestDBus(c *C) {
+	d := &denials.Denial{
+		Kind:          denials.KindAppArmor,
+		Operation:     "dbus_method_call",
+		Name:          "org.freedesktop.NetworkManager",
+		Mask:          "send",
+		DBusBus:       "system",
+		DBusPath:      "/org/freedesktop/NetworkManager/Devices/1",
+		DBusInterface: "org.freedesktop.DBus.Properties",
+		DBusMember:    "GetAll",
+		DBusPeerLabel: "unconfined",
+	}
+	c.Check(denials.Suggest(d), DeepEquals, []string{"system"})
+
+	d.DBusMember = "Set"
+	c.Check(denials.Suggest(d), IsNil)
+
+	d.DBusMember = "Get"
+	d.Mask = "receive"
+	c.Check(denials.Suggest(d), IsNil)
+
+	d.Mask = "send"
+	d.DBusPeerLabel = "snap.network-manager.networkmanager"
+	c.Check(denials.Suggest(d), IsNil)
+}
+
+func (s *suggestSuite) TestSuggestSecComp(c *C) {
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindSecComp, Syscall: "mount"}), DeepEquals, []string{"system"})
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindSecComp, Syscall: "socket"}), DeepEquals, []string{"system"})
+	c.Check(denials.Suggest(&denials.Denial{Kind: denials.KindSecComp, Syscall: "165"}), IsNil)
+}
+
+func (s *suggestSuite) TestGlobToRegexp(c *C) {
+	for _, t := range []struct{ glob, rx string }{
+		{"/etc/shadow", `/etc/shadow`},
+		{"/dev/tty[0-9]*", `/dev/tty[0-9][^/]*`},
+		{"/sys/**", `/sys/.*`},
+		{"@{PROC}/@{pid}/stat", `/proc/[0-9]+/stat`},
+		{"/var/snap/@{SNAP_INSTANCE_NAME}/{,*}", `/var/snap/[^/]+/(|[^/]*)`},
+		{"/run/###SLOT_NAME###", `/run/[^/]+`},
+		{"/dev/sd?", `/dev/sd[^/]`},
+	} {
+		c.Check(denials.GlobToRegexp(t.glob), Equals, t.rx, Commentf(t.glob))
+	}
+}
+
+type builtinSuggestSuite struct{}
+
+var _ = Suite(&builtinSuggestSuite{})
+
+func (s *builtinSuggestSuite) TestSuggestBuiltin(c *C) {
+	suggested := denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "capable", Capability: "net_admin"})
+	c.Check(suggested, testutil.Contains, "network-control")
+
+	suggested = denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "open", Name: "/sys/class/net/eth0/statistics/rx_bytes", Mask: "r"})
+	c.Check(suggested, testutil.Contains, "hardware-observe")
+
+	suggested = denials.Suggest(&denials.Denial{Kind: denials.KindAppArmor, Operation: "create", Family: "bluetooth", SockType: "raw"})
+	c.Check(suggested, testutil.Contains, "bluetooth-control")
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/kmod"
 	"github.com/snapcore/snapd/interfaces/mount"
 	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
@@ -103,6 +104,13 @@ type TestInterface struct {
 	SystemdConnectedSlotCallback func(spec *systemd.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
 	SystemdPermanentPlugCallback func(spec *systemd.Specification, plug *snap.PlugInfo) error
 	SystemdPermanentSlotCallback func(spec *systemd.Specification, slot *snap.SlotInfo) error
+
+	// Support for interacting with the selinux backend.
+
+	SELinuxConnectedPlugCallback func(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	SELinuxConnectedSlotCallback func(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error
+	SELinuxPermanentPlugCallback func(spec *selinux.Specification, plug *snap.PlugInfo) error
+	SELinuxPermanentSlotCallback func(spec *selinux.Specification, slot *snap.SlotInfo) error
 }
 
 // TestHotplugInterface is an interface for various kinds of tests
@@ -412,6 +420,36 @@ func (t *TestInterface) SystemdPermanentPlug(spec *systemd.Specification, plug *
 	return nil
 }
 
+// Support for interacting with the selinux backend.
+
+func (t *TestInterface) SELinuxConnectedPlug(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.SELinuxConnectedPlugCallback != nil {
+		return t.SELinuxConnectedPlugCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) SELinuxConnectedSlot(spec *selinux.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if t.SELinuxConnectedSlotCallback != nil {
+		return t.SELinuxConnectedSlotCallback(spec, plug, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) SELinuxPermanentSlot(spec *selinux.Specification, slot *snap.SlotInfo) error {
+	if t.SELinuxPermanentSlotCallback != nil {
+		return t.SELinuxPermanentSlotCallback(spec, slot)
+	}
+	return nil
+}
+
+func (t *TestInterface) SELinuxPermanentPlug(spec *selinux.Specification, plug *snap.PlugInfo) error {
+	if t.SELinuxPermanentPlugCallback != nil {
+		return t.SELinuxPermanentPlugCallback(spec, plug)
+	}
+	return nil
+}
+
 // Support for interacting with hotplug subsystem.
 
 func (t *TestHotplugInterface) HotplugKey(deviceInfo *hotplug.HotplugDeviceInfo) (snap.HotplugKey, error) {
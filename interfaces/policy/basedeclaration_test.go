@@ -201,6 +201,7 @@ func (s *baseDeclSuite) TestAutoConnectPlugSlot(c *C) {
 	snowflakes := map[string]bool{
 		"classic-support": true,
 		"content":         true,
+		"custom-device":   true,
 		"home":            true,
 		"lxd-support":     true,
 	}
@@ -595,6 +596,7 @@ var (
 		"browser-support":         {"core"},
 		"content":                 {"app", "gadget"},
 		"core-support":            {"core"},
+		"custom-device":           {"gadget", "kernel"},
 		"dbus":                    {"app"},
 		"docker-support":          {"core"},
 		"fwupd":                   {"app", "core"},
@@ -703,6 +705,7 @@ func (s *baseDeclSuite) TestPlugInstallation(c *C) {
 		"kernel-module-control": true,
 		"kubernetes-support":    true,
 		"lxd-support":           true,
+		"mount-control":         true,
 		"multipass-support":     true,
 		"packagekit-control":    true,
 		"personal-files":        true,
@@ -748,6 +751,7 @@ func (s *baseDeclSuite) TestConnection(c *C) {
 	// case-by-case basis
 	noconnect := map[string]bool{
 		"content":                   true,
+		"custom-device":             true,
 		"docker":                    true,
 		"fwupd":                     true,
 		"location-control":          true,
@@ -836,6 +840,7 @@ func (s *baseDeclSuite) TestSanity(c *C) {
 		"kernel-module-control": true,
 		"kubernetes-support":    true,
 		"lxd-support":           true,
+		"mount-control":         true,
 		"multipass-support":     true,
 		"packagekit-control":    true,
 		"personal-files":        true,
@@ -39,17 +39,26 @@ type InstallCandidate struct {
 
 	Model *asserts.Model
 	Store *asserts.Store
+
+	// Trace, if set, records how the rules were evaluated.
+	Trace *Trace
 }
 
 func (ic *InstallCandidate) checkSlotRule(slot *snap.SlotInfo, rule *asserts.SlotRule, snapRule bool) error {
 	context := ""
+	var snapDecl *asserts.SnapDeclaration
 	if snapRule {
+		snapDecl = ic.SnapDeclaration
 		context = fmt.Sprintf(" for %q snap", ic.SnapDeclaration.SnapName())
 	}
-	if checkSlotInstallationAltConstraints(ic, slot, rule.DenyInstallation) == nil {
+	err := checkSlotInstallationAltConstraints(ic, slot, rule.DenyInstallation)
+	ic.Trace.record(snapDecl, "slot", slot.Name, slot.Interface, "deny-installation", err)
+	if err == nil {
 		return fmt.Errorf("installation denied by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
 	}
-	if checkSlotInstallationAltConstraints(ic, slot, rule.AllowInstallation) != nil {
+	err = checkSlotInstallationAltConstraints(ic, slot, rule.AllowInstallation)
+	ic.Trace.record(snapDecl, "slot", slot.Name, slot.Interface, "allow-installation", err)
+	if err != nil {
 		return fmt.Errorf("installation not allowed by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
 	}
 	return nil
@@ -57,13 +66,19 @@ func (ic *InstallCandidate) checkSlotRule(slot *snap.SlotInfo, rule *asserts.Slo
 
 func (ic *InstallCandidate) checkPlugRule(plug *snap.PlugInfo, rule *asserts.PlugRule, snapRule bool) error {
 	context := ""
+	var snapDecl *asserts.SnapDeclaration
 	if snapRule {
+		snapDecl = ic.SnapDeclaration
 		context = fmt.Sprintf(" for %q snap", ic.SnapDeclaration.SnapName())
 	}
-	if checkPlugInstallationAltConstraints(ic, plug, rule.DenyInstallation) == nil {
+	err := checkPlugInstallationAltConstraints(ic, plug, rule.DenyInstallation)
+	ic.Trace.record(snapDecl, "plug", plug.Name, plug.Interface, "deny-installation", err)
+	if err == nil {
 		return fmt.Errorf("installation denied by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
 	}
-	if checkPlugInstallationAltConstraints(ic, plug, rule.AllowInstallation) != nil {
+	err = checkPlugInstallationAltConstraints(ic, plug, rule.AllowInstallation)
+	ic.Trace.record(snapDecl, "plug", plug.Name, plug.Interface, "allow-installation", err)
+	if err != nil {
 		return fmt.Errorf("installation not allowed by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
 	}
 	return nil
@@ -118,6 +133,24 @@ func (ic *InstallCandidate) Check() error {
 	return nil
 }
 
+// CheckSlot checks whether the installation of the given slot of the
+// snap is allowed.
+func (ic *InstallCandidate) CheckSlot(slot *snap.SlotInfo) error {
+	if ic.BaseDeclaration == nil {
+		return fmt.Errorf("internal error: improperly initialized InstallCandidate")
+	}
+	return ic.checkSlot(slot)
+}
+
+// CheckPlug checks whether the installation of the given plug of the
+// snap is allowed.
+func (ic *InstallCandidate) CheckPlug(plug *snap.PlugInfo) error {
+	if ic.BaseDeclaration == nil {
+		return fmt.Errorf("internal error: improperly initialized InstallCandidate")
+	}
+	return ic.checkPlug(plug)
+}
+
 // ConnectCandidate represents a candidate connection.
 type ConnectCandidate struct {
 	Plug                *interfaces.ConnectedPlug
@@ -130,6 +163,9 @@ type ConnectCandidate struct {
 
 	Model *asserts.Model
 	Store *asserts.Store
+
+	// Trace, if set, records how the rules were evaluated.
+	Trace *Trace
 }
 
 func nestedGet(which string, attrs interfaces.Attrer, path string) (interface{}, error) {
@@ -178,7 +214,9 @@ func (connc *ConnectCandidate) slotPublisherID() string {
 
 func (connc *ConnectCandidate) checkPlugRule(kind string, rule *asserts.PlugRule, snapRule bool) (interfaces.SideArity, error) {
 	context := ""
+	var snapDecl *asserts.SnapDeclaration
 	if snapRule {
+		snapDecl = connc.PlugSnapDeclaration
 		context = fmt.Sprintf(" for %q snap", connc.PlugSnapDeclaration.SnapName())
 	}
 	denyConst := rule.DenyConnection
@@ -187,11 +225,14 @@ func (connc *ConnectCandidate) checkPlugRule(kind string, rule *asserts.PlugRule
 		denyConst = rule.DenyAutoConnection
 		allowConst = rule.AllowAutoConnection
 	}
-	if _, err := checkPlugConnectionAltConstraints(connc, denyConst); err == nil {
+	_, err := checkPlugConnectionAltConstraints(connc, denyConst)
+	connc.Trace.record(snapDecl, "plug", connc.Plug.Name(), connc.Plug.Interface(), "deny-"+kind, err)
+	if err == nil {
 		return nil, fmt.Errorf("%s denied by plug rule of interface %q%s", kind, connc.Plug.Interface(), context)
 	}
 
 	allowedConstraints, err := checkPlugConnectionAltConstraints(connc, allowConst)
+	connc.Trace.record(snapDecl, "plug", connc.Plug.Name(), connc.Plug.Interface(), "allow-"+kind, err)
 	if err != nil {
 		return nil, fmt.Errorf("%s not allowed by plug rule of interface %q%s", kind, connc.Plug.Interface(), context)
 	}
@@ -200,7 +241,9 @@ func (connc *ConnectCandidate) checkPlugRule(kind string, rule *asserts.PlugRule
 
 func (connc *ConnectCandidate) checkSlotRule(kind string, rule *asserts.SlotRule, snapRule bool) (interfaces.SideArity, error) {
 	context := ""
+	var snapDecl *asserts.SnapDeclaration
 	if snapRule {
+		snapDecl = connc.SlotSnapDeclaration
 		context = fmt.Sprintf(" for %q snap", connc.SlotSnapDeclaration.SnapName())
 	}
 	denyConst := rule.DenyConnection
@@ -209,11 +252,14 @@ func (connc *ConnectCandidate) checkSlotRule(kind string, rule *asserts.SlotRule
 		denyConst = rule.DenyAutoConnection
 		allowConst = rule.AllowAutoConnection
 	}
-	if _, err := checkSlotConnectionAltConstraints(connc, denyConst); err == nil {
+	_, err := checkSlotConnectionAltConstraints(connc, denyConst)
+	connc.Trace.record(snapDecl, "slot", connc.Slot.Name(), connc.Plug.Interface(), "deny-"+kind, err)
+	if err == nil {
 		return nil, fmt.Errorf("%s denied by slot rule of interface %q%s", kind, connc.Plug.Interface(), context)
 	}
 
 	allowedConstraints, err := checkSlotConnectionAltConstraints(connc, allowConst)
+	connc.Trace.record(snapDecl, "slot", connc.Slot.Name(), connc.Plug.Interface(), "allow-"+kind, err)
 	if err != nil {
 		return nil, fmt.Errorf("%s not allowed by slot rule of interface %q%s", kind, connc.Plug.Interface(), context)
 	}
@@ -1040,6 +1040,65 @@ func (s *policySuite) TestSnapDeclAllowDenyAutoConnection(c *C) {
 	}
 }
 
+func (s *policySuite) TestConnectionTrace(c *C) {
+	// the base declaration denies the connection but the slot
+	// snap-declaration overrides it
+	trace := &policy.Trace{}
+	cand := policy.ConnectCandidate{
+		Plug:                interfaces.NewConnectedPlug(s.plugSnap.Plugs["base-deny-snap-slot-allow"], nil, nil),
+		Slot:                interfaces.NewConnectedSlot(s.slotSnap.Slots["base-deny-snap-slot-allow"], nil, nil),
+		PlugSnapDeclaration: s.plugDecl,
+		SlotSnapDeclaration: s.slotDecl,
+		BaseDeclaration:     s.baseDecl,
+		Trace:               trace,
+	}
+	c.Assert(cand.Check(), IsNil)
+	c.Assert(trace.Steps, HasLen, 2)
+	c.Check(trace.Steps[0], DeepEquals, policy.TraceStep{
+		Declaration: "snap-declaration",
+		Snap:        "slot-snap",
+		Side:        "slot",
+		Name:        "base-deny-snap-slot-allow",
+		Interface:   "base-deny-snap-slot-allow",
+		Constraints: "deny-connection",
+		Matched:     false,
+		Reason:      trace.Steps[0].Reason,
+	})
+	c.Check(trace.Steps[0].Reason, Not(Equals), "")
+	c.Check(trace.Steps[1], DeepEquals, policy.TraceStep{
+		Declaration: "snap-declaration",
+		Snap:        "slot-snap",
+		Side:        "slot",
+		Name:        "base-deny-snap-slot-allow",
+		Interface:   "base-deny-snap-slot-allow",
+		Constraints: "allow-connection",
+		Matched:     true,
+	})
+
+	// a base declaration rule not matching because of attributes
+	trace = &policy.Trace{}
+	cand = policy.ConnectCandidate{
+		Plug:            interfaces.NewConnectedPlug(s.plugSnap.Plugs["base-plug-not-allow-slots"], nil, nil),
+		Slot:            interfaces.NewConnectedSlot(s.slotSnap.Slots["base-plug-not-allow-slots"], nil, nil),
+		BaseDeclaration: s.baseDecl,
+		Trace:           trace,
+	}
+	_, err := cand.CheckAutoConnect()
+	c.Assert(err, IsNil)
+	c.Assert(cand.Check(), ErrorMatches, `connection not allowed by plug rule of interface "base-plug-not-allow-slots"`)
+	c.Assert(trace.Steps, HasLen, 4)
+	c.Check(trace.Steps[0].Constraints, Equals, "deny-auto-connection")
+	c.Check(trace.Steps[1].Constraints, Equals, "allow-auto-connection")
+	c.Check(trace.Steps[1].Matched, Equals, true)
+	last := trace.Steps[3]
+	c.Check(last.Declaration, Equals, "base-declaration")
+	c.Check(last.Snap, Equals, "")
+	c.Check(last.Side, Equals, "plug")
+	c.Check(last.Constraints, Equals, "allow-connection")
+	c.Check(last.Matched, Equals, false)
+	c.Check(last.Reason, Matches, `.*attribute "s".*`)
+}
+
 func (s *policySuite) TestSnapTypeCheckConnection(c *C) {
 	gadgetSnap := snaptest.MockInfo(c, `
 name: gadget
@@ -1451,6 +1510,45 @@ slots:
 	}
 }
 
+func (s *policySuite) TestInstallationTrace(c *C) {
+	installSnap := snaptest.MockInfo(c, `name: install-snap
+version: 0
+slots:
+  install-slot-coreonly:
+  install-slot-attr-ok:
+    attr: ok
+`, nil)
+
+	trace := &policy.Trace{}
+	cand := policy.InstallCandidate{
+		Snap:            installSnap,
+		BaseDeclaration: s.baseDecl,
+		Trace:           trace,
+	}
+
+	c.Check(cand.CheckSlot(installSnap.Slots["install-slot-attr-ok"]), IsNil)
+	c.Check(cand.CheckSlot(installSnap.Slots["install-slot-coreonly"]), ErrorMatches, `installation not allowed by "install-slot-coreonly" slot rule of interface "install-slot-coreonly"`)
+
+	c.Assert(trace.Steps, HasLen, 4)
+	for i, exp := range []struct {
+		name, constraints string
+		matched           bool
+	}{
+		{"install-slot-attr-ok", "deny-installation", false},
+		{"install-slot-attr-ok", "allow-installation", true},
+		{"install-slot-coreonly", "deny-installation", false},
+		{"install-slot-coreonly", "allow-installation", false},
+	} {
+		step := trace.Steps[i]
+		c.Check(step.Declaration, Equals, "base-declaration")
+		c.Check(step.Side, Equals, "slot")
+		c.Check(step.Name, Equals, exp.name)
+		c.Check(step.Constraints, Equals, exp.constraints)
+		c.Check(step.Matched, Equals, exp.matched)
+	}
+	c.Check(trace.Steps[3].Reason, Equals, "snap type does not match")
+}
+
 func (s *policySuite) TestSnapDeclAllowDenyInstallation(c *C) {
 
 	tests := []struct {
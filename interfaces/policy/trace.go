@@ -0,0 +1,74 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package policy
+
+import (
+	"github.com/snapcore/snapd/asserts"
+)
+
+// Trace records how the rules of the declarations were evaluated by a
+// policy check, to explain its outcome.
+type Trace struct {
+	Steps []TraceStep
+}
+
+// TraceStep describes the evaluation of the constraints of one rule.
+type TraceStep struct {
+	// Declaration is either "snap-declaration" or "base-declaration".
+	Declaration string
+	// Snap is the name of the snap of the snap-declaration the rule
+	// comes from, it is empty for the base-declaration.
+	Snap string
+	// Side is either "plug" or "slot", the side of the rule.
+	Side string
+	// Name is the name of the plug or slot.
+	Name      string
+	Interface string
+	// Constraints are the evaluated constraints of the rule, e.g.
+	// "deny-auto-connection".
+	Constraints string
+	// Matched is whether any of the alternative constraints matched.
+	Matched bool
+	// Reason tells why the constraints did not match, for the first
+	// alternative.
+	Reason string
+}
+
+func (t *Trace) record(snapDecl *asserts.SnapDeclaration, side, name, iface, constraints string, err error) {
+	if t == nil {
+		return
+	}
+	step := TraceStep{
+		Declaration: "base-declaration",
+		Side:        side,
+		Name:        name,
+		Interface:   iface,
+		Constraints: constraints,
+		Matched:     err == nil,
+	}
+	if snapDecl != nil {
+		step.Declaration = "snap-declaration"
+		step.Snap = snapDecl.SnapName()
+	}
+	if err != nil {
+		step.Reason = err.Error()
+	}
+	t.Steps = append(t.Steps, step)
+}
@@ -949,44 +949,135 @@ func (r *Repository) SnapSpecification(securitySystem SecuritySystem, snapName s
 	r.m.Lock()
 	defer r.m.Unlock()
 
-	var backend SecurityBackend
+	backend, err := r.backend(securitySystem, snapName)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := backend.NewSpecification()
+	err = r.addSnapSpecification(snapName, func(*SpecificationSource) Specification {
+		return spec
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// SpecificationSource identifies the plug or slot of a snap, and the
+// connection if any, whose interface contributed to a specification.
+type SpecificationSource struct {
+	Interface string
+	// Side is either "plug" or "slot", the other side is only set for
+	// connected plugs and slots.
+	Side string
+	Plug PlugRef
+	Slot SlotRef
+}
+
+// Connected returns whether the source is a connected plug or slot.
+func (src *SpecificationSource) Connected() bool {
+	return src.Plug.Snap != "" && src.Slot.Snap != ""
+}
+
+// String returns a description of the source, for example
+// "connected plug foo:home (slot core:home)".
+func (src *SpecificationSource) String() string {
+	var side, other string
+	switch src.Side {
+	case "plug":
+		side, other = src.Plug.String(), fmt.Sprintf("slot %s", src.Slot)
+	default:
+		side, other = src.Slot.String(), fmt.Sprintf("plug %s", src.Plug)
+	}
+	if src.Connected() {
+		return fmt.Sprintf("connected %s %s (%s)", src.Side, side, other)
+	}
+	return fmt.Sprintf("permanent %s %s", src.Side, side)
+}
+
+// SpecificationPart is the part of the specification of a snap contributed
+// by a single source.
+type SpecificationPart struct {
+	Source SpecificationSource
+	Spec   Specification
+}
+
+// SnapSpecificationParts returns the specification of a given snap in a
+// given security system split into the parts contributed by each of its
+// plugs and slots and by each of their connections.
+func (r *Repository) SnapSpecificationParts(securitySystem SecuritySystem, snapName string) ([]*SpecificationPart, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	backend, err := r.backend(securitySystem, snapName)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []*SpecificationPart
+	err = r.addSnapSpecification(snapName, func(src *SpecificationSource) Specification {
+		part := &SpecificationPart{Source: *src, Spec: backend.NewSpecification()}
+		parts = append(parts, part)
+		return part.Spec
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(bySpecificationSource(parts))
+	return parts, nil
+}
+
+func (r *Repository) backend(securitySystem SecuritySystem, snapName string) (SecurityBackend, error) {
 	for _, b := range r.backends {
 		if b.Name() == securitySystem {
-			backend = b
-			break
+			return b, nil
 		}
 	}
-	if backend == nil {
-		return nil, fmt.Errorf("cannot handle interfaces of snap %q, security system %q is not known", snapName, securitySystem)
-	}
-
-	spec := backend.NewSpecification()
+	return nil, fmt.Errorf("cannot handle interfaces of snap %q, security system %q is not known", snapName, securitySystem)
+}
 
+// addSnapSpecification records the side-effects of the plugs and slots of
+// the given snap, and of their connections, into the specifications
+// returned by specFor for each source.
+func (r *Repository) addSnapSpecification(snapName string, specFor func(src *SpecificationSource) Specification) error {
 	// slot side
 	for _, slotInfo := range r.slots[snapName] {
 		iface := r.ifaces[slotInfo.Interface]
-		if err := spec.AddPermanentSlot(iface, slotInfo); err != nil {
-			return nil, err
+		src := &SpecificationSource{
+			Interface: slotInfo.Interface,
+			Side:      "slot",
+			Slot:      SlotRef{Snap: snapName, Name: slotInfo.Name},
+		}
+		if err := specFor(src).AddPermanentSlot(iface, slotInfo); err != nil {
+			return err
 		}
 		for _, conn := range r.slotPlugs[slotInfo] {
-			if err := spec.AddConnectedSlot(iface, conn.Plug, conn.Slot); err != nil {
-				return nil, err
+			src.Plug = *conn.Plug.Ref()
+			if err := specFor(src).AddConnectedSlot(iface, conn.Plug, conn.Slot); err != nil {
+				return err
 			}
 		}
 	}
 	// plug side
 	for _, plugInfo := range r.plugs[snapName] {
 		iface := r.ifaces[plugInfo.Interface]
-		if err := spec.AddPermanentPlug(iface, plugInfo); err != nil {
-			return nil, err
+		src := &SpecificationSource{
+			Interface: plugInfo.Interface,
+			Side:      "plug",
+			Plug:      PlugRef{Snap: snapName, Name: plugInfo.Name},
+		}
+		if err := specFor(src).AddPermanentPlug(iface, plugInfo); err != nil {
+			return err
 		}
 		for _, conn := range r.plugSlots[plugInfo] {
-			if err := spec.AddConnectedPlug(iface, conn.Plug, conn.Slot); err != nil {
-				return nil, err
+			src.Slot = *conn.Slot.Ref()
+			if err := specFor(src).AddConnectedPlug(iface, conn.Plug, conn.Slot); err != nil {
+				return err
 			}
 		}
 	}
-	return spec, nil
+	return nil
 }
 
 // AddSnap adds plugs and slots declared by the given snap to the repository.
@@ -1684,6 +1684,50 @@ func (s *RepositorySuite) TestSnapSpecificationFailureWithPermanentSnippets(c *C
 	c.Assert(spec, IsNil)
 }
 
+func (s *RepositorySuite) TestSnapSpecificationParts(c *C) {
+	repo := s.emptyRepo
+	backend := &ifacetest.TestSecurityBackend{BackendName: testSecurity}
+	c.Assert(repo.AddBackend(backend), IsNil)
+	c.Assert(repo.AddInterface(testInterface), IsNil)
+	c.Assert(repo.AddPlug(s.plug), IsNil)
+	c.Assert(repo.AddSlot(s.slot), IsNil)
+	connRef := NewConnRef(s.plug, s.slot)
+	_, err := repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	parts, err := repo.SnapSpecificationParts(testSecurity, s.plug.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(parts, HasLen, 2)
+	c.Check(parts[0].Source, DeepEquals, SpecificationSource{
+		Interface: "interface",
+		Side:      "plug",
+		Plug:      PlugRef{Snap: "consumer", Name: "plug"},
+	})
+	c.Check(parts[0].Source.Connected(), Equals, false)
+	c.Check(parts[0].Source.String(), Equals, "permanent plug consumer:plug")
+	c.Check(parts[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static plug snippet"})
+	c.Check(parts[1].Source, DeepEquals, SpecificationSource{
+		Interface: "interface",
+		Side:      "plug",
+		Plug:      PlugRef{Snap: "consumer", Name: "plug"},
+		Slot:      SlotRef{Snap: "producer", Name: "slot"},
+	})
+	c.Check(parts[1].Source.Connected(), Equals, true)
+	c.Check(parts[1].Source.String(), Equals, "connected plug consumer:plug (slot producer:slot)")
+	c.Check(parts[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific plug snippet"})
+
+	parts, err = repo.SnapSpecificationParts(testSecurity, s.slot.Snap.InstanceName())
+	c.Assert(err, IsNil)
+	c.Assert(parts, HasLen, 2)
+	c.Check(parts[0].Source.String(), Equals, "permanent slot producer:slot")
+	c.Check(parts[0].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"static slot snippet"})
+	c.Check(parts[1].Source.String(), Equals, "connected slot producer:slot (plug consumer:plug)")
+	c.Check(parts[1].Spec.(*ifacetest.Specification).Snippets, DeepEquals, []string{"connection-specific slot snippet"})
+
+	_, err = repo.SnapSpecificationParts("unknown", s.plug.Snap.InstanceName())
+	c.Assert(err, ErrorMatches, `cannot handle interfaces of snap "consumer", security system "unknown" is not known`)
+}
+
 type testSideArity struct {
 	sideSnapName string
 }
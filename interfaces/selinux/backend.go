@@ -0,0 +1,173 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package selinux implements integration between snapd and SELinux.
+//
+// Snappy creates an SELinux policy module for each snap. The module declares
+// a domain for each application and hook of the snap, named after the
+// security tag (see DomainType), and grants it a base set of permissions
+// needed to run the files of the snap. Interfaces may extend the domains by
+// providing snippets written in the reference policy language via their
+// respective "SELinux*" methods.
+//
+// The modules are written to /var/lib/snapd/selinux, built with the SELinux
+// policy development tools and installed in the running policy with
+// semodule. Snaps in devmode get permissive domains, while snaps using
+// classic confinement get unconfined ones.
+package selinux
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timings"
+)
+
+// Backend is responsible for maintaining SELinux policy modules for snaps.
+type Backend struct{}
+
+// Initialize does nothing.
+func (b *Backend) Initialize() error {
+	return nil
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() interfaces.SecuritySystem {
+	return interfaces.SecuritySELinux
+}
+
+// Setup creates the SELinux policy module of the given snap and, when it
+// changed, builds it and loads it into the running policy.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	snapName := snapInfo.InstanceName()
+	// Get the snippets that apply to this snap
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return fmt.Errorf("cannot obtain SELinux specification for snap %q: %s", snapName, err)
+	}
+
+	name := ModuleName(snapName)
+	content := deriveContent(spec.(*Specification), snapInfo, opts)
+	if content == nil {
+		return b.Remove(snapName)
+	}
+
+	dir := dirs.SnapSELinuxDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for SELinux policy modules %q: %s", dir, err)
+	}
+	changed, _, err := osutil.EnsureDirStateGlobs(dir, sourceGlobs(name), content)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if err := loadModule(dir, name); err != nil {
+		// Remove the sources so that the module gets rebuilt on retry.
+		osutil.EnsureDirStateGlobs(dir, sourceGlobs(name), nil)
+		return err
+	}
+	return nil
+}
+
+// Remove removes the SELinux policy module of the given snap from the
+// running policy and removes its sources.
+//
+// If the method fails it should be re-tried (with a sensible strategy) by the caller.
+func (b *Backend) Remove(snapName string) error {
+	name := ModuleName(snapName)
+	globs := append(sourceGlobs(name), name+".pp")
+	_, removed, err := osutil.EnsureDirStateGlobs(dirs.SnapSELinuxDir, globs, nil)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		// the module was never loaded
+		return nil
+	}
+	return unloadModule(name)
+}
+
+// NewSpecification returns a new SELinux specification.
+func (b *Backend) NewSpecification() interfaces.Specification {
+	return &Specification{}
+}
+
+// SandboxFeatures returns the list of features supported by snapd for
+// SELinux.
+func (b *Backend) SandboxFeatures() []string {
+	return []string{"policy-modules"}
+}
+
+// sourceGlobs returns the file names of the sources of the given policy
+// module.
+func sourceGlobs(name string) []string {
+	return []string{name + ".te", name + ".fc"}
+}
+
+// deriveContent computes the sources of the policy module of the given snap.
+//
+// The type enforcement file declares the domains of all applications and
+// hooks of the snap, the file contexts file is empty as snaps do not label
+// any files of their own.
+func deriveContent(spec *Specification, snapInfo *snap.Info, opts interfaces.ConfinementOptions) map[string]osutil.FileState {
+	var tags []string
+	for _, appInfo := range snapInfo.Apps {
+		tags = append(tags, appInfo.SecurityTag())
+	}
+	for _, hookInfo := range snapInfo.Hooks {
+		tags = append(tags, hookInfo.SecurityTag())
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	sort.Strings(tags)
+
+	name := ModuleName(snapInfo.InstanceName())
+	var buffer bytes.Buffer
+	buffer.WriteString(strings.Replace(moduleHeader, "###MODULE###", name, -1))
+	for _, tag := range tags {
+		snippets := spec.SnippetForTag(tag)
+		if opts.Classic {
+			snippets += classicSnippet
+		} else if opts.DevMode {
+			snippets += permissiveSnippet
+		}
+		policy := strings.NewReplacer(
+			"###SECURITY_TAG###", tag,
+			"###SNIPPETS###", snippets,
+		).Replace(domainTemplate)
+		buffer.WriteString(strings.Replace(policy, "###DOMAIN###", DomainType(tag), -1))
+	}
+
+	return map[string]osutil.FileState{
+		name + ".te": &osutil.MemoryFileState{Content: buffer.Bytes(), Mode: 0644},
+		name + ".fc": &osutil.MemoryFileState{Content: nil, Mode: 0644},
+	}
+}
@@ -0,0 +1,214 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package selinux_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/selinux"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+	makeCmd     *testutil.MockCmd
+	semoduleCmd *testutil.MockCmd
+	meas        *timings.Span
+}
+
+var _ = Suite(&backendSuite{})
+
+var testedConfinementOpts = []interfaces.ConfinementOptions{
+	{},
+	{DevMode: true},
+	{JailMode: true},
+	{Classic: true},
+}
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &selinux.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+	s.makeCmd = testutil.MockCommand(c, "make", "")
+	s.semoduleCmd = testutil.MockCommand(c, "semodule", "")
+
+	perf := timings.New(nil)
+	s.meas = perf.StartSpan("", "")
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	s.makeCmd.Restore()
+	s.semoduleCmd.Restore()
+	s.BackendSuite.TearDownTest(c)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecuritySELinux)
+}
+
+func (s *backendSuite) TestDomainType(c *C) {
+	c.Check(selinux.DomainType("snap.foo.app"), Equals, "snap__foo__app_t")
+	c.Check(selinux.DomainType("snap.foo-bar.hook.post-refresh"), Equals, "snap__foo___bar__hook__post___refresh_t")
+	c.Check(selinux.DomainType("snap.foo_bar.app"), Equals, "snap__foo_bar__app_t")
+	c.Check(selinux.ModuleName("foo-bar_baz"), Equals, "snap__foo___bar_baz")
+}
+
+func (s *backendSuite) TestInstallingSnapWritesAndLoadsModule(c *C) {
+	s.Iface.SELinuxPermanentSlotCallback = func(spec *selinux.Specification, slot *snap.SlotInfo) error {
+		spec.AddSnippet("corenet_tcp_connect_all_ports(###DOMAIN###)")
+		return nil
+	}
+
+	dir := dirs.SnapSELinuxDir
+	te := filepath.Join(dir, "snap__samba.te")
+	fc := filepath.Join(dir, "snap__samba.fc")
+	for _, opts := range testedConfinementOpts {
+		s.makeCmd.ForgetCalls()
+		s.semoduleCmd.ForgetCalls()
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1WithNmbd, 0)
+
+		c.Check(fc, testutil.FileEquals, "")
+		c.Check(te, testutil.FileContains, "policy_module(snap__samba, 1.0.0)\n")
+		for _, tag := range []string{"snap.samba.nmbd", "snap.samba.smbd"} {
+			domain := selinux.DomainType(tag)
+			c.Check(te, testutil.FileContains, "# "+tag+"\n")
+			c.Check(te, testutil.FileContains, "type "+domain+";\n")
+			c.Check(te, testutil.FileContains, "allow snappy_confine_t "+domain+":process { transition noatsecure rlimitinh siginh };\n")
+			c.Check(te, testutil.FileContains, "domain_entry_file("+domain+", snappy_exec_t)\n")
+			c.Check(te, testutil.FileContains, "corenet_tcp_connect_all_ports("+domain+")\n")
+			if opts.Classic {
+				c.Check(te, testutil.FileContains, "unconfined_domain("+domain+")\n")
+			} else if opts.DevMode {
+				c.Check(te, testutil.FileContains, "permissive "+domain+";\n")
+			}
+		}
+		c.Check(te, Not(testutil.FileMatches), `(?s).*###[A-Z_]+###.*`)
+		if !opts.Classic {
+			c.Check(te, Not(testutil.FileContains), "unconfined_domain(")
+		}
+		if !opts.DevMode {
+			c.Check(te, Not(testutil.FileContains), "permissive ")
+		}
+
+		c.Check(s.makeCmd.Calls(), DeepEquals, [][]string{
+			{"make", "-C", dir, "-f", "/usr/share/selinux/devel/Makefile", "snap__samba.pp"},
+		})
+		c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+			{"semodule", "-i", filepath.Join(dir, "snap__samba.pp")},
+		})
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestRemovingSnapUnloadsModule(c *C) {
+	te := filepath.Join(dirs.SnapSELinuxDir, "snap__samba.te")
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		c.Assert(osutil.FileExists(te), Equals, true)
+		s.semoduleCmd.ForgetCalls()
+		s.RemoveSnap(c, snapInfo)
+		c.Check(osutil.FileExists(te), Equals, false)
+		c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{
+			{"semodule", "-r", "snap__samba"},
+		})
+	}
+}
+
+func (s *backendSuite) TestRemovingUnknownSnapDoesNothing(c *C) {
+	c.Assert(s.Backend.Remove("samba"), IsNil)
+	c.Check(s.semoduleCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestSecurityIsStable(c *C) {
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		s.makeCmd.ForgetCalls()
+		s.semoduleCmd.ForgetCalls()
+		err := s.Backend.Setup(snapInfo, opts, s.Repo, s.meas)
+		c.Assert(err, IsNil)
+		// the module is not rebuilt when nothing changes
+		c.Check(s.makeCmd.Calls(), HasLen, 0)
+		c.Check(s.semoduleCmd.Calls(), HasLen, 0)
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestUpdatingSnapReloadsModule(c *C) {
+	te := filepath.Join(dirs.SnapSELinuxDir, "snap__samba.te")
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 1)
+		c.Check(te, Not(testutil.FileContains), "snap__samba__nmbd_t")
+		s.makeCmd.ForgetCalls()
+		s.semoduleCmd.ForgetCalls()
+		snapInfo = s.UpdateSnap(c, snapInfo, opts, ifacetest.SambaYamlV1WithNmbd, 2)
+		c.Check(te, testutil.FileContains, "type snap__samba__nmbd_t;\n")
+		c.Check(s.makeCmd.Calls(), HasLen, 1)
+		c.Check(s.semoduleCmd.Calls(), HasLen, 1)
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestSetupFailureRemovesSources(c *C) {
+	restore := selinux.MockDevelMakefile("/custom/Makefile")
+	defer restore()
+
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 1)
+	c.Check(s.makeCmd.Calls(), DeepEquals, [][]string{
+		{"make", "-C", dirs.SnapSELinuxDir, "-f", "/custom/Makefile", "snap__samba.pp"},
+	})
+
+	s.makeCmd.Restore()
+	s.makeCmd = testutil.MockCommand(c, "make", "echo missing policy tools; exit 1")
+	s.semoduleCmd.ForgetCalls()
+	snapInfo = snaptest.MockInfo(c, ifacetest.SambaYamlV1WithNmbd, &snap.SideInfo{Revision: snap.R(2)})
+	err := s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas)
+	c.Assert(err, ErrorMatches, `(?s)cannot build SELinux policy module "snap__samba": exit status 1.*missing policy tools.*`)
+	c.Check(s.semoduleCmd.Calls(), HasLen, 0)
+	// the sources are removed so that a retry rebuilds the module
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapSELinuxDir, "snap__samba.te")), Equals, false)
+}
+
+func (s *backendSuite) TestSnapWithoutAppsOrHooks(c *C) {
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Assert(s.semoduleCmd.Calls(), HasLen, 1)
+	s.semoduleCmd.ForgetCalls()
+	snapInfo.Apps = nil
+	c.Assert(s.Backend.Setup(snapInfo, interfaces.ConfinementOptions{}, s.Repo, s.meas), IsNil)
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapSELinuxDir, "snap__samba.te")), Equals, false)
+	c.Check(s.semoduleCmd.Calls(), DeepEquals, [][]string{{"semodule", "-r", "snap__samba"}})
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	c.Assert(s.Backend.SandboxFeatures(), DeepEquals, []string{"policy-modules"})
+}
@@ -0,0 +1,79 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package selinux
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+// develMakefile is the makefile provided by the SELinux policy development
+// tools for building policy modules written in the reference policy language.
+var develMakefile = "/usr/share/selinux/devel/Makefile"
+
+var policyNameReplacer = strings.NewReplacer(".", "__", "-", "___")
+
+// policyName maps a security tag to a name that is a valid SELinux
+// identifier.
+//
+// Underscores are kept, dots become two underscores and dashes become three.
+// Neither snap, instance key, application nor hook names may start or end
+// with a dash, therefore the mapping is unambiguous.
+func policyName(securityTag string) string {
+	return policyNameReplacer.Replace(securityTag)
+}
+
+// DomainType returns the SELinux domain of the given security tag.
+func DomainType(securityTag string) string {
+	return policyName(securityTag) + "_t"
+}
+
+// ModuleName returns the name of the SELinux policy module of the given snap.
+func ModuleName(snapName string) string {
+	return policyName(snap.SecurityTag(snapName))
+}
+
+// loadModule builds the policy module with the given name from its sources
+// in the given directory and installs it in the running policy.
+func loadModule(dir, name string) error {
+	output, err := exec.Command("make", "-C", dir, "-f", develMakefile, name+".pp").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot build SELinux policy module %q: %s\nmake output:\n%s", name, err, string(output))
+	}
+	output, err = exec.Command("semodule", "-i", filepath.Join(dir, name+".pp")).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot load SELinux policy module %q: %s\nsemodule output:\n%s", name, err, string(output))
+	}
+	return nil
+}
+
+// unloadModule removes the policy module with the given name from the
+// running policy.
+func unloadModule(name string) error {
+	output, err := exec.Command("semodule", "-r", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cannot unload SELinux policy module %q: %s\nsemodule output:\n%s", name, err, string(output))
+	}
+	return nil
+}
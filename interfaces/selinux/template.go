@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package selinux
+
+// moduleHeader is the beginning of the policy module of each snap.
+//
+// It declares the types provided by the snapd policy which are used by the
+// domains of the applications and hooks of the snap.
+var moduleHeader = `policy_module(###MODULE###, 1.0.0)
+
+# This file is automatically generated.
+
+gen_require(` + "`" + `
+	type snappy_snap_t, snappy_exec_t, snappy_confine_t;
+')
+`
+
+// domainTemplate is the base policy of the domain of each application and
+// hook of a snap.
+//
+// The ###DOMAIN### placeholder is replaced with the name of the domain and
+// ###SNIPPETS### with the policy snippets contributed by interfaces.
+var domainTemplate = `
+########################################
+#
+# ###SECURITY_TAG###
+#
+
+type ###DOMAIN###;
+domain_type(###DOMAIN###)
+domain_entry_file(###DOMAIN###, snappy_snap_t)
+# snap-confine executes snap-exec, which is the one of the host for snaps
+# using classic confinement
+domain_entry_file(###DOMAIN###, snappy_exec_t)
+role system_r types ###DOMAIN###;
+
+# Allow snap-confine to switch to the domain of the application, keeping
+# the environment, the resource limits and the signal state it set up
+allow snappy_confine_t ###DOMAIN###:process { transition noatsecure rlimitinh siginh };
+
+# Allow executing and reading the files of the snap
+can_exec(###DOMAIN###, snappy_snap_t)
+list_dirs_pattern(###DOMAIN###, snappy_snap_t, snappy_snap_t)
+read_files_pattern(###DOMAIN###, snappy_snap_t, snappy_snap_t)
+read_lnk_files_pattern(###DOMAIN###, snappy_snap_t, snappy_snap_t)
+
+# Allow basic process operations
+allow ###DOMAIN### self:process { fork sigchld sigkill sigstop signull signal getsched };
+allow ###DOMAIN### self:fifo_file rw_fifo_file_perms;
+allow ###DOMAIN### self:unix_stream_socket create_stream_socket_perms;
+
+corecmd_exec_bin(###DOMAIN###)
+corecmd_exec_shell(###DOMAIN###)
+files_read_etc_files(###DOMAIN###)
+libs_use_ld_so(###DOMAIN###)
+libs_use_shared_libs(###DOMAIN###)
+miscfiles_read_localization(###DOMAIN###)
+###SNIPPETS###`
+
+// permissiveSnippet makes the domain permissive, denials are logged but not
+// enforced. It is used for snaps in devmode.
+var permissiveSnippet = `
+# The snap is in devmode, only log denials
+permissive ###DOMAIN###;
+`
+
+// classicSnippet makes the domain unconfined. It is used for snaps using
+// classic confinement.
+var classicSnippet = `
+# The snap uses classic confinement
+unconfined_domain(###DOMAIN###)
+`
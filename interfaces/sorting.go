@@ -104,3 +104,28 @@ func (c byInterfaceName) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
 func (c byInterfaceName) Less(i, j int) bool {
 	return c[i].Name() < c[j].Name()
 }
+
+type bySpecificationSource []*SpecificationPart
+
+func (c bySpecificationSource) Len() int      { return len(c) }
+func (c bySpecificationSource) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c bySpecificationSource) Less(i, j int) bool {
+	si, sj := &c[i].Source, &c[j].Source
+	if si.Side != sj.Side {
+		return si.Side < sj.Side
+	}
+	// the own plug or slot sorts before the connected one
+	ownI, otherI := si.Plug.String(), si.Slot.String()
+	ownJ, otherJ := sj.Plug.String(), sj.Slot.String()
+	if si.Side == "slot" {
+		ownI, otherI = otherI, ownI
+		ownJ, otherJ = otherJ, ownJ
+	}
+	if ownI != ownJ {
+		return ownI < ownJ
+	}
+	if si.Connected() != sj.Connected() {
+		return !si.Connected()
+	}
+	return otherI < otherJ
+}
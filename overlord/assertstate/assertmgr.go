@@ -43,6 +43,10 @@ func Manager(s *state.State, runner *state.TaskRunner) (*AssertManager, error) {
 
 	runner.AddHandler("validate-snap", doValidateSnap, nil)
 
+	// hook checking privileged resource controls into snapstate
+	// installation and refresh logic
+	snapstate.AddCheckSnapCallback(checkPrivilegedResourceControls)
+
 	db, err := sysdb.Open()
 	if err != nil {
 		return nil, err
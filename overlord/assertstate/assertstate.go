@@ -24,6 +24,7 @@ package assertstate
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/snapcore/snapd/asserts"
@@ -320,6 +321,42 @@ func delayedCrossMgrInit() {
 	snapstate.AutoAliases = AutoAliases
 }
 
+// checkPrivilegedResourceControls checks that the apps of the snap only
+// use privileged values for their resource controls, like a negative
+// nice value, if its snap-declaration allows it.
+func checkPrivilegedResourceControls(st *state.State, snapInfo, _ *snap.Info, _ snap.Container, _ snapstate.Flags, _ snapstate.DeviceContext) error {
+	if snapInfo.SnapID == "" {
+		// unasserted snaps are installed with --dangerous and get
+		// what they ask for
+		return nil
+	}
+
+	appNames := make([]string, 0, len(snapInfo.Apps))
+	for appName := range snapInfo.Apps {
+		appNames = append(appNames, appName)
+	}
+	sort.Strings(appNames)
+
+	var decl *asserts.SnapDeclaration
+	for _, appName := range appNames {
+		privileged := snapInfo.Apps[appName].PrivilegedResourceControls()
+		if len(privileged) == 0 {
+			continue
+		}
+		if decl == nil {
+			var err error
+			decl, err = SnapDeclaration(st, snapInfo.SnapID)
+			if err != nil {
+				return fmt.Errorf("cannot find snap declaration for %q: %v", snapInfo.InstanceName(), err)
+			}
+		}
+		if !decl.PrivilegedResourceControls() {
+			return fmt.Errorf("cannot use privileged value for %q in app %q of snap %q: not allowed by its snap-declaration", privileged[0], appName, snapInfo.InstanceName())
+		}
+	}
+	return nil
+}
+
 // AutoRefreshAssertions tries to refresh all assertions
 func AutoRefreshAssertions(s *state.State, userID int) error {
 	return RefreshSnapDeclarations(s, userID)
@@ -1407,6 +1407,45 @@ func (s *assertMgrSuite) TestSnapDeclaration(c *C) {
 	c.Check(snapDecl.SnapName(), Equals, "foo")
 }
 
+func (s *assertMgrSuite) TestCheckPrivilegedResourceControls(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	err := assertstate.Add(s.state, s.storeSigning.StoreAccountKey(""))
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, s.dev1Acct)
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, s.snapDecl(c, "foo", nil))
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, s.snapDecl(c, "bar", map[string]interface{}{
+		"privileged-resource-controls": "true",
+	}))
+	c.Assert(err, IsNil)
+
+	const yamlTemplate = `name: %s
+version: 1
+apps:
+  svc:
+    daemon: simple
+    nice: %d
+    oom-score-adjust: 100
+`
+	check := func(name string, nice int, snapID string) error {
+		info := snaptest.MockInfo(c, fmt.Sprintf(yamlTemplate, name, nice), &snap.SideInfo{SnapID: snapID})
+		return assertstate.CheckPrivilegedResourceControls(s.state, info, nil, nil, snapstate.Flags{}, nil)
+	}
+
+	// unprivileged values are always fine
+	c.Check(check("foo", 10, "foo-id"), IsNil)
+	// privileged ones need to be allowed by the snap-declaration
+	c.Check(check("foo", -10, "foo-id"), ErrorMatches, `cannot use privileged value for "nice" in app "svc" of snap "foo": not allowed by its snap-declaration`)
+	c.Check(check("bar", -10, "bar-id"), IsNil)
+	// unasserted snaps get what they ask for
+	c.Check(check("foo", -10, ""), IsNil)
+	// no declaration
+	c.Check(check("baz", -10, "baz-id"), ErrorMatches, `cannot find snap declaration for "baz": .*`)
+}
+
 func (s *assertMgrSuite) TestAutoAliasesTemporaryFallback(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -21,5 +21,6 @@ package assertstate
 
 // expose for testing
 var (
-	DoFetch = doFetch
+	DoFetch                         = doFetch
+	CheckPrivilegedResourceControls = checkPrivilegedResourceControls
 )
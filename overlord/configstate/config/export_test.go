@@ -21,6 +21,7 @@ package config
 
 import (
 	"encoding/json"
+	"time"
 )
 
 var PurgeNulls = purgeNulls
@@ -28,3 +29,19 @@ var PurgeNulls = purgeNulls
 func (t *Transaction) PristineConfig() map[string]map[string]*json.RawMessage {
 	return t.pristine
 }
+
+func MockMaxHistoryEntries(n int) (restore func()) {
+	old := maxHistoryEntries
+	maxHistoryEntries = n
+	return func() {
+		maxHistoryEntries = old
+	}
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() {
+		timeNow = old
+	}
+}
@@ -260,7 +260,7 @@ func DeleteSnapConfig(st *state.State, snapName string) error {
 
 	err := st.Get("config", &config)
 	if err == state.ErrNoState {
-		return nil
+		return deleteHistory(st, snapName)
 	} else if err != nil {
 		return fmt.Errorf("internal error: cannot unmarshal configuration: %v", err)
 	}
@@ -268,7 +268,7 @@ func DeleteSnapConfig(st *state.State, snapName string) error {
 		delete(config, snapName)
 		st.Set("config", config)
 	}
-	return nil
+	return deleteHistory(st, snapName)
 }
 
 // Conf is an interface describing both state and transaction.
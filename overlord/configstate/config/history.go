@@ -0,0 +1,205 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// Origin describes what caused a configuration change.
+type Origin string
+
+const (
+	// OriginSystem is used for changes made internally by snapd.
+	OriginSystem Origin = "system"
+	// OriginUser is used for changes requested via the API, e.g. snap set.
+	OriginUser Origin = "user"
+	// OriginHook is used for changes made by a hook via snapctl.
+	OriginHook Origin = "hook"
+	// OriginGadgetDefault is used for defaults applied from the gadget.
+	OriginGadgetDefault Origin = "gadget-default"
+	// OriginRemodel is used for changes applied while remodeling.
+	OriginRemodel Origin = "remodel"
+)
+
+// KeyChange describes how a single configuration option changed. A nil
+// Old or New value means the option was unset at that point.
+type KeyChange struct {
+	Key string           `json:"key"`
+	Old *json.RawMessage `json:"old,omitempty"`
+	New *json.RawMessage `json:"new,omitempty"`
+}
+
+// HistoryEntry describes a committed configuration change of a snap.
+type HistoryEntry struct {
+	ID      int         `json:"id"`
+	Time    time.Time   `json:"time"`
+	Origin  Origin      `json:"origin"`
+	Changes []KeyChange `json:"changes"`
+}
+
+// maxHistoryEntries is the number of configuration changes remembered
+// for every snap, older entries are dropped.
+var maxHistoryEntries = 20
+
+var timeNow = time.Now
+
+func getHistory(st *state.State) (map[string][]*HistoryEntry, error) {
+	var history map[string][]*HistoryEntry // snap => entries
+	err := st.Get("config-history", &history)
+	if err == state.ErrNoState {
+		return make(map[string][]*HistoryEntry), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot unmarshal configuration history: %v", err)
+	}
+	return history, nil
+}
+
+// History returns the remembered configuration changes of the given
+// snap, oldest first.
+//
+// The caller is responsible for locking the state.
+func History(st *state.State, snapName string) ([]*HistoryEntry, error) {
+	history, err := getHistory(st)
+	if err != nil {
+		return nil, err
+	}
+	return history[snapName], nil
+}
+
+func addHistoryEntry(st *state.State, snapName string, entry *HistoryEntry) error {
+	history, err := getHistory(st)
+	if err != nil {
+		return err
+	}
+	entries := history[snapName]
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	entries = append(entries, entry)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+	history[snapName] = entries
+	st.Set("config-history", history)
+	return nil
+}
+
+func deleteHistory(st *state.State, snapName string) error {
+	history, err := getHistory(st)
+	if err != nil {
+		return err
+	}
+	if _, ok := history[snapName]; ok {
+		delete(history, snapName)
+		st.Set("config-history", history)
+	}
+	return nil
+}
+
+// valueAt returns the raw value of the given key in the configuration of
+// a snap or nil if it's not set.
+func valueAt(snapName string, subkeys []string, config map[string]*json.RawMessage) *json.RawMessage {
+	var raw json.RawMessage
+	if err := getFromConfig(snapName, subkeys, 0, config, &raw); err != nil {
+		return nil
+	}
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	return &raw
+}
+
+func sameValue(a, b *json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	var av, bv interface{}
+	if err := jsonutil.DecodeWithNumber(bytes.NewReader(*a), &av); err != nil {
+		return false
+	}
+	if err := jsonutil.DecodeWithNumber(bytes.NewReader(*b), &bv); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// RollbackPatch returns the configuration patch that brings the
+// configuration of the given snap back to how it was right after the
+// change with the given history id. Rolling back to the id preceding the
+// oldest remembered change is allowed as well.
+//
+// The caller is responsible for locking the state.
+func RollbackPatch(st *state.State, snapName string, id int) (map[string]interface{}, error) {
+	entries, err := History(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("snap %q has no configuration history", snapName)
+	}
+	first, last := entries[0].ID, entries[len(entries)-1].ID
+	if id == last {
+		return nil, fmt.Errorf("configuration of snap %q is already at change %d", snapName, id)
+	}
+	if id < first-1 || id > last {
+		return nil, fmt.Errorf("cannot roll back configuration of snap %q to change %d: available changes are %d to %d", snapName, id, first-1, last-1)
+	}
+
+	// undo the changes one at a time, newest first, on a throwaway
+	// transaction; it's never committed
+	tr := NewTransaction(st)
+	for i := len(entries) - 1; i >= 0 && entries[i].ID > id; i-- {
+		for _, change := range entries[i].Changes {
+			if err := tr.Set(snapName, change.Key, change.Old); err != nil {
+				return nil, fmt.Errorf("cannot roll back configuration of snap %q: %v", snapName, err)
+			}
+		}
+	}
+
+	var target map[string]*json.RawMessage
+	if err := tr.Get(snapName, "", &target); err != nil && !IsNoOption(err) {
+		return nil, err
+	}
+	current := tr.copyPristine(snapName)
+	purgeNulls(current)
+
+	patch := make(map[string]interface{})
+	for key, value := range current {
+		if _, ok := target[key]; !ok && value != nil {
+			patch[key] = nil
+		}
+	}
+	for key, value := range target {
+		if !sameValue(current[key], value) {
+			patch[key] = value
+		}
+	}
+	return patch, nil
+}
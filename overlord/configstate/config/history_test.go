@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package config_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type historySuite struct {
+	state *state.State
+	now   time.Time
+}
+
+var _ = Suite(&historySuite{})
+
+func (s *historySuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+	s.now = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func (s *historySuite) set(c *C, origin config.Origin, kv ...interface{}) {
+	tr := config.NewTransaction(s.state)
+	tr.SetOrigin(origin)
+	for i := 0; i < len(kv); i += 2 {
+		c.Assert(tr.Set("test-snap", kv[i].(string), kv[i+1]), IsNil)
+	}
+	tr.Commit()
+}
+
+func raw(s string) *json.RawMessage {
+	r := json.RawMessage(s)
+	return &r
+}
+
+func (s *historySuite) TestHistoryRecorded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	restore := config.MockTimeNow(func() time.Time { return s.now })
+	defer restore()
+
+	s.set(c, config.OriginUser, "foo", "bar", "a.b", 1)
+	s.set(c, config.OriginHook, "foo", "baz", "a.b", 1)
+	// no actual change, nothing recorded
+	s.set(c, config.OriginHook, "foo", "baz")
+	s.set(c, config.OriginSystem, "foo", nil)
+
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, DeepEquals, []*config.HistoryEntry{{
+		ID:     1,
+		Time:   s.now,
+		Origin: config.OriginUser,
+		Changes: []config.KeyChange{
+			{Key: "foo", New: raw(`"bar"`)},
+			{Key: "a.b", New: raw(`1`)},
+		},
+	}, {
+		ID:      2,
+		Time:    s.now,
+		Origin:  config.OriginHook,
+		Changes: []config.KeyChange{{Key: "foo", Old: raw(`"bar"`), New: raw(`"baz"`)}},
+	}, {
+		ID:      3,
+		Time:    s.now,
+		Origin:  config.OriginSystem,
+		Changes: []config.KeyChange{{Key: "foo", Old: raw(`"baz"`)}},
+	}})
+
+	history, err = config.History(s.state, "other-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
+
+func (s *historySuite) TestHistoryBounded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	restore := config.MockMaxHistoryEntries(3)
+	defer restore()
+
+	for i := 1; i <= 5; i++ {
+		s.set(c, config.OriginUser, "foo", i)
+	}
+
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	c.Check(history[0].ID, Equals, 3)
+	c.Check(history[2].ID, Equals, 5)
+	c.Check(history[2].Changes, DeepEquals, []config.KeyChange{{Key: "foo", Old: raw(`4`), New: raw(`5`)}})
+}
+
+func (s *historySuite) TestHistoryDeletedWithConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.set(c, config.OriginUser, "foo", "bar")
+	c.Assert(config.DeleteSnapConfig(s.state, "test-snap"), IsNil)
+
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
+
+func (s *historySuite) TestRollbackPatch(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.set(c, config.OriginUser, "foo", "bar")                  // 1
+	s.set(c, config.OriginUser, "a.b", 1, "a.c", 2)            // 2
+	s.set(c, config.OriginHook, "a", map[string]interface{}{}) // 3
+	s.set(c, config.OriginUser, "foo", nil, "baz", true)       // 4
+
+	for _, t := range []struct {
+		id    int
+		patch map[string]interface{}
+	}{
+		{0, map[string]interface{}{"baz": nil}},
+		{1, map[string]interface{}{"foo": raw(`"bar"`), "baz": nil}},
+		{2, map[string]interface{}{"foo": raw(`"bar"`), "baz": nil, "a": raw(`{"b":1,"c":2}`)}},
+		{3, map[string]interface{}{"foo": raw(`"bar"`), "baz": nil}},
+	} {
+		patch, err := config.RollbackPatch(s.state, "test-snap", t.id)
+		c.Assert(err, IsNil)
+		c.Check(patch, HasLen, len(t.patch), Commentf("%d", t.id))
+		for k, v := range t.patch {
+			if v == nil {
+				c.Check(patch[k], IsNil, Commentf("%d: %s", t.id, k))
+				continue
+			}
+			data, err := json.Marshal(patch[k])
+			c.Assert(err, IsNil)
+			c.Check(string(data), Equals, string(*v.(*json.RawMessage)), Commentf("%d: %s", t.id, k))
+		}
+	}
+}
+
+func (s *historySuite) TestRollbackPatchErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := config.RollbackPatch(s.state, "test-snap", 1)
+	c.Check(err, ErrorMatches, `snap "test-snap" has no configuration history`)
+
+	s.set(c, config.OriginUser, "foo", 1)
+	s.set(c, config.OriginUser, "foo", 2)
+
+	_, err = config.RollbackPatch(s.state, "test-snap", 2)
+	c.Check(err, ErrorMatches, `configuration of snap "test-snap" is already at change 2`)
+	_, err = config.RollbackPatch(s.state, "test-snap", 3)
+	c.Check(err, ErrorMatches, `cannot roll back configuration of snap "test-snap" to change 3: available changes are 0 to 1`)
+	_, err = config.RollbackPatch(s.state, "test-snap", -1)
+	c.Check(err, ErrorMatches, `cannot roll back configuration of snap "test-snap" to change -1: available changes are 0 to 1`)
+}
@@ -42,14 +42,17 @@ type Transaction struct {
 	state    *state.State
 	pristine map[string]map[string]*json.RawMessage // snap => key => value
 	changes  map[string]map[string]interface{}
+	keys     map[string][]string // snap => keys set, in order
+	origin   Origin
 }
 
 // NewTransaction creates a new configuration transaction initialized with the given state.
 //
 // The provided state must be locked by the caller.
 func NewTransaction(st *state.State) *Transaction {
-	transaction := &Transaction{state: st}
+	transaction := &Transaction{state: st, origin: OriginSystem}
 	transaction.changes = make(map[string]map[string]interface{})
+	transaction.keys = make(map[string][]string)
 
 	// Record the current state of the map containing the config of every snap
 	// in the system. We'll use it for this transaction.
@@ -67,6 +70,14 @@ func (t *Transaction) State() *state.State {
 	return t.state
 }
 
+// SetOrigin sets the origin recorded in the configuration history for the
+// changes committed by the transaction.
+func (t *Transaction) SetOrigin(origin Origin) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.origin = origin
+}
+
 func changes(cfgStr string, cfg map[string]interface{}) []string {
 	var out []string
 	for k := range cfg {
@@ -146,9 +157,19 @@ func (t *Transaction) Set(instanceName, key string, value interface{}) error {
 	}
 
 	t.changes[instanceName] = config
+	t.recordKey(instanceName, strings.Join(subkeys, "."))
 	return nil
 }
 
+func (t *Transaction) recordKey(instanceName, key string) {
+	for _, k := range t.keys[instanceName] {
+		if k == key {
+			return
+		}
+	}
+	t.keys[instanceName] = append(t.keys[instanceName], key)
+}
+
 func (t *Transaction) copyPristine(snapName string) map[string]*json.RawMessage {
 	out := make(map[string]*json.RawMessage)
 	if config, ok := t.pristine[snapName]; ok {
@@ -260,15 +281,38 @@ func (t *Transaction) Commit() {
 		if !ok {
 			config = make(map[string]*json.RawMessage)
 		}
+		keys := t.keys[instanceName]
+		old := make([]*json.RawMessage, len(keys))
+		for i, key := range keys {
+			subkeys, _ := ParseKey(key)
+			old[i] = valueAt(instanceName, subkeys, config)
+		}
+
 		applyChanges(config, snapChanges)
 		purgeNulls(config)
 		t.pristine[instanceName] = config
+
+		var keyChanges []KeyChange
+		for i, key := range keys {
+			subkeys, _ := ParseKey(key)
+			cur := valueAt(instanceName, subkeys, config)
+			if !sameValue(old[i], cur) {
+				keyChanges = append(keyChanges, KeyChange{Key: key, Old: old[i], New: cur})
+			}
+		}
+		if len(keyChanges) > 0 {
+			entry := &HistoryEntry{Time: timeNow(), Origin: t.origin, Changes: keyChanges}
+			if err := addHistoryEntry(t.state, instanceName, entry); err != nil {
+				panic(err)
+			}
+		}
 	}
 
 	t.state.Set("config", t.pristine)
 
 	// The cache has been flushed, reset it.
 	t.changes = make(map[string]map[string]interface{})
+	t.keys = make(map[string][]string)
 }
 
 func applyChanges(config map[string]*json.RawMessage, changes map[string]interface{}) {
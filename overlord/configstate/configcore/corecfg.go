@@ -22,6 +22,7 @@ package configcore
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/release"
@@ -48,6 +49,23 @@ func coreCfg(tr config.Conf, key string) (result string, err error) {
 // The actual values are populated by `init()` functions in each module.
 var supportedConfigurations = make(map[string]bool, 32)
 
+// supportedConfigurationPrefixes contains handled configuration keys
+// whose sub-keys are free-form, e.g. the names of kernel parameters.
+// The actual values are populated by `init()` functions in each module.
+var supportedConfigurationPrefixes []string
+
+func isSupportedConfiguration(key string) bool {
+	if supportedConfigurations[key] {
+		return true
+	}
+	for _, prefix := range supportedConfigurationPrefixes {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
 func validateBoolFlag(tr config.Conf, flag string) error {
 	value, err := coreCfg(tr, flag)
 	if err != nil {
@@ -65,7 +83,7 @@ func validateBoolFlag(tr config.Conf, flag string) error {
 func Run(tr config.Conf) error {
 	// check if the changes
 	for _, k := range tr.Changes() {
-		if !supportedConfigurations[k] {
+		if !isSupportedConfiguration(k) {
 			return fmt.Errorf("cannot set %q: unsupported system option", k)
 		}
 	}
@@ -91,6 +109,24 @@ func Run(tr config.Conf) error {
 	if err := validateAutomaticSnapshotsExpiration(tr); err != nil {
 		return err
 	}
+	if err := validateTimezone(tr); err != nil {
+		return err
+	}
+	if err := validateHostname(tr); err != nil {
+		return err
+	}
+	if err := validateTimeservers(tr); err != nil {
+		return err
+	}
+	if err := validateJournalSettings(tr); err != nil {
+		return err
+	}
+	if err := validateSysctls(tr); err != nil {
+		return err
+	}
+	if err := validateSwapSettings(tr); err != nil {
+		return err
+	}
 	// FIXME: ensure the user cannot set "core seed.loaded"
 
 	// capture cloud information
@@ -136,6 +172,30 @@ func Run(tr config.Conf) error {
 	if err := handleNetworkConfiguration(tr); err != nil {
 		return err
 	}
+	// system.timezone
+	if err := handleTimezoneConfiguration(tr); err != nil {
+		return err
+	}
+	// system.hostname
+	if err := handleHostnameConfiguration(tr); err != nil {
+		return err
+	}
+	// system.timeserver
+	if err := handleTimeserverConfiguration(tr); err != nil {
+		return err
+	}
+	// journal.{persistent,max-size}
+	if err := handleJournalConfiguration(tr); err != nil {
+		return err
+	}
+	// system.kernel.sysctl.*
+	if err := handleSysctlConfiguration(tr); err != nil {
+		return err
+	}
+	// swap.size
+	if err := handleSwapConfiguration(tr); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -19,9 +19,26 @@
 
 package configcore
 
+import (
+	"os"
+	"syscall"
+)
+
 var (
 	UpdatePiConfig       = updatePiConfig
 	SwitchHandlePowerKey = switchHandlePowerKey
 	SwitchDisableService = switchDisableService
 	UpdateKeyValueStream = updateKeyValueStream
 )
+
+func MockOsMkdirAll(f func(string, os.FileMode) error) (restore func()) {
+	old := osMkdirAll
+	osMkdirAll = f
+	return func() { osMkdirAll = old }
+}
+
+func MockSyscallStatfs(f func(string, *syscall.Statfs_t) error) (restore func()) {
+	old := syscallStatfs
+	syscallStatfs = f
+	return func() { syscallStatfs = old }
+}
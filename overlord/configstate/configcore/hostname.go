@@ -0,0 +1,90 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.system.hostname"] = true
+}
+
+// validHostname matches host names as per RFC 1123, dot separated labels
+// of letters, digits and hyphens that don't start or end with a hyphen.
+var validHostname = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// maxHostnameLength is HOST_NAME_MAX on Linux.
+const maxHostnameLength = 64
+
+func validateHostname(tr config.Conf) error {
+	hostname, err := coreCfg(tr, "system.hostname")
+	if err != nil {
+		return err
+	}
+	if hostname == "" {
+		return nil
+	}
+	if len(hostname) > maxHostnameLength || !validHostname.MatchString(hostname) {
+		return fmt.Errorf("cannot set hostname %q: name is not valid", hostname)
+	}
+	return nil
+}
+
+// handleHostnameConfiguration writes the configured hostname to the
+// writable /etc/hostname of Ubuntu Core and applies it right away.
+func handleHostnameConfiguration(tr config.Conf) error {
+	hostname, err := coreCfg(tr, "system.hostname")
+	if err != nil {
+		return err
+	}
+	if hostname == "" {
+		// leave whatever the image came with alone
+		return nil
+	}
+
+	hostnameFile := filepath.Join(dirs.GlobalRootDir, "/etc/writable/hostname")
+	content := []byte(hostname + "\n")
+	if current, err := ioutil.ReadFile(hostnameFile); err == nil && bytes.Equal(current, content) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(hostnameFile), 0755); err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(hostnameFile, content, 0644, 0); err != nil {
+		return err
+	}
+
+	if output, err := exec.Command("hostname", hostname).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot set hostname %q: %v", hostname, osutil.OutputErr(output, err))
+	}
+	return nil
+}
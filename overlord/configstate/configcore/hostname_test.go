@@ -0,0 +1,141 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type hostnameSuite struct {
+	configcoreSuite
+
+	mockHostname *testutil.MockCmd
+}
+
+var _ = Suite(&hostnameSuite{})
+
+func (s *hostnameSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+	s.mockHostname = testutil.MockCommand(c, "hostname", "")
+
+	c.Assert(os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/etc/environment"), nil, 0644), IsNil)
+}
+
+func (s *hostnameSuite) TearDownTest(c *C) {
+	s.mockHostname.Restore()
+	s.configcoreSuite.TearDownTest(c)
+}
+
+func (s *hostnameSuite) TestConfigureHostname(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.hostname": "my-device.example.com",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(dirs.GlobalRootDir, "/etc/writable/hostname"), testutil.FileEquals, "my-device.example.com\n")
+	c.Check(s.mockHostname.Calls(), DeepEquals, [][]string{{"hostname", "my-device.example.com"}})
+
+	// setting it again is a noop
+	s.mockHostname.ForgetCalls()
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.hostname": "my-device.example.com",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.mockHostname.Calls(), HasLen, 0)
+}
+
+func (s *hostnameSuite) TestConfigureHostnameUnsetIsNoop(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{state: s.state})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(dirs.GlobalRootDir, "/etc/writable/hostname"), testutil.FileAbsent)
+	c.Check(s.mockHostname.Calls(), HasLen, 0)
+}
+
+func (s *hostnameSuite) TestConfigureHostnameOnClassicOnlyValidates(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.hostname": "my-device",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.mockHostname.Calls(), HasLen, 0)
+}
+
+func (s *hostnameSuite) TestConfigureHostnameFails(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	mockHostname := testutil.MockCommand(c, "hostname", "echo 'permission denied'; exit 1")
+	defer mockHostname.Restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.hostname": "my-device",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set hostname "my-device": permission denied`)
+}
+
+func (s *hostnameSuite) TestConfigureHostnameInvalid(c *C) {
+	for _, name := range []string{
+		"-foo",
+		"foo-",
+		"foo_bar",
+		"foo..bar",
+		"foo bar",
+		strings.Repeat("a", 64) + ".com",
+		strings.Repeat("a", 65),
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.hostname": name,
+			},
+		})
+		c.Check(err, ErrorMatches, `cannot set hostname ".*": name is not valid`, Commentf(name))
+	}
+}
@@ -0,0 +1,181 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.journal.persistent"] = true
+	supportedConfigurations["core.journal.max-size"] = true
+}
+
+// validJournalSize matches the sizes journald understands, in bytes or
+// with a K, M, G or T suffix (base 1024).
+var validJournalSize = regexp.MustCompile(`^[1-9][0-9]*[KMGT]?$`)
+
+var osMkdirAll = os.MkdirAll
+
+func validateJournalSettings(tr config.Conf) error {
+	if err := validateBoolFlag(tr, "journal.persistent"); err != nil {
+		return err
+	}
+	maxSize, err := coreCfg(tr, "journal.max-size")
+	if err != nil {
+		return err
+	}
+	if maxSize != "" && !validJournalSize.MatchString(maxSize) {
+		return fmt.Errorf("journal.max-size must be a size in bytes, optionally with a K, M, G or T suffix, got %q", maxSize)
+	}
+	return nil
+}
+
+func journalDir() string {
+	return filepath.Join(dirs.GlobalRootDir, "/var/log/journal")
+}
+
+// journalMarker is created in /var/log/journal when snapd creates it, so
+// that snapd only ever removes a journal directory it made.
+func journalMarker() string {
+	return filepath.Join(journalDir(), ".snapd-created")
+}
+
+func isReadOnly(err error) bool {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err == syscall.EROFS
+	}
+	return false
+}
+
+// setJournalPersistent makes journald keep the logs across reboots by
+// creating /var/log/journal, or go back to a volatile journal by removing
+// it. It returns whether anything changed.
+func setJournalPersistent(persistent bool) (changed bool, err error) {
+	dir := journalDir()
+	if persistent {
+		if osutil.IsDirectory(dir) {
+			return false, nil
+		}
+		if err := osMkdirAll(dir, 0755); err != nil {
+			if isReadOnly(err) {
+				return false, fmt.Errorf("cannot enable persistent journal: %s is on a read-only filesystem", filepath.Dir(dir))
+			}
+			return false, fmt.Errorf("cannot enable persistent journal: %v", err)
+		}
+		if err := ioutil.WriteFile(journalMarker(), nil, 0644); err != nil {
+			return false, err
+		}
+		// apply the ownership and permissions journald expects
+		if output, err := exec.Command("systemd-tmpfiles", "--create", "--prefix", dir).CombinedOutput(); err != nil {
+			return false, fmt.Errorf("cannot enable persistent journal: %v", osutil.OutputErr(output, err))
+		}
+		return true, nil
+	}
+
+	if !osutil.IsDirectory(dir) {
+		return false, nil
+	}
+	if !osutil.FileExists(journalMarker()) {
+		return false, fmt.Errorf("cannot disable persistent journal: %s was not created by snapd", dir)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// setJournalMaxSize limits the disk space used by the journal with a
+// journald drop-in. It returns whether anything changed.
+func setJournalMaxSize(maxSize string) (changed bool, err error) {
+	dir := filepath.Join(dirs.GlobalRootDir, "/etc/systemd/journald.conf.d")
+	name := "00-snap-core.conf"
+	dirContent := make(map[string]osutil.FileState, 1)
+	if maxSize != "" {
+		dirContent[name] = &osutil.MemoryFileState{
+			Content: []byte(fmt.Sprintf("[Journal]\nSystemMaxUse=%s\n", maxSize)),
+			Mode:    0644,
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return false, err
+		}
+	}
+
+	glob := name
+	written, removed, err := osutil.EnsureDirState(dir, glob, dirContent)
+	if err != nil {
+		return false, err
+	}
+	return len(written) > 0 || len(removed) > 0, nil
+}
+
+func handleJournalConfiguration(tr config.Conf) error {
+	persistent, err := coreCfg(tr, "journal.persistent")
+	if err != nil {
+		return err
+	}
+	maxSize, err := coreCfg(tr, "journal.max-size")
+	if err != nil {
+		return err
+	}
+
+	var persistentChanged bool
+	switch persistent {
+	case "true", "false":
+		persistentChanged, err = setJournalPersistent(persistent == "true")
+		if err != nil {
+			return err
+		}
+	case "":
+		// leave the journal as the image set it up
+	default:
+		return fmt.Errorf("unsupported journal.persistent option: %q", persistent)
+	}
+
+	maxSizeChanged, err := setJournalMaxSize(maxSize)
+	if err != nil {
+		return err
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, &sysdLogger{})
+	switch {
+	case maxSizeChanged || (persistentChanged && persistent == "false"):
+		// journald only reads its configuration on startup, and
+		// keeps writing to the removed journal files until then
+		return sysd.Restart("systemd-journald.service", 5*time.Minute)
+	case persistentChanged:
+		// ask journald to flush the volatile journal to disk
+		return sysd.Kill("systemd-journald.service", "USR1", "main")
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type journalSuite struct {
+	configcoreSuite
+
+	mockTmpfiles *testutil.MockCmd
+	journalDir   string
+	dropIn       string
+}
+
+var _ = Suite(&journalSuite{})
+
+func (s *journalSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+	s.systemctlArgs = nil
+	s.mockTmpfiles = testutil.MockCommand(c, "systemd-tmpfiles", "")
+
+	c.Assert(os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/etc/environment"), nil, 0644), IsNil)
+	s.journalDir = filepath.Join(dirs.GlobalRootDir, "/var/log/journal")
+	s.dropIn = filepath.Join(dirs.GlobalRootDir, "/etc/systemd/journald.conf.d/00-snap-core.conf")
+}
+
+func (s *journalSuite) TearDownTest(c *C) {
+	s.mockTmpfiles.Restore()
+	s.configcoreSuite.TearDownTest(c)
+}
+
+func (s *journalSuite) run(conf map[string]interface{}) error {
+	return configcore.Run(&mockConf{state: s.state, conf: conf})
+}
+
+func (s *journalSuite) TestConfigurePersistentJournal(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := s.run(map[string]interface{}{"journal.persistent": "true"})
+	c.Assert(err, IsNil)
+	c.Check(osutil.IsDirectory(s.journalDir), Equals, true)
+	c.Check(filepath.Join(s.journalDir, ".snapd-created"), testutil.FilePresent)
+	c.Check(s.mockTmpfiles.Calls(), DeepEquals, [][]string{
+		{"systemd-tmpfiles", "--create", "--prefix", s.journalDir},
+	})
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"kill", "systemd-journald.service", "-s", "USR1", "--kill-who=main"},
+	})
+
+	// nothing to do the second time around
+	s.systemctlArgs = nil
+	err = s.run(map[string]interface{}{"journal.persistent": "true"})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+
+	err = s.run(map[string]interface{}{"journal.persistent": "false"})
+	c.Assert(err, IsNil)
+	c.Check(s.journalDir, testutil.FileAbsent)
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"stop", "systemd-journald.service"},
+		{"show", "--property=ActiveState", "systemd-journald.service"},
+		{"start", "systemd-journald.service"},
+	})
+}
+
+func (s *journalSuite) TestConfigurePersistentJournalNotCreatedBySnapd(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	c.Assert(os.MkdirAll(s.journalDir, 0755), IsNil)
+
+	// already persistent
+	err := s.run(map[string]interface{}{"journal.persistent": "true"})
+	c.Assert(err, IsNil)
+	c.Check(s.mockTmpfiles.Calls(), HasLen, 0)
+	c.Check(s.systemctlArgs, HasLen, 0)
+
+	err = s.run(map[string]interface{}{"journal.persistent": "false"})
+	c.Check(err, ErrorMatches, `cannot disable persistent journal: .*/var/log/journal was not created by snapd`)
+	c.Check(osutil.IsDirectory(s.journalDir), Equals, true)
+}
+
+func (s *journalSuite) TestConfigurePersistentJournalReadOnly(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	restore = configcore.MockOsMkdirAll(func(path string, perm os.FileMode) error {
+		return &os.PathError{Op: "mkdir", Path: path, Err: syscall.EROFS}
+	})
+	defer restore()
+
+	err := s.run(map[string]interface{}{"journal.persistent": "true"})
+	c.Check(err, ErrorMatches, `cannot enable persistent journal: .*/var/log is on a read-only filesystem`)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *journalSuite) TestConfigureJournalMaxSize(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := s.run(map[string]interface{}{"journal.max-size": "100M"})
+	c.Assert(err, IsNil)
+	c.Check(s.dropIn, testutil.FileEquals, "[Journal]\nSystemMaxUse=100M\n")
+	c.Check(s.systemctlArgs, HasLen, 3)
+	c.Check(s.systemctlArgs[2], DeepEquals, []string{"start", "systemd-journald.service"})
+
+	s.systemctlArgs = nil
+	err = s.run(map[string]interface{}{"journal.max-size": "100M"})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+
+	err = s.run(nil)
+	c.Assert(err, IsNil)
+	c.Check(s.dropIn, testutil.FileAbsent)
+	c.Check(s.systemctlArgs, HasLen, 3)
+}
+
+func (s *journalSuite) TestConfigureJournalInvalid(c *C) {
+	for _, t := range []struct {
+		conf map[string]interface{}
+		err  string
+	}{
+		{map[string]interface{}{"journal.persistent": "yes"}, `journal.persistent can only be set to 'true' or 'false'`},
+		{map[string]interface{}{"journal.max-size": "100MB"}, `journal.max-size must be a size in bytes, optionally with a K, M, G or T suffix, got "100MB"`},
+		{map[string]interface{}{"journal.max-size": "-1"}, `journal.max-size must be .*`},
+		{map[string]interface{}{"journal.max-size": "0"}, `journal.max-size must be .*`},
+	} {
+		c.Check(s.run(t.conf), ErrorMatches, t.err)
+	}
+}
@@ -0,0 +1,181 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurationPrefixes = append(supportedConfigurationPrefixes, "core.system.kernel.sysctl")
+}
+
+// validSysctlName matches the names of the kernel parameters, which are made
+// of at least two dot-separated segments.
+var validSysctlName = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)+$`)
+
+// sysctls lists the kernel parameters set via system.kernel.sysctl.<key>,
+// keyed by their sysctl name. Option names cannot contain underscores, so
+// hyphens in them stand for the underscores of the sysctl names, e.g.
+// system.kernel.sysctl.net.core.rmem-max sets net.core.rmem_max.
+func sysctls(tr config.Conf) (map[string]string, error) {
+	var value map[string]interface{}
+	if err := tr.Get("core", "system.kernel.sysctl", &value); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+
+	params := make(map[string]string)
+	var flatten func(prefix string, m map[string]interface{}) error
+	flatten = func(prefix string, m map[string]interface{}) error {
+		for k, v := range m {
+			name := k
+			if prefix != "" {
+				name = prefix + "." + k
+			}
+			switch v := v.(type) {
+			case nil:
+				// unset
+			case map[string]interface{}:
+				if err := flatten(name, v); err != nil {
+					return err
+				}
+			default:
+				s := fmt.Sprintf("%v", v)
+				if strings.ContainsAny(s, "\n\r") {
+					return fmt.Errorf("cannot set kernel parameter %q: value cannot span multiple lines", name)
+				}
+				params[strings.Replace(name, "-", "_", -1)] = s
+			}
+		}
+		return nil
+	}
+	if err := flatten("", value); err != nil {
+		return nil, err
+	}
+
+	for name := range params {
+		if !validSysctlName.MatchString(name) {
+			return nil, fmt.Errorf("cannot set kernel parameter %q: not a valid sysctl name", name)
+		}
+		if name == "net.ipv6.conf.all.disable_ipv6" {
+			return nil, fmt.Errorf("cannot set kernel parameter %q: use network.disable-ipv6 instead", name)
+		}
+	}
+	return params, nil
+}
+
+func validateSysctls(tr config.Conf) error {
+	_, err := sysctls(tr)
+	return err
+}
+
+// sysctlsInDropIn returns the names of the kernel parameters set by the
+// given sysctl.d file.
+func sysctlsInDropIn(path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			names = append(names, strings.TrimSpace(kv[0]))
+		}
+	}
+	return names, nil
+}
+
+func handleSysctlConfiguration(tr config.Conf) error {
+	params, err := sysctls(tr)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(dirs.GlobalRootDir, "/etc/sysctl.d")
+	name := "99-snapd-sysctl.conf"
+	dirContent := make(map[string]osutil.FileState, 1)
+
+	previous, err := sysctlsInDropIn(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	var unset []string
+	for _, k := range previous {
+		if _, ok := params[k]; !ok {
+			unset = append(unset, k)
+		}
+	}
+
+	lines := make([]string, 0, len(params))
+	for k, v := range params {
+		lines = append(lines, fmt.Sprintf("%s = %s\n", k, v))
+	}
+	if len(lines) > 0 {
+		// We order the parameters to have predictable output
+		sort.Strings(lines)
+		dirContent[name] = &osutil.MemoryFileState{
+			Content: []byte(strings.Join(lines, "")),
+			Mode:    0644,
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	glob := name
+	changed, _, err := osutil.EnsureDirState(dir, glob, dirContent)
+	if err != nil {
+		return err
+	}
+
+	// load the new parameters into the kernel
+	if len(changed) > 0 {
+		output, err := exec.Command("sysctl", "-p", filepath.Join(dir, name)).CombinedOutput()
+		if err != nil {
+			return osutil.OutputErr(output, err)
+		}
+	}
+
+	// the kernel keeps the current value of parameters that were
+	// unset, they only go back to their defaults on the next boot
+	if len(unset) > 0 {
+		st := tr.State()
+		st.Lock()
+		defer st.Unlock()
+		st.Warnf("the kernel parameters %s were unset, reboot for the change to take effect", strings.Join(unset, ", "))
+	}
+
+	return nil
+}
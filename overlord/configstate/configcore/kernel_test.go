@@ -0,0 +1,200 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type kernelSuite struct {
+	configcoreSuite
+
+	mockSysctl *testutil.MockCmd
+	dropIn     string
+}
+
+var _ = Suite(&kernelSuite{})
+
+func (s *kernelSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+	s.mockSysctl = testutil.MockCommand(c, "sysctl", "")
+
+	c.Assert(os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/etc/environment"), nil, 0644), IsNil)
+	s.dropIn = filepath.Join(dirs.GlobalRootDir, "/etc/sysctl.d/99-snapd-sysctl.conf")
+}
+
+func (s *kernelSuite) TearDownTest(c *C) {
+	s.mockSysctl.Restore()
+	s.configcoreSuite.TearDownTest(c)
+}
+
+func (s *kernelSuite) TestConfigureSysctl(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.kernel.sysctl": map[string]interface{}{
+				"vm": map[string]interface{}{
+					"swappiness": json.Number("10"),
+				},
+				"net": map[string]interface{}{
+					"core": map[string]interface{}{
+						"rmem-max": json.Number("26214400"),
+						"wmem-max": nil,
+					},
+				},
+			},
+		},
+		changes: map[string]interface{}{
+			"system.kernel.sysctl.vm.swappiness": json.Number("10"),
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.dropIn, testutil.FileEquals, "net.core.rmem_max = 26214400\nvm.swappiness = 10\n")
+	c.Check(s.mockSysctl.Calls(), DeepEquals, [][]string{{"sysctl", "-p", s.dropIn}})
+
+	// nothing changed
+	s.mockSysctl.ForgetCalls()
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.kernel.sysctl": map[string]interface{}{
+				"net": map[string]interface{}{"core": map[string]interface{}{"rmem-max": json.Number("26214400")}},
+				"vm":  map[string]interface{}{"swappiness": json.Number("10")},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.mockSysctl.Calls(), HasLen, 0)
+
+	s.state.Lock()
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+	s.state.Unlock()
+}
+
+func (s *kernelSuite) TestConfigureSysctlUnset(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.kernel.sysctl": map[string]interface{}{
+				"net": map[string]interface{}{"core": map[string]interface{}{"rmem-max": json.Number("26214400")}},
+				"vm":  map[string]interface{}{"swappiness": json.Number("10")},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	// unset one parameter, the others are reloaded
+	s.mockSysctl.ForgetCalls()
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.kernel.sysctl": map[string]interface{}{
+				"vm": map[string]interface{}{"swappiness": json.Number("10")},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.dropIn, testutil.FileEquals, "vm.swappiness = 10\n")
+	c.Check(s.mockSysctl.Calls(), DeepEquals, [][]string{{"sysctl", "-p", s.dropIn}})
+
+	s.state.Lock()
+	warnings := s.state.AllWarnings()
+	s.state.Unlock()
+	c.Assert(warnings, HasLen, 1)
+	c.Check(warnings[0].String(), Equals, "the kernel parameters net.core.rmem_max were unset, reboot for the change to take effect")
+
+	// unset everything
+	s.mockSysctl.ForgetCalls()
+	err = configcore.Run(&mockConf{state: s.state})
+	c.Assert(err, IsNil)
+	c.Check(s.dropIn, testutil.FileAbsent)
+	c.Check(s.mockSysctl.Calls(), HasLen, 0)
+
+	s.state.Lock()
+	warnings = s.state.AllWarnings()
+	s.state.Unlock()
+	c.Assert(warnings, HasLen, 2)
+	c.Check(warnings[1].String(), Equals, "the kernel parameters vm.swappiness were unset, reboot for the change to take effect")
+}
+
+func (s *kernelSuite) TestConfigureSysctlInvalid(c *C) {
+	for _, t := range []struct {
+		sysctl map[string]interface{}
+		err    string
+	}{
+		{map[string]interface{}{"swappiness": json.Number("10")}, `cannot set kernel parameter "swappiness": not a valid sysctl name`},
+		{map[string]interface{}{"vm": map[string]interface{}{"Swappiness": json.Number("10")}}, `cannot set kernel parameter "vm.Swappiness": not a valid sysctl name`},
+		{map[string]interface{}{"vm": map[string]interface{}{"swappiness ": json.Number("10")}}, `cannot set kernel parameter "vm.swappiness ": not a valid sysctl name`},
+		{map[string]interface{}{"vm": map[string]interface{}{"swap=piness": json.Number("10")}}, `cannot set kernel parameter "vm.swap=piness": not a valid sysctl name`},
+		{map[string]interface{}{"vm": map[string]interface{}{"swappiness": "10\nkernel.panic = 1"}}, `cannot set kernel parameter "vm.swappiness": value cannot span multiple lines`},
+		{map[string]interface{}{"net": map[string]interface{}{"ipv6": map[string]interface{}{"conf": map[string]interface{}{"all": map[string]interface{}{"disable-ipv6": json.Number("1")}}}}},
+			`cannot set kernel parameter "net.ipv6.conf.all.disable_ipv6": use network.disable-ipv6 instead`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf:  map[string]interface{}{"system.kernel.sysctl": t.sysctl},
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *kernelSuite) TestSysctlKeysAreSupported(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"system.kernel.sysctl":               map[string]interface{}{},
+			"system.kernel.sysctl.vm.swappiness": json.Number("10"),
+		},
+	})
+	c.Check(err, IsNil)
+
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"system.kernel.sysctlfoo": "1",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set "core.system.kernel.sysctlfoo": unsupported system option`)
+
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		changes: map[string]interface{}{
+			"system.kernel.cmdline-append": "quiet",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set "core.system.kernel.cmdline-append": unsupported system option`)
+}
@@ -0,0 +1,194 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.swap.size"] = true
+}
+
+// swapFile is where the swap file lives, on writable storage.
+const swapFile = "/var/tmp/swapfile.swp"
+
+const (
+	// minSwapSize is the smallest swap file that can be configured.
+	minSwapSize = 1024 * 1024
+	// swapFreeSpaceMargin is the space that must be left free on the
+	// filesystem after the swap file was created or grown.
+	swapFreeSpaceMargin = 64 * 1024 * 1024
+)
+
+// swapUnsupportedFilesystems are the filesystems the kernel can't swap
+// to, or where swap files are known to cause trouble.
+var swapUnsupportedFilesystems = map[int64]string{
+	0x01021994: "tmpfs",
+	0x9123683e: "btrfs",
+	0x794c7630: "overlayfs",
+	0x73717368: "squashfs",
+	0x6969:     "nfs",
+	0x65735546: "fuse",
+}
+
+var syscallStatfs = syscall.Statfs
+
+// parseSwapSize parses the swap.size option, a size in bytes optionally
+// followed by a unit as understood by strutil.ParseByteSize.
+func parseSwapSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		n, err = strutil.ParseByteSize(size)
+		if err != nil {
+			return 0, fmt.Errorf("invalid swap.size option %q: must be a size in bytes", size)
+		}
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("invalid swap.size option %q: size cannot be negative", size)
+	}
+	if n != 0 && n < minSwapSize {
+		return 0, fmt.Errorf("invalid swap.size option %q: size must be 0 or at least %d bytes", size, minSwapSize)
+	}
+	return n, nil
+}
+
+func validateSwapSettings(tr config.Conf) error {
+	size, err := coreCfg(tr, "swap.size")
+	if err != nil {
+		return err
+	}
+	_, err = parseSwapSize(size)
+	return err
+}
+
+// checkSwapFilesystem checks that a swap file of the given size can be
+// created in dir, growing one of the current size.
+func checkSwapFilesystem(dir string, size, current int64) error {
+	var st syscall.Statfs_t
+	if err := syscallStatfs(dir, &st); err != nil {
+		return fmt.Errorf("cannot check filesystem of %s: %v", dir, err)
+	}
+	if fs, ok := swapUnsupportedFilesystems[int64(st.Type)]; ok {
+		return fmt.Errorf("cannot create swap file in %s: swap files are not supported on %s", dir, fs)
+	}
+	needed := size - current
+	if needed <= 0 {
+		return nil
+	}
+	free := int64(st.Bavail) * int64(st.Bsize)
+	if free < needed+swapFreeSpaceMargin {
+		return fmt.Errorf("cannot create swap file of %d bytes in %s: not enough free space", size, dir)
+	}
+	return nil
+}
+
+// createSwapFile allocates a swap file of the given size at path and
+// formats it as swap space.
+func createSwapFile(path string, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if output, err := exec.Command("fallocate", "-l", strconv.FormatInt(size, 10), path).CombinedOutput(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("cannot allocate swap file: %v", osutil.OutputErr(output, err))
+	}
+	// swapon refuses world readable swap files
+	if err := os.Chmod(path, 0600); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if output, err := exec.Command("mkswap", path).CombinedOutput(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("cannot format swap file: %v", osutil.OutputErr(output, err))
+	}
+	return nil
+}
+
+func handleSwapConfiguration(tr config.Conf) error {
+	value, err := coreCfg(tr, "swap.size")
+	if err != nil {
+		return err
+	}
+	size, err := parseSwapSize(value)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dirs.GlobalRootDir, swapFile)
+	// the swap file is only ever touched when it belongs to the swap
+	// unit snapd wrote for it
+	unitExists := osutil.FileExists(systemd.SwapUnitPath(swapFile))
+	var current int64
+	if fi, err := os.Stat(path); err == nil {
+		if !unitExists {
+			if size == 0 {
+				return nil
+			}
+			return fmt.Errorf("cannot create swap file: %s exists and was not created by snapd", swapFile)
+		}
+		current = fi.Size()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if size == current && (size == 0 || unitExists) {
+		return nil
+	}
+	if size != 0 {
+		if err := checkSwapFilesystem(filepath.Dir(path), size, current); err != nil {
+			return err
+		}
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, &sysdLogger{})
+	// the swap space needs to be released before the file can go
+	if err := sysd.RemoveSwapUnitFile(swapFile); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	if err := createSwapFile(path, size); err != nil {
+		return err
+	}
+	if _, err := sysd.AddSwapUnitFile(swapFile); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
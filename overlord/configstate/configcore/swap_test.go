@@ -0,0 +1,238 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type swapSuite struct {
+	configcoreSuite
+
+	mockFallocate *testutil.MockCmd
+	mockMkswap    *testutil.MockCmd
+	restore       func()
+	statfs        syscall.Statfs_t
+	swapFile      string
+	swapUnit      string
+}
+
+var _ = Suite(&swapSuite{})
+
+func (s *swapSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+	s.systemctlArgs = nil
+	// fallocate -l <size> <path>
+	s.mockFallocate = testutil.MockCommand(c, "fallocate", `truncate -s "$2" "$3"`)
+	s.mockMkswap = testutil.MockCommand(c, "mkswap", "")
+
+	// ext4 with plenty of free space
+	s.statfs = syscall.Statfs_t{Type: 0xef53, Bsize: 4096, Bavail: 1024 * 1024}
+	s.restore = configcore.MockSyscallStatfs(func(path string, st *syscall.Statfs_t) error {
+		*st = s.statfs
+		return nil
+	})
+
+	c.Assert(os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/etc/environment"), nil, 0644), IsNil)
+	s.swapFile = filepath.Join(dirs.GlobalRootDir, "/var/tmp/swapfile.swp")
+	s.swapUnit = systemd.SwapUnitPath("/var/tmp/swapfile.swp")
+}
+
+func (s *swapSuite) TearDownTest(c *C) {
+	s.mockFallocate.Restore()
+	s.mockMkswap.Restore()
+	s.restore()
+	s.configcoreSuite.TearDownTest(c)
+}
+
+func (s *swapSuite) run(conf map[string]interface{}) error {
+	return configcore.Run(&mockConf{state: s.state, conf: conf})
+}
+
+func (s *swapSuite) TestConfigureSwapSize(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Assert(err, IsNil)
+
+	fi, err := os.Stat(s.swapFile)
+	c.Assert(err, IsNil)
+	c.Check(fi.Size(), Equals, int64(2097152))
+	c.Check(fi.Mode().Perm(), Equals, os.FileMode(0600))
+	c.Check(s.mockFallocate.Calls(), DeepEquals, [][]string{
+		{"fallocate", "-l", "2097152", s.swapFile},
+	})
+	c.Check(s.mockMkswap.Calls(), DeepEquals, [][]string{
+		{"mkswap", s.swapFile},
+	})
+	c.Check(s.swapUnit, testutil.FileContains, "What=/var/tmp/swapfile.swp\n")
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "enable", "var-tmp-swapfile.swp.swap"},
+		{"start", "var-tmp-swapfile.swp.swap"},
+	})
+
+	// nothing to do the second time around
+	s.systemctlArgs = nil
+	s.mockFallocate.ForgetCalls()
+	err = s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+	c.Check(s.mockFallocate.Calls(), HasLen, 0)
+}
+
+func (s *swapSuite) TestConfigureSwapResize(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Assert(err, IsNil)
+
+	s.systemctlArgs = nil
+	s.mockFallocate.ForgetCalls()
+	err = s.run(map[string]interface{}{"swap.size": "4MB"})
+	c.Assert(err, IsNil)
+
+	fi, err := os.Stat(s.swapFile)
+	c.Assert(err, IsNil)
+	c.Check(fi.Size(), Equals, int64(4000000))
+	c.Check(s.mockFallocate.Calls(), DeepEquals, [][]string{
+		{"fallocate", "-l", "4000000", s.swapFile},
+	})
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"stop", "var-tmp-swapfile.swp.swap"},
+		{"show", "--property=ActiveState", "var-tmp-swapfile.swp.swap"},
+		{"--root", dirs.GlobalRootDir, "disable", "var-tmp-swapfile.swp.swap"},
+		{"daemon-reload"},
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "enable", "var-tmp-swapfile.swp.swap"},
+		{"start", "var-tmp-swapfile.swp.swap"},
+	})
+}
+
+func (s *swapSuite) TestConfigureSwapRemove(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Assert(err, IsNil)
+
+	s.systemctlArgs = nil
+	err = s.run(map[string]interface{}{"swap.size": "0"})
+	c.Assert(err, IsNil)
+	c.Check(s.swapFile, testutil.FileAbsent)
+	c.Check(s.swapUnit, testutil.FileAbsent)
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"stop", "var-tmp-swapfile.swp.swap"},
+		{"show", "--property=ActiveState", "var-tmp-swapfile.swp.swap"},
+		{"--root", dirs.GlobalRootDir, "disable", "var-tmp-swapfile.swp.swap"},
+		{"daemon-reload"},
+	})
+
+	// unsetting is a no-op now
+	s.systemctlArgs = nil
+	err = s.run(map[string]interface{}{"swap.size": ""})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *swapSuite) TestConfigureSwapLeavesForeignSwapFileAlone(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	// a swap file that snapd did not create
+	c.Assert(os.MkdirAll(filepath.Dir(s.swapFile), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(s.swapFile, []byte("swap"), 0600), IsNil)
+
+	err := s.run(map[string]interface{}{"swap.size": "0"})
+	c.Assert(err, IsNil)
+	c.Check(s.swapFile, testutil.FileEquals, "swap")
+
+	err = s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Check(err, ErrorMatches, `cannot create swap file: /var/tmp/swapfile.swp exists and was not created by snapd`)
+	c.Check(s.swapFile, testutil.FileEquals, "swap")
+	c.Check(s.mockFallocate.Calls(), HasLen, 0)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *swapSuite) TestConfigureSwapUnsupportedFilesystem(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	s.statfs.Type = 0x9123683e
+	err := s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Check(err, ErrorMatches, `cannot create swap file in .*/var/tmp: swap files are not supported on btrfs`)
+	c.Check(s.swapFile, testutil.FileAbsent)
+	c.Check(s.mockFallocate.Calls(), HasLen, 0)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *swapSuite) TestConfigureSwapNotEnoughSpace(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	// 64MiB free, all of it reserved
+	s.statfs.Bavail = 16 * 1024
+	err := s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Check(err, ErrorMatches, `cannot create swap file of 2097152 bytes in .*/var/tmp: not enough free space`)
+	c.Check(s.swapFile, testutil.FileAbsent)
+	c.Check(s.systemctlArgs, HasLen, 0)
+}
+
+func (s *swapSuite) TestConfigureSwapFallocateError(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	mockFallocate := testutil.MockCommand(c, "fallocate", `echo "no space"; exit 1`)
+	defer mockFallocate.Restore()
+
+	err := s.run(map[string]interface{}{"swap.size": "2097152"})
+	c.Check(err, ErrorMatches, `cannot allocate swap file: no space`)
+	c.Check(s.swapFile, testutil.FileAbsent)
+	c.Check(s.swapUnit, testutil.FileAbsent)
+}
+
+func (s *swapSuite) TestValidateSwapSize(c *C) {
+	for _, t := range []struct {
+		size string
+		err  string
+	}{
+		{"foo", `invalid swap.size option "foo": must be a size in bytes`},
+		{"-1", `invalid swap.size option "-1": size cannot be negative`},
+		{"1024", `invalid swap.size option "1024": size must be 0 or at least 1048576 bytes`},
+		{"1kB", `invalid swap.size option "1kB": size must be 0 or at least 1048576 bytes`},
+	} {
+		err := s.run(map[string]interface{}{"swap.size": t.size})
+		c.Check(err, ErrorMatches, t.err, Commentf(t.size))
+	}
+}
@@ -0,0 +1,169 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.system.timezone"] = true
+	supportedConfigurations["core.system.timeserver"] = true
+}
+
+// validTimezone matches names from the tz database, e.g. "UTC",
+// "Europe/Berlin" or "America/Argentina/Buenos_Aires".
+var validTimezone = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+
+func validateTimezone(tr config.Conf) error {
+	timezone, err := coreCfg(tr, "system.timezone")
+	if err != nil {
+		return err
+	}
+	if timezone != "" && !validTimezone.MatchString(timezone) {
+		return fmt.Errorf("cannot set timezone %q: name is not valid", timezone)
+	}
+	return nil
+}
+
+// handleTimezoneConfiguration points the writable /etc/localtime and
+// /etc/timezone of Ubuntu Core at the configured timezone.
+func handleTimezoneConfiguration(tr config.Conf) error {
+	timezone, err := coreCfg(tr, "system.timezone")
+	if err != nil {
+		return err
+	}
+	if timezone == "" {
+		// leave whatever the image came with alone
+		return nil
+	}
+
+	zoneinfo := filepath.Join("/usr/share/zoneinfo", timezone)
+	if !osutil.FileExists(filepath.Join(dirs.GlobalRootDir, zoneinfo)) {
+		return fmt.Errorf("cannot set timezone %q: unknown timezone", timezone)
+	}
+
+	writableDir := filepath.Join(dirs.GlobalRootDir, "/etc/writable")
+	if err := os.MkdirAll(writableDir, 0755); err != nil {
+		return err
+	}
+
+	localtime := filepath.Join(writableDir, "localtime")
+	if target, err := os.Readlink(localtime); err != nil || target != zoneinfo {
+		tmp := localtime + ".tmp"
+		os.Remove(tmp)
+		if err := os.Symlink(zoneinfo, tmp); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, localtime); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+
+	return osutil.AtomicWriteFile(filepath.Join(writableDir, "timezone"), []byte(timezone+"\n"), 0644, 0)
+}
+
+func timeservers(tr config.Conf) ([]string, error) {
+	var value interface{}
+	if err := tr.Get("core", "system.timeserver", &value); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+
+	var servers []string
+	switch v := value.(type) {
+	case nil:
+		// unset
+	case string:
+		// a single server can be given as is
+		if v != "" {
+			servers = []string{v}
+		}
+	case []interface{}:
+		for _, server := range v {
+			s, ok := server.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot set time servers: %v is not a string", server)
+			}
+			servers = append(servers, s)
+		}
+	default:
+		return nil, fmt.Errorf("cannot set time servers: expected a list of servers, got %v", value)
+	}
+
+	for _, server := range servers {
+		if net.ParseIP(server) == nil && !validHostname.MatchString(server) {
+			return nil, fmt.Errorf("cannot set time servers: %q is not a valid host name or address", server)
+		}
+	}
+	return servers, nil
+}
+
+func validateTimeservers(tr config.Conf) error {
+	_, err := timeservers(tr)
+	return err
+}
+
+// handleTimeserverConfiguration makes systemd-timesyncd use the configured
+// time servers instead of the ones it comes with.
+func handleTimeserverConfiguration(tr config.Conf) error {
+	servers, err := timeservers(tr)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(dirs.GlobalRootDir, "/etc/systemd/timesyncd.conf.d")
+	name := "00-snapd.conf"
+	dirContent := make(map[string]osutil.FileState, 1)
+	if len(servers) > 0 {
+		dirContent[name] = &osutil.MemoryFileState{
+			Content: []byte(fmt.Sprintf("[Time]\nNTP=%s\n", strings.Join(servers, " "))),
+			Mode:    0644,
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	glob := name
+	changed, removed, err := osutil.EnsureDirState(dir, glob, dirContent)
+	if err != nil {
+		return err
+	}
+
+	// make timesyncd pick up the new servers
+	if len(changed) > 0 || len(removed) > 0 {
+		sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, &sysdLogger{})
+		return sysd.Restart("systemd-timesyncd.service", 5*time.Minute)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type timedateSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&timedateSuite{})
+
+func (s *timedateSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+	c.Assert(os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/etc/environment"), nil, 0644), IsNil)
+
+	zoneinfo := filepath.Join(dirs.GlobalRootDir, "/usr/share/zoneinfo")
+	c.Assert(os.MkdirAll(filepath.Join(zoneinfo, "Europe"), 0755), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(zoneinfo, "America/Argentina"), 0755), IsNil)
+	for _, tz := range []string{"UTC", "Europe/Berlin", "America/Argentina/Buenos_Aires"} {
+		f, err := os.Create(filepath.Join(zoneinfo, tz))
+		c.Assert(err, IsNil)
+		f.Close()
+	}
+}
+
+func (s *timedateSuite) TestConfigureTimezone(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	for _, tz := range []string{"Europe/Berlin", "America/Argentina/Buenos_Aires", "UTC"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.timezone": tz,
+			},
+		})
+		c.Assert(err, IsNil)
+
+		target, err := os.Readlink(filepath.Join(dirs.GlobalRootDir, "/etc/writable/localtime"))
+		c.Assert(err, IsNil)
+		c.Check(target, Equals, "/usr/share/zoneinfo/"+tz)
+		c.Check(filepath.Join(dirs.GlobalRootDir, "/etc/writable/timezone"), testutil.FileEquals, tz+"\n")
+	}
+}
+
+func (s *timedateSuite) TestConfigureTimezoneUnsetIsNoop(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{state: s.state})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(dirs.GlobalRootDir, "/etc/writable/timezone"), testutil.FileAbsent)
+}
+
+func (s *timedateSuite) TestConfigureTimezoneInvalid(c *C) {
+	for _, tz := range []string{"../../etc/passwd", "Europe/", "Europe Berlin", "/UTC"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.timezone": tz,
+			},
+		})
+		c.Check(err, ErrorMatches, `cannot set timezone ".*": name is not valid`, Commentf(tz))
+	}
+}
+
+func (s *timedateSuite) TestConfigureTimezoneUnknown(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.timezone": "Mars/Olympus_Mons",
+		},
+	})
+	c.Check(err, ErrorMatches, `cannot set timezone "Mars/Olympus_Mons": unknown timezone`)
+}
+
+func (s *timedateSuite) TestConfigureTimeservers(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	drop := filepath.Join(dirs.GlobalRootDir, "/etc/systemd/timesyncd.conf.d/00-snapd.conf")
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.timeserver": []interface{}{"ntp.example.com", "192.168.1.1", "fe80::1"},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(drop, testutil.FileEquals, "[Time]\nNTP=ntp.example.com 192.168.1.1 fe80::1\n")
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"stop", "systemd-timesyncd.service"},
+		{"show", "--property=ActiveState", "systemd-timesyncd.service"},
+		{"start", "systemd-timesyncd.service"},
+	})
+
+	// nothing changed, no restart
+	s.systemctlArgs = nil
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.timeserver": []interface{}{"ntp.example.com", "192.168.1.1", "fe80::1"},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+
+	// a single server works too
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.timeserver": "ntp.example.com",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(drop, testutil.FileEquals, "[Time]\nNTP=ntp.example.com\n")
+
+	// unsetting goes back to the defaults of timesyncd
+	s.systemctlArgs = nil
+	err = configcore.Run(&mockConf{state: s.state})
+	c.Assert(err, IsNil)
+	c.Check(drop, testutil.FileAbsent)
+	c.Check(s.systemctlArgs, HasLen, 3)
+}
+
+func (s *timedateSuite) TestConfigureTimeserversInvalid(c *C) {
+	for _, t := range []struct {
+		value interface{}
+		err   string
+	}{
+		{[]interface{}{"ntp.example.com", "-bad-"}, `cannot set time servers: "-bad-" is not a valid host name or address`},
+		{[]interface{}{"ntp example com"}, `cannot set time servers: "ntp example com" is not a valid host name or address`},
+		{[]interface{}{1}, `cannot set time servers: 1 is not a string`},
+		{map[string]interface{}{"a": "b"}, `cannot set time servers: expected a list of servers, got .*`},
+	} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.timeserver": t.value,
+			},
+		})
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
@@ -24,9 +24,12 @@ package configstate
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/schema"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -93,10 +96,31 @@ func ConfigureInstalled(st *state.State, snapName string, patch map[string]inter
 		return nil, err
 	}
 
+	// catch invalid values early so that they are reported directly
+	// instead of through a failed change
+	if err := validatePatch(st, snapName, patch); err != nil {
+		return nil, err
+	}
+
 	taskset := Configure(st, snapName, patch, flags)
 	return taskset, nil
 }
 
+// RollbackConfig returns a taskset that restores the configuration of an
+// installed snap to how it was right after the given change from its
+// configuration history. The configure hook of the snap is run as for any
+// other configuration change.
+func RollbackConfig(st *state.State, snapName string, id int, flags int) (*state.TaskSet, error) {
+	if err := canConfigure(st, snapName); err != nil {
+		return nil, err
+	}
+	patch, err := config.RollbackPatch(st, snapName, id)
+	if err != nil {
+		return nil, err
+	}
+	return ConfigureInstalled(st, snapName, patch, flags)
+}
+
 // Configure returns a taskset to apply the given configuration patch.
 func Configure(st *state.State, snapName string, patch map[string]interface{}, flags int) *state.TaskSet {
 	summary := fmt.Sprintf(i18n.G("Run configure hook of %q snap"), snapName)
@@ -107,8 +131,9 @@ func Configure(st *state.State, snapName string, patch map[string]interface{}, f
 		Optional:    len(patch) == 0,
 		IgnoreError: flags&snapstate.IgnoreHookError != 0,
 		TrackError:  flags&snapstate.TrackHookError != 0,
-		// all configure hooks must finish within this timeout
-		Timeout: ConfigureHookTimeout(),
+		// configure hooks must finish within this timeout unless
+		// the snap declares its own
+		DefaultTimeout: ConfigureHookTimeout(),
 	}
 	var contextData map[string]interface{}
 	if flags&snapstate.UseConfigDefaults != 0 {
@@ -125,6 +150,69 @@ func Configure(st *state.State, snapName string, patch map[string]interface{}, f
 	return state.NewTaskSet(task)
 }
 
+// SnapConfigSchema returns the configuration schema declared by the
+// given snap in meta/config-schema.json, or nil if the snap does not
+// declare one.
+func SnapConfigSchema(st *state.State, snapName string) (*schema.Schema, error) {
+	// "core" configuration is handled internally by configcore
+	if snapName == "core" {
+		return nil, nil
+	}
+
+	var snapst snapstate.SnapState
+	err := snapstate.Get(st, snapName, &snapst)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !snapst.IsInstalled() {
+		return nil, nil
+	}
+
+	fn := filepath.Join(snap.MountDir(snapName, snapst.Current), "meta", "config-schema.json")
+	sch, err := schema.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read configuration schema of snap %q: %v", snapName, err)
+	}
+	return sch, nil
+}
+
+// validateConfig validates the configuration of the given snap as seen
+// by the transaction against the schema declared by the snap, if any.
+func validateConfig(tr *config.Transaction, snapName string) error {
+	sch, err := SnapConfigSchema(tr.State(), snapName)
+	if err != nil || sch == nil {
+		return err
+	}
+
+	var doc interface{}
+	if err := tr.GetMaybe(snapName, "", &doc); err != nil {
+		return err
+	}
+	return sch.Validate(doc)
+}
+
+// validatePatch validates the configuration the given snap would have
+// once the patch is applied.
+func validatePatch(st *state.State, snapName string, patch map[string]interface{}) error {
+	if len(patch) == 0 {
+		return nil
+	}
+
+	tr := config.NewTransaction(st)
+	for _, key := range sortPatchKeysByDepth(patch) {
+		if err := tr.Set(snapName, key, patch[key]); err != nil {
+			return err
+		}
+	}
+	return validateConfig(tr, snapName)
+}
+
 // RemapSnapFromRequest renames a snap as received from an API request
 func RemapSnapFromRequest(snapName string) string {
 	if snapName == "system" {
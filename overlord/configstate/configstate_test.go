@@ -21,17 +21,22 @@ package configstate_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/schema"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -121,7 +126,8 @@ func (s *tasksetsSuite) TestConfigureInstalled(c *C) {
 		c.Assert(hooksup.Hook, Equals, "configure")
 		c.Assert(hooksup.Optional, Equals, test.optional)
 		c.Assert(hooksup.IgnoreError, Equals, test.ignoreError)
-		c.Assert(hooksup.Timeout, Equals, 5*time.Minute)
+		c.Assert(hooksup.Timeout, Equals, time.Duration(0))
+		c.Assert(hooksup.DefaultTimeout, Equals, 5*time.Minute)
 
 		context, err := hookstate.NewContext(task, task.State(), &hooksup, nil, "")
 		c.Check(err, IsNil)
@@ -215,6 +221,121 @@ func (s *tasksetsSuite) TestConfigureDenySnapd(c *C) {
 	c.Check(err, ErrorMatches, `cannot configure the "snapd" snap, please use "system" instead`)
 }
 
+const mockConfigSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "foo": {"type": "string"},
+    "port": {"type": "integer", "maximum": 65535}
+  }
+}`
+
+func (s *tasksetsSuite) mockSnapWithSchema(c *C, name, configSchema string) {
+	dirs.SetRootDir(c.MkDir())
+	info := snaptest.MockSnap(c, fmt.Sprintf("name: %s\nversion: 1\n", name), &snap.SideInfo{Revision: snap.R(1)})
+	if configSchema != "" {
+		err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(configSchema), 0644)
+		c.Assert(err, IsNil)
+	}
+	snapstate.Set(s.state, name, &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: name, Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		Active:   true,
+		SnapType: "app",
+	})
+}
+
+func (s *tasksetsSuite) TestConfigureInstalledValidatesSchema(c *C) {
+	defer dirs.SetRootDir("/")
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnapWithSchema(c, "test-snap", mockConfigSchema)
+
+	_, err := configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"foo": "bar", "port": 80}, 0)
+	c.Check(err, IsNil)
+
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"port": "80"}, 0)
+	c.Check(err, ErrorMatches, `invalid option "port": expected integer, got string`)
+	c.Check(schema.IsValidationError(err), Equals, true)
+
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"prot": 80}, 0)
+	c.Check(err, ErrorMatches, `invalid option "prot": option is not declared in the configuration schema`)
+}
+
+func (s *tasksetsSuite) TestConfigureInstalledValidatesAgainstCurrentConfig(c *C) {
+	defer dirs.SetRootDir("/")
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnapWithSchema(c, "test-snap", `{"properties": {"a": {"type": "object", "required": ["b"]}}}`)
+
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("test-snap", "a.b", 1), IsNil)
+	tr.Commit()
+
+	_, err := configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"a.c": 2}, 0)
+	c.Check(err, IsNil)
+
+	_, err = configstate.ConfigureInstalled(s.state, "test-snap", map[string]interface{}{"a.b": nil}, 0)
+	c.Check(err, ErrorMatches, `invalid option "a.b": required option is missing`)
+}
+
+func (s *tasksetsSuite) TestRollbackConfig(c *C) {
+	defer dirs.SetRootDir("/")
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnapWithSchema(c, "test-snap", "")
+
+	for _, value := range []interface{}{"bar", "baz"} {
+		tr := config.NewTransaction(s.state)
+		c.Assert(tr.Set("test-snap", "foo", value), IsNil)
+		tr.Commit()
+	}
+
+	ts, err := configstate.RollbackConfig(s.state, "test-snap", 1, 0)
+	c.Assert(err, IsNil)
+	tasks := ts.Tasks()
+	c.Assert(tasks, HasLen, 1)
+	c.Check(tasks[0].Kind(), Equals, "run-hook")
+
+	var hookContext struct {
+		Patch map[string]interface{} `json:"patch"`
+	}
+	c.Assert(tasks[0].Get("hook-context", &hookContext), IsNil)
+	c.Check(hookContext.Patch, DeepEquals, map[string]interface{}{"foo": "bar"})
+
+	_, err = configstate.RollbackConfig(s.state, "test-snap", 2, 0)
+	c.Check(err, ErrorMatches, `configuration of snap "test-snap" is already at change 2`)
+
+	_, err = configstate.RollbackConfig(s.state, "other-snap", 1, 0)
+	c.Check(err, ErrorMatches, `snap "other-snap" is not installed`)
+}
+
+func (s *tasksetsSuite) TestSnapConfigSchema(c *C) {
+	defer dirs.SetRootDir("/")
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockSnapWithSchema(c, "no-schema", "")
+	sch, err := configstate.SnapConfigSchema(s.state, "no-schema")
+	c.Assert(err, IsNil)
+	c.Check(sch, IsNil)
+
+	sch, err = configstate.SnapConfigSchema(s.state, "not-installed")
+	c.Assert(err, IsNil)
+	c.Check(sch, IsNil)
+
+	s.mockSnapWithSchema(c, "bad-schema", `{"type": "string"}`)
+	_, err = configstate.SnapConfigSchema(s.state, "bad-schema")
+	c.Check(err, ErrorMatches, `cannot read configuration schema of snap "bad-schema": cannot parse configuration schema: top-level type must be "object"`)
+
+	s.mockSnapWithSchema(c, "test-snap", mockConfigSchema)
+	sch, err = configstate.SnapConfigSchema(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(sch.Properties, HasLen, 2)
+}
+
 type configcoreHijackSuite struct {
 	testutil.BaseTest
 
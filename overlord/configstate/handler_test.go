@@ -115,6 +115,99 @@ func (s *configureHandlerSuite) TestBeforeInitializesTransaction(c *C) {
 	c.Check(value, Equals, "bar")
 }
 
+func (s *configureHandlerSuite) TestBeforeSetsHistoryOrigin(c *C) {
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{
+		"foo": "bar",
+	})
+	s.context.Unlock()
+
+	c.Check(s.handler.Before(), IsNil)
+
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+	c.Assert(tr.Set("test-snap", "baz", 1), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	tr.Commit()
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Origin, Equals, config.OriginUser)
+	c.Check(history[0].Changes, HasLen, 2)
+}
+
+func (s *configureHandlerSuite) TestBeforeSetsHistoryOriginRemodel(c *C) {
+	s.state.Lock()
+	task, _ := s.context.Task()
+	chg := s.state.NewChange("remodel", "...")
+	chg.AddTask(task)
+	s.state.Unlock()
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{
+		"foo": "bar",
+	})
+	s.context.Unlock()
+
+	c.Check(s.handler.Before(), IsNil)
+
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	tr.Commit()
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Origin, Equals, config.OriginRemodel)
+}
+
+func (s *configureHandlerSuite) TestContextTransactionHookOrigin(c *C) {
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+	c.Assert(tr.Set("test-snap", "foo", "bar"), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	tr.Commit()
+	history, err := config.History(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Origin, Equals, config.OriginHook)
+}
+
+func (s *configureHandlerSuite) TestBeforeValidatesSchema(c *C) {
+	s.state.Lock()
+	info := snaptest.MockSnap(c, "name: test-snap\nversion: 1\n", &snap.SideInfo{Revision: snap.R(1)})
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(`{
+  "properties": {"foo": {"enum": ["bar", "baz"]}}
+}`), 0644)
+	c.Assert(err, IsNil)
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+	s.state.Unlock()
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{
+		"foo": "qux",
+	})
+	s.context.Unlock()
+
+	c.Check(s.handler.Before(), ErrorMatches, `invalid option "foo": must be one of "bar", "baz"`)
+}
+
 func makeModel(override map[string]interface{}) *asserts.Model {
 	model := map[string]interface{}{
 		"type":         "model",
@@ -436,3 +529,101 @@ volumes:
 	c.Assert(err, IsNil)
 	c.Check(foo, Equals, "bar")
 }
+
+func (s *configcoreHandlerSuite) TestTimeDateGadgetDefaults(c *C) {
+	r := release.MockOnClassic(false)
+	defer r()
+
+	var mockGadgetYaml = `
+defaults:
+  system:
+      system:
+          timezone: Europe/Berlin
+          hostname: my-device
+          timeserver: [ntp1.example.com, ntp2.example.com]
+
+volumes:
+    volume-id:
+        bootloader: grub
+`
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	ts := configstate.Configure(s.state, "core", nil, snapstate.UseConfigDefaults)
+	chg := s.state.NewChange("configure-core", "configure core")
+	chg.AddAll(ts)
+
+	snaptest.MockSnapWithFiles(c, mockGadgetSnapYaml, &snap.SideInfo{Revision: snap.R(1)}, [][]string{
+		{"meta/gadget.yaml", mockGadgetYaml},
+	})
+
+	snapstate.Set(s.state, "core", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "core", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "os",
+	})
+
+	witnessConfigcoreRun := func(conf config.Conf) error {
+		// called with no state lock!
+		conf.State().Lock()
+		defer conf.State().Unlock()
+		var timezone, hostname string
+		var servers []string
+		c.Assert(conf.Get("core", "system.timezone", &timezone), IsNil)
+		c.Assert(conf.Get("core", "system.hostname", &hostname), IsNil)
+		c.Assert(conf.Get("core", "system.timeserver", &servers), IsNil)
+		c.Check(timezone, Equals, "Europe/Berlin")
+		c.Check(hostname, Equals, "my-device")
+		c.Check(servers, DeepEquals, []string{"ntp1.example.com", "ntp2.example.com"})
+		return nil
+	}
+	r = configstate.MockConfigcoreRun(witnessConfigcoreRun)
+	defer r()
+
+	s.state.Unlock()
+	err := s.o.Settle(5 * time.Second)
+	s.state.Lock()
+	c.Assert(err, IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+}
+
+func (s *configureHandlerSuite) TestDoneValidatesSchema(c *C) {
+	s.state.Lock()
+	info := snaptest.MockSnap(c, "name: test-snap\nversion: 1\n", &snap.SideInfo{Revision: snap.R(1)})
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "config-schema.json"), []byte(`{
+  "properties": {"foo": {"enum": ["bar", "baz"]}}
+}`), 0644)
+	c.Assert(err, IsNil)
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+	s.state.Unlock()
+
+	s.context.Lock()
+	s.context.Set("patch", map[string]interface{}{
+		"foo": "bar",
+	})
+	s.context.Unlock()
+
+	c.Assert(s.handler.Before(), IsNil)
+
+	// the hook sets an invalid value via snapctl
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+	c.Assert(tr.Set("test-snap", "foo", "qux"), IsNil)
+
+	c.Check(s.handler.Done(), ErrorMatches, `invalid option "foo": must be one of "bar", "baz"`)
+
+	// a valid value is accepted
+	c.Assert(tr.Set("test-snap", "foo", "baz"), IsNil)
+	c.Check(s.handler.Done(), IsNil)
+}
@@ -48,6 +48,7 @@ func ContextTransaction(context *hookstate.Context) *config.Transaction {
 
 	// It wasn't already cached, so create and cache a new one
 	tr = config.NewTransaction(context.State())
+	tr.SetOrigin(config.OriginHook)
 
 	context.OnDone(func() error {
 		tr.Commit()
@@ -114,6 +115,17 @@ func (h *configureHandler) Before() error {
 		}
 	}
 
+	// record where the configuration changes come from; anything
+	// the hook itself changes is attributed to the same origin
+	switch {
+	case isRemodel(h.context):
+		tr.SetOrigin(config.OriginRemodel)
+	case useDefaults:
+		tr.SetOrigin(config.OriginGadgetDefault)
+	case len(patch) > 0:
+		tr.SetOrigin(config.OriginUser)
+	}
+
 	patchKeys := sortPatchKeysByDepth(patch)
 	for _, key := range patchKeys {
 		if err := tr.Set(instanceName, key, patch[key]); err != nil {
@@ -121,13 +133,35 @@ func (h *configureHandler) Before() error {
 		}
 	}
 
+	// the configure hook only ever sees configuration that matches
+	// the schema declared by the snap
+	if len(patch) > 0 {
+		if err := validateConfig(tr, instanceName); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func isRemodel(context *hookstate.Context) bool {
+	task, ok := context.Task()
+	if !ok || task.Change() == nil {
+		return false
+	}
+	return task.Change().Kind() == "remodel"
+}
+
 // Done is called by the HookManager after the configure hook has exited
 // successfully.
 func (h *configureHandler) Done() error {
-	return nil
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	// the hook may have changed the configuration via snapctl, make
+	// sure what gets committed still matches the schema of the snap
+	tr := ContextTransaction(h.context)
+	return validateConfig(tr, h.context.InstanceName())
 }
 
 // Error is called by the HookManager after the configure hook has exited
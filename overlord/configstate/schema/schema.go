@@ -0,0 +1,442 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package schema implements the subset of JSON Schema that snaps can use
+// to declare the shape of their configuration in meta/config-schema.json.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/snapcore/snapd/jsonutil"
+)
+
+// Schema describes the expected shape of a configuration value.
+type Schema struct {
+	// Types lists the JSON types accepted for the value, any type is
+	// accepted when empty.
+	Types       []string
+	Description string
+
+	// object
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties bool
+
+	// array
+	Items    *Schema
+	MinItems *int
+	MaxItems *int
+
+	// string
+	MinLength *int
+	MaxLength *int
+	Pattern   *regexp.Regexp
+
+	// number and integer
+	Minimum *float64
+	Maximum *float64
+
+	Enum []interface{}
+
+	raw json.RawMessage
+}
+
+// MarshalJSON returns the schema as it was originally declared.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	return s.raw, nil
+}
+
+var validTypes = map[string]bool{
+	"object":  true,
+	"array":   true,
+	"string":  true,
+	"integer": true,
+	"number":  true,
+	"boolean": true,
+	"null":    true,
+}
+
+// knownKeywords are the JSON Schema keywords supported by this package;
+// anything else is rejected so that typos in schemas don't go unnoticed.
+var knownKeywords = map[string]bool{
+	"$schema":              true,
+	"$comment":             true,
+	"title":                true,
+	"description":          true,
+	"default":              true,
+	"examples":             true,
+	"type":                 true,
+	"properties":           true,
+	"required":             true,
+	"additionalProperties": true,
+	"items":                true,
+	"minItems":             true,
+	"maxItems":             true,
+	"minLength":            true,
+	"maxLength":            true,
+	"pattern":              true,
+	"minimum":              true,
+	"maximum":              true,
+	"enum":                 true,
+}
+
+type schemaJSON struct {
+	Type                 json.RawMessage            `json:"type"`
+	Description          string                     `json:"description"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties *bool                      `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              *string                    `json:"pattern"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	Enum                 []interface{}              `json:"enum"`
+}
+
+// ReadFile reads and parses the schema in the given file.
+func ReadFile(fn string) (*Schema, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a configuration schema.
+func Parse(data []byte) (*Schema, error) {
+	s, err := parse("", data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse configuration schema: %v", err)
+	}
+	if len(s.Types) > 0 && (len(s.Types) != 1 || s.Types[0] != "object") {
+		return nil, fmt.Errorf("cannot parse configuration schema: top-level type must be \"object\"")
+	}
+	return s, nil
+}
+
+func where(path string) string {
+	if path == "" {
+		return "top-level"
+	}
+	return fmt.Sprintf("%q", path)
+}
+
+func parse(path string, data []byte) (*Schema, error) {
+	var keywords map[string]json.RawMessage
+	if err := jsonutil.DecodeWithNumber(bytes.NewReader(data), &keywords); err != nil {
+		return nil, fmt.Errorf("%s schema must be an object", where(path))
+	}
+	for k := range keywords {
+		if !knownKeywords[k] {
+			return nil, fmt.Errorf("%s schema uses unsupported keyword %q", where(path), k)
+		}
+	}
+
+	// enum values are compared against configuration values which are
+	// decoded with json.Number, so decode them the same way
+	var sj schemaJSON
+	if err := jsonutil.DecodeWithNumber(bytes.NewReader(data), &sj); err != nil {
+		return nil, fmt.Errorf("%s schema is invalid: %v", where(path), err)
+	}
+
+	s := &Schema{
+		Description: sj.Description,
+		Required:    sj.Required,
+		MinItems:    sj.MinItems,
+		MaxItems:    sj.MaxItems,
+		MinLength:   sj.MinLength,
+		MaxLength:   sj.MaxLength,
+		Minimum:     sj.Minimum,
+		Maximum:     sj.Maximum,
+		Enum:        sj.Enum,
+		raw:         json.RawMessage(data),
+		// additional properties are allowed unless explicitly
+		// forbidden, as in JSON Schema
+		AdditionalProperties: sj.AdditionalProperties == nil || *sj.AdditionalProperties,
+	}
+
+	if len(sj.Type) > 0 {
+		var typ string
+		if err := json.Unmarshal(sj.Type, &typ); err == nil {
+			s.Types = []string{typ}
+		} else if err := json.Unmarshal(sj.Type, &s.Types); err != nil {
+			return nil, fmt.Errorf("%s schema type must be a string or a list of strings", where(path))
+		}
+		for _, typ := range s.Types {
+			if !validTypes[typ] {
+				return nil, fmt.Errorf("%s schema has unsupported type %q", where(path), typ)
+			}
+		}
+	}
+
+	if len(sj.Properties) > 0 {
+		s.Properties = make(map[string]*Schema, len(sj.Properties))
+		for name, raw := range sj.Properties {
+			if name == "" || strings.Contains(name, ".") {
+				return nil, fmt.Errorf("%s schema has invalid property name %q", where(path), name)
+			}
+			prop, err := parse(join(path, name), raw)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[name] = prop
+		}
+	}
+
+	if len(sj.Items) > 0 {
+		items, err := parse(path+"[]", sj.Items)
+		if err != nil {
+			return nil, err
+		}
+		s.Items = items
+	}
+
+	if sj.Pattern != nil {
+		re, err := regexp.Compile(*sj.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s schema has invalid pattern: %v", where(path), err)
+		}
+		s.Pattern = re
+	}
+
+	return s, nil
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// ValidationError is returned when a configuration value does not match
+// its schema.
+type ValidationError struct {
+	// Path is the dotted path of the offending option, empty for the
+	// whole configuration document.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("invalid configuration: %s", e.Message)
+	}
+	return fmt.Sprintf("invalid option %q: %s", e.Path, e.Message)
+}
+
+// IsValidationError returns whether the provided error is a *ValidationError.
+func IsValidationError(err error) bool {
+	_, ok := err.(*ValidationError)
+	return ok
+}
+
+func invalid(path, format string, a ...interface{}) error {
+	return &ValidationError{Path: path, Message: fmt.Sprintf(format, a...)}
+}
+
+// Validate checks the given configuration document, as decoded with
+// jsonutil.DecodeWithNumber, against the schema.
+func (s *Schema) Validate(value interface{}) error {
+	if value == nil {
+		// no configuration at all
+		value = map[string]interface{}{}
+	}
+	return s.validate("", value)
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case int, int64:
+		return "integer"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func (s *Schema) hasType(typ string) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		// integers are numbers too
+		if t == typ || (t == "number" && typ == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := strconv.ParseFloat(string(v), 64)
+		return f
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+func enumEqual(a, b interface{}) bool {
+	if an, ok := a.(json.Number); ok {
+		if bn, ok := b.(json.Number); ok {
+			return toFloat(an) == toFloat(bn)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	typ := typeOf(value)
+	if !s.hasType(typ) {
+		if len(s.Types) == 1 {
+			return invalid(path, "expected %s, got %s", s.Types[0], typ)
+		}
+		return invalid(path, "expected one of %s, got %s", strings.Join(s.Types, ", "), typ)
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if enumEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed := make([]string, len(s.Enum))
+			for i, e := range s.Enum {
+				b, _ := json.Marshal(e)
+				allowed[i] = string(b)
+			}
+			return invalid(path, "must be one of %s", strings.Join(allowed, ", "))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.validateObject(path, v)
+	case []interface{}:
+		return s.validateArray(path, v)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			return invalid(path, "must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return invalid(path, "must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(v) {
+			return invalid(path, "must match %q", s.Pattern.String())
+		}
+	case json.Number, float64, int, int64:
+		f := toFloat(v)
+		if s.Minimum != nil && f < *s.Minimum {
+			return invalid(path, "must be greater than or equal to %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return invalid(path, "must be less than or equal to %v", *s.Maximum)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(path string, obj map[string]interface{}) error {
+	for _, req := range s.Required {
+		if _, ok := obj[req]; !ok {
+			return invalid(join(path, req), "required option is missing")
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		prop, ok := s.Properties[k]
+		if !ok {
+			if !s.AdditionalProperties {
+				return invalid(join(path, k), "option is not declared in the configuration schema")
+			}
+			continue
+		}
+		if err := prop.validate(join(path, k), obj[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(path string, arr []interface{}) error {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		return invalid(path, "must have at least %d items", *s.MinItems)
+	}
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		return invalid(path, "must have at most %d items", *s.MaxItems)
+	}
+	if s.Items == nil {
+		return nil
+	}
+	for i, item := range arr {
+		if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
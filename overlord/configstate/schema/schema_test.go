@@ -0,0 +1,157 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package schema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/configstate/schema"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type schemaSuite struct{}
+
+var _ = Suite(&schemaSuite{})
+
+const mockSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "server": {
+      "type": "object",
+      "required": ["port"],
+      "properties": {
+        "port": {"type": "integer", "minimum": 1, "maximum": 65535},
+        "host": {"type": "string", "pattern": "^[a-z0-9.-]+$", "maxLength": 16}
+      }
+    },
+    "mode": {"enum": ["fast", "slow", 3]},
+    "ratio": {"type": "number", "minimum": 0.5},
+    "debug": {"type": "boolean"},
+    "tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "minLength": 2}},
+    "extra": {"type": ["string", "null"]}
+  }
+}`
+
+func decode(c *C, doc string) interface{} {
+	var v interface{}
+	err := jsonutil.DecodeWithNumber(bytes.NewBufferString(doc), &v)
+	c.Assert(err, IsNil)
+	return v
+}
+
+func (s *schemaSuite) TestValidateHappy(c *C) {
+	sch, err := schema.Parse([]byte(mockSchema))
+	c.Assert(err, IsNil)
+
+	for _, doc := range []string{
+		`{}`,
+		`{"server": {"port": 8080}}`,
+		`{"server": {"port": 8080, "host": "example.com", "other": 1}}`,
+		`{"mode": "fast", "ratio": 1, "debug": false}`,
+		`{"mode": 3, "ratio": 0.75}`,
+		`{"tags": ["aa", "bb"], "extra": "x"}`,
+	} {
+		c.Check(sch.Validate(decode(c, doc)), IsNil, Commentf(doc))
+	}
+	c.Check(sch.Validate(nil), IsNil)
+}
+
+func (s *schemaSuite) TestValidateUnhappy(c *C) {
+	sch, err := schema.Parse([]byte(mockSchema))
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		doc  string
+		path string
+		err  string
+	}{
+		{`{"servr": {}}`, "servr", `invalid option "servr": option is not declared in the configuration schema`},
+		{`{"server": {}}`, "server.port", `invalid option "server.port": required option is missing`},
+		{`{"server": {"port": "80"}}`, "server.port", `invalid option "server.port": expected integer, got string`},
+		{`{"server": {"port": 1.5}}`, "server.port", `invalid option "server.port": expected integer, got number`},
+		{`{"server": {"port": 0}}`, "server.port", `invalid option "server.port": must be greater than or equal to 1`},
+		{`{"server": {"port": 70000}}`, "server.port", `invalid option "server.port": must be less than or equal to 65535`},
+		{`{"server": {"port": 1, "host": "Foo"}}`, "server.host", `invalid option "server.host": must match "\^\[a-z0-9.-\]\+\$"`},
+		{`{"server": {"port": 1, "host": "a-very-long-hostname"}}`, "server.host", `invalid option "server.host": must be at most 16 characters long`},
+		{`{"server": 1}`, "server", `invalid option "server": expected object, got integer`},
+		{`{"mode": "medium"}`, "mode", `invalid option "mode": must be one of "fast", "slow", 3`},
+		{`{"ratio": 0.1}`, "ratio", `invalid option "ratio": must be greater than or equal to 0.5`},
+		{`{"debug": "yes"}`, "debug", `invalid option "debug": expected boolean, got string`},
+		{`{"tags": ["aa", "b"]}`, "tags[1]", `invalid option "tags\[1\]": must be at least 2 characters long`},
+		{`{"tags": ["aa", "bb", "cc"]}`, "tags", `invalid option "tags": must have at most 2 items`},
+		{`{"extra": 1}`, "extra", `invalid option "extra": expected one of string, null, got integer`},
+	} {
+		err := sch.Validate(decode(c, t.doc))
+		c.Assert(err, NotNil, Commentf(t.doc))
+		c.Check(err, ErrorMatches, t.err, Commentf(t.doc))
+		c.Assert(schema.IsValidationError(err), Equals, true)
+		c.Check(err.(*schema.ValidationError).Path, Equals, t.path)
+	}
+
+	err = sch.Validate([]interface{}{})
+	c.Check(err, ErrorMatches, `invalid configuration: expected object, got array`)
+}
+
+func (s *schemaSuite) TestParseErrors(c *C) {
+	for _, t := range []struct {
+		schema string
+		err    string
+	}{
+		{`[]`, `cannot parse configuration schema: top-level schema must be an object`},
+		{`{"type": "string"}`, `cannot parse configuration schema: top-level type must be "object"`},
+		{`{"typ": "object"}`, `cannot parse configuration schema: top-level schema uses unsupported keyword "typ"`},
+		{`{"properties": {"a": {"type": "int"}}}`, `cannot parse configuration schema: "a" schema has unsupported type "int"`},
+		{`{"properties": {"a": {"type": 1}}}`, `cannot parse configuration schema: "a" schema type must be a string or a list of strings`},
+		{`{"properties": {"a.b": {}}}`, `cannot parse configuration schema: top-level schema has invalid property name "a.b"`},
+		{`{"properties": {"a": {"properties": {"b": {"oneOf": []}}}}}`, `cannot parse configuration schema: "a.b" schema uses unsupported keyword "oneOf"`},
+		{`{"properties": {"a": {"pattern": "("}}}`, `cannot parse configuration schema: "a" schema has invalid pattern: .*`},
+		{`{"properties": {"a": {"items": 1}}}`, `cannot parse configuration schema: "a\[\]" schema must be an object`},
+		{`{"properties": {"a": {"minLength": "x"}}}`, `cannot parse configuration schema: "a" schema is invalid: .*`},
+	} {
+		_, err := schema.Parse([]byte(t.schema))
+		c.Check(err, ErrorMatches, t.err, Commentf(t.schema))
+	}
+}
+
+func (s *schemaSuite) TestReadFile(c *C) {
+	_, err := schema.ReadFile("/does/not/exist")
+	c.Check(err, ErrorMatches, ".* no such file or directory")
+}
+
+func (s *schemaSuite) TestMarshalJSON(c *C) {
+	sch, err := schema.Parse([]byte(mockSchema))
+	c.Assert(err, IsNil)
+
+	data, err := json.Marshal(sch)
+	c.Assert(err, IsNil)
+	c.Check(decode(c, string(data)), DeepEquals, decode(c, mockSchema))
+
+	data, err = json.Marshal(sch.Properties["debug"])
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"type":"boolean"}`)
+}
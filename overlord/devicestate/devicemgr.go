@@ -262,6 +262,15 @@ func (m *DeviceManager) operatingMode() string {
 	return m.modeEnv.Mode
 }
 
+// SystemMode returns the current mode of the system. Systems without
+// a modeenv are always in "run" mode.
+func (m *DeviceManager) SystemMode() string {
+	if m.modeEnv.Mode == "" {
+		return "run"
+	}
+	return m.modeEnv.Mode
+}
+
 func (m *DeviceManager) ensureOperational() error {
 	m.state.Lock()
 	defer m.state.Unlock()
@@ -102,6 +102,37 @@ func findSerial(st *state.State, device *auth.DeviceState) (*asserts.Serial, err
 	return a.(*asserts.Serial), nil
 }
 
+// SerialFromState returns the device serial assertion, or
+// state.ErrNoState if the device is not registered yet.
+func SerialFromState(st *state.State) (*asserts.Serial, error) {
+	return findSerial(st, nil)
+}
+
+// SystemModeInfo holds details about the mode the system is in.
+type SystemModeInfo struct {
+	Mode       string
+	HasModeenv bool
+	Seeded     bool
+}
+
+// SystemModeInfoFromState returns details about the mode the system
+// is in, as seen by the device manager associated with the state.
+func SystemModeInfoFromState(st *state.State) (*SystemModeInfo, error) {
+	mgr := deviceMgr(st)
+
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+
+	return &SystemModeInfo{
+		Mode:       mgr.SystemMode(),
+		HasModeenv: mgr.operatingMode() != "",
+		Seeded:     seeded,
+	}, nil
+}
+
 // auto-refresh
 func canAutoRefresh(st *state.State) (bool, error) {
 	// we need to be seeded first
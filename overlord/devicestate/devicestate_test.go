@@ -1064,3 +1064,37 @@ func (s *deviceMgrSuite) TestDeviceManagerReadsModeenv(c *C) {
 	c.Assert(mgr, NotNil)
 	c.Assert(devicestate.OperatingMode(mgr), Equals, "install")
 }
+
+func (s *deviceMgrSuite) TestSystemModeInfoFromStateNoModeenv(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	smi, err := devicestate.SystemModeInfoFromState(s.state)
+	c.Assert(err, IsNil)
+	c.Check(smi, DeepEquals, &devicestate.SystemModeInfo{
+		Mode: "run",
+	})
+}
+
+func (s *deviceMgrSuite) TestSystemModeInfoFromStateModeenv(c *C) {
+	modeEnv := &boot.Modeenv{Mode: "install"}
+	err := modeEnv.Write("")
+	c.Assert(err, IsNil)
+
+	runner := s.o.TaskRunner()
+	mgr, err := devicestate.Manager(s.state, s.hookMgr, runner, s.newStore)
+	c.Assert(err, IsNil)
+	c.Check(mgr.SystemMode(), Equals, "install")
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", true)
+
+	smi, err := devicestate.SystemModeInfoFromState(s.state)
+	c.Assert(err, IsNil)
+	c.Check(smi, DeepEquals, &devicestate.SystemModeInfo{
+		Mode:       "install",
+		HasModeenv: true,
+		Seeded:     true,
+	})
+}
@@ -44,6 +44,10 @@ type Context struct {
 	id      string
 	handler Handler
 
+	// deadline is when the running hook gets killed, it's zero for
+	// ephemeral contexts
+	deadline time.Time
+
 	cache  map[interface{}]interface{}
 	onDone []func() error
 
@@ -96,6 +100,13 @@ func (c *Context) Timeout() time.Duration {
 	return c.setup.Timeout
 }
 
+// Deadline returns the time at which the running hook will be killed
+// for exceeding its timeout, or false if there is no hook running under
+// this context.
+func (c *Context) Deadline() (time.Time, bool) {
+	return c.deadline, !c.deadline.IsZero()
+}
+
 // ID returns the ID of the context.
 func (c *Context) ID() string {
 	return c.id
@@ -65,6 +65,29 @@ func (c *baseCommand) context() *hookstate.Context {
 	return c.c
 }
 
+// stdinKey is the index into the context cache where the data read by
+// snapctl on behalf of the command is stored.
+type stdinKey struct{}
+
+// SetStdin makes the data read by snapctl on behalf of the command, such
+// as the file given to set --file, available to the next command run in
+// the given context.
+//
+// The context must be locked by the caller.
+func SetStdin(context *hookstate.Context, data []byte) {
+	context.Cache(stdinKey{}, data)
+}
+
+func (c *baseCommand) stdin() []byte {
+	if c.c == nil {
+		return nil
+	}
+	c.c.Lock()
+	defer c.c.Unlock()
+	data, _ := c.c.Cached(stdinKey{}).([]byte)
+	return data
+}
+
 type command interface {
 	setStdout(w io.Writer)
 	setStderr(w io.Writer)
@@ -113,6 +136,16 @@ func (f *ForbiddenCommand) Execute(args []string) error {
 	return &ForbiddenCommandError{Message: fmt.Sprintf("cannot use %q with uid %d, try with sudo", f.Name, f.Uid)}
 }
 
+// nonRootAllowed lists the commands that regular users can use
+var nonRootAllowed = map[string]bool{
+	"get":            true,
+	"services":       true,
+	"set-health":     true,
+	"model":          true,
+	"system-mode":    true,
+	"remaining-time": true,
+}
+
 // Run runs the requested command.
 func Run(context *hookstate.Context, args []string, uid uint32) (stdout, stderr []byte, err error) {
 	parser := flags.NewParser(nil, flags.PassDoubleDash|flags.HelpFlag)
@@ -124,7 +157,7 @@ func Run(context *hookstate.Context, args []string, uid uint32) (stdout, stderr
 		var data interface{}
 		// commands listed here will be allowed for regular users
 		// note: commands still need valid context and snaps can only access own config.
-		if uid == 0 || name == "get" || name == "services" || name == "set-health" {
+		if uid == 0 || nonRootAllowed[name] {
 			cmd := cmdInfo.generator()
 			cmd.setStdout(&stdoutBuffer)
 			cmd.setStderr(&stderrBuffer)
@@ -21,7 +21,9 @@ package ctlcmd
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -36,6 +38,12 @@ func MockServicestateControlFunc(f func(*state.State, []*snap.AppInfo, *services
 	return func() { servicestateControl = old }
 }
 
+func MockDevicestateSystemModeInfoFromState(f func(*state.State) (*devicestate.SystemModeInfo, error)) (restore func()) {
+	old := devicestateSystemModeInfoFromState
+	devicestateSystemModeInfoFromState = f
+	return func() { devicestateSystemModeInfoFromState = old }
+}
+
 func AddMockCommand(name string) *MockCommand {
 	return addMockCmd(name, false)
 }
@@ -88,3 +96,15 @@ func (c *MockCommand) Execute(args []string) error {
 
 	return nil
 }
+
+func MockContextDeadline(f func(*hookstate.Context) (time.Time, bool)) (restore func()) {
+	old := contextDeadline
+	contextDeadline = f
+	return func() { contextDeadline = old }
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() { timeNow = old }
+}
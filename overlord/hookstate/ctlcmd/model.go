@@ -0,0 +1,136 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var (
+	shortModelHelp = i18n.G("Get the active model for this device")
+	longModelHelp  = i18n.G(`
+The model command returns the active model assertion information for this
+device.
+
+By default, the brand-id, model and serial of the device are shown. With
+--assertion the raw model assertion is printed instead.
+
+Only snaps published by the brand of the device, as well as the gadget and
+kernel snaps of the model, are allowed to query the model.
+`)
+)
+
+func init() {
+	addCommand("model", shortModelHelp, longModelHelp, func() command { return &modelCommand{} })
+}
+
+type modelCommand struct {
+	baseCommand
+
+	Assertion bool `long:"assertion" description:"print the raw model assertion"`
+}
+
+// checkModelAccess verifies that the snap is allowed to see the model
+// assertion, that is, it is either the gadget or kernel of the model or
+// it is published by the brand of the model.
+func checkModelAccess(st *state.State, snapName string, model *asserts.Model) error {
+	info, err := snapstate.CurrentInfo(st, snapName)
+	if err != nil {
+		return err
+	}
+
+	switch info.GetType() {
+	case snap.TypeGadget:
+		if info.SnapName() == model.Gadget() {
+			return nil
+		}
+	case snap.TypeKernel:
+		if info.SnapName() == model.Kernel() {
+			return nil
+		}
+	}
+
+	if info.SnapID != "" {
+		snapDecl, err := assertstate.SnapDeclaration(st, info.SnapID)
+		if err != nil && !asserts.IsNotFound(err) {
+			return err
+		}
+		if snapDecl != nil && snapDecl.PublisherID() == model.BrandID() {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot get model assertion for snap %q: must be either a gadget or kernel snap of the model or published by the brand of the model", snapName)
+}
+
+func (c *modelCommand) Execute([]string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf(i18n.G("cannot %s without a context"), "get model")
+	}
+	context.Lock()
+	defer context.Unlock()
+
+	st := context.State()
+	task, _ := context.Task()
+	deviceCtx, err := devicestate.DeviceCtx(st, task, nil)
+	if err == state.ErrNoState {
+		return fmt.Errorf("cannot get model assertion: no model assertion yet")
+	}
+	if err != nil {
+		return err
+	}
+	model := deviceCtx.Model()
+
+	if err := checkModelAccess(st, context.InstanceName(), model); err != nil {
+		return err
+	}
+
+	if c.Assertion {
+		c.printf("%s", asserts.Encode(model))
+		return nil
+	}
+
+	serial := "-"
+	serialAs, err := devicestate.SerialFromState(st)
+	switch err {
+	case nil:
+		serial = serialAs.Serial()
+	case state.ErrNoState:
+		// device not registered yet
+	default:
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 5, 3, 2, ' ', 0)
+	fmt.Fprintf(w, "brand-id:\t%s\n", model.BrandID())
+	fmt.Fprintf(w, "model:\t%s\n", model.Model())
+	fmt.Fprintf(w, "serial:\t%s\n", serial)
+	return w.Flush()
+}
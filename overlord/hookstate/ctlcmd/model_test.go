@@ -0,0 +1,224 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type modelSuite struct {
+	testutil.BaseTest
+	st           *state.State
+	storeSigning *assertstest.StoreStack
+	brands       *assertstest.SigningAccounts
+	mockHandler  *hooktest.MockHandler
+}
+
+var _ = Suite(&modelSuite{})
+
+func (s *modelSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("/") })
+	s.AddCleanup(snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {}))
+
+	s.mockHandler = hooktest.NewMockHandler()
+	s.st = state.New(nil)
+
+	s.storeSigning = assertstest.NewStoreStack("canonical", nil)
+	s.brands = assertstest.NewSigningAccounts(s.storeSigning)
+	s.brands.Register("my-brand", brandPrivKey, nil)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:       asserts.NewMemoryBackstore(),
+		Trusted:         s.storeSigning.Trusted,
+		OtherPredefined: s.storeSigning.Generic,
+	})
+	c.Assert(err, IsNil)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	assertstate.ReplaceDB(s.st, db)
+
+	c.Assert(db.Add(s.storeSigning.StoreAccountKey("")), IsNil)
+	for _, a := range s.brands.AccountsAndKeys("my-brand") {
+		c.Assert(db.Add(a), IsNil)
+	}
+	c.Assert(db.Add(s.brands.Model("my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "pc-kernel",
+	})), IsNil)
+}
+
+var brandPrivKey, _ = assertstest.GenerateKey(752)
+
+func (s *modelSuite) mockSnap(c *C, name, snapType, publisherID string) {
+	si := &snap.SideInfo{RealName: name, Revision: snap.R(1), SnapID: name + "-id"}
+	snaptest.MockSnap(c, fmt.Sprintf("name: %s\nversion: 1\ntype: %s\n", name, snapType), si)
+	snapstate.Set(s.st, name, &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		SnapType: snapType,
+	})
+
+	if publisherID == "" {
+		return
+	}
+	if publisherID != "my-brand" {
+		acct := assertstest.NewAccount(s.storeSigning, publisherID, map[string]interface{}{
+			"account-id": publisherID,
+		}, "")
+		c.Assert(assertstate.Add(s.st, acct), IsNil)
+	}
+	snapDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      name + "-id",
+		"snap-name":    name,
+		"publisher-id": publisherID,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(assertstate.Add(s.st, snapDecl), IsNil)
+}
+
+func (s *modelSuite) mockContext(c *C, name string) *hookstate.Context {
+	task := s.st.NewTask("test-task", "my test task")
+	setup := &hookstate.HookSetup{Snap: name, Revision: snap.R(1), Hook: "install"}
+	ctx, err := hookstate.NewContext(task, s.st, setup, s.mockHandler, "")
+	c.Assert(err, IsNil)
+	return ctx
+}
+
+func (s *modelSuite) setDevice(c *C, serial string) {
+	c.Assert(devicestatetest.SetDevice(s.st, &auth.DeviceState{
+		Brand:  "my-brand",
+		Model:  "my-model",
+		Serial: serial,
+	}), IsNil)
+}
+
+func (s *modelSuite) TestModelNoContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"model"}, 0)
+	c.Check(err, ErrorMatches, "cannot get model without a context")
+}
+
+func (s *modelSuite) TestModelNoModelYet(c *C) {
+	s.st.Lock()
+	s.mockSnap(c, "pc", "gadget", "")
+	ctx := s.mockContext(c, "pc")
+	s.st.Unlock()
+
+	_, _, err := ctlcmd.Run(ctx, []string{"model"}, 0)
+	c.Check(err, ErrorMatches, "cannot get model assertion: no model assertion yet")
+}
+
+func (s *modelSuite) TestModelGadget(c *C) {
+	s.st.Lock()
+	s.setDevice(c, "")
+	s.mockSnap(c, "pc", "gadget", "")
+	ctx := s.mockContext(c, "pc")
+	s.st.Unlock()
+
+	stdout, stderr, err := ctlcmd.Run(ctx, []string{"model"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `brand-id:  my-brand
+model:     my-model
+serial:    -
+`)
+	c.Check(string(stderr), Equals, "")
+}
+
+func (s *modelSuite) TestModelSameBrandWithSerial(c *C) {
+	s.st.Lock()
+	s.setDevice(c, "serialserial")
+	devKey, _ := assertstest.GenerateKey(752)
+	encDevKey, err := asserts.EncodePublicKey(devKey.PublicKey())
+	c.Assert(err, IsNil)
+	serial, err := s.brands.Signing("my-brand").Sign(asserts.SerialType, map[string]interface{}{
+		"brand-id":            "my-brand",
+		"model":               "my-model",
+		"serial":              "serialserial",
+		"device-key":          string(encDevKey),
+		"device-key-sha3-384": devKey.PublicKey().ID(),
+		"timestamp":           time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(assertstate.Add(s.st, serial), IsNil)
+	s.mockSnap(c, "brand-app", "app", "my-brand")
+	ctx := s.mockContext(c, "brand-app")
+	s.st.Unlock()
+
+	stdout, _, err := ctlcmd.Run(ctx, []string{"model"}, 1000)
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `brand-id:  my-brand
+model:     my-model
+serial:    serialserial
+`)
+}
+
+func (s *modelSuite) TestModelAssertion(c *C) {
+	s.st.Lock()
+	s.setDevice(c, "")
+	s.mockSnap(c, "pc-kernel", "kernel", "")
+	ctx := s.mockContext(c, "pc-kernel")
+	s.st.Unlock()
+
+	stdout, _, err := ctlcmd.Run(ctx, []string{"model", "--assertion"}, 0)
+	c.Assert(err, IsNil)
+	a, err := asserts.Decode(stdout)
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.ModelType)
+	c.Check(a.HeaderString("model"), Equals, "my-model")
+}
+
+func (s *modelSuite) TestModelOtherPublisherForbidden(c *C) {
+	s.st.Lock()
+	s.setDevice(c, "")
+	s.mockSnap(c, "other-app", "app", "other-publisher")
+	s.mockSnap(c, "other-gadget", "gadget", "")
+	ctx1 := s.mockContext(c, "other-app")
+	ctx2 := s.mockContext(c, "other-gadget")
+	s.st.Unlock()
+
+	_, _, err := ctlcmd.Run(ctx1, []string{"model"}, 0)
+	c.Check(err, ErrorMatches, `cannot get model assertion for snap "other-app": must be either a gadget or kernel snap of the model or published by the brand of the model`)
+	_, _, err = ctlcmd.Run(ctx2, []string{"model"}, 0)
+	c.Check(err, ErrorMatches, `cannot get model assertion for snap "other-gadget": .*`)
+}
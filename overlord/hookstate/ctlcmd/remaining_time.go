@@ -0,0 +1,76 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/hookstate"
+)
+
+var (
+	shortRemainingTimeHelp = i18n.G("Print the time left before the running hook is killed")
+	longRemainingTimeHelp  = i18n.G(`
+The remaining-time command prints the number of whole seconds left before
+the running hook exceeds its timeout and gets killed. Long running hooks can
+use it to decide when to checkpoint their work.
+
+The timeout of a hook can be declared in snap.yaml, e.g.:
+
+    hooks:
+      post-refresh:
+        timeout: 30m
+`)
+)
+
+var (
+	contextDeadline = (*hookstate.Context).Deadline
+	timeNow         = time.Now
+)
+
+func init() {
+	addCommand("remaining-time", shortRemainingTimeHelp, longRemainingTimeHelp, func() command { return &remainingTimeCommand{} })
+}
+
+type remainingTimeCommand struct {
+	baseCommand
+}
+
+func (c *remainingTimeCommand) Execute([]string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf(i18n.G("cannot %s without a context"), "get remaining time")
+	}
+
+	deadline, ok := contextDeadline(context)
+	if !ok {
+		return errors.New(i18n.G("cannot get remaining time: not running from a hook"))
+	}
+
+	remaining := deadline.Sub(timeNow())
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.printf("%d\n", remaining/time.Second)
+	return nil
+}
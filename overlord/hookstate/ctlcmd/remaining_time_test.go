@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type remainingTimeSuite struct {
+	testutil.BaseTest
+	mockContext *hookstate.Context
+	now         time.Time
+}
+
+var _ = Suite(&remainingTimeSuite{})
+
+func (s *remainingTimeSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	task := st.NewTask("test-task", "my test task")
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "post-refresh"}
+	ctx, err := hookstate.NewContext(task, st, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, IsNil)
+	s.mockContext = ctx
+
+	s.now = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	s.AddCleanup(ctlcmd.MockTimeNow(func() time.Time { return s.now }))
+}
+
+func (s *remainingTimeSuite) TestRemainingTimeNoContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"remaining-time"}, 0)
+	c.Check(err, ErrorMatches, "cannot get remaining time without a context")
+}
+
+func (s *remainingTimeSuite) TestRemainingTimeNotInHook(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"remaining-time"}, 0)
+	c.Check(err, ErrorMatches, "cannot get remaining time: not running from a hook")
+}
+
+func (s *remainingTimeSuite) TestRemainingTime(c *C) {
+	for _, t := range []struct {
+		deadline time.Time
+		output   string
+	}{
+		{s.now.Add(10*time.Minute + 500*time.Millisecond), "600\n"},
+		{s.now.Add(time.Second), "1\n"},
+		{s.now.Add(-time.Second), "0\n"},
+	} {
+		restore := ctlcmd.MockContextDeadline(func(ctx *hookstate.Context) (time.Time, bool) {
+			c.Check(ctx, Equals, s.mockContext)
+			return t.deadline, true
+		})
+		defer restore()
+
+		stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"remaining-time"}, 1000)
+		c.Assert(err, IsNil)
+		c.Check(string(stdout), Equals, t.output)
+		c.Check(string(stderr), Equals, "")
+	}
+}
@@ -21,9 +21,12 @@ package ctlcmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/overlord/configstate"
@@ -34,6 +37,9 @@ import (
 type setCommand struct {
 	baseCommand
 
+	File    string   `long:"file"`
+	Replace []string `long:"replace"`
+
 	Positional struct {
 		PlugOrSlotSpec string   `positional-arg-name:":<plug|slot>"`
 		ConfValues     []string `positional-arg-name:"key=value"`
@@ -60,6 +66,13 @@ Plug and slot attributes may be set in the respective prepare and connect hooks
 naming the respective plug or slot:
 
     $ snapctl set :myplug path=/dev/ttyS0
+
+Larger configurations may be read from a YAML or JSON file, or from
+standard input when the file is "-". The options in the file are merged
+into the existing configuration, except under the keys given with --replace,
+which are replaced as a whole:
+
+    $ snapctl set --file=$SNAP/defaults.yaml --replace=server.tls
 `)
 
 func init() {
@@ -67,15 +80,22 @@ func init() {
 }
 
 func (s *setCommand) Execute(args []string) error {
-	if s.Positional.PlugOrSlotSpec == "" && len(s.Positional.ConfValues) == 0 {
+	if s.Positional.PlugOrSlotSpec == "" && len(s.Positional.ConfValues) == 0 && s.File == "" {
 		return fmt.Errorf(i18n.G("set which option?"))
 	}
+	if len(s.Replace) > 0 && s.File == "" {
+		return errors.New(i18n.G("cannot use --replace without --file"))
+	}
 
 	context := s.context()
 	if context == nil {
 		return fmt.Errorf("cannot set without a context")
 	}
 
+	if s.Positional.PlugOrSlotSpec == "" {
+		return s.setConfigSetting(context)
+	}
+
 	// treat PlugOrSlotSpec argument as key=value if it contans '=' or doesn't contain ':' - this is to support
 	// values such as "device-service.url=192.168.0.1:5555" and error out on invalid key=value if only "key" is given.
 	if strings.Contains(s.Positional.PlugOrSlotSpec, "=") || !strings.Contains(s.Positional.PlugOrSlotSpec, ":") {
@@ -92,6 +112,9 @@ func (s *setCommand) Execute(args []string) error {
 	if snap != "" {
 		return fmt.Errorf(`"snapctl set %s" not supported, use "snapctl set :%s" instead`, s.Positional.PlugOrSlotSpec, parts[1])
 	}
+	if s.File != "" {
+		return errors.New(i18n.G("cannot use --file with interface attributes"))
+	}
 	return s.setInterfaceSetting(context, name)
 }
 
@@ -100,6 +123,27 @@ func (s *setCommand) setConfigSetting(context *hookstate.Context) error {
 	tr := configstate.ContextTransaction(context)
 	context.Unlock()
 
+	if s.File != "" {
+		data := s.stdin()
+		if data == nil {
+			return fmt.Errorf(i18n.G("cannot read configuration file %q: no data received"), s.File)
+		}
+		patch, err := client.ConfPatchFromDocument(data, s.Replace)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(patch))
+		for key := range patch {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := tr.Set(context.InstanceName(), key, patch[key]); err != nil {
+				return err
+			}
+		}
+	}
+
 	for _, patchValue := range s.Positional.ConfValues {
 		parts := strings.SplitN(patchValue, "=", 2)
 		if len(parts) == 1 && strings.HasSuffix(patchValue, "!") {
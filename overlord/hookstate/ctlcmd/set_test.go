@@ -99,6 +99,54 @@ func (s *setSuite) TestCommand(c *C) {
 	c.Check(value, Equals, "qux")
 }
 
+func (s *setSuite) TestCommandFile(c *C) {
+	s.mockContext.State().Lock()
+	tr := config.NewTransaction(s.mockContext.State())
+	c.Assert(tr.Set("test-snap", "server.tls.key", "old-key"), IsNil)
+	c.Assert(tr.Set("test-snap", "server.host", "localhost"), IsNil)
+	tr.Commit()
+	s.mockContext.State().Unlock()
+
+	s.mockContext.Lock()
+	ctlcmd.SetStdin(s.mockContext, []byte("server:\n  port: 8080\n  tls:\n    cert: foo\nmode: fast\n"))
+	s.mockContext.Unlock()
+
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "--file=config.yaml", "--replace=server.tls", "mode=slow"}, 0)
+	c.Assert(err, IsNil)
+
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	c.Check(s.mockContext.Done(), IsNil)
+
+	tr = config.NewTransaction(s.mockContext.State())
+	var value interface{}
+	c.Assert(tr.Get("test-snap", "", &value), IsNil)
+	c.Check(value, DeepEquals, map[string]interface{}{
+		"server": map[string]interface{}{
+			"host": "localhost",
+			"port": json.Number("8080"),
+			"tls":  map[string]interface{}{"cert": "foo"},
+		},
+		"mode": "slow",
+	})
+}
+
+func (s *setSuite) TestCommandFileErrors(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "--file=config.yaml"}, 0)
+	c.Check(err, ErrorMatches, `cannot read configuration file "config.yaml": no data received`)
+
+	s.mockContext.Lock()
+	ctlcmd.SetStdin(s.mockContext, []byte("- foo\n"))
+	s.mockContext.Unlock()
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"set", "--file=config.yaml"}, 0)
+	c.Check(err, ErrorMatches, `cannot parse configuration document: expected a map of options`)
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"set", "--replace=foo", "foo=bar"}, 0)
+	c.Check(err, ErrorMatches, `cannot use --replace without --file`)
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"set", "--file=-", ":plug", "foo=bar"}, 0)
+	c.Check(err, ErrorMatches, `cannot use --file with interface attributes`)
+}
+
 func (s *setSuite) TestSetRegularUserForbidden(c *C) {
 	_, _, err := ctlcmd.Run(s.mockContext, []string{"set", "test-key1"}, 1000)
 	c.Assert(err, ErrorMatches, `cannot use "set" with uid 1000, try with sudo`)
@@ -0,0 +1,71 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/devicestate"
+)
+
+var (
+	shortSystemModeHelp = i18n.G("Get the current system mode and associated details")
+	longSystemModeHelp  = i18n.G(`
+The system-mode command returns information about the device's current system
+mode.
+
+This information includes:
+ - the system mode (e.g. run, install or recover)
+ - whether the seed has been loaded
+`)
+)
+
+var devicestateSystemModeInfoFromState = devicestate.SystemModeInfoFromState
+
+func init() {
+	addCommand("system-mode", shortSystemModeHelp, longSystemModeHelp, func() command { return &systemModeCommand{} })
+}
+
+type systemModeCommand struct {
+	baseCommand
+}
+
+func (c *systemModeCommand) Execute([]string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf(i18n.G("cannot %s without a context"), "get system mode")
+	}
+	context.Lock()
+	defer context.Unlock()
+
+	smi, err := devicestateSystemModeInfoFromState(context.State())
+	if err != nil {
+		return err
+	}
+
+	c.printf("system-mode: %s\n", smi.Mode)
+	if smi.Seeded {
+		c.printf("seed-loaded: true\n")
+	} else {
+		c.printf("seed-loaded: false\n")
+	}
+	return nil
+}
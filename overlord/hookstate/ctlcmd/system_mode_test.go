@@ -0,0 +1,91 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type systemModeSuite struct {
+	testutil.BaseTest
+	st          *state.State
+	mockContext *hookstate.Context
+}
+
+var _ = Suite(&systemModeSuite{})
+
+func (s *systemModeSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.st = state.New(nil)
+	s.st.Lock()
+	defer s.st.Unlock()
+	task := s.st.NewTask("test-task", "my test task")
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "install"}
+	ctx, err := hookstate.NewContext(task, s.st, setup, hooktest.NewMockHandler(), "")
+	c.Assert(err, IsNil)
+	s.mockContext = ctx
+}
+
+func (s *systemModeSuite) TestSystemModeNoContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"system-mode"}, 0)
+	c.Check(err, ErrorMatches, "cannot get system mode without a context")
+}
+
+func (s *systemModeSuite) TestSystemMode(c *C) {
+	for _, smi := range []*devicestate.SystemModeInfo{
+		{Mode: "run", Seeded: true},
+		{Mode: "install", HasModeenv: true, Seeded: false},
+		{Mode: "recover", HasModeenv: true, Seeded: true},
+	} {
+		restore := ctlcmd.MockDevicestateSystemModeInfoFromState(func(st *state.State) (*devicestate.SystemModeInfo, error) {
+			c.Check(st, Equals, s.st)
+			return smi, nil
+		})
+		defer restore()
+
+		for _, uid := range []uint32{0, 1000} {
+			stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"system-mode"}, uid)
+			c.Assert(err, IsNil)
+			c.Check(string(stdout), Equals, fmt.Sprintf("system-mode: %s\nseed-loaded: %v\n", smi.Mode, smi.Seeded))
+			c.Check(string(stderr), Equals, "")
+		}
+	}
+}
+
+func (s *systemModeSuite) TestSystemModeError(c *C) {
+	restore := ctlcmd.MockDevicestateSystemModeInfoFromState(func(*state.State) (*devicestate.SystemModeInfo, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"system-mode"}, 0)
+	c.Check(err, ErrorMatches, "boom")
+}
@@ -44,3 +44,21 @@ func MockErrtrackerReport(mock func(string, string, string, map[string]string) (
 	errtrackerReport = mock
 	return func() { errtrackerReport = prev }
 }
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() { timeNow = old }
+}
+
+func MockMaxHookRuns(n int) (restore func()) {
+	old := maxHookRuns
+	maxHookRuns = n
+	return func() { maxHookRuns = old }
+}
+
+func MockMaxHookRunOutput(n int) (restore func()) {
+	old := maxHookRunOutput
+	maxHookRunOutput = n
+	return func() { maxHookRunOutput = old }
+}
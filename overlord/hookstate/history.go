@@ -0,0 +1,156 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hookstate
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// HookRun describes a past run of a hook of a snap.
+type HookRun struct {
+	Hook     string        `json:"hook"`
+	Revision snap.Revision `json:"revision"`
+	// ChangeID is the ID of the change that triggered the hook.
+	ChangeID string    `json:"change-id,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	// ExitStatus is the exit status of the hook, or -1 if the hook did
+	// not exit on its own, e.g. because it was killed on timeout.
+	ExitStatus int    `json:"exit-status"`
+	Error      string `json:"error,omitempty"`
+	// Output is the tail of the combined stdout and stderr of the hook.
+	Output string `json:"output,omitempty"`
+}
+
+var (
+	// maxHookRuns is the number of hook runs remembered for every snap,
+	// older runs are dropped.
+	maxHookRuns = 10
+	// maxHookRunOutput is the number of bytes of output kept for every
+	// hook run.
+	maxHookRunOutput = 4096
+)
+
+func getHookRuns(st *state.State) (map[string][]*HookRun, error) {
+	var runs map[string][]*HookRun // snap => runs
+	err := st.Get("hook-runs", &runs)
+	if err == state.ErrNoState {
+		return make(map[string][]*HookRun), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot unmarshal hook runs: %v", err)
+	}
+	return runs, nil
+}
+
+// HookRuns returns the recent hook runs of the given snap, oldest first.
+//
+// The caller is responsible for locking the state.
+func HookRuns(st *state.State, snapName string) ([]*HookRun, error) {
+	runs, err := getHookRuns(st)
+	if err != nil {
+		return nil, err
+	}
+	return runs[snapName], nil
+}
+
+func recordHookRun(st *state.State, snapName string, run *HookRun) error {
+	runs, err := getHookRuns(st)
+	if err != nil {
+		return err
+	}
+	snapRuns := append(runs[snapName], run)
+	if len(snapRuns) > maxHookRuns {
+		snapRuns = snapRuns[len(snapRuns)-maxHookRuns:]
+	}
+	runs[snapName] = snapRuns
+	st.Set("hook-runs", runs)
+	return nil
+}
+
+// DiscardHookRuns forgets the recorded hook runs of the given snap, once
+// the snap is removed.
+//
+// The caller is responsible for locking the state.
+func DiscardHookRuns(st *state.State, snapName string) error {
+	runs, err := getHookRuns(st)
+	if err != nil {
+		return err
+	}
+	if _, ok := runs[snapName]; !ok {
+		return nil
+	}
+	delete(runs, snapName)
+	if len(runs) == 0 {
+		st.Set("hook-runs", nil)
+		return nil
+	}
+	st.Set("hook-runs", runs)
+	return nil
+}
+
+// newHookRun builds the record of a finished run of the hook in the given
+// context.
+//
+// The caller is responsible for locking the state.
+func newHookRun(context *Context, start, end time.Time, output []byte, runErr error) *HookRun {
+	run := &HookRun{
+		Hook:     context.HookName(),
+		Revision: context.SnapRevision(),
+		Start:    start,
+		End:      end,
+	}
+	if task, ok := context.Task(); ok {
+		if chg := task.Change(); chg != nil {
+			run.ChangeID = chg.ID()
+		}
+	}
+	if runErr != nil {
+		run.Error = runErr.Error()
+		exitStatus, err := osutil.ExitCode(runErr)
+		if err != nil {
+			exitStatus = -1
+		}
+		run.ExitStatus = exitStatus
+	}
+	run.Output = string(outputTail(output, maxHookRunOutput))
+	return run
+}
+
+// outputTail returns at most the last max bytes of output, without
+// splitting a UTF-8 encoded character.
+func outputTail(output []byte, max int) []byte {
+	if len(output) <= max {
+		return output
+	}
+	output = output[len(output)-max:]
+	for i := 0; i < len(output) && i < utf8.UTFMax; i++ {
+		if utf8.RuneStart(output[i]) {
+			return output[i:]
+		}
+	}
+	return output
+}
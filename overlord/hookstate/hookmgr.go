@@ -85,6 +85,10 @@ type HookSetup struct {
 	Always      bool          `json:"always,omitempty"`       // run handler even if script is missing
 	IgnoreError bool          `json:"ignore-error,omitempty"` // do not run handler's Error() on error
 	TrackError  bool          `json:"track-error,omitempty"`  // report hook error to oopsie
+
+	// DefaultTimeout is used when neither the caller nor the snap ask
+	// for a specific timeout.
+	DefaultTimeout time.Duration `json:"default-timeout,omitempty"`
 }
 
 // Manager returns a new HookManager.
@@ -296,10 +300,19 @@ func (m *HookManager) runHook(task *state.Task, tomb *tomb.Tomb, snapst *snapsta
 			return fmt.Errorf("cannot read %q snap details: %v", hooksup.Snap, err)
 		}
 
-		hookExists = info.Hooks[hooksup.Hook] != nil
+		hook := info.Hooks[hooksup.Hook]
+		hookExists = hook != nil
 		if !hookExists && !hooksup.Optional {
 			return fmt.Errorf("snap %q has no %q hook", hooksup.Snap, hooksup.Hook)
 		}
+		// a timeout declared by the snap itself is used unless the
+		// caller asked for a specific one
+		if hookExists && hook.Timeout != 0 && hooksup.Timeout == 0 {
+			hooksup.Timeout = time.Duration(hook.Timeout)
+		}
+	}
+	if hooksup.Timeout == 0 {
+		hooksup.Timeout = hooksup.DefaultTimeout
 	}
 
 	if hookExists || mustHijack {
@@ -341,6 +354,12 @@ func (m *HookManager) runHook(task *state.Task, tomb *tomb.Tomb, snapst *snapsta
 	}
 	context.handler = handlers[0]
 
+	timeout := hooksup.Timeout
+	if timeout == 0 {
+		timeout = defaultHookTimeout
+	}
+	context.deadline = timeNow().Add(timeout)
+
 	contextID := context.ID()
 	m.contextsMutex.Lock()
 	m.contexts[contextID] = context
@@ -361,7 +380,16 @@ func (m *HookManager) runHook(task *state.Task, tomb *tomb.Tomb, snapst *snapsta
 	if f := m.hijacked(hooksup.Hook, hooksup.Snap); f != nil {
 		err = f(context)
 	} else if hookExists {
+		start := timeNow()
 		output, err = runHook(context, tomb)
+		end := timeNow()
+		task.State().Lock()
+		run := newHookRun(context, start, end, output, err)
+		recordErr := recordHookRun(task.State(), hooksup.Snap, run)
+		task.State().Unlock()
+		if recordErr != nil {
+			logger.Noticef("cannot record run of hook %q of snap %q: %v", hooksup.Hook, hooksup.Snap, recordErr)
+		}
 	}
 	if err != nil {
 		if hooksup.TrackError {
@@ -434,6 +462,8 @@ func snapCmd() string {
 
 var defaultHookTimeout = 10 * time.Minute
 
+var timeNow = time.Now
+
 func runHookAndWait(snapName string, revision snap.Revision, hookName, hookContext string, timeout time.Duration, tomb *tomb.Tomb) ([]byte, error) {
 	argv := []string{snapCmd(), "run", "--hook", hookName, "-r", revision.String(), snapName}
 	if timeout == 0 {
@@ -31,6 +31,7 @@ func init() {
 	snapstate.SetupPreRefreshHook = SetupPreRefreshHook
 	snapstate.SetupPostRefreshHook = SetupPostRefreshHook
 	snapstate.SetupRemoveHook = SetupRemoveHook
+	snapstate.DiscardHookRuns = DiscardHookRuns
 }
 
 func SetupInstallHook(st *state.State, snapName string) *state.Task {
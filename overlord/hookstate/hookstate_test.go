@@ -29,6 +29,7 @@ import (
 	"time"
 
 	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord"
@@ -135,6 +136,12 @@ type hookManagerSuite struct {
 	baseHookManagerSuite
 }
 
+// mockSnapYaml replaces the snap.yaml of the current revision of test-snap.
+func (s *hookManagerSuite) mockSnapYaml(c *C, yaml string) {
+	sideInfo := &snap.SideInfo{RealName: "test-snap", SnapID: "some-snap-id", Revision: snap.R(1)}
+	snaptest.MockSnap(c, yaml, sideInfo)
+}
+
 var _ = Suite(&hookManagerSuite{})
 
 var snapYaml = `
@@ -489,6 +496,328 @@ func (s *hookManagerSuite) TestHookTaskEnforcesDefaultTimeout(c *C) {
 	checkTaskLogContains(c, s.task, `.*exceeded maximum runtime of 150ms`)
 }
 
+func (s *hookManagerSuite) TestHookTaskEnforcesSnapDeclaredTimeout(c *C) {
+	s.mockSnapYaml(c, `
+name: test-snap
+version: 1.0
+hooks:
+    configure:
+        timeout: 250ms
+`)
+
+	restore := hookstate.MockDefaultHookTimeout(time.Hour)
+	defer restore()
+
+	// Force the snap command to hang
+	cmd := testutil.MockCommand(c, "snap", "while true; do sleep 1; done")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.mockHandler.ErrorCalled, Equals, true)
+	c.Check(s.mockHandler.Err, ErrorMatches, `.*exceeded maximum runtime of 250ms.*`)
+	c.Check(s.task.Status(), Equals, state.ErrorStatus)
+	checkTaskLogContains(c, s.task, `.*exceeded maximum runtime of 250ms`)
+}
+
+func (s *hookManagerSuite) TestHookTaskCallerTimeoutOverridesSnapDeclaredTimeout(c *C) {
+	s.mockSnapYaml(c, `
+name: test-snap
+version: 1.0
+hooks:
+    configure:
+        timeout: 1h
+`)
+
+	// the timeout requested by the caller wins over the one declared
+	// by the snap
+	var hooksup hookstate.HookSetup
+	s.state.Lock()
+	s.task.Get("hook-setup", &hooksup)
+	hooksup.Timeout = 250 * time.Millisecond
+	s.task.Set("hook-setup", &hooksup)
+	s.state.Unlock()
+
+	// Force the snap command to hang
+	cmd := testutil.MockCommand(c, "snap", "while true; do sleep 1; done")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.mockHandler.ErrorCalled, Equals, true)
+	c.Check(s.mockHandler.Err, ErrorMatches, `.*exceeded maximum runtime of 250ms.*`)
+	c.Check(s.task.Status(), Equals, state.ErrorStatus)
+	checkTaskLogContains(c, s.task, `.*exceeded maximum runtime of 250ms`)
+}
+
+func (s *hookManagerSuite) TestHookTaskSnapDeclaredTimeoutOverridesDefaultTimeout(c *C) {
+	s.mockSnapYaml(c, `
+name: test-snap
+version: 1.0
+hooks:
+    configure:
+        timeout: 250ms
+`)
+
+	// the timeout declared by the snap wins over the default one
+	// requested by the caller
+	var hooksup hookstate.HookSetup
+	s.state.Lock()
+	s.task.Get("hook-setup", &hooksup)
+	hooksup.DefaultTimeout = time.Hour
+	s.task.Set("hook-setup", &hooksup)
+	s.state.Unlock()
+
+	// Force the snap command to hang
+	cmd := testutil.MockCommand(c, "snap", "while true; do sleep 1; done")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.mockHandler.ErrorCalled, Equals, true)
+	c.Check(s.mockHandler.Err, ErrorMatches, `.*exceeded maximum runtime of 250ms.*`)
+	c.Check(s.task.Status(), Equals, state.ErrorStatus)
+	checkTaskLogContains(c, s.task, `.*exceeded maximum runtime of 250ms`)
+}
+
+func (s *hookManagerSuite) TestHookTaskEnforcesCallerDefaultTimeout(c *C) {
+	var hooksup hookstate.HookSetup
+	s.state.Lock()
+	s.task.Get("hook-setup", &hooksup)
+	hooksup.DefaultTimeout = 250 * time.Millisecond
+	s.task.Set("hook-setup", &hooksup)
+	s.state.Unlock()
+
+	restore := hookstate.MockDefaultHookTimeout(time.Hour)
+	defer restore()
+
+	// Force the snap command to hang
+	cmd := testutil.MockCommand(c, "snap", "while true; do sleep 1; done")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.mockHandler.ErrorCalled, Equals, true)
+	c.Check(s.mockHandler.Err, ErrorMatches, `.*exceeded maximum runtime of 250ms.*`)
+	c.Check(s.task.Status(), Equals, state.ErrorStatus)
+}
+
+func (s *hookManagerSuite) TestHookContextDeadline(c *C) {
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	restore := hookstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.mockSnapYaml(c, `
+name: test-snap
+version: 1.0
+hooks:
+    configure:
+        timeout: 1h
+`)
+
+	var deadline time.Time
+	var running bool
+	restore = hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		c.Check(ctx.Timeout(), Equals, time.Hour)
+		deadline, running = ctx.Deadline()
+		return nil, nil
+	})
+	defer restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.task.Status(), Equals, state.DoneStatus)
+	c.Check(running, Equals, true)
+	c.Check(deadline.Equal(now.Add(time.Hour)), Equals, true)
+}
+
+func (s *hookManagerSuite) TestHookContextDeadlineDefaultTimeout(c *C) {
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	restore := hookstate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	restore = hookstate.MockDefaultHookTimeout(3 * time.Minute)
+	defer restore()
+
+	var deadline time.Time
+	restore = hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		deadline, _ = ctx.Deadline()
+		return nil, nil
+	})
+	defer restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	c.Check(deadline.Equal(now.Add(3*time.Minute)), Equals, true)
+}
+
+func (s *hookManagerSuite) TestHookRunRecorded(c *C) {
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	restore := hookstate.MockTimeNow(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})
+	defer restore()
+
+	cmd := testutil.MockCommand(c, "snap", "echo 'setting things up'; echo 'oops' >&2; exit 3")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.task.Status(), Equals, state.ErrorStatus)
+
+	runs, err := hookstate.HookRuns(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(runs, HasLen, 1)
+	run := runs[0]
+	c.Check(run.Hook, Equals, "configure")
+	c.Check(run.Revision, Equals, snap.R(1))
+	c.Check(run.ChangeID, Equals, s.change.ID())
+	c.Check(run.End.Sub(run.Start), Equals, time.Second)
+	c.Check(run.ExitStatus, Equals, 3)
+	c.Check(run.Error, Equals, "exit status 3")
+	c.Check(run.Output, Equals, "setting things up\noops\n")
+
+	runs, err = hookstate.HookRuns(s.state, "other-snap")
+	c.Assert(err, IsNil)
+	c.Check(runs, HasLen, 0)
+}
+
+func (s *hookManagerSuite) TestHookRunRecordedOnTimeout(c *C) {
+	restore := hookstate.MockDefaultHookTimeout(100 * time.Millisecond)
+	defer restore()
+
+	cmd := testutil.MockCommand(c, "snap", "while true; do sleep 1; done")
+	defer cmd.Restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	runs, err := hookstate.HookRuns(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(runs, HasLen, 1)
+	c.Check(runs[0].ExitStatus, Equals, -1)
+	c.Check(runs[0].Error, Equals, "exceeded maximum runtime of 100ms")
+}
+
+func (s *hookManagerSuite) TestHookRunsBounded(c *C) {
+	restore := hookstate.MockMaxHookRuns(2)
+	defer restore()
+	restore = hookstate.MockMaxHookRunOutput(4)
+	defer restore()
+
+	n := 0
+	restore = hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		n++
+		return []byte(fmt.Sprintf("run %d", n)), nil
+	})
+	defer restore()
+
+	for i := 0; i < 3; i++ {
+		s.state.Lock()
+		task := hookstate.HookTask(s.state, "test summary", &hookstate.HookSetup{Snap: "test-snap", Hook: "configure", Revision: snap.R(1)}, nil)
+		s.state.NewChange("kind", "summary").AddTask(task)
+		s.state.Unlock()
+	}
+
+	// hooks of the same snap run one at a time
+	for i := 0; i < 4; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	runs, err := hookstate.HookRuns(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(runs, HasLen, 2)
+	c.Check(runs[0].ExitStatus, Equals, 0)
+	c.Check(runs[0].Error, Equals, "")
+	// only the tail of the output is kept
+	c.Check(runs[0].Output, Equals, "un 3")
+	c.Check(runs[1].Output, Equals, "un 4")
+}
+
+func (s *hookManagerSuite) TestHookRunOutputTruncatedAtRuneBoundary(c *C) {
+	restore := hookstate.MockMaxHookRunOutput(4)
+	defer restore()
+
+	restore = hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		// the last 4 bytes start in the middle of "é"
+		return []byte("caf\u00e9\u00e9!"), nil
+	})
+	defer restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	runs, err := hookstate.HookRuns(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(runs, HasLen, 1)
+	c.Check(runs[0].Output, Equals, "\u00e9!")
+}
+
+func (s *hookManagerSuite) TestDiscardHookRuns(c *C) {
+	restore := hookstate.MockRunHook(func(ctx *hookstate.Context, tomb *tomb.Tomb) ([]byte, error) {
+		return nil, nil
+	})
+	defer restore()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	runs, err := hookstate.HookRuns(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Assert(runs, HasLen, 1)
+
+	// nothing to do for snaps without runs
+	c.Assert(snapstate.DiscardHookRuns(s.state, "other-snap"), IsNil)
+	runs, err = hookstate.HookRuns(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(runs, HasLen, 1)
+
+	c.Assert(snapstate.DiscardHookRuns(s.state, "test-snap"), IsNil)
+	runs, err = hookstate.HookRuns(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(runs, HasLen, 0)
+
+	var raw interface{}
+	c.Check(s.state.Get("hook-runs", &raw), Equals, state.ErrNoState)
+}
+
 func (s *hookManagerSuite) TestHookTaskEnforcedTimeoutWithIgnoreError(c *C) {
 	var hooksup hookstate.HookSetup
 
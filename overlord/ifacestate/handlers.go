@@ -501,6 +501,10 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		logger.Debugf("Connect handler: skipping setupSnapSecurity for snaps %q and %q", plug.Snap.InstanceName(), slot.Snap.InstanceName())
 	}
 
+	if err := ensureConnectedServicesOrder(st, snapAndPlugOrSlot{plugRef.Snap, plugRef.Name}, snapAndPlugOrSlot{slotRef.Snap, slotRef.Name}); err != nil {
+		return err
+	}
+
 	conns[connRef.ID()] = &connState{
 		Interface:        conn.Interface(),
 		StaticPlugAttrs:  conn.Plug.StaticAttrs(),
@@ -565,6 +569,9 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 			return err
 		}
 	}
+	if err := ensureConnectedServicesOrder(st, snapAndPlugOrSlot{plugRef.Snap, plugRef.Name}, snapAndPlugOrSlot{slotRef.Snap, slotRef.Name}); err != nil {
+		return err
+	}
 
 	cref := interfaces.ConnRef{PlugRef: plugRef, SlotRef: slotRef}
 	conn, ok := conns[cref.ID()]
@@ -668,6 +675,9 @@ func (m *InterfaceManager) undoDisconnect(task *state.Task, _ *tomb.Tomb) error
 	if err := m.setupSnapSecurity(task, plug.Snap, plugOpts, perfTimings); err != nil {
 		return err
 	}
+	if err := ensureConnectedServicesOrder(st, snapAndPlugOrSlot{plugRef.Snap, plugRef.Name}, snapAndPlugOrSlot{slotRef.Snap, slotRef.Name}); err != nil {
+		return err
+	}
 
 	conns[connRef.ID()] = &oldconn
 	setConns(st, conns)
@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
 	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
+	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/timings"
@@ -124,6 +125,11 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 		return false
 	})
 
+	// order the services of connected snaps against each other
+	servicestate.ConnectedServices = func(st *state.State, instanceName string) (map[string][]*snap.AppInfo, error) {
+		return connectedServices(m.repo, instanceName)
+	}
+
 	return m, nil
 }
 
@@ -279,6 +285,15 @@ func (m *InterfaceManager) ConnectionStates() (connStateByRef map[string]Connect
 	return connStateByRef, nil
 }
 
+// HotplugSlots returns the hotplug slots tracked by the manager, indexed by
+// slot name. This includes the slots of unplugged devices whose connections
+// are remembered, so that they are restored when the device re-appears.
+func (m *InterfaceManager) HotplugSlots() (map[string]*HotplugSlotInfo, error) {
+	m.state.Lock()
+	defer m.state.Unlock()
+	return getHotplugSlots(m.state)
+}
+
 // DisableUDevMonitor disables the instantiation of udev monitor, but has no effect
 // if udev is already created; it should be called after creating InterfaceManager, before
 // first Ensure.
@@ -37,8 +37,11 @@ import (
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/policy"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
@@ -54,6 +57,7 @@ import (
 	seccomp_compiler "github.com/snapcore/snapd/sandbox/seccomp"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
 )
@@ -1317,6 +1321,219 @@ slots:
 	check(change)
 }
 
+func (s *interfaceManagerSuite) TestTraceSnapPolicy(c *C) {
+	s.MockModel(c, nil)
+
+	restore := assertstest.MockBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+slots:
+  test:
+    allow-connection:
+      plug-publisher-id:
+        - $SLOT_PUBLISHER_ID
+`))
+	defer restore()
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+
+	s.MockSnapDecl(c, "consumer", "consumer-publisher", nil)
+	s.mockSnap(c, consumerYaml)
+	s.MockSnapDecl(c, "producer", "producer-publisher", nil)
+	s.mockSnap(c, producerYaml)
+	// no snap-declaration
+	s.mockSnap(c, producer2Yaml)
+
+	_ = s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	sp, err := ifacestate.TraceSnapPolicy(s.state, "consumer")
+	c.Assert(err, IsNil)
+	c.Check(sp.Snap, Equals, "consumer")
+
+	// no rules for the plugs
+	c.Check(sp.Installation, DeepEquals, []*ifacestate.InstallationPolicy{
+		{Side: "plug", Name: "otherplug", Interface: "test2", Check: ifacestate.PolicyCheck{Allowed: true}},
+		{Side: "plug", Name: "plug", Interface: "test", Check: ifacestate.PolicyCheck{Allowed: true}},
+	})
+
+	c.Assert(sp.Connections, HasLen, 2)
+	cp := sp.Connections[0]
+	c.Check(cp.Plug, Equals, interfaces.PlugRef{Snap: "consumer", Name: "plug"})
+	c.Check(cp.Slot, Equals, interfaces.SlotRef{Snap: "producer", Name: "slot"})
+	c.Check(cp.Interface, Equals, "test")
+	c.Check(cp.Connection.Allowed, Equals, false)
+	c.Check(cp.Connection.Error, Equals, `connection not allowed by slot rule of interface "test"`)
+	c.Check(cp.Connection.Trace, DeepEquals, []policy.TraceStep{
+		{Declaration: "base-declaration", Side: "slot", Name: "slot", Interface: "test", Constraints: "deny-connection", Reason: cp.Connection.Trace[0].Reason},
+		{Declaration: "base-declaration", Side: "slot", Name: "slot", Interface: "test", Constraints: "allow-connection", Reason: "publisher id does not match"},
+	})
+	// the rule has no auto-connection constraints
+	c.Check(cp.AutoConnection.Allowed, Equals, true)
+	c.Assert(cp.AutoConnection.Trace, HasLen, 2)
+	c.Check(cp.AutoConnection.Trace[1].Constraints, Equals, "allow-auto-connection")
+	c.Check(cp.AutoConnection.Trace[1].Matched, Equals, true)
+
+	cp = sp.Connections[1]
+	c.Check(cp.Slot, Equals, interfaces.SlotRef{Snap: "producer2", Name: "slot"})
+	c.Check(cp.Connection, DeepEquals, ifacestate.PolicyCheck{
+		Allowed: true,
+		Skipped: `snap "producer2" has no snap-declaration, it was installed with --dangerous`,
+	})
+
+	_, err = ifacestate.TraceSnapPolicy(s.state, "missing")
+	c.Check(err, ErrorMatches, `snap "missing" is not installed`)
+}
+
+type sandboxTestInterface struct {
+	ifacetest.TestInterface
+}
+
+func (iface *sandboxTestInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	spec.AddSnippet(fmt.Sprintf("# access to %s", slot.Ref()))
+	return nil
+}
+
+func (iface *sandboxTestInterface) AppArmorPermanentSlot(spec *apparmor.Specification, slot *snap.SlotInfo) error {
+	spec.AddSnippet("# provide the service")
+	return nil
+}
+
+func (iface *sandboxTestInterface) KModPermanentSlot(spec *kmod.Specification, slot *snap.SlotInfo) error {
+	return spec.AddModule("test-module")
+}
+
+func (s *interfaceManagerSuite) TestSnapSandboxProfiles(c *C) {
+	consumer := s.mockSnap(c, `
+name: consumer
+version: 1
+apps:
+ app:
+  command: foo
+plugs:
+ plug:
+  interface: test
+`)
+	producer := s.mockSnap(c, `
+name: producer
+version: 1
+apps:
+ daemon:
+  command: foo
+  daemon: simple
+slots:
+ slot:
+  interface: test
+`)
+
+	repo := interfaces.NewRepository()
+	c.Assert(repo.AddBackend(&apparmor.Backend{}), IsNil)
+	c.Assert(repo.AddBackend(&kmod.Backend{}), IsNil)
+	c.Assert(repo.AddInterface(&sandboxTestInterface{ifacetest.TestInterface{InterfaceName: "test"}}), IsNil)
+	c.Assert(repo.AddSnap(consumer), IsNil)
+	c.Assert(repo.AddSnap(producer), IsNil)
+	connRef := interfaces.NewConnRef(consumer.Plugs["plug"], producer.Slots["slot"])
+	_, err := repo.Connect(connRef, nil, nil, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	ifacerepo.Replace(s.state, repo)
+
+	// only the profile of the consumer was written
+	profile := "profile \"snap.consumer.app\" {\n  # access to producer:slot\n}\n"
+	c.Assert(os.MkdirAll(dirs.SnapAppArmorDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapAppArmorDir, "snap.consumer.app"), []byte(profile), 0644), IsNil)
+
+	plugRef := interfaces.PlugRef{Snap: "consumer", Name: "plug"}
+	slotRef := interfaces.SlotRef{Snap: "producer", Name: "slot"}
+
+	sb, err := ifacestate.SnapSandboxProfiles(s.state, "consumer")
+	c.Assert(err, IsNil)
+	c.Check(sb, DeepEquals, &ifacestate.SnapSandbox{
+		Snap: "consumer",
+		Profiles: []*ifacestate.SandboxProfile{{
+			Backend:     "apparmor",
+			Name:        "snap.consumer.app",
+			SecurityTag: "snap.consumer.app",
+			Path:        filepath.Join(dirs.SnapAppArmorDir, "snap.consumer.app"),
+			Content:     profile,
+			Snippets: []*ifacestate.SandboxSnippet{{
+				Source:  interfaces.SpecificationSource{Interface: "test", Side: "plug", Plug: plugRef, Slot: slotRef},
+				Snippet: "# access to producer:slot",
+			}},
+		}},
+	})
+
+	sb, err = ifacestate.SnapSandboxProfiles(s.state, "producer")
+	c.Assert(err, IsNil)
+	slotSource := interfaces.SpecificationSource{Interface: "test", Side: "slot", Slot: slotRef}
+	c.Check(sb, DeepEquals, &ifacestate.SnapSandbox{
+		Snap: "producer",
+		Profiles: []*ifacestate.SandboxProfile{{
+			Backend:     "apparmor",
+			Name:        "snap.producer.daemon",
+			SecurityTag: "snap.producer.daemon",
+			Path:        filepath.Join(dirs.SnapAppArmorDir, "snap.producer.daemon"),
+			Snippets: []*ifacestate.SandboxSnippet{{
+				Source:  slotSource,
+				Snippet: "# provide the service",
+			}},
+		}, {
+			Backend: "kmod",
+			Name:    "snap.producer.conf",
+			Path:    filepath.Join(dirs.SnapKModModulesDir, "snap.producer.conf"),
+			Snippets: []*ifacestate.SandboxSnippet{{
+				Source:  slotSource,
+				Snippet: "test-module",
+			}},
+		}},
+	})
+
+	_, err = ifacestate.SnapSandboxProfiles(s.state, "missing")
+	c.Check(err, ErrorMatches, `snap "missing" is not installed`)
+}
+
+func (s *interfaceManagerSuite) TestSnapSandboxProfilesWithoutSnippets(c *C) {
+	info := s.mockSnap(c, `
+name: bystander
+version: 1
+apps:
+ app:
+  command: foo
+hooks:
+ configure:
+`)
+
+	repo := interfaces.NewRepository()
+	c.Assert(repo.AddBackend(&apparmor.Backend{}), IsNil)
+	c.Assert(repo.AddBackend(&kmod.Backend{}), IsNil)
+	c.Assert(repo.AddSnap(info), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	ifacerepo.Replace(s.state, repo)
+
+	sb, err := ifacestate.SnapSandboxProfiles(s.state, "bystander")
+	c.Assert(err, IsNil)
+	c.Check(sb, DeepEquals, &ifacestate.SnapSandbox{
+		Snap: "bystander",
+		Profiles: []*ifacestate.SandboxProfile{{
+			Backend:     "apparmor",
+			Name:        "snap.bystander.app",
+			SecurityTag: "snap.bystander.app",
+			Path:        filepath.Join(dirs.SnapAppArmorDir, "snap.bystander.app"),
+		}, {
+			Backend:     "apparmor",
+			Name:        "snap.bystander.hook.configure",
+			SecurityTag: "snap.bystander.hook.configure",
+			Path:        filepath.Join(dirs.SnapAppArmorDir, "snap.bystander.hook.configure"),
+		}},
+	})
+}
+
 func (s *interfaceManagerSuite) TestConnectTaskCheckDeviceScopeNoStore(c *C) {
 	s.MockModel(c, nil)
 
@@ -3678,6 +3895,81 @@ func (s *interfaceManagerSuite) TestConnectTracksConnectionsInState(c *C) {
 	})
 }
 
+func (s *interfaceManagerSuite) TestConnectDisconnectOrdersConnectedServices(c *C) {
+	s.MockModel(c, nil)
+
+	var sysctlArgs [][]string
+	restore := systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		sysctlArgs = append(sysctlArgs, args)
+		return nil, nil
+	})
+	defer restore()
+
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	consumer := s.mockSnap(c, `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+apps:
+ svc:
+  command: bin/svc
+  daemon: simple
+  after-connected: [plug]
+`)
+	s.mockSnap(c, `
+name: producer
+version: 1
+slots:
+ slot:
+  interface: test
+apps:
+ db:
+  command: bin/db
+  daemon: simple
+ cmd:
+  command: bin/cmd
+`)
+	svcFile := consumer.Apps["svc"].ServiceFile()
+
+	_ = s.manager(c)
+
+	s.state.Lock()
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("connect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	c.Assert(change.Err(), IsNil)
+	s.state.Unlock()
+
+	c.Check(svcFile, testutil.FileContains, "\nWants=snap.producer.db.service\nAfter=snap.producer.db.service\n")
+	c.Check(sysctlArgs, DeepEquals, [][]string{{"daemon-reload"}})
+	sysctlArgs = nil
+
+	conn := s.getConnection(c, "consumer", "plug", "producer", "slot")
+	s.state.Lock()
+	ts, err = ifacestate.Disconnect(s.state, conn)
+	c.Assert(err, IsNil)
+	change = s.state.NewChange("disconnect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	c.Assert(change.Err(), IsNil)
+	s.state.Unlock()
+
+	c.Check(svcFile, Not(testutil.FileContains), "snap.producer.db.service")
+	c.Check(sysctlArgs, DeepEquals, [][]string{{"daemon-reload"}})
+}
+
 func (s *interfaceManagerSuite) TestConnectSetsUpSecurity(c *C) {
 	s.MockModel(c, nil)
 
@@ -7174,6 +7466,45 @@ func (s *interfaceManagerSuite) TestConnectionStatesHotplugGone(c *C) {
 		}})
 }
 
+func (s *interfaceManagerSuite) TestHotplugSlots(c *C) {
+	mgr := s.manager(c)
+
+	slots, err := mgr.HotplugSlots()
+	c.Assert(err, IsNil)
+	c.Check(slots, HasLen, 0)
+
+	s.state.Lock()
+	s.state.Set("hotplug-slots", map[string]interface{}{
+		"webcam": map[string]interface{}{
+			"name":         "webcam",
+			"interface":    "camera",
+			"hotplug-key":  "1234",
+			"static-attrs": map[string]interface{}{"path": "/dev/video0"}},
+		"hidraw": map[string]interface{}{
+			"name":         "hidraw",
+			"interface":    "hidraw",
+			"hotplug-key":  "5678",
+			"hotplug-gone": true}})
+	s.state.Unlock()
+
+	slots, err = mgr.HotplugSlots()
+	c.Assert(err, IsNil)
+	c.Check(slots, DeepEquals, map[string]*ifacestate.HotplugSlotInfo{
+		"webcam": {
+			Name:        "webcam",
+			Interface:   "camera",
+			HotplugKey:  "1234",
+			StaticAttrs: map[string]interface{}{"path": "/dev/video0"},
+		},
+		"hidraw": {
+			Name:        "hidraw",
+			Interface:   "hidraw",
+			HotplugKey:  "5678",
+			HotplugGone: true,
+		},
+	})
+}
+
 const someSnapYaml = `name: some-snap
 version: 1
 plugs:
@@ -0,0 +1,262 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// PolicyCheck is the outcome of a declaration based policy check along
+// with the trace of how the rules of the declarations were evaluated.
+type PolicyCheck struct {
+	Allowed bool
+	// Error tells why the check failed.
+	Error string
+	// Skipped tells why the check is not performed at all, the
+	// outcome is then allowed.
+	Skipped string
+	Trace   []policy.TraceStep
+}
+
+// InstallationPolicy explains whether a plug or slot of a snap is
+// allowed to be installed.
+type InstallationPolicy struct {
+	// Side is either "plug" or "slot".
+	Side      string
+	Name      string
+	Interface string
+	Check     PolicyCheck
+}
+
+// ConnectionPolicy explains whether a plug and a slot are allowed to be
+// connected, manually and automatically.
+type ConnectionPolicy struct {
+	Plug           interfaces.PlugRef
+	Slot           interfaces.SlotRef
+	Interface      string
+	Connection     PolicyCheck
+	AutoConnection PolicyCheck
+}
+
+// SnapPolicy explains the outcome of the policy checks for the plugs and
+// slots of a snap.
+type SnapPolicy struct {
+	Snap         string
+	Installation []*InstallationPolicy
+	Connections  []*ConnectionPolicy
+}
+
+const noSnapDeclarationReason = "snap %q has no snap-declaration, it was installed with --dangerous"
+
+type policyTracer struct {
+	st       *state.State
+	baseDecl *asserts.BaseDeclaration
+	model    *asserts.Model
+	store    *asserts.Store
+	cache    map[string]*asserts.SnapDeclaration
+}
+
+func (t *policyTracer) snapDeclaration(info *snap.Info) (*asserts.SnapDeclaration, error) {
+	if info.SnapID == "" {
+		return nil, nil
+	}
+	if snapDecl := t.cache[info.SnapID]; snapDecl != nil {
+		return snapDecl, nil
+	}
+	snapDecl, err := assertstate.SnapDeclaration(t.st, info.SnapID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find snap declaration for %q: %v", info.InstanceName(), err)
+	}
+	t.cache[info.SnapID] = snapDecl
+	return snapDecl, nil
+}
+
+func policyCheckOutcome(err error, trace *policy.Trace) PolicyCheck {
+	check := PolicyCheck{Allowed: err == nil, Trace: trace.Steps}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+func (t *policyTracer) installation(info *snap.Info, side, name, iface string, checkOne func(ic *policy.InstallCandidate) error) *InstallationPolicy {
+	ip := &InstallationPolicy{
+		Side:      side,
+		Name:      name,
+		Interface: iface,
+	}
+	if info.SnapID == "" {
+		// only the minimal checks are performed, see CheckInterfaces
+		ip.Check = PolicyCheck{Allowed: true, Skipped: fmt.Sprintf(noSnapDeclarationReason, info.InstanceName())}
+		return ip
+	}
+	snapDecl, err := t.snapDeclaration(info)
+	if err != nil {
+		ip.Check = PolicyCheck{Error: err.Error()}
+		return ip
+	}
+	trace := &policy.Trace{}
+	ic := &policy.InstallCandidate{
+		Snap:            info,
+		SnapDeclaration: snapDecl,
+		BaseDeclaration: t.baseDecl,
+		Model:           t.model,
+		Store:           t.store,
+		Trace:           trace,
+	}
+	ip.Check = policyCheckOutcome(checkOne(ic), trace)
+	return ip
+}
+
+func (t *policyTracer) connection(plug *snap.PlugInfo, slot *snap.SlotInfo) *ConnectionPolicy {
+	cp := &ConnectionPolicy{
+		Plug:      interfaces.PlugRef{Snap: plug.Snap.InstanceName(), Name: plug.Name},
+		Slot:      interfaces.SlotRef{Snap: slot.Snap.InstanceName(), Name: slot.Name},
+		Interface: plug.Interface,
+	}
+	plugDecl, err := t.snapDeclaration(plug.Snap)
+	if err == nil {
+		var slotDecl *asserts.SnapDeclaration
+		slotDecl, err = t.snapDeclaration(slot.Snap)
+		if err == nil {
+			t.checkConnection(cp, plug, slot, plugDecl, slotDecl)
+			return cp
+		}
+	}
+	// neither connection nor auto-connection is possible then
+	cp.Connection = PolicyCheck{Error: err.Error()}
+	cp.AutoConnection = PolicyCheck{Error: err.Error()}
+	return cp
+}
+
+func (t *policyTracer) checkConnection(cp *ConnectionPolicy, plug *snap.PlugInfo, slot *snap.SlotInfo, plugDecl, slotDecl *asserts.SnapDeclaration) {
+	candidate := func(trace *policy.Trace) *policy.ConnectCandidate {
+		return &policy.ConnectCandidate{
+			Plug:                interfaces.NewConnectedPlug(plug, nil, nil),
+			PlugSnapDeclaration: plugDecl,
+			Slot:                interfaces.NewConnectedSlot(slot, nil, nil),
+			SlotSnapDeclaration: slotDecl,
+			BaseDeclaration:     t.baseDecl,
+			Model:               t.model,
+			Store:               t.store,
+			Trace:               trace,
+		}
+	}
+
+	// manual connections of snaps without snap-declaration are not
+	// checked, see connectChecker
+	switch {
+	case plugDecl == nil:
+		cp.Connection = PolicyCheck{Allowed: true, Skipped: fmt.Sprintf(noSnapDeclarationReason, plug.Snap.InstanceName())}
+	case slotDecl == nil:
+		cp.Connection = PolicyCheck{Allowed: true, Skipped: fmt.Sprintf(noSnapDeclarationReason, slot.Snap.InstanceName())}
+	default:
+		trace := &policy.Trace{}
+		cp.Connection = policyCheckOutcome(candidate(trace).Check(), trace)
+	}
+
+	trace := &policy.Trace{}
+	_, err := candidate(trace).CheckAutoConnect()
+	cp.AutoConnection = policyCheckOutcome(err, trace)
+}
+
+// TraceSnapPolicy evaluates the declaration based policy for the
+// installation of the plugs and slots of the given snap and for their
+// connection, manual and automatic, to all the slots and plugs of the
+// same interfaces, recording which rules were evaluated and why they
+// matched or not.
+//
+// The caller is responsible for locking the state.
+func TraceSnapPolicy(st *state.State, instanceName string) (*SnapPolicy, error) {
+	info, err := snapstate.CurrentInfo(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	modelAs := deviceCtx.Model()
+	var storeAs *asserts.Store
+	if modelAs.Store() != "" {
+		storeAs, err = assertstate.Store(st, modelAs.Store())
+		if err != nil && !asserts.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	baseDecl, err := assertstate.BaseDeclaration(st)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot find base declaration: %v", err)
+	}
+
+	t := &policyTracer{
+		st:       st,
+		baseDecl: baseDecl,
+		model:    modelAs,
+		store:    storeAs,
+		cache:    make(map[string]*asserts.SnapDeclaration),
+	}
+	sp := &SnapPolicy{Snap: instanceName}
+
+	// the repository has the implicit slots of the system snap too
+	repo := ifacerepo.Get(st)
+	plugs := repo.Plugs(instanceName)
+	slots := repo.Slots(instanceName)
+
+	for _, plug := range plugs {
+		plug := plug
+		sp.Installation = append(sp.Installation, t.installation(info, "plug", plug.Name, plug.Interface, func(ic *policy.InstallCandidate) error {
+			return ic.CheckPlug(plug)
+		}))
+	}
+	for _, slot := range slots {
+		slot := slot
+		sp.Installation = append(sp.Installation, t.installation(info, "slot", slot.Name, slot.Interface, func(ic *policy.InstallCandidate) error {
+			return ic.CheckSlot(slot)
+		}))
+	}
+
+	for _, plug := range plugs {
+		for _, slot := range repo.AllSlots(plug.Interface) {
+			sp.Connections = append(sp.Connections, t.connection(plug, slot))
+		}
+	}
+	for _, slot := range slots {
+		for _, plug := range repo.AllPlugs(slot.Interface) {
+			if plug.Snap.InstanceName() == instanceName {
+				// covered above
+				continue
+			}
+			sp.Connections = append(sp.Connections, t.connection(plug, slot))
+		}
+	}
+
+	return sp, nil
+}
@@ -0,0 +1,251 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/dbus"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/interfaces/selinux"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// SandboxSnippet is a part of a security profile of a snap along with the
+// plug, slot or connection whose interface contributed it.
+type SandboxSnippet struct {
+	Source  interfaces.SpecificationSource
+	Snippet string
+}
+
+// SandboxProfile is a security profile generated by a security backend for
+// a snap, along with the snippets contributed to it by the interfaces.
+type SandboxProfile struct {
+	Backend interfaces.SecuritySystem
+	// Name is the security tag of the app or hook the profile applies
+	// to, or the name of the generated file for snap-wide profiles.
+	Name string
+	// SecurityTag is empty for snap-wide profiles.
+	SecurityTag string
+	// Path is the file the backend writes the profile to and Content is
+	// the profile as currently found there, empty if it was not written.
+	Path     string
+	Content  string
+	Snippets []*SandboxSnippet
+}
+
+// SnapSandbox collects the security profiles of a snap.
+type SnapSandbox struct {
+	Snap     string
+	Profiles []*SandboxProfile
+}
+
+type profileSnippet struct {
+	profile     string
+	securityTag string
+	snippet     string
+}
+
+func taggedSnippets(snippets map[string][]string) []profileSnippet {
+	tags := make([]string, 0, len(snippets))
+	for tag := range snippets {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var result []profileSnippet
+	for _, tag := range tags {
+		for _, snippet := range snippets[tag] {
+			result = append(result, profileSnippet{profile: tag, securityTag: tag, snippet: snippet})
+		}
+	}
+	return result
+}
+
+// specSnippets returns the snippets recorded in the given specification,
+// named after the profiles generated from them by the respective backends.
+func specSnippets(instanceName string, spec interfaces.Specification) []profileSnippet {
+	snapTag := snap.SecurityTag(instanceName)
+	var result []profileSnippet
+	switch spec := spec.(type) {
+	case *apparmor.Specification:
+		result = taggedSnippets(spec.Snippets())
+		for _, snippet := range spec.UpdateNS() {
+			result = append(result, profileSnippet{profile: "snap-update-ns." + instanceName, snippet: snippet})
+		}
+	case *seccomp.Specification:
+		result = taggedSnippets(spec.Snippets())
+	case *dbus.Specification:
+		result = taggedSnippets(spec.Snippets())
+	case *selinux.Specification:
+		result = taggedSnippets(spec.Snippets())
+	case *udev.Specification:
+		for _, snippet := range spec.Snippets() {
+			result = append(result, profileSnippet{profile: fmt.Sprintf("70-%s.rules", snapTag), snippet: snippet})
+		}
+	case *mount.Specification:
+		for _, entry := range spec.MountEntries() {
+			result = append(result, profileSnippet{profile: snapTag + ".fstab", snippet: entry.String()})
+		}
+		for _, entry := range spec.UserMountEntries() {
+			result = append(result, profileSnippet{profile: snapTag + ".user-fstab", snippet: entry.String()})
+		}
+	case *kmod.Specification:
+		modules := make([]string, 0, len(spec.Modules()))
+		for module := range spec.Modules() {
+			modules = append(modules, module)
+		}
+		sort.Strings(modules)
+		for _, module := range modules {
+			result = append(result, profileSnippet{profile: snapTag + ".conf", snippet: module})
+		}
+	case *systemd.Specification:
+		services := spec.Services()
+		names := make([]string, 0, len(services))
+		for name := range services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			result = append(result, profileSnippet{profile: name, snippet: services[name].String()})
+		}
+	}
+	return result
+}
+
+// profilePath returns the file the given backend writes the named profile
+// of a snap to.
+func profilePath(backend interfaces.SecuritySystem, instanceName, name string) string {
+	switch backend {
+	case interfaces.SecurityAppArmor:
+		return filepath.Join(dirs.SnapAppArmorDir, name)
+	case interfaces.SecuritySecComp:
+		return filepath.Join(dirs.SnapSeccompDir, name+".src")
+	case interfaces.SecurityDBus:
+		return filepath.Join(dirs.SnapBusPolicyDir, name+".conf")
+	case interfaces.SecuritySELinux:
+		// all the domains of a snap are in a single policy module
+		return filepath.Join(dirs.SnapSELinuxDir, selinux.ModuleName(instanceName)+".te")
+	case interfaces.SecurityUDev:
+		return filepath.Join(dirs.SnapUdevRulesDir, name)
+	case interfaces.SecurityMount:
+		return filepath.Join(dirs.SnapMountPolicyDir, name)
+	case interfaces.SecurityKMod:
+		return filepath.Join(dirs.SnapKModModulesDir, name)
+	case interfaces.SecuritySystemd:
+		return filepath.Join(dirs.SnapServicesDir, name)
+	}
+	return ""
+}
+
+// perTagProfiles returns whether the backend generates a profile for every
+// app and hook of a snap, regardless of the snippets contributed to it.
+func perTagProfiles(backend interfaces.SecuritySystem) bool {
+	switch backend {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecuritySELinux:
+		return true
+	}
+	return false
+}
+
+// SnapSandboxProfiles returns the security profiles of the given snap, for
+// all the security backends, as written by them, along with the snippets that
+// the interfaces contribute to each profile and the plug, slot or connection
+// that contributed each of them.
+//
+// The caller is responsible for locking the state.
+func SnapSandboxProfiles(st *state.State, instanceName string) (*SnapSandbox, error) {
+	info, err := snapstate.CurrentInfo(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, app := range info.Apps {
+		tags = append(tags, app.SecurityTag())
+	}
+	for _, hook := range info.Hooks {
+		tags = append(tags, hook.SecurityTag())
+	}
+
+	sb := &SnapSandbox{Snap: instanceName}
+	repo := ifacerepo.Get(st)
+	for _, backend := range repo.Backends() {
+		parts, err := repo.SnapSpecificationParts(backend.Name(), instanceName)
+		if err != nil {
+			return nil, err
+		}
+		profiles := make(map[string]*SandboxProfile)
+		var names []string
+		addProfile := func(name, securityTag string) *SandboxProfile {
+			profile := profiles[name]
+			if profile == nil {
+				profile = &SandboxProfile{
+					Backend:     backend.Name(),
+					Name:        name,
+					SecurityTag: securityTag,
+					Path:        profilePath(backend.Name(), instanceName, name),
+				}
+				profiles[name] = profile
+				names = append(names, name)
+			}
+			return profile
+		}
+		if perTagProfiles(backend.Name()) {
+			for _, tag := range tags {
+				addProfile(tag, tag)
+			}
+		}
+		for _, part := range parts {
+			for _, ps := range specSnippets(instanceName, part.Spec) {
+				profile := addProfile(ps.profile, ps.securityTag)
+				profile.Snippets = append(profile.Snippets, &SandboxSnippet{
+					Source:  part.Source,
+					Snippet: ps.snippet,
+				})
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			profile := profiles[name]
+			if profile.Path != "" {
+				content, err := ioutil.ReadFile(profile.Path)
+				if err != nil && !os.IsNotExist(err) {
+					return nil, err
+				}
+				profile.Content = string(content)
+			}
+			sb.Profiles = append(sb.Profiles, profile)
+		}
+	}
+	return sb, nil
+}
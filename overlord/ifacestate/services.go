@@ -0,0 +1,126 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"sort"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+// connectedServices returns the services of the snaps on the other side of
+// the connections of the plugs and slots of the given snap, by name of the
+// plug or slot.
+func connectedServices(repo *interfaces.Repository, instanceName string) (map[string][]*snap.AppInfo, error) {
+	conns, err := repo.Connections(instanceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var connected map[string][]*snap.AppInfo
+	for _, cref := range conns {
+		var name string
+		var apps map[string]*snap.AppInfo
+		if cref.PlugRef.Snap == instanceName {
+			slot := repo.Slot(cref.SlotRef.Snap, cref.SlotRef.Name)
+			if slot == nil {
+				continue
+			}
+			name, apps = cref.PlugRef.Name, slot.Apps
+		} else {
+			plug := repo.Plug(cref.PlugRef.Snap, cref.PlugRef.Name)
+			if plug == nil {
+				continue
+			}
+			name, apps = cref.SlotRef.Name, plug.Apps
+		}
+		for _, app := range apps {
+			if !app.IsService() {
+				continue
+			}
+			if connected == nil {
+				connected = make(map[string][]*snap.AppInfo)
+			}
+			connected[name] = append(connected[name], app)
+		}
+	}
+	for _, apps := range connected {
+		sort.Slice(apps, func(i, j int) bool { return apps[i].ServiceName() < apps[j].ServiceName() })
+	}
+	return connected, nil
+}
+
+// ordersConnectedServices returns whether any service of the snap is
+// ordered against the services connected to the given plug or slot.
+func ordersConnectedServices(info *snap.Info, plugOrSlotName string) bool {
+	for _, app := range info.Services() {
+		if strutil.ListContains(app.AfterConnected, plugOrSlotName) || strutil.ListContains(app.BeforeConnected, plugOrSlotName) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureConnectedServicesOrder rewrites the service units of the snaps on
+// both sides of a connection that was just made or removed, if their
+// services are ordered against the services connected to the plug or slot
+// in question. The services are not restarted, the ordering only matters
+// when they get started.
+func ensureConnectedServicesOrder(st *state.State, refs ...snapAndPlugOrSlot) error {
+	for _, ref := range refs {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, ref.snap, &snapst); err != nil {
+			if err == state.ErrNoState {
+				continue
+			}
+			return err
+		}
+		if !snapst.Active {
+			// the services of inactive snaps have no units
+			continue
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return err
+		}
+		if !ordersConnectedServices(info, ref.name) {
+			continue
+		}
+		opts, err := snapstate.SnapServiceOptions(st, ref.snap)
+		if err != nil {
+			return err
+		}
+		if _, err := wrappers.EnsureSnapServices(info, opts, progress.Null); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type snapAndPlugOrSlot struct {
+	snap string
+	name string
+}
@@ -0,0 +1,437 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+func init() {
+	snapstate.SnapServiceOptions = SnapServiceOptions
+	snapstate.EnsureSnapAbsentFromQuotaGroup = EnsureSnapAbsentFromQuotaGroup
+}
+
+// ErrQuotaNotFound is returned when the requested quota group does not
+// exist.
+var ErrQuotaNotFound = errors.New("quota group not found")
+
+// AllQuotas returns all the quota groups, by name.
+//
+// The caller is responsible for locking the state.
+func AllQuotas(st *state.State) (map[string]*quota.Group, error) {
+	var quotas map[string]*quota.Group
+	err := st.Get("quotas", &quotas)
+	if err == state.ErrNoState {
+		return make(map[string]*quota.Group), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot unmarshal quota groups: %v", err)
+	}
+	if err := quota.ResolveCrossReferences(quotas); err != nil {
+		return nil, fmt.Errorf("internal error: quota groups in the state are inconsistent: %v", err)
+	}
+	return quotas, nil
+}
+
+// GetQuota returns the quota group with the given name, or
+// ErrQuotaNotFound.
+//
+// The caller is responsible for locking the state.
+func GetQuota(st *state.State, name string) (*quota.Group, error) {
+	grps, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	grp, ok := grps[name]
+	if !ok {
+		return nil, ErrQuotaNotFound
+	}
+	return grp, nil
+}
+
+func groupOfSnap(grps map[string]*quota.Group, snapName string) *quota.Group {
+	for _, grp := range grps {
+		if strutil.ListContains(grp.Snaps, snapName) {
+			return grp
+		}
+	}
+	return nil
+}
+
+// ConnectedServices returns the services of other snaps connected to the
+// plugs and slots of the given snap, by name of the plug or slot. It is
+// set by ifacestate.
+var ConnectedServices = func(st *state.State, instanceName string) (map[string][]*snap.AppInfo, error) {
+	return nil, nil
+}
+
+// SnapServiceOptions returns the options to use when generating the
+// service units of the given snap, putting them in the slice of its quota
+// group and ordering them against the services of the snaps connected
+// to it.
+//
+// The caller is responsible for locking the state.
+func SnapServiceOptions(st *state.State, instanceName string) (*wrappers.SnapServiceOptions, error) {
+	grps, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	return snapServiceOptions(st, grps, instanceName)
+}
+
+func snapServiceOptions(st *state.State, grps map[string]*quota.Group, instanceName string) (*wrappers.SnapServiceOptions, error) {
+	connected, err := ConnectedServices(st, instanceName)
+	if err != nil {
+		return nil, err
+	}
+	grp := groupOfSnap(grps, instanceName)
+	if grp == nil && len(connected) == 0 {
+		return nil, nil
+	}
+	return &wrappers.SnapServiceOptions{QuotaGroup: grp, ConnectedServices: connected}, nil
+}
+
+// EnsureSnapAbsentFromQuotaGroup removes the given snap from its quota
+// group, if any. It's used when the snap is removed, its services are
+// gone already.
+//
+// The caller is responsible for locking the state.
+func EnsureSnapAbsentFromQuotaGroup(st *state.State, instanceName string) error {
+	grps, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	grp := groupOfSnap(grps, instanceName)
+	if grp == nil {
+		return nil
+	}
+	grp.Snaps = removeFromList(grp.Snaps, instanceName)
+	st.Set("quotas", grps)
+	return nil
+}
+
+func removeFromList(list []string, item string) []string {
+	var out []string
+	for _, s := range list {
+		if s != item {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func addSnapsToGroup(st *state.State, grps map[string]*quota.Group, grp *quota.Group, snaps []string) error {
+	for _, name := range snaps {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, name, &snapst); err != nil {
+			if err == state.ErrNoState {
+				return fmt.Errorf("cannot add snap %q to quota group %q: snap is not installed", name, grp.Name)
+			}
+			return err
+		}
+		if other := groupOfSnap(grps, name); other != nil {
+			if other == grp {
+				continue
+			}
+			return fmt.Errorf("cannot add snap %q to quota group %q: snap is already in quota group %q", name, grp.Name, other.Name)
+		}
+		grp.Snaps = append(grp.Snaps, name)
+	}
+	sort.Strings(grp.Snaps)
+	return nil
+}
+
+// applyQuotas saves the given quota groups and makes the system match
+// them: the slices of the groups are written and the service units of
+// the given snaps are regenerated and the services restarted, so that
+// they run in the slice of their current group. The slices of the
+// removed groups are removed last, once they are empty.
+//
+// The state must be locked by the caller, it is unlocked while the
+// services are restarted.
+func applyQuotas(st *state.State, grps map[string]*quota.Group, snaps []string, removed []*quota.Group) error {
+	meter := progress.Null
+	if err := wrappers.EnsureQuotaSlices(quota.SortedGroups(grps), meter); err != nil {
+		return err
+	}
+
+	sort.Strings(snaps)
+	var restart []*snap.AppInfo
+	for _, name := range snaps {
+		info, err := snapstate.CurrentInfo(st, name)
+		if err != nil {
+			return err
+		}
+		opts, err := snapServiceOptions(st, grps, name)
+		if err != nil {
+			return err
+		}
+		changed, err := wrappers.EnsureSnapServices(info, opts, meter)
+		if err != nil {
+			return err
+		}
+		restart = append(restart, changed...)
+	}
+
+	// stopping the services may take long, don't block the state
+	// meanwhile
+	st.Unlock()
+	err := wrappers.RestartServices(restart, meter)
+	st.Lock()
+	if err != nil {
+		return err
+	}
+
+	if err := wrappers.RemoveQuotaSlices(removed, meter); err != nil {
+		return err
+	}
+
+	st.Set("quotas", grps)
+	return nil
+}
+
+// QuotaControlAction describes the change to the quota groups carried out
+// by a quota-control task.
+type QuotaControlAction struct {
+	// Action is one of "create", "update" or "remove".
+	Action    string `json:"action"`
+	QuotaName string `json:"quota-name"`
+	// ParentName is the parent group of a group being created.
+	ParentName string `json:"parent-name,omitempty"`
+	// AddSnaps are the snaps to put in the group.
+	AddSnaps []string `json:"add-snaps,omitempty"`
+	// MemoryLimit is the (new) memory limit of the group in bytes, 0
+	// means no limit when creating and no change when updating.
+	MemoryLimit uint64 `json:"memory-limit,omitempty"`
+	// CPULimit is the (new) CPU limit of the group in percent, with the
+	// same convention as MemoryLimit.
+	CPULimit int `json:"cpu-limit,omitempty"`
+}
+
+// apply carries out the action on the given quota groups, it returns the
+// snaps whose services need to be regenerated and the groups that were
+// removed.
+func (action *QuotaControlAction) apply(st *state.State, grps map[string]*quota.Group) (snaps []string, removed []*quota.Group, err error) {
+	name := action.QuotaName
+	switch action.Action {
+	case "create":
+		if _, ok := grps[name]; ok {
+			return nil, nil, fmt.Errorf("cannot create quota group %q: group already exists", name)
+		}
+		var grp *quota.Group
+		if action.ParentName == "" {
+			grp, err = quota.NewGroup(name, action.MemoryLimit, action.CPULimit)
+		} else {
+			parent, ok := grps[action.ParentName]
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot create quota group %q: parent group %q does not exist", name, action.ParentName)
+			}
+			grp, err = parent.NewSubGroup(name, action.MemoryLimit, action.CPULimit)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create quota group %q: %v", name, err)
+		}
+		grps[name] = grp
+
+		if err := addSnapsToGroup(st, grps, grp, action.AddSnaps); err != nil {
+			return nil, nil, err
+		}
+		return grp.Snaps, nil, nil
+	case "update":
+		grp, ok := grps[name]
+		if !ok {
+			return nil, nil, ErrQuotaNotFound
+		}
+		if action.MemoryLimit != 0 {
+			grp.MemoryLimit = action.MemoryLimit
+		}
+		if action.CPULimit != 0 {
+			grp.CPULimit = action.CPULimit
+		}
+		// check the new limits against the parent and sub-groups
+		if err := quota.ResolveCrossReferences(grps); err != nil {
+			return nil, nil, fmt.Errorf("cannot update quota group %q: %v", name, err)
+		}
+
+		for _, snapName := range action.AddSnaps {
+			if !strutil.ListContains(grp.Snaps, snapName) {
+				snaps = append(snaps, snapName)
+			}
+		}
+		if err := addSnapsToGroup(st, grps, grp, snaps); err != nil {
+			return nil, nil, err
+		}
+		return snaps, nil, nil
+	case "remove":
+		grp, ok := grps[name]
+		if !ok {
+			return nil, nil, ErrQuotaNotFound
+		}
+		if len(grp.SubGroups) != 0 {
+			return nil, nil, fmt.Errorf("cannot remove quota group %q with sub-groups, remove the sub-groups first", name)
+		}
+
+		delete(grps, name)
+		if parent := grp.Parent(); parent != nil {
+			parent.SubGroups = removeFromList(parent.SubGroups, name)
+		}
+		if err := quota.ResolveCrossReferences(grps); err != nil {
+			return nil, nil, fmt.Errorf("internal error: cannot remove quota group %q: %v", name, err)
+		}
+		return grp.Snaps, []*quota.Group{grp}, nil
+	default:
+		return nil, nil, fmt.Errorf("internal error: unknown quota action %q", action.Action)
+	}
+}
+
+// affectedSnaps returns the snaps whose services the action touches.
+func (action *QuotaControlAction) affectedSnaps(st *state.State) ([]string, error) {
+	if action.Action != "remove" {
+		return action.AddSnaps, nil
+	}
+	grp, err := GetQuota(st, action.QuotaName)
+	if err == ErrQuotaNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return grp.Snaps, nil
+}
+
+func quotaControlAffectedSnaps(t *state.Task) ([]string, error) {
+	var action QuotaControlAction
+	if err := t.Get("quota-control-action", &action); err != nil {
+		return nil, fmt.Errorf("internal error: cannot get quota control action: %v", err)
+	}
+	return action.affectedSnaps(t.State())
+}
+
+// checkQuotaControlConflict checks that the action neither conflicts with
+// changes of the affected snaps nor with other changes of the quota group.
+func checkQuotaControlConflict(st *state.State, action *QuotaControlAction) error {
+	for _, t := range st.Tasks() {
+		if t.Kind() != "quota-control" || t.Status().Ready() {
+			continue
+		}
+		var other QuotaControlAction
+		if err := t.Get("quota-control-action", &other); err != nil {
+			return fmt.Errorf("internal error: cannot get quota control action: %v", err)
+		}
+		if other.QuotaName == action.QuotaName {
+			kind := t.Change().Kind()
+			return &snapstate.ChangeConflictError{
+				Message:    fmt.Sprintf("quota group %q has %q change in progress", action.QuotaName, kind),
+				ChangeKind: kind,
+			}
+		}
+	}
+
+	snaps, err := action.affectedSnaps(st)
+	if err != nil {
+		return err
+	}
+	if len(snaps) == 0 {
+		return nil
+	}
+	return snapstate.CheckChangeConflictMany(st, snaps, "")
+}
+
+// quotaControl checks that the given action can be carried out on the
+// current quota groups and returns the task set doing it.
+func quotaControl(st *state.State, action *QuotaControlAction, summary string) (*state.TaskSet, error) {
+	if err := checkQuotaControlConflict(st, action); err != nil {
+		return nil, err
+	}
+	grps, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := action.apply(st, grps); err != nil {
+		return nil, err
+	}
+
+	t := st.NewTask("quota-control", summary)
+	t.Set("quota-control-action", action)
+	return state.NewTaskSet(t), nil
+}
+
+// CreateQuota returns a task set creating a quota group with the given
+// limits, nested in the given parent group if any, and putting the given
+// snaps in it. A limit of 0 means no limit.
+//
+// The caller is responsible for locking the state.
+func CreateQuota(st *state.State, name string, parentName string, snaps []string, memoryLimit uint64, cpuLimit int) (*state.TaskSet, error) {
+	return quotaControl(st, &QuotaControlAction{
+		Action:      "create",
+		QuotaName:   name,
+		ParentName:  parentName,
+		AddSnaps:    snaps,
+		MemoryLimit: memoryLimit,
+		CPULimit:    cpuLimit,
+	}, fmt.Sprintf("Create quota group %q", name))
+}
+
+// QuotaGroupUpdate describes a change to a quota group, zero values leave
+// the group alone.
+type QuotaGroupUpdate struct {
+	// AddSnaps are the snaps to put in the group.
+	AddSnaps []string
+	// NewMemoryLimit is the new memory limit of the group in bytes.
+	NewMemoryLimit uint64
+	// NewCPULimit is the new CPU limit of the group in percent.
+	NewCPULimit int
+}
+
+// UpdateQuota returns a task set changing the limits of an existing quota
+// group and adding snaps to it.
+//
+// The caller is responsible for locking the state.
+func UpdateQuota(st *state.State, name string, update QuotaGroupUpdate) (*state.TaskSet, error) {
+	return quotaControl(st, &QuotaControlAction{
+		Action:      "update",
+		QuotaName:   name,
+		AddSnaps:    update.AddSnaps,
+		MemoryLimit: update.NewMemoryLimit,
+		CPULimit:    update.NewCPULimit,
+	}, fmt.Sprintf("Update quota group %q", name))
+}
+
+// RemoveQuota returns a task set removing a quota group without
+// sub-groups; the services of its snaps go back to running outside of
+// any quota group.
+//
+// The caller is responsible for locking the state.
+func RemoveQuota(st *state.State, name string) (*state.TaskSet, error) {
+	return quotaControl(st, &QuotaControlAction{
+		Action:    "remove",
+		QuotaName: name,
+	}, fmt.Sprintf("Remove quota group %q", name))
+}
@@ -0,0 +1,491 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type quotaSuite struct {
+	testutil.BaseTest
+	state         *state.State
+	runner        *state.TaskRunner
+	systemctlArgs [][]string
+}
+
+var _ = Suite(&quotaSuite{})
+
+func (s *quotaSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("/") })
+	s.AddCleanup(snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {}))
+
+	s.AddCleanup(systemd.MockStopDelays(time.Millisecond, 25*time.Second))
+	s.systemctlArgs = nil
+	s.AddCleanup(systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		s.systemctlArgs = append(s.systemctlArgs, args)
+		return []byte("ActiveState=inactive"), nil
+	}))
+
+	s.state = state.New(nil)
+	s.runner = state.NewTaskRunner(s.state)
+	servicestate.Manager(s.state, s.runner)
+	s.AddCleanup(s.runner.Stop)
+}
+
+// settle runs the tasks of the given change until it is ready. The state
+// must be locked.
+func (s *quotaSuite) settle(c *C, chg *state.Change) {
+	for i := 0; i < 10 && !chg.IsReady(); i++ {
+		s.state.Unlock()
+		s.runner.Ensure()
+		s.runner.Wait()
+		s.state.Lock()
+	}
+	c.Assert(chg.IsReady(), Equals, true)
+}
+
+// run runs the given task set to completion and returns the error of its
+// change, if any. The state must be locked.
+func (s *quotaSuite) run(c *C, ts *state.TaskSet, err error) error {
+	if err != nil {
+		return err
+	}
+	chg := s.state.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+	s.settle(c, chg)
+	return chg.Err()
+}
+
+func (s *quotaSuite) createQuota(c *C, name, parent string, snaps []string, memory uint64, cpu int) error {
+	ts, err := servicestate.CreateQuota(s.state, name, parent, snaps, memory, cpu)
+	return s.run(c, ts, err)
+}
+
+func (s *quotaSuite) updateQuota(c *C, name string, update servicestate.QuotaGroupUpdate) error {
+	ts, err := servicestate.UpdateQuota(s.state, name, update)
+	return s.run(c, ts, err)
+}
+
+func (s *quotaSuite) removeQuota(c *C, name string) error {
+	ts, err := servicestate.RemoveQuota(s.state, name)
+	return s.run(c, ts, err)
+}
+
+const testSnapYaml = `name: test-snap
+version: 1
+apps:
+  svc:
+    command: bin/svc
+    daemon: simple
+  app:
+    command: bin/app
+`
+
+func (s *quotaSuite) mockSnap(c *C, name string) {
+	si := &snap.SideInfo{RealName: name, Revision: snap.R(1)}
+	snaptest.MockSnapCurrent(c, "name: "+name+testSnapYaml[len("name: test-snap"):], si)
+	snapstate.Set(s.state, name, &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		SnapType: "app",
+	})
+}
+
+func (s *quotaSuite) serviceFile(name string) string {
+	return filepath.Join(dirs.SnapServicesDir, "snap."+name+".svc.service")
+}
+
+func (s *quotaSuite) TestCreateQuota(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	err := s.createQuota(c, "foo", "", []string{"test-snap"}, 512*1024*1024, 50)
+	c.Assert(err, IsNil)
+
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp.Snaps, DeepEquals, []string{"test-snap"})
+	c.Check(grp.MemoryLimit, Equals, uint64(512*1024*1024))
+	c.Check(grp.CPULimit, Equals, 50)
+
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileEquals, `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Slice for snap quota group foo
+Before=slices.target
+X-Snappy=yes
+
+[Slice]
+# Always enable accounting, so that the usage of the group can be queried
+CPUAccounting=true
+MemoryAccounting=true
+CPUQuota=50%
+MemoryMax=536870912
+MemoryLimit=536870912
+`)
+	c.Check(s.serviceFile("test-snap"), testutil.FileContains, "\nSlice=snap.foo.slice\n")
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "is-active", "snap.test-snap.svc.service"},
+		{"stop", "snap.test-snap.svc.service"},
+		{"show", "--property=ActiveState", "snap.test-snap.svc.service"},
+		{"start", "snap.test-snap.svc.service"},
+	})
+
+	opts, err := servicestate.SnapServiceOptions(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(opts.QuotaGroup.Name, Equals, "foo")
+	opts, err = servicestate.SnapServiceOptions(s.state, "other-snap")
+	c.Assert(err, IsNil)
+	c.Check(opts, IsNil)
+
+	// the hook used by snapstate is set
+	opts, err = snapstate.SnapServiceOptions(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(opts.QuotaGroup.Name, Equals, "foo")
+}
+
+func (s *quotaSuite) TestCreateQuotaRestartsServicesUnlocked(c *C) {
+	var stopped, unlocked bool
+	s.AddCleanup(systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		if args[0] == "stop" {
+			stopped = true
+			// the state can be locked by others while the
+			// services are restarted
+			done := make(chan struct{})
+			go func() {
+				s.state.Lock()
+				s.state.Unlock()
+				close(done)
+			}()
+			select {
+			case <-done:
+				unlocked = true
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return []byte("ActiveState=inactive"), nil
+	}))
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	err := s.createQuota(c, "foo", "", []string{"test-snap"}, 512*1024*1024, 0)
+	c.Assert(err, IsNil)
+	c.Check(stopped, Equals, true)
+	c.Check(unlocked, Equals, true)
+}
+
+func (s *quotaSuite) TestSnapServiceOptionsConnectedServices(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	other := &snap.AppInfo{Name: "db", Daemon: "simple", Snap: &snap.Info{SuggestedName: "db-snap"}}
+	old := servicestate.ConnectedServices
+	defer func() { servicestate.ConnectedServices = old }()
+	servicestate.ConnectedServices = func(st *state.State, instanceName string) (map[string][]*snap.AppInfo, error) {
+		if instanceName != "test-snap" {
+			return nil, nil
+		}
+		return map[string][]*snap.AppInfo{"db": {other}}, nil
+	}
+
+	opts, err := servicestate.SnapServiceOptions(s.state, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(opts, DeepEquals, &wrappers.SnapServiceOptions{
+		ConnectedServices: map[string][]*snap.AppInfo{"db": {other}},
+	})
+	opts, err = servicestate.SnapServiceOptions(s.state, "other-snap")
+	c.Assert(err, IsNil)
+	c.Check(opts, IsNil)
+
+	servicestate.ConnectedServices = func(st *state.State, instanceName string) (map[string][]*snap.AppInfo, error) {
+		return nil, errors.New("boom")
+	}
+	_, err = servicestate.SnapServiceOptions(s.state, "test-snap")
+	c.Assert(err, ErrorMatches, "boom")
+}
+
+func (s *quotaSuite) TestCreateQuotaErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	err := s.createQuota(c, "foo", "", []string{"test-snap"}, 1024*1024, 0)
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		name, parent string
+		snaps        []string
+		memory       uint64
+		err          string
+	}{
+		{"foo", "", nil, 0, `cannot create quota group "foo": group already exists`},
+		{"bar", "baz", nil, 0, `cannot create quota group "bar": parent group "baz" does not exist`},
+		{"Bar", "", nil, 0, `cannot create quota group "Bar": invalid group name "Bar": .*`},
+		{"bar", "foo", nil, 2 * 1024 * 1024, `cannot create quota group "bar": group "bar" memory limit must not exceed the limit of its parent group "foo" \(1MB\)`},
+		{"bar", "", []string{"missing-snap"}, 0, `cannot add snap "missing-snap" to quota group "bar": snap is not installed`},
+		{"bar", "", []string{"test-snap"}, 0, `cannot add snap "test-snap" to quota group "bar": snap is already in quota group "foo"`},
+	} {
+		err := s.createQuota(c, t.name, t.parent, t.snaps, t.memory, 0)
+		c.Check(err, ErrorMatches, t.err, Commentf(t.name))
+	}
+
+	grps, err := servicestate.AllQuotas(s.state)
+	c.Assert(err, IsNil)
+	c.Check(grps, HasLen, 1)
+}
+
+func (s *quotaSuite) TestNestedQuotasAndUpdate(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+	s.mockSnap(c, "other-snap")
+
+	c.Assert(s.createQuota(c, "foo", "", nil, 1024*1024*1024, 0), IsNil)
+	c.Assert(s.createQuota(c, "bar", "foo", []string{"test-snap"}, 512*1024*1024, 0), IsNil)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo-bar.slice"), testutil.FilePresent)
+	c.Check(s.serviceFile("test-snap"), testutil.FileContains, "\nSlice=snap.foo-bar.slice\n")
+
+	grps, err := servicestate.AllQuotas(s.state)
+	c.Assert(err, IsNil)
+	c.Check(grps["foo"].SubGroups, DeepEquals, []string{"bar"})
+	c.Check(grps["bar"].Parent(), Equals, grps["foo"])
+
+	// the sub-group cannot outgrow its parent
+	err = s.updateQuota(c, "bar", servicestate.QuotaGroupUpdate{NewMemoryLimit: 2 * 1024 * 1024 * 1024})
+	c.Check(err, ErrorMatches, `cannot update quota group "bar": group "bar" memory limit must not exceed the limit of its parent group "foo" \(1GB\)`)
+
+	s.systemctlArgs = nil
+	err = s.updateQuota(c, "foo", servicestate.QuotaGroupUpdate{
+		AddSnaps:       []string{"other-snap"},
+		NewMemoryLimit: 2 * 1024 * 1024 * 1024,
+	})
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileContains, "\nMemoryMax=2147483648\n")
+	c.Check(s.serviceFile("other-snap"), testutil.FileContains, "\nSlice=snap.foo.slice\n")
+	// test-snap was not touched
+	c.Check(s.systemctlArgs, Not(testutil.DeepContains), []string{"stop", "snap.test-snap.svc.service"})
+
+	err = s.updateQuota(c, "missing", servicestate.QuotaGroupUpdate{})
+	c.Check(err, Equals, servicestate.ErrQuotaNotFound)
+}
+
+func (s *quotaSuite) TestRemoveQuota(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	c.Assert(s.createQuota(c, "foo", "", nil, 0, 0), IsNil)
+	c.Assert(s.createQuota(c, "bar", "foo", []string{"test-snap"}, 0, 0), IsNil)
+
+	err := s.removeQuota(c, "foo")
+	c.Check(err, ErrorMatches, `cannot remove quota group "foo" with sub-groups, remove the sub-groups first`)
+
+	s.systemctlArgs = nil
+	err = s.removeQuota(c, "bar")
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo-bar.slice"), testutil.FileAbsent)
+	c.Check(s.serviceFile("test-snap"), Not(testutil.FileContains), "Slice=")
+	// the service was moved out of the slice before it was stopped
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "is-active", "snap.test-snap.svc.service"},
+		{"stop", "snap.test-snap.svc.service"},
+		{"show", "--property=ActiveState", "snap.test-snap.svc.service"},
+		{"start", "snap.test-snap.svc.service"},
+		{"stop", "snap.foo-bar.slice"},
+		{"show", "--property=ActiveState", "snap.foo-bar.slice"},
+		{"daemon-reload"},
+	})
+
+	grps, err := servicestate.AllQuotas(s.state)
+	c.Assert(err, IsNil)
+	c.Check(grps["foo"].SubGroups, HasLen, 0)
+
+	c.Check(s.removeQuota(c, "bar"), Equals, servicestate.ErrQuotaNotFound)
+	c.Assert(s.removeQuota(c, "foo"), IsNil)
+	grps, err = servicestate.AllQuotas(s.state)
+	c.Assert(err, IsNil)
+	c.Check(grps, HasLen, 0)
+}
+
+func (s *quotaSuite) TestEnsureSnapAbsentFromQuotaGroup(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	c.Assert(s.createQuota(c, "foo", "", []string{"test-snap"}, 0, 0), IsNil)
+
+	c.Assert(snapstate.EnsureSnapAbsentFromQuotaGroup(s.state, "test-snap"), IsNil)
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp.Snaps, HasLen, 0)
+
+	// not in a group
+	c.Assert(servicestate.EnsureSnapAbsentFromQuotaGroup(s.state, "test-snap"), IsNil)
+}
+
+func (s *quotaSuite) TestGetQuotaNotFound(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := servicestate.GetQuota(s.state, "foo")
+	c.Check(err, Equals, servicestate.ErrQuotaNotFound)
+
+	s.state.Set("quotas", map[string]*quota.Group{"foo": {Name: "foo", ParentGroup: "bar"}})
+	_, err = servicestate.AllQuotas(s.state)
+	c.Check(err, ErrorMatches, `internal error: quota groups in the state are inconsistent: group "foo" has missing parent group "bar"`)
+}
+
+func (s *quotaSuite) runWithFailure(c *C, ts *state.TaskSet) *state.Change {
+	s.runner.AddHandler("error-trigger", func(t *state.Task, _ *tomb.Tomb) error {
+		return errors.New("boom")
+	}, nil)
+
+	chg := s.state.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+	errTask := s.state.NewTask("error-trigger", "provoking undo")
+	errTask.WaitAll(ts)
+	chg.AddTask(errTask)
+	s.settle(c, chg)
+	return chg
+}
+
+func (s *quotaSuite) TestCreateQuotaUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	ts, err := servicestate.CreateQuota(s.state, "foo", "", []string{"test-snap"}, 512*1024*1024, 0)
+	c.Assert(err, IsNil)
+	chg := s.runWithFailure(c, ts)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(ts.Tasks()[0].Status(), Equals, state.UndoneStatus)
+
+	_, err = servicestate.GetQuota(s.state, "foo")
+	c.Check(err, Equals, servicestate.ErrQuotaNotFound)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileAbsent)
+	c.Check(s.serviceFile("test-snap"), Not(testutil.FileContains), "Slice=")
+}
+
+func (s *quotaSuite) TestUpdateQuotaUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	c.Assert(s.createQuota(c, "foo", "", nil, 1024*1024, 0), IsNil)
+
+	ts, err := servicestate.UpdateQuota(s.state, "foo", servicestate.QuotaGroupUpdate{
+		AddSnaps:       []string{"test-snap"},
+		NewMemoryLimit: 2 * 1024 * 1024,
+	})
+	c.Assert(err, IsNil)
+	chg := s.runWithFailure(c, ts)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp.MemoryLimit, Equals, uint64(1024*1024))
+	c.Check(grp.Snaps, HasLen, 0)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileContains, "\nMemoryMax=1048576\n")
+	c.Check(s.serviceFile("test-snap"), Not(testutil.FileContains), "Slice=")
+}
+
+func (s *quotaSuite) TestRemoveQuotaUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	c.Assert(s.createQuota(c, "foo", "", []string{"test-snap"}, 0, 0), IsNil)
+
+	ts, err := servicestate.RemoveQuota(s.state, "foo")
+	c.Assert(err, IsNil)
+	chg := s.runWithFailure(c, ts)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp.Snaps, DeepEquals, []string{"test-snap"})
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FilePresent)
+	c.Check(s.serviceFile("test-snap"), testutil.FileContains, "\nSlice=snap.foo.slice\n")
+}
+
+func (s *quotaSuite) TestQuotaControlConflicts(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockSnap(c, "test-snap")
+
+	ts, err := servicestate.CreateQuota(s.state, "foo", "", []string{"test-snap"}, 0, 0)
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+
+	// the same group cannot be changed concurrently
+	_, err = servicestate.CreateQuota(s.state, "foo", "", nil, 0, 0)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, `quota group "foo" has "quota-control" change in progress`)
+
+	// and the snaps in it are busy
+	err = snapstate.CheckChangeConflict(s.state, "test-snap", nil)
+	c.Check(err, ErrorMatches, `snap "test-snap" has "quota-control" change in progress`)
+	_, err = servicestate.CreateQuota(s.state, "bar", "", []string{"test-snap"}, 0, 0)
+	c.Check(err, ErrorMatches, `snap "test-snap" has "quota-control" change in progress`)
+
+	// other groups are fine
+	_, err = servicestate.CreateQuota(s.state, "bar", "", nil, 0, 0)
+	c.Check(err, IsNil)
+
+	s.settle(c, chg)
+	c.Assert(chg.Err(), IsNil)
+
+	// the snaps of a group being removed are busy too
+	ts, err = servicestate.RemoveQuota(s.state, "foo")
+	c.Assert(err, IsNil)
+	chg = s.state.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+	err = snapstate.CheckChangeConflict(s.state, "test-snap", nil)
+	c.Check(err, ErrorMatches, `snap "test-snap" has "quota-control" change in progress`)
+}
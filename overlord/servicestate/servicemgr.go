@@ -0,0 +1,139 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"fmt"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+// ServiceManager is responsible for the quota groups the services of
+// snaps run in.
+type ServiceManager struct {
+	state *state.State
+}
+
+// Manager returns a new ServiceManager.
+func Manager(st *state.State, runner *state.TaskRunner) *ServiceManager {
+	m := &ServiceManager{state: st}
+
+	runner.AddHandler("quota-control", m.doQuotaControl, m.undoQuotaControl)
+
+	// quota groups are nested, only change one of them at a time
+	runner.AddBlocked(func(t *state.Task, running []*state.Task) bool {
+		if t.Kind() != "quota-control" {
+			return false
+		}
+		for _, other := range running {
+			if other.Kind() == "quota-control" {
+				return true
+			}
+		}
+		return false
+	})
+
+	snapstate.AddAffectedSnapsByKind("quota-control", quotaControlAffectedSnaps)
+
+	return m
+}
+
+// Ensure is part of the overlord.StateManager interface.
+func (m *ServiceManager) Ensure() error {
+	return nil
+}
+
+func (m *ServiceManager) doQuotaControl(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var action QuotaControlAction
+	if err := t.Get("quota-control-action", &action); err != nil {
+		return fmt.Errorf("internal error: cannot get quota control action: %v", err)
+	}
+
+	oldGrps, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	grps, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	snaps, removed, err := action.apply(st, grps)
+	if err != nil {
+		return err
+	}
+	if err := applyQuotas(st, grps, snaps, removed); err != nil {
+		// put back what was changed already
+		if rerr := applyQuotas(st, oldGrps, snaps, addedGroups(oldGrps, grps)); rerr != nil {
+			logger.Noticef("cannot restore quota groups after failure: %v", rerr)
+		}
+		return err
+	}
+
+	t.Set("old-quotas", oldGrps)
+	t.Set("affected-snaps", snaps)
+	return nil
+}
+
+func (m *ServiceManager) undoQuotaControl(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var oldGrps map[string]*quota.Group
+	if err := t.Get("old-quotas", &oldGrps); err != nil {
+		return fmt.Errorf("internal error: cannot get previous quota groups: %v", err)
+	}
+	if oldGrps == nil {
+		oldGrps = make(map[string]*quota.Group)
+	}
+	if err := quota.ResolveCrossReferences(oldGrps); err != nil {
+		return fmt.Errorf("internal error: previous quota groups are inconsistent: %v", err)
+	}
+	var snaps []string
+	if err := t.Get("affected-snaps", &snaps); err != nil && err != state.ErrNoState {
+		return err
+	}
+
+	grps, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	return applyQuotas(st, oldGrps, snaps, addedGroups(oldGrps, grps))
+}
+
+// addedGroups returns the groups of grps that are not in oldGrps.
+func addedGroups(oldGrps, grps map[string]*quota.Group) []*quota.Group {
+	var added []*quota.Group
+	for name, grp := range grps {
+		if _, ok := oldGrps[name]; !ok {
+			added = append(added, grp)
+		}
+	}
+	return added
+}
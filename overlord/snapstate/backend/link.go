@@ -78,15 +78,26 @@ func hasFontConfigCache(info *snap.Info) bool {
 	return false
 }
 
+// LinkContext carries additional information about how the snap is to be
+// linked.
+type LinkContext struct {
+	// PrevDisabledServices are the services of the snap that were
+	// disabled in a previous revision and are to be kept disabled.
+	PrevDisabledServices []string
+	// ServiceOptions are used when generating the service units of the
+	// snap.
+	ServiceOptions *wrappers.SnapServiceOptions
+}
+
 // LinkSnap makes the snap available by generating wrappers and setting the current symlinks.
-func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model, prevDisabledSvcs []string, tm timings.Measurer) (e error) {
+func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model, linkCtx LinkContext, tm timings.Measurer) (e error) {
 	if info.Revision.Unset() {
 		return fmt.Errorf("cannot link snap %q with unset revision", info.InstanceName())
 	}
 
 	var err error
 	timings.Run(tm, "generate-wrappers", fmt.Sprintf("generate wrappers for snap %s", info.InstanceName()), func(timings.Measurer) {
-		err = generateWrappers(info, prevDisabledSvcs)
+		err = generateWrappers(info, linkCtx)
 	})
 	if err != nil {
 		return err
@@ -143,7 +154,7 @@ func (b Backend) StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReaso
 	return wrappers.StopServices(apps, reason, meter, tm)
 }
 
-func generateWrappers(s *snap.Info, disabledSvcs []string) error {
+func generateWrappers(s *snap.Info, linkCtx LinkContext) error {
 	var err error
 	var cleanupFuncs []func(*snap.Info) error
 	defer func() {
@@ -161,13 +172,19 @@ func generateWrappers(s *snap.Info, disabledSvcs []string) error {
 	cleanupFuncs = append(cleanupFuncs, wrappers.RemoveSnapBinaries)
 
 	// add the daemons from the snap.yaml
-	if err = wrappers.AddSnapServices(s, disabledSvcs, progress.Null); err != nil {
+	if err = wrappers.AddSnapServices(s, linkCtx.PrevDisabledServices, linkCtx.ServiceOptions, progress.Null); err != nil {
 		return err
 	}
 	cleanupFuncs = append(cleanupFuncs, func(s *snap.Info) error {
 		return wrappers.RemoveSnapServices(s, progress.Null)
 	})
 
+	// add the D-Bus activation files of the daemons
+	if err = wrappers.AddSnapDBusActivationFiles(s); err != nil {
+		return err
+	}
+	cleanupFuncs = append(cleanupFuncs, wrappers.RemoveSnapDBusActivationFiles)
+
 	// add the desktop files
 	if err = wrappers.AddSnapDesktopFiles(s); err != nil {
 		return err
@@ -204,7 +221,12 @@ func removeGeneratedWrappers(s *snap.Info, meter progress.Meter) error {
 		logger.Noticef("Cannot remove desktop icons for %q: %v", s.InstanceName(), err4)
 	}
 
-	return firstErr(err1, err2, err3, err4)
+	err5 := wrappers.RemoveSnapDBusActivationFiles(s)
+	if err5 != nil {
+		logger.Noticef("Cannot remove D-Bus activation files for %q: %v", s.InstanceName(), err5)
+	}
+
+	return firstErr(err1, err2, err3, err4, err5)
 }
 
 // UnlinkSnap makes the snap unavailable to the system removing wrappers and symlinks.
@@ -95,7 +95,7 @@ apps:
 	})
 	defer r()
 
-	err := s.be.LinkSnap(info, nil, []string{"svc"}, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, backend.LinkContext{PrevDisabledServices: []string{"svc"}}, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	c.Assert(svcsDisabled, DeepEquals, []string{"snap.hello.bin.service"})
@@ -116,7 +116,7 @@ apps:
 `
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	l, err := filepath.Glob(filepath.Join(dirs.SnapBinariesDir, "*"))
@@ -138,6 +138,34 @@ apps:
 	c.Assert(l, HasLen, 0)
 }
 
+func (s *linkSuite) TestLinkDoUndoGenerateDBusActivationFiles(c *C) {
+	const yaml = `name: hello
+version: 1.0
+slots:
+ dbus-slot:
+   interface: dbus
+   bus: system
+   name: org.example.Hello
+apps:
+ svc:
+   command: svc
+   daemon: simple
+   activates-on: [dbus-slot]
+`
+	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
+
+	err := s.be.LinkSnap(info, nil, backend.LinkContext{}, s.perfTimings)
+	c.Assert(err, IsNil)
+
+	serviceFile := filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Hello.service")
+	c.Check(serviceFile, testutil.FileContains, "SystemdService=snap.hello.svc.service\n")
+
+	// undo will remove
+	err = s.be.UnlinkSnap(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(serviceFile), Equals, false)
+}
+
 func (s *linkSuite) TestLinkDoUndoCurrentSymlink(c *C) {
 	const yaml = `name: hello
 version: 1.0
@@ -146,7 +174,7 @@ version: 1.0
 
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	mountDir := info.MountDir()
@@ -188,10 +216,10 @@ apps:
 
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, IsNil)
 
-	err = s.be.LinkSnap(info, nil, nil, s.perfTimings)
+	err = s.be.LinkSnap(info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	l, err := filepath.Glob(filepath.Join(dirs.SnapBinariesDir, "*"))
@@ -230,7 +258,7 @@ apps:
 
 	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	err = s.be.UnlinkSnap(info, progress.Null)
@@ -258,7 +286,7 @@ func (s *linkSuite) TestLinkFailsForUnsetRevision(c *C) {
 	info := &snap.Info{
 		SuggestedName: "foo",
 	}
-	err := s.be.LinkSnap(info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, ErrorMatches, `cannot link snap "foo" with unset revision`)
 }
 
@@ -315,7 +343,7 @@ func (s *linkCleanupSuite) testLinkCleanupDirOnFail(c *C, dir string) {
 	c.Assert(os.Chmod(dir, 0), IsNil)
 	defer os.Chmod(dir, 0755)
 
-	err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(s.info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, NotNil)
 	_, isPathError := err.(*os.PathError)
 	_, isLinkError := err.(*os.LinkError)
@@ -352,7 +380,7 @@ func (s *linkCleanupSuite) TestLinkCleanupOnSystemctlFail(c *C) {
 	})
 	defer r()
 
-	err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(s.info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, ErrorMatches, "ouchie")
 
 	for _, d := range []string{dirs.SnapBinariesDir, dirs.SnapDesktopFilesDir, dirs.SnapServicesDir} {
@@ -372,7 +400,7 @@ func (s *linkCleanupSuite) TestLinkCleansUpDataDirAndSymlinksOnSymlinkFail(c *C)
 	c.Assert(os.Chmod(d, 0), IsNil)
 	defer os.Chmod(d, 0755)
 
-	err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
+	err := s.be.LinkSnap(s.info, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, ErrorMatches, `(?i).*symlink.*permission denied.*`)
 
 	c.Check(s.info.DataDir(), testutil.FileAbsent)
@@ -395,7 +423,7 @@ func (s *linkCleanupSuite) TestLinkRunsUpdateFontconfigCachesClassic(c *C) {
 		})
 		defer restore()
 
-		err := s.be.LinkSnap(s.info, nil, nil, s.perfTimings)
+		err := s.be.LinkSnap(s.info, nil, backend.LinkContext{}, s.perfTimings)
 		c.Assert(err, IsNil)
 		if onClassic {
 			c.Assert(updateFontconfigCaches, Equals, 1)
@@ -437,7 +465,7 @@ type: os
 	})
 	defer restore()
 
-	err = s.be.LinkSnap(infoNew, nil, nil, s.perfTimings)
+	err = s.be.LinkSnap(infoNew, nil, backend.LinkContext{}, s.perfTimings)
 	c.Assert(err, IsNil)
 
 	c.Check(oldCmdV6.Calls(), HasLen, 0)
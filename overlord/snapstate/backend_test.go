@@ -801,7 +801,7 @@ func (f *fakeSnappyBackend) CopySnapData(newInfo, oldInfo *snap.Info, p progress
 	return nil
 }
 
-func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model, disabledSvcs []string, tm timings.Measurer) error {
+func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model, linkCtx backend.LinkContext, tm timings.Measurer) error {
 	if info.MountDir() == f.linkSnapWaitTrigger {
 		f.linkSnapWaitCh <- 1
 		<-f.linkSnapWaitCh
@@ -813,8 +813,8 @@ func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model, disa
 	}
 
 	// only add the services to the op if there's something to add
-	if len(disabledSvcs) != 0 {
-		op.disabledServices = disabledSvcs
+	if len(linkCtx.PrevDisabledServices) != 0 {
+		op.disabledServices = linkCtx.PrevDisabledServices
 	}
 
 	if info.MountDir() == f.linkSnapFailTrigger {
@@ -903,8 +903,17 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	opts, err := SnapServiceOptions(st, snapsup.InstanceName())
+	if err != nil {
+		return err
+	}
+	linkCtx := backend.LinkContext{
+		PrevDisabledServices: svcsToDisable,
+		ServiceOptions:       opts,
+	}
+
 	snapst.Active = true
-	err = m.backend.LinkSnap(oldInfo, model, svcsToDisable, perfTimings)
+	err = m.backend.LinkSnap(oldInfo, model, linkCtx, perfTimings)
 	if err != nil {
 		return err
 	}
@@ -1184,9 +1193,18 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 		return err
 	}
 
+	opts, err := SnapServiceOptions(st, snapsup.InstanceName())
+	if err != nil {
+		return err
+	}
+	linkCtx := backend.LinkContext{
+		PrevDisabledServices: svcsToDisable,
+		ServiceOptions:       opts,
+	}
+
 	// XXX: this block is slightly ugly, find a pattern when we have more examples
 	model, _ := ModelFromTask(t)
-	err = m.backend.LinkSnap(newInfo, model, svcsToDisable, perfTimings)
+	err = m.backend.LinkSnap(newInfo, model, linkCtx, perfTimings)
 	// defer a cleanup helper which will unlink the snap if anything fails after
 	// this point
 	defer func() {
@@ -1894,6 +1912,12 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		if err != nil {
 			return err
 		}
+		if err := EnsureSnapAbsentFromQuotaGroup(st, snapsup.InstanceName()); err != nil {
+			return err
+		}
+		if err := DiscardHookRuns(st, snapsup.InstanceName()); err != nil {
+			return err
+		}
 		err = m.backend.DiscardSnapNamespace(snapsup.InstanceName())
 		if err != nil {
 			t.Errorf("cannot discard snap namespace %q, will retry in 3 mins: %s", snapsup.InstanceName(), err)
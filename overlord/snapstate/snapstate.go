@@ -47,6 +47,7 @@ import (
 	"github.com/snapcore/snapd/snap/channel"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/wrappers"
 )
 
 // control flags for doInstall
@@ -415,6 +416,25 @@ var CheckHealthHook = func(st *state.State, snapName string, rev snap.Revision)
 	panic("internal error: snapstate.CheckHealthHook is unset")
 }
 
+// SnapServiceOptions returns the options to use when generating the
+// service units of the given snap, e.g. to put them in the slice of the
+// quota group of the snap. It is set by servicestate.
+var SnapServiceOptions = func(st *state.State, instanceName string) (*wrappers.SnapServiceOptions, error) {
+	return nil, nil
+}
+
+// EnsureSnapAbsentFromQuotaGroup removes the given snap from its quota
+// group, if any, once the snap is gone. It is set by servicestate.
+var EnsureSnapAbsentFromQuotaGroup = func(st *state.State, instanceName string) error {
+	return nil
+}
+
+// DiscardHookRuns forgets the recorded hook runs of the given snap once
+// the snap is gone. It is set by hookstate.
+var DiscardHookRuns = func(st *state.State, instanceName string) error {
+	return nil
+}
+
 // WaitRestart will return a Retry error if there is a pending restart
 // and a real error if anything went wrong (like a rollback across
 // restarts)
@@ -85,3 +85,13 @@ func MockIsEnabled(isEnabled func() (bool, error)) (restore func()) {
 		selinuxIsEnabled = old
 	}
 }
+
+// MockIsEnforcing makes the system believe a certain SELinux enforcement state
+// is currently true
+func MockIsEnforcing(isEnforcing func() (bool, error)) (restore func()) {
+	old := selinuxIsEnforcing
+	selinuxIsEnforcing = isEnforcing
+	return func() {
+		selinuxIsEnforcing = old
+	}
+}
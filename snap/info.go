@@ -759,6 +759,16 @@ func (st StopModeType) Validate() error {
 	return fmt.Errorf(`"stop-mode" field contains invalid value %q`, st)
 }
 
+// DaemonScope represents the scope of a daemon, i.e. whether it runs
+// as a system service or as a service of every user session. Daemons
+// without an explicit scope are system daemons.
+type DaemonScope string
+
+const (
+	SystemDaemon DaemonScope = "system"
+	UserDaemon   DaemonScope = "user"
+)
+
 // AppInfo provides information about an app.
 type AppInfo struct {
 	Snap *Info
@@ -770,6 +780,7 @@ type AppInfo struct {
 	CommonID      string
 
 	Daemon          string
+	DaemonScope     DaemonScope
 	StopTimeout     timeout.Timeout
 	StartTimeout    timeout.Timeout
 	WatchdogTimeout timeout.Timeout
@@ -787,6 +798,10 @@ type AppInfo struct {
 	// https://github.com/snapcore/snapd/pull/794#discussion_r58688496
 	BusName string
 
+	// ActivatesOn lists the D-Bus slots of the snap whose bus names
+	// activate the service when first used
+	ActivatesOn []*SlotInfo
+
 	Plugs   map[string]*PlugInfo
 	Slots   map[string]*SlotInfo
 	Sockets map[string]*SocketInfo
@@ -798,9 +813,23 @@ type AppInfo struct {
 	After  []string
 	Before []string
 
+	// names of plugs and slots of this service whose connected
+	// services, in other snaps, this service will start after or before
+	AfterConnected  []string
+	BeforeConnected []string
+
 	Timer *TimerInfo
 
 	Autostart string
+
+	// resource controls of services, passed on to systemd; Nice and
+	// OOMScoreAdjust are nil when not set as zero is a meaningful value
+	MemoryMax      string
+	CPUWeight      int
+	TasksMax       int
+	Nice           *int
+	IOWeight       int
+	OOMScoreAdjust *int
 }
 
 // ScreenshotInfo provides information about a screenshot.
@@ -846,6 +875,10 @@ type HookInfo struct {
 	Environment  strutil.OrderedMap
 	CommandChain []string
 
+	// Timeout is the maximum time the hook may run for, if declared
+	// by the snap.
+	Timeout timeout.Timeout
+
 	Explicit bool
 }
 
@@ -941,6 +974,9 @@ func (app *AppInfo) ServiceName() string {
 
 // ServiceFile returns the systemd service file path for the daemon app.
 func (app *AppInfo) ServiceFile() string {
+	if app.DaemonScope == UserDaemon {
+		return filepath.Join(dirs.SnapUserServicesDir, app.ServiceName())
+	}
 	return filepath.Join(dirs.SnapServicesDir, app.ServiceName())
 }
 
@@ -959,6 +995,27 @@ func (app *AppInfo) IsService() bool {
 	return app.Daemon != ""
 }
 
+// IsUserService returns whether the app is a service running in the
+// session of every logged in user rather than as a system service.
+func (app *AppInfo) IsUserService() bool {
+	return app.IsService() && app.DaemonScope == UserDaemon
+}
+
+// PrivilegedResourceControls returns the names of the resource controls
+// of the app that are set to values granting it an advantage over the
+// rest of the system, like a negative nice value. Using those needs to be
+// allowed by the snap-declaration of the snap.
+func (app *AppInfo) PrivilegedResourceControls() []string {
+	var privileged []string
+	if app.Nice != nil && *app.Nice < 0 {
+		privileged = append(privileged, "nice")
+	}
+	if app.OOMScoreAdjust != nil && *app.OOMScoreAdjust < 0 {
+		privileged = append(privileged, "oom-score-adjust")
+	}
+	return privileged
+}
+
 // SecurityTag returns the hook-specific security tag.
 //
 // Security tags are used by various security subsystems as "profile names" and
@@ -72,7 +72,8 @@ type appYaml struct {
 	Command      string   `yaml:"command"`
 	CommandChain []string `yaml:"command-chain,omitempty"`
 
-	Daemon string `yaml:"daemon"`
+	Daemon      string      `yaml:"daemon"`
+	DaemonScope DaemonScope `yaml:"daemon-scope,omitempty"`
 
 	StopCommand     string          `yaml:"stop-command,omitempty"`
 	ReloadCommand   string          `yaml:"reload-command,omitempty"`
@@ -89,8 +90,9 @@ type appYaml struct {
 	SlotNames    []string         `yaml:"slots,omitempty"`
 	PlugNames    []string         `yaml:"plugs,omitempty"`
 
-	BusName  string `yaml:"bus-name,omitempty"`
-	CommonID string `yaml:"common-id,omitempty"`
+	BusName     string   `yaml:"bus-name,omitempty"`
+	ActivatesOn []string `yaml:"activates-on,omitempty"`
+	CommonID    string   `yaml:"common-id,omitempty"`
 
 	Environment strutil.OrderedMap `yaml:"environment,omitempty"`
 
@@ -99,9 +101,19 @@ type appYaml struct {
 	After  []string `yaml:"after,omitempty"`
 	Before []string `yaml:"before,omitempty"`
 
+	AfterConnected  []string `yaml:"after-connected,omitempty"`
+	BeforeConnected []string `yaml:"before-connected,omitempty"`
+
 	Timer string `yaml:"timer,omitempty"`
 
 	Autostart string `yaml:"autostart,omitempty"`
+
+	MemoryMax      string `yaml:"memory-max,omitempty"`
+	CPUWeight      int    `yaml:"cpu-weight,omitempty"`
+	TasksMax       int    `yaml:"tasks-max,omitempty"`
+	Nice           *int   `yaml:"nice,omitempty"`
+	IOWeight       int    `yaml:"io-weight,omitempty"`
+	OOMScoreAdjust *int   `yaml:"oom-score-adjust,omitempty"`
 }
 
 type hookYaml struct {
@@ -109,6 +121,7 @@ type hookYaml struct {
 	SlotNames    []string           `yaml:"slots,omitempty"`
 	Environment  strutil.OrderedMap `yaml:"environment,omitempty"`
 	CommandChain []string           `yaml:"command-chain,omitempty"`
+	Timeout      timeout.Timeout    `yaml:"timeout,omitempty"`
 }
 
 type layoutYaml struct {
@@ -349,6 +362,7 @@ func setAppsFromSnapYaml(y snapYaml, snap *Info, strk *scopedTracker) error {
 			CommandChain:    yApp.CommandChain,
 			StartTimeout:    yApp.StartTimeout,
 			Daemon:          yApp.Daemon,
+			DaemonScope:     yApp.DaemonScope,
 			StopTimeout:     yApp.StopTimeout,
 			StopCommand:     yApp.StopCommand,
 			ReloadCommand:   yApp.ReloadCommand,
@@ -363,8 +377,16 @@ func setAppsFromSnapYaml(y snapYaml, snap *Info, strk *scopedTracker) error {
 			RefreshMode:     yApp.RefreshMode,
 			Before:          yApp.Before,
 			After:           yApp.After,
+			AfterConnected:  yApp.AfterConnected,
+			BeforeConnected: yApp.BeforeConnected,
 			Autostart:       yApp.Autostart,
 			WatchdogTimeout: yApp.WatchdogTimeout,
+			MemoryMax:       yApp.MemoryMax,
+			CPUWeight:       yApp.CPUWeight,
+			TasksMax:        yApp.TasksMax,
+			Nice:            yApp.Nice,
+			IOWeight:        yApp.IOWeight,
+			OOMScoreAdjust:  yApp.OOMScoreAdjust,
 		}
 		if len(y.Plugs) > 0 || len(yApp.PlugNames) > 0 {
 			app.Plugs = make(map[string]*PlugInfo)
@@ -417,6 +439,17 @@ func setAppsFromSnapYaml(y snapYaml, snap *Info, strk *scopedTracker) error {
 			app.Slots[slotName] = slot
 			slot.Apps[appName] = app
 		}
+		for _, slotName := range yApp.ActivatesOn {
+			slot, ok := snap.Slots[slotName]
+			if !ok {
+				return fmt.Errorf("invalid activates-on value %q on app %q: slot not found", slotName, appName)
+			}
+			app.ActivatesOn = append(app.ActivatesOn, slot)
+			// Implicitly bind the slot to the app
+			strk.markSlot(slot)
+			app.Slots[slotName] = slot
+			slot.Apps[appName] = app
+		}
 		for name, data := range yApp.Sockets {
 			app.Sockets[name] = &SocketInfo{
 				App:          app,
@@ -451,6 +484,7 @@ func setHooksFromSnapYaml(y snapYaml, snap *Info, strk *scopedTracker) {
 			Name:         hookName,
 			Environment:  yHook.Environment,
 			CommandChain: yHook.CommandChain,
+			Timeout:      yHook.Timeout,
 			Explicit:     true,
 		}
 		if len(y.Plugs) > 0 || len(yHook.PlugNames) > 0 {
@@ -939,6 +939,19 @@ hooks:
 	})
 }
 
+func (s *YamlSuite) TestUnmarshalHookTimeout(c *C) {
+	// NOTE: yaml content cannot use tabs, indent the section with spaces.
+	info, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+hooks:
+    test-hook:
+        timeout: 30m
+`))
+	c.Assert(err, IsNil)
+	c.Assert(info.Hooks, HasLen, 1)
+	c.Check(info.Hooks["test-hook"].Timeout, Equals, timeout.Timeout(30*time.Minute))
+}
+
 func (s *YamlSuite) TestUnmarshalUnsupportedHook(c *C) {
 	s.restore()
 	hookType := snap.NewHookType(regexp.MustCompile("not-test-hook"))
@@ -1672,6 +1685,60 @@ apps:
 	})
 }
 
+func (s *YamlSuite) TestSnapYamlAppConnectedStartOrder(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ foo:
+   daemon: simple
+   plugs: [db]
+   slots: [feed]
+   after-connected: [db]
+   before-connected: [feed]
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	c.Check(info.Apps["foo"].AfterConnected, DeepEquals, []string{"db"})
+	c.Check(info.Apps["foo"].BeforeConnected, DeepEquals, []string{"feed"})
+}
+
+func (s *YamlSuite) TestSnapYamlAppActivatesOn(c *C) {
+	y := []byte(`name: wat
+version: 42
+slots:
+ dbus-slot:
+   interface: dbus
+   bus: system
+   name: org.example.Foo
+apps:
+ foo:
+   daemon: simple
+   activates-on: [dbus-slot]
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	slot := info.Slots["dbus-slot"]
+	app := info.Apps["foo"]
+	c.Check(app.ActivatesOn, DeepEquals, []*snap.SlotInfo{slot})
+	// the activated slot is implicitly bound to the app
+	c.Check(app.Slots, DeepEquals, map[string]*snap.SlotInfo{"dbus-slot": slot})
+	c.Check(slot.Apps, DeepEquals, map[string]*snap.AppInfo{"foo": app})
+}
+
+func (s *YamlSuite) TestSnapYamlAppActivatesOnMissingSlot(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ foo:
+   daemon: simple
+   activates-on: [dbus-slot]
+`)
+	_, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, ErrorMatches, `invalid activates-on value "dbus-slot" on app "foo": slot not found`)
+}
+
 func (s *YamlSuite) TestSnapYamlWatchdog(c *C) {
 	y := []byte(`
 name: foo
@@ -1686,6 +1753,42 @@ apps:
 	c.Check(info.Apps["foo"].WatchdogTimeout, Equals, timeout.Timeout(12*time.Second))
 }
 
+func (s *YamlSuite) TestSnapYamlResourceControls(c *C) {
+	y := []byte(`
+name: foo
+version: 1.0
+apps:
+  foo:
+    daemon: simple
+    memory-max: 512M
+    cpu-weight: 200
+    tasks-max: 64
+    nice: -5
+    io-weight: 50
+    oom-score-adjust: 0
+  bar:
+    daemon: simple
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	app := info.Apps["foo"]
+	c.Check(app.MemoryMax, Equals, "512M")
+	c.Check(app.CPUWeight, Equals, 200)
+	c.Check(app.TasksMax, Equals, 64)
+	c.Assert(app.Nice, NotNil)
+	c.Check(*app.Nice, Equals, -5)
+	c.Check(app.IOWeight, Equals, 50)
+	c.Assert(app.OOMScoreAdjust, NotNil)
+	c.Check(*app.OOMScoreAdjust, Equals, 0)
+	c.Check(app.PrivilegedResourceControls(), DeepEquals, []string{"nice"})
+
+	app = info.Apps["bar"]
+	c.Check(app.Nice, IsNil)
+	c.Check(app.OOMScoreAdjust, IsNil)
+	c.Check(app.PrivilegedResourceControls(), HasLen, 0)
+}
+
 func (s *YamlSuite) TestLayout(c *C) {
 	y := []byte(`
 name: foo
@@ -0,0 +1,303 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package quota defines the quota groups snaps can be put in to limit
+// the resources used by their services.
+package quota
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+)
+
+// Group is a quota group of snaps. The services of the snaps in the
+// group run in a systemd slice which enforces the limits of the group;
+// groups can be nested, in which case the limits of the parent group
+// apply to all of its sub-groups together.
+type Group struct {
+	Name string `json:"name"`
+
+	// MemoryLimit is the memory limit of the group in bytes, 0 means
+	// there is no limit.
+	MemoryLimit uint64 `json:"memory-limit,omitempty"`
+	// CPULimit is the CPU limit of the group in percent of a single
+	// CPU, 0 means there is no limit.
+	CPULimit int `json:"cpu-limit,omitempty"`
+
+	ParentGroup string   `json:"parent-group,omitempty"`
+	SubGroups   []string `json:"sub-groups,omitempty"`
+	Snaps       []string `json:"snaps,omitempty"`
+
+	parentGroup *Group
+	subGroups   []*Group
+}
+
+var validGroupName = regexp.MustCompile(`^[a-z0-9](?:-?[a-z0-9])*$`)
+
+// maxGroupNameLength keeps the slice names of nested groups reasonable.
+const maxGroupNameLength = 24
+
+// ValidateGroupName checks that the given name is a valid quota group
+// name.
+func ValidateGroupName(name string) error {
+	if name == "" {
+		return fmt.Errorf("group name must not be empty")
+	}
+	if len(name) > maxGroupNameLength {
+		return fmt.Errorf("group name %q is too long, it must be at most %d characters", name, maxGroupNameLength)
+	}
+	if !validGroupName.MatchString(name) {
+		return fmt.Errorf("invalid group name %q: must contain only lowercase letters, digits and hyphens, and cannot start or end with a hyphen", name)
+	}
+	return nil
+}
+
+// NewGroup creates a new top-level quota group with the given limits.
+func NewGroup(name string, memoryLimit uint64, cpuLimit int) (*Group, error) {
+	grp := &Group{
+		Name:        name,
+		MemoryLimit: memoryLimit,
+		CPULimit:    cpuLimit,
+	}
+	if err := grp.validate(); err != nil {
+		return nil, err
+	}
+	return grp, nil
+}
+
+// NewSubGroup creates a new quota group nested in the receiver.
+func (grp *Group) NewSubGroup(name string, memoryLimit uint64, cpuLimit int) (*Group, error) {
+	sub := &Group{
+		Name:        name,
+		MemoryLimit: memoryLimit,
+		CPULimit:    cpuLimit,
+		ParentGroup: grp.Name,
+		parentGroup: grp,
+	}
+	if err := sub.validate(); err != nil {
+		return nil, err
+	}
+	grp.SubGroups = append(grp.SubGroups, name)
+	grp.subGroups = append(grp.subGroups, sub)
+	return sub, nil
+}
+
+// Parent returns the group the receiver is nested in, or nil for a
+// top-level group.
+func (grp *Group) Parent() *Group {
+	return grp.parentGroup
+}
+
+func (grp *Group) validate() error {
+	if err := ValidateGroupName(grp.Name); err != nil {
+		return err
+	}
+	if grp.CPULimit < 0 {
+		return fmt.Errorf("group %q has invalid CPU limit: must be positive", grp.Name)
+	}
+	if grp.ParentGroup != "" {
+		if grp.ParentGroup == grp.Name {
+			return fmt.Errorf("group %q cannot be its own parent", grp.Name)
+		}
+		parent := grp.parentGroup
+		if parent == nil || parent.Name != grp.ParentGroup {
+			return fmt.Errorf("internal error: parent group of %q not resolved", grp.Name)
+		}
+		if parent.MemoryLimit != 0 && (grp.MemoryLimit == 0 || grp.MemoryLimit > parent.MemoryLimit) {
+			return fmt.Errorf("group %q memory limit must not exceed the limit of its parent group %q (%s)", grp.Name, parent.Name, strutil.SizeToStr(int64(parent.MemoryLimit)))
+		}
+		if parent.CPULimit != 0 && (grp.CPULimit == 0 || grp.CPULimit > parent.CPULimit) {
+			return fmt.Errorf("group %q CPU limit must not exceed the limit of its parent group %q (%d%%)", grp.Name, parent.Name, parent.CPULimit)
+		}
+	}
+	return nil
+}
+
+// ResolveCrossReferences links the groups of the given map, as loaded
+// from the state, with their parent and sub-groups and validates them.
+func ResolveCrossReferences(grps map[string]*Group) error {
+	for name, grp := range grps {
+		if grp.Name != name {
+			return fmt.Errorf("group %q has mismatched name %q", name, grp.Name)
+		}
+		grp.parentGroup = nil
+		grp.subGroups = nil
+	}
+	for name, grp := range grps {
+		if grp.ParentGroup != "" {
+			parent, ok := grps[grp.ParentGroup]
+			if !ok {
+				return fmt.Errorf("group %q has missing parent group %q", name, grp.ParentGroup)
+			}
+			if !strutil.ListContains(parent.SubGroups, name) {
+				return fmt.Errorf("group %q is not listed as a sub-group of its parent group %q", name, parent.Name)
+			}
+			grp.parentGroup = parent
+		}
+		for _, subName := range grp.SubGroups {
+			sub, ok := grps[subName]
+			if !ok {
+				return fmt.Errorf("group %q has missing sub-group %q", name, subName)
+			}
+			if sub.ParentGroup != name {
+				return fmt.Errorf("group %q lists %q as a sub-group, but its parent group is %q", name, subName, sub.ParentGroup)
+			}
+			grp.subGroups = append(grp.subGroups, sub)
+		}
+	}
+	for _, grp := range grps {
+		// detect loops in the parent chain
+		seen := map[string]bool{grp.Name: true}
+		for p := grp.parentGroup; p != nil; p = p.parentGroup {
+			if seen[p.Name] {
+				return fmt.Errorf("group %q is nested in itself", grp.Name)
+			}
+			seen[p.Name] = true
+		}
+		if err := grp.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ancestry returns the names of the group and of all the groups it is
+// nested in, outermost first.
+func (grp *Group) ancestry() []string {
+	var names []string
+	for g := grp; g != nil; g = g.parentGroup {
+		names = append([]string{g.Name}, names...)
+	}
+	return names
+}
+
+// SliceFileName returns the name of the systemd slice unit of the
+// group. Nesting is expressed with dashes as systemd expects, so the
+// group names are escaped.
+func (grp *Group) SliceFileName() string {
+	names := grp.ancestry()
+	for i, name := range names {
+		names[i] = systemd.EscapeUnitNamePath(name)
+	}
+	return fmt.Sprintf("snap.%s.slice", strings.Join(names, "-"))
+}
+
+// cgroupPath returns the path of the cgroup of the group, relative to
+// the root of a cgroup hierarchy; systemd nests the cgroup of a slice
+// in the ones of its parent slices.
+func (grp *Group) cgroupPath() string {
+	var parts []string
+	for g := grp; g != nil; g = g.parentGroup {
+		parts = append([]string{g.SliceFileName()}, parts...)
+	}
+	return filepath.Join(parts...)
+}
+
+func readCgroupUint(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// CurrentMemoryUsage returns the memory currently used by the services
+// of the group, read from the cgroup of its slice. It is 0 when the
+// slice is not active.
+func (grp *Group) CurrentMemoryUsage() (uint64, error) {
+	cgroupRoot := filepath.Join(dirs.GlobalRootDir, "/sys/fs/cgroup")
+	var path string
+	if cgroup.IsUnified() {
+		path = filepath.Join(cgroupRoot, grp.cgroupPath(), "memory.current")
+	} else {
+		path = filepath.Join(cgroupRoot, "memory", grp.cgroupPath(), "memory.usage_in_bytes")
+	}
+	usage, err := readCgroupUint(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot read memory usage of quota group %q: %v", grp.Name, err)
+	}
+	return usage, nil
+}
+
+// CurrentCPUUsage returns the CPU time used by the services of the group
+// since its slice was started, read from the cgroup of the slice. It is 0
+// when the slice is not active.
+func (grp *Group) CurrentCPUUsage() (time.Duration, error) {
+	cgroupRoot := filepath.Join(dirs.GlobalRootDir, "/sys/fs/cgroup")
+	if !cgroup.IsUnified() {
+		// cpuacct.usage is in nanoseconds
+		usage, err := readCgroupUint(filepath.Join(cgroupRoot, "cpuacct", grp.cgroupPath(), "cpuacct.usage"))
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot read CPU usage of quota group %q: %v", grp.Name, err)
+		}
+		return time.Duration(usage), nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(cgroupRoot, grp.cgroupPath(), "cpu.stat"))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot read CPU usage of quota group %q: %v", grp.Name, err)
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fields := strings.Fields(string(line))
+		if len(fields) != 2 || fields[0] != "usage_usec" {
+			continue
+		}
+		usec, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot read CPU usage of quota group %q: %v", grp.Name, err)
+		}
+		return time.Duration(usec) * time.Microsecond, nil
+	}
+	return 0, fmt.Errorf("cannot read CPU usage of quota group %q: no usage_usec in cpu.stat", grp.Name)
+}
+
+// SortedGroups returns the groups of the given map sorted by name,
+// parent groups before their sub-groups.
+func SortedGroups(grps map[string]*Group) []*Group {
+	all := make([]*Group, 0, len(grps))
+	for _, grp := range grps {
+		all = append(all, grp)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].ancestry(), "/") < strings.Join(all[j].ancestry(), "/")
+	})
+	return all
+}
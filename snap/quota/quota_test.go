@@ -0,0 +1,221 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package quota_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type quotaSuite struct{}
+
+var _ = Suite(&quotaSuite{})
+
+func (s *quotaSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+}
+
+func (s *quotaSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("/")
+}
+
+func (s *quotaSuite) TestValidateGroupName(c *C) {
+	for _, name := range []string{"a", "foo", "foo-bar", "f00", "0a-1"} {
+		c.Check(quota.ValidateGroupName(name), IsNil, Commentf(name))
+	}
+	for _, t := range []struct {
+		name string
+		err  string
+	}{
+		{"", `group name must not be empty`},
+		{"-foo", `invalid group name "-foo": .*`},
+		{"foo-", `invalid group name "foo-": .*`},
+		{"foo--bar", `invalid group name "foo--bar": .*`},
+		{"Foo", `invalid group name "Foo": .*`},
+		{"foo.bar", `invalid group name "foo.bar": .*`},
+		{"aaaaaaaaaaaaaaaaaaaaaaaaa", `group name "a+" is too long, it must be at most 24 characters`},
+	} {
+		c.Check(quota.ValidateGroupName(t.name), ErrorMatches, t.err, Commentf(t.name))
+	}
+}
+
+func (s *quotaSuite) TestNewGroups(c *C) {
+	grp, err := quota.NewGroup("foo", 1024*1024*1024, 100)
+	c.Assert(err, IsNil)
+	c.Check(grp.Parent(), IsNil)
+	c.Check(grp.SliceFileName(), Equals, "snap.foo.slice")
+
+	sub, err := grp.NewSubGroup("bar-baz", 512*1024*1024, 50)
+	c.Assert(err, IsNil)
+	c.Check(sub.Parent(), Equals, grp)
+	c.Check(sub.ParentGroup, Equals, "foo")
+	c.Check(grp.SubGroups, DeepEquals, []string{"bar-baz"})
+	c.Check(sub.SliceFileName(), Equals, `snap.foo-bar\x2dbaz.slice`)
+
+	_, err = quota.NewGroup("foo_", 0, 0)
+	c.Check(err, ErrorMatches, `invalid group name "foo_": .*`)
+	_, err = quota.NewGroup("foo", 0, -1)
+	c.Check(err, ErrorMatches, `group "foo" has invalid CPU limit: must be positive`)
+}
+
+func (s *quotaSuite) TestNewSubGroupLimits(c *C) {
+	grp, err := quota.NewGroup("foo", 1024, 100)
+	c.Assert(err, IsNil)
+
+	_, err = grp.NewSubGroup("bar", 2048, 50)
+	c.Check(err, ErrorMatches, `group "bar" memory limit must not exceed the limit of its parent group "foo" \(1kB\)`)
+	_, err = grp.NewSubGroup("bar", 0, 50)
+	c.Check(err, ErrorMatches, `group "bar" memory limit must not exceed the limit of its parent group "foo" \(1kB\)`)
+	_, err = grp.NewSubGroup("bar", 1024, 200)
+	c.Check(err, ErrorMatches, `group "bar" CPU limit must not exceed the limit of its parent group "foo" \(100%\)`)
+	c.Check(grp.SubGroups, HasLen, 0)
+
+	// no limits in the parent, anything goes
+	grp, err = quota.NewGroup("unlimited", 0, 0)
+	c.Assert(err, IsNil)
+	_, err = grp.NewSubGroup("bar", 2000, 200)
+	c.Check(err, IsNil)
+}
+
+func (s *quotaSuite) TestResolveCrossReferences(c *C) {
+	grps := map[string]*quota.Group{
+		"foo":   {Name: "foo", MemoryLimit: 1000, SubGroups: []string{"bar"}},
+		"bar":   {Name: "bar", MemoryLimit: 500, ParentGroup: "foo", SubGroups: []string{"baz"}},
+		"baz":   {Name: "baz", MemoryLimit: 100, ParentGroup: "bar", Snaps: []string{"some-snap"}},
+		"other": {Name: "other"},
+	}
+	c.Assert(quota.ResolveCrossReferences(grps), IsNil)
+	c.Check(grps["baz"].Parent(), Equals, grps["bar"])
+	c.Check(grps["bar"].Parent(), Equals, grps["foo"])
+	c.Check(grps["baz"].SliceFileName(), Equals, "snap.foo-bar-baz.slice")
+
+	var names []string
+	for _, grp := range quota.SortedGroups(grps) {
+		names = append(names, grp.Name)
+	}
+	c.Check(names, DeepEquals, []string{"foo", "bar", "baz", "other"})
+}
+
+func (s *quotaSuite) TestResolveCrossReferencesErrors(c *C) {
+	for _, t := range []struct {
+		grps map[string]*quota.Group
+		err  string
+	}{{
+		map[string]*quota.Group{"foo": {Name: "bar"}},
+		`group "foo" has mismatched name "bar"`,
+	}, {
+		map[string]*quota.Group{"foo": {Name: "foo", ParentGroup: "bar"}},
+		`group "foo" has missing parent group "bar"`,
+	}, {
+		map[string]*quota.Group{"foo": {Name: "foo", SubGroups: []string{"bar"}}},
+		`group "foo" has missing sub-group "bar"`,
+	}, {
+		map[string]*quota.Group{
+			"foo": {Name: "foo"},
+			"bar": {Name: "bar", ParentGroup: "foo"},
+		},
+		`group "bar" is not listed as a sub-group of its parent group "foo"`,
+	}, {
+		map[string]*quota.Group{
+			"foo": {Name: "foo", SubGroups: []string{"bar"}},
+			"bar": {Name: "bar"},
+		},
+		`group "foo" lists "bar" as a sub-group, but its parent group is ""`,
+	}, {
+		map[string]*quota.Group{
+			"foo": {Name: "foo", ParentGroup: "bar", SubGroups: []string{"bar"}},
+			"bar": {Name: "bar", ParentGroup: "foo", SubGroups: []string{"foo"}},
+		},
+		`group "(foo|bar)" is nested in itself`,
+	}, {
+		map[string]*quota.Group{
+			"foo": {Name: "foo", MemoryLimit: 100, SubGroups: []string{"bar"}},
+			"bar": {Name: "bar", MemoryLimit: 200, ParentGroup: "foo"},
+		},
+		`group "bar" memory limit must not exceed the limit of its parent group "foo" \(100B\)`,
+	}} {
+		c.Check(quota.ResolveCrossReferences(t.grps), ErrorMatches, t.err)
+	}
+}
+
+func (s *quotaSuite) writeCgroupFile(c *C, path, content string) {
+	path = filepath.Join(dirs.GlobalRootDir, "/sys/fs/cgroup", path)
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+}
+
+func (s *quotaSuite) TestCurrentUsageUnified(c *C) {
+	restore := cgroup.MockVersion(cgroup.V2, nil)
+	defer restore()
+
+	grp, err := quota.NewGroup("foo", 0, 0)
+	c.Assert(err, IsNil)
+	sub, err := grp.NewSubGroup("bar", 0, 0)
+	c.Assert(err, IsNil)
+
+	// slice not active
+	mem, err := sub.CurrentMemoryUsage()
+	c.Assert(err, IsNil)
+	c.Check(mem, Equals, uint64(0))
+	cpu, err := sub.CurrentCPUUsage()
+	c.Assert(err, IsNil)
+	c.Check(cpu, Equals, time.Duration(0))
+
+	s.writeCgroupFile(c, "snap.foo.slice/snap.foo-bar.slice/memory.current", "4096\n")
+	s.writeCgroupFile(c, "snap.foo.slice/snap.foo-bar.slice/cpu.stat", "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n")
+	mem, err = sub.CurrentMemoryUsage()
+	c.Assert(err, IsNil)
+	c.Check(mem, Equals, uint64(4096))
+	cpu, err = sub.CurrentCPUUsage()
+	c.Assert(err, IsNil)
+	c.Check(cpu, Equals, 1500*time.Millisecond)
+
+	s.writeCgroupFile(c, "snap.foo.slice/snap.foo-bar.slice/memory.current", "garbage\n")
+	_, err = sub.CurrentMemoryUsage()
+	c.Check(err, ErrorMatches, `cannot read memory usage of quota group "bar": .*invalid syntax`)
+}
+
+func (s *quotaSuite) TestCurrentUsageV1(c *C) {
+	restore := cgroup.MockVersion(cgroup.V1, nil)
+	defer restore()
+
+	grp, err := quota.NewGroup("foo", 0, 0)
+	c.Assert(err, IsNil)
+
+	s.writeCgroupFile(c, "memory/snap.foo.slice/memory.usage_in_bytes", "8192\n")
+	s.writeCgroupFile(c, "cpuacct/snap.foo.slice/cpuacct.usage", "2000000000\n")
+	mem, err := grp.CurrentMemoryUsage()
+	c.Assert(err, IsNil)
+	c.Check(mem, Equals, uint64(8192))
+	cpu, err := grp.CurrentCPUUsage()
+	c.Assert(err, IsNil)
+	c.Check(cpu, Equals, 2*time.Second)
+}
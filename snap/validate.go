@@ -28,6 +28,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/snapcore/snapd/osutil"
@@ -157,9 +158,19 @@ func ValidateHook(hook *HookInfo) error {
 		}
 	}
 
+	if hook.Timeout < 0 {
+		return fmt.Errorf("hook timeout cannot be negative")
+	}
+	if time.Duration(hook.Timeout) > MaxHookTimeout {
+		return fmt.Errorf("hook timeout cannot be longer than %v", MaxHookTimeout)
+	}
+
 	return nil
 }
 
+// MaxHookTimeout is the longest timeout a snap can declare for its hooks.
+const MaxHookTimeout = 2 * time.Hour
+
 // ValidateAlias checks if a string can be used as an alias name.
 func ValidateAlias(alias string) error {
 	return naming.ValidateAlias(alias)
@@ -318,6 +329,11 @@ func Validate(info *Info) error {
 		return err
 	}
 
+	// validate that each slot activates a single service
+	if err := validateActivatesOnUnique(info.Services()); err != nil {
+		return err
+	}
+
 	// validate aliases
 	for alias, app := range info.LegacyAliases {
 		if err := naming.ValidateAlias(alias); err != nil {
@@ -513,6 +529,73 @@ func validateAppOrderNames(app *AppInfo, dependencies []string) error {
 		if !other.IsService() {
 			return fmt.Errorf("before/after references a non-service application %q", dep)
 		}
+
+		if other.IsUserService() != app.IsUserService() {
+			return fmt.Errorf("before/after references service with different daemon-scope %q", dep)
+		}
+	}
+	return nil
+}
+
+func validateAppOrderConnected(app *AppInfo, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if !app.IsService() {
+		return errors.New("must be a service to define before-connected/after-connected ordering")
+	}
+	if app.IsUserService() {
+		return errors.New("cannot define before-connected/after-connected ordering for user services")
+	}
+
+	for _, name := range names {
+		// the services of the other snaps are reached through
+		// connections of plugs and slots bound to the service
+		_, isPlug := app.Plugs[name]
+		_, isSlot := app.Slots[name]
+		if !isPlug && !isSlot {
+			return fmt.Errorf("before-connected/after-connected references plug or slot %q not bound to the application", name)
+		}
+	}
+	return nil
+}
+
+func validateAppActivatesOn(app *AppInfo) error {
+	if len(app.ActivatesOn) == 0 {
+		return nil
+	}
+	if !app.IsService() {
+		return errors.New("activates-on is only applicable to services")
+	}
+
+	scope := SystemDaemon
+	if app.IsUserService() {
+		scope = UserDaemon
+	}
+	for _, slot := range app.ActivatesOn {
+		// the slot attributes are validated by the dbus interface
+		if slot.Interface != "dbus" {
+			return fmt.Errorf("invalid activates-on value %q: slot does not use dbus interface", slot.Name)
+		}
+		bus, _ := slot.Attrs["bus"].(string)
+		if (bus == "session" && scope != UserDaemon) || (bus == "system" && scope != SystemDaemon) {
+			return fmt.Errorf("invalid activates-on value %q: bus %q does not match daemon-scope %q", slot.Name, bus, scope)
+		}
+	}
+	return nil
+}
+
+func validateActivatesOnUnique(apps []*AppInfo) error {
+	activatedBy := make(map[string]string)
+	for _, app := range apps {
+		for _, slot := range app.ActivatesOn {
+			if other, ok := activatedBy[slot.Name]; ok {
+				names := []string{other, app.Name}
+				sort.Strings(names)
+				return fmt.Errorf("cannot have slot %q activate both %q and %q", slot.Name, names[0], names[1])
+			}
+			activatedBy[slot.Name] = app.Name
+		}
 	}
 	return nil
 }
@@ -540,6 +623,49 @@ func validateAppTimeouts(app *AppInfo) error {
 	return nil
 }
 
+var validMemoryMax = regexp.MustCompile(`^[1-9][0-9]*[KMGT]?$`)
+
+func validateAppResourceControls(app *AppInfo) error {
+	type T struct {
+		desc     string
+		set      bool
+		value    int
+		min, max int
+	}
+	checks := []T{
+		{"cpu-weight", app.CPUWeight != 0, app.CPUWeight, 1, 10000},
+		{"io-weight", app.IOWeight != 0, app.IOWeight, 1, 10000},
+		{"tasks-max", app.TasksMax != 0, app.TasksMax, 1, 1<<31 - 1},
+	}
+	if app.Nice != nil {
+		checks = append(checks, T{"nice", true, *app.Nice, -20, 19})
+	}
+	if app.OOMScoreAdjust != nil {
+		checks = append(checks, T{"oom-score-adjust", true, *app.OOMScoreAdjust, -1000, 1000})
+	}
+	for _, t := range checks {
+		if !t.set {
+			continue
+		}
+		if !app.IsService() {
+			return fmt.Errorf("%s is only applicable to services", t.desc)
+		}
+		if t.value < t.min || t.value > t.max {
+			return fmt.Errorf("%s must be between %d and %d", t.desc, t.min, t.max)
+		}
+	}
+
+	if app.MemoryMax != "" {
+		if !app.IsService() {
+			return errors.New("memory-max is only applicable to services")
+		}
+		if !validMemoryMax.MatchString(app.MemoryMax) {
+			return fmt.Errorf("memory-max has invalid value %q: expected a size in bytes optionally followed by K, M, G or T", app.MemoryMax)
+		}
+	}
+	return nil
+}
+
 func validateAppTimer(app *AppInfo) error {
 	if app.Timer == nil {
 		return nil
@@ -602,6 +728,20 @@ func ValidateApp(app *AppInfo) error {
 		return fmt.Errorf(`"daemon" field contains invalid value %q`, app.Daemon)
 	}
 
+	switch app.DaemonScope {
+	case "":
+		// system daemon or not a daemon at all
+	case SystemDaemon, UserDaemon:
+		if app.Daemon == "" {
+			return fmt.Errorf(`"daemon-scope" can only be set for daemons`)
+		}
+	default:
+		return fmt.Errorf(`invalid "daemon-scope": %q`, app.DaemonScope)
+	}
+	if app.DaemonScope == UserDaemon && (len(app.Sockets) > 0 || app.Timer != nil) {
+		return fmt.Errorf("user daemons cannot be socket or timer activated")
+	}
+
 	// Validate app name
 	if !ValidAppName(app.Name) {
 		return fmt.Errorf("cannot have %q as app name - use letters, digits, and dash as separator", app.Name)
@@ -651,11 +791,24 @@ func ValidateApp(app *AppInfo) error {
 	if err := validateAppOrderNames(app, app.After); err != nil {
 		return err
 	}
+	if err := validateAppOrderConnected(app, app.BeforeConnected); err != nil {
+		return err
+	}
+	if err := validateAppOrderConnected(app, app.AfterConnected); err != nil {
+		return err
+	}
+	if err := validateAppActivatesOn(app); err != nil {
+		return err
+	}
 
 	if err := validateAppTimeouts(app); err != nil {
 		return err
 	}
 
+	if err := validateAppResourceControls(app); err != nil {
+		return err
+	}
+
 	// validate stop-mode
 	if err := app.StopMode.Validate(); err != nil {
 		return err
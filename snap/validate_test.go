@@ -24,12 +24,14 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	. "gopkg.in/check.v1"
 
 	. "github.com/snapcore/snapd/snap"
 
 	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timeout"
 )
 
 type ValidateSuite struct {
@@ -169,6 +171,13 @@ func (s *ValidateSuite) TestValidateHook(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestValidateHookTimeout(c *C) {
+	c.Check(ValidateHook(&HookInfo{Name: "install", Timeout: timeout.Timeout(time.Hour)}), IsNil)
+	c.Check(ValidateHook(&HookInfo{Name: "install", Timeout: timeout.Timeout(MaxHookTimeout)}), IsNil)
+	c.Check(ValidateHook(&HookInfo{Name: "install", Timeout: timeout.Timeout(-time.Second)}), ErrorMatches, `hook timeout cannot be negative`)
+	c.Check(ValidateHook(&HookInfo{Name: "install", Timeout: timeout.Timeout(3 * time.Hour)}), ErrorMatches, `hook timeout cannot be longer than 2h0m0s`)
+}
+
 // ValidateApp
 
 func (s *ValidateSuite) TestValidateAppSockets(c *C) {
@@ -1172,6 +1181,83 @@ apps:
 	}
 }
 
+func (s *ValidateSuite) TestValidateAppOrderConnected(c *C) {
+	meta := []byte(`
+name: foo
+version: 1.0
+plugs:
+  db:
+    interface: content
+slots:
+  feed:
+    interface: content
+`)
+	for _, tc := range []struct {
+		desc string
+		err  string
+	}{
+		{"apps:\n  foo:\n    daemon: simple\n    plugs: [db]\n    after-connected: [db]\n", ""},
+		{"apps:\n  foo:\n    daemon: simple\n    slots: [feed]\n    before-connected: [feed]\n", ""},
+		// plugs and slots declared at the top level are bound to all apps
+		{"apps:\n  foo:\n    daemon: simple\n    after-connected: [db]\n    before-connected: [feed]\n", ""},
+		{"apps:\n  foo:\n    after-connected: [db]\n", `must be a service to define before-connected/after-connected ordering`},
+		{"apps:\n  foo:\n    daemon: simple\n    daemon-scope: user\n    after-connected: [db]\n", `cannot define before-connected/after-connected ordering for user services`},
+		{"apps:\n  foo:\n    daemon: simple\n    after-connected: [other]\n", `before-connected/after-connected references plug or slot "other" not bound to the application`},
+		{"apps:\n  foo:\n    daemon: simple\n    plugs: [db]\n  bar:\n    daemon: simple\n    plugs: [home]\n    before-connected: [db]\n", `before-connected/after-connected references plug or slot "db" not bound to the application`},
+	} {
+		info, err := InfoFromSnapYaml(append(meta, tc.desc...))
+		c.Assert(err, IsNil)
+
+		err = Validate(info)
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, `invalid definition of application "(foo|bar)": `+tc.err, Commentf(tc.desc))
+		} else {
+			c.Check(err, IsNil, Commentf(tc.desc))
+		}
+	}
+}
+
+func (s *ValidateSuite) TestValidateAppActivatesOn(c *C) {
+	meta := []byte(`
+name: foo
+version: 1.0
+slots:
+  system-slot:
+    interface: dbus
+    bus: system
+    name: org.example.System
+  session-slot:
+    interface: dbus
+    bus: session
+    name: org.example.Session
+  content-slot:
+    interface: content
+`)
+	for _, tc := range []struct {
+		desc string
+		err  string
+	}{
+		{"apps:\n  foo:\n    daemon: simple\n    activates-on: [system-slot]\n", ""},
+		{"apps:\n  foo:\n    daemon: simple\n    daemon-scope: user\n    activates-on: [session-slot]\n", ""},
+		{"apps:\n  foo:\n    daemon: simple\n    activates-on: [system-slot]\n  bar:\n    daemon: simple\n    daemon-scope: user\n    activates-on: [session-slot]\n", ""},
+		{"apps:\n  foo:\n    activates-on: [system-slot]\n", `invalid definition of application "foo": activates-on is only applicable to services`},
+		{"apps:\n  foo:\n    daemon: simple\n    activates-on: [content-slot]\n", `invalid definition of application "foo": invalid activates-on value "content-slot": slot does not use dbus interface`},
+		{"apps:\n  foo:\n    daemon: simple\n    activates-on: [session-slot]\n", `invalid definition of application "foo": invalid activates-on value "session-slot": bus "session" does not match daemon-scope "system"`},
+		{"apps:\n  foo:\n    daemon: simple\n    daemon-scope: user\n    activates-on: [system-slot]\n", `invalid definition of application "foo": invalid activates-on value "system-slot": bus "system" does not match daemon-scope "user"`},
+		{"apps:\n  foo:\n    daemon: simple\n    activates-on: [system-slot]\n  bar:\n    daemon: simple\n    activates-on: [system-slot]\n", `cannot have slot "system-slot" activate both "bar" and "foo"`},
+	} {
+		info, err := InfoFromSnapYaml(append(meta, tc.desc...))
+		c.Assert(err, IsNil)
+
+		err = Validate(info)
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, tc.err, Commentf(tc.desc))
+		} else {
+			c.Check(err, IsNil, Commentf(tc.desc))
+		}
+	}
+}
+
 func (s *ValidateSuite) TestValidateAppWatchdogTimeout(c *C) {
 	s.testValidateAppTimeout(c, "watchdog")
 }
@@ -1560,6 +1646,79 @@ apps:
 	}
 }
 
+func (s *ValidateSuite) TestValidateAppDaemonScope(c *C) {
+	meta := []byte(`
+name: foo
+version: 1.0
+apps:
+  foo:
+`)
+	tcs := []struct {
+		desc string
+		err  string
+	}{
+		{"    daemon: simple\n", ""},
+		{"    daemon: simple\n    daemon-scope: system\n", ""},
+		{"    daemon: simple\n    daemon-scope: user\n", ""},
+		{"    daemon-scope: user\n", `"daemon-scope" can only be set for daemons`},
+		{"    daemon: simple\n    daemon-scope: session\n", `invalid "daemon-scope": "session"`},
+		{"    daemon: simple\n    daemon-scope: user\n    timer: 10:00\n", `user daemons cannot be socket or timer activated`},
+		{"    daemon: simple\n    daemon-scope: user\n    after: [bar]\n  bar:\n    daemon: simple\n", `before/after references service with different daemon-scope "bar"`},
+	}
+	for _, tc := range tcs {
+		c.Logf("trying %q", tc.desc)
+		info, err := InfoFromSnapYaml(append(meta, tc.desc...))
+		c.Assert(err, IsNil)
+
+		err = Validate(info)
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, `invalid definition of application "foo": `+tc.err)
+		} else {
+			c.Check(err, IsNil)
+		}
+	}
+}
+
+func (s *ValidateSuite) TestValidateAppResourceControls(c *C) {
+	meta := []byte(`
+name: foo
+version: 1.0
+apps:
+  foo:
+`)
+	tcs := []struct {
+		desc string
+		err  string
+	}{
+		{"    daemon: simple\n    memory-max: 512M\n    cpu-weight: 200\n    tasks-max: 64\n    io-weight: 50\n", ""},
+		{"    daemon: simple\n    nice: -20\n    oom-score-adjust: 1000\n", ""},
+		{"    daemon: simple\n    nice: 0\n    oom-score-adjust: 0\n", ""},
+		{"    daemon: simple\n    memory-max: 1048576\n", ""},
+		{"    memory-max: 512M\n", `memory-max is only applicable to services`},
+		{"    nice: 5\n", `nice is only applicable to services`},
+		{"    cpu-weight: 5\n", `cpu-weight is only applicable to services`},
+		{"    daemon: simple\n    memory-max: 512MB\n", `memory-max has invalid value "512MB": expected a size in bytes optionally followed by K, M, G or T`},
+		{"    daemon: simple\n    memory-max: 0\n", `memory-max has invalid value "0": .*`},
+		{"    daemon: simple\n    cpu-weight: 10001\n", `cpu-weight must be between 1 and 10000`},
+		{"    daemon: simple\n    io-weight: -1\n", `io-weight must be between 1 and 10000`},
+		{"    daemon: simple\n    tasks-max: -1\n", `tasks-max must be between 1 and 2147483647`},
+		{"    daemon: simple\n    nice: 20\n", `nice must be between -20 and 19`},
+		{"    daemon: simple\n    oom-score-adjust: -1001\n", `oom-score-adjust must be between -1000 and 1000`},
+	}
+	for _, tc := range tcs {
+		c.Logf("trying %q", tc.desc)
+		info, err := InfoFromSnapYaml(append(meta, tc.desc...))
+		c.Assert(err, IsNil)
+
+		err = Validate(info)
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, `invalid definition of application "foo": `+tc.err)
+		} else {
+			c.Check(err, IsNil)
+		}
+	}
+}
+
 func (s *ValidateSuite) TestValidateSystemUsernames(c *C) {
 	const yaml1 = `name: binary
 version: 1.0
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -129,20 +130,64 @@ func Available() error {
 
 var osutilStreamCommand = osutil.StreamCommand
 
+// LogOptions holds the options for reading logs from the journal.
+type LogOptions struct {
+	// N is the maximum number of log lines to read initially, no limit
+	// if negative.
+	N int
+	// Follow keeps reading new lines as they appear.
+	Follow bool
+	// Since and Until restrict the logs to the given time range, the
+	// zero time means no restriction.
+	Since time.Time
+	Until time.Time
+	// Priority only includes entries of the given priority or more
+	// important, see ValidateLogPriority.
+	Priority string
+}
+
+var logPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// ValidateLogPriority checks that the given priority is either a syslog
+// level name, as understood by journalctl, or its number.
+func ValidateLogPriority(priority string) error {
+	for i, name := range logPriorities {
+		if priority == name || priority == strconv.Itoa(i) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid log priority %q, expected one of: %s", priority, strings.Join(logPriorities, ", "))
+}
+
 // jctl calls journalctl to get the JSON logs of the given services.
-var jctl = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+var jctl = func(svcs []string, opts LogOptions) (io.ReadCloser, error) {
 	// args will need two entries per service, plus a fixed number (give or take
-	// one) for the initial options.
-	args := make([]string, 0, 2*len(svcs)+6)        // the fixed number is 6
-	args = append(args, "-o", "json", "--no-pager") //   3...
-	if n < 0 {
+	// one) for the initial options, and one more for every filter.
+	nfilters := 0
+	for _, set := range []bool{!opts.Since.IsZero(), !opts.Until.IsZero(), opts.Priority != ""} {
+		if set {
+			nfilters++
+		}
+	}
+	args := make([]string, 0, 2*len(svcs)+6+nfilters) // the fixed number is 6
+	args = append(args, "-o", "json", "--no-pager")   //   3...
+	if opts.N < 0 {
 		args = append(args, "--no-tail") // < 2
 	} else {
-		args = append(args, "-n", strconv.Itoa(n)) // ... + 2 ...
+		args = append(args, "-n", strconv.Itoa(opts.N)) // ... + 2 ...
 	}
-	if follow {
+	if opts.Follow {
 		args = append(args, "-f") // ... + 1 == 6
 	}
+	if !opts.Since.IsZero() {
+		args = append(args, fmt.Sprintf("--since=@%d", opts.Since.Unix()))
+	}
+	if !opts.Until.IsZero() {
+		args = append(args, fmt.Sprintf("--until=@%d", opts.Until.Unix()))
+	}
+	if opts.Priority != "" {
+		args = append(args, "--priority="+opts.Priority)
+	}
 
 	for i := range svcs {
 		args = append(args, "-u", svcs[i]) // this is why 2×
@@ -151,7 +196,7 @@ var jctl = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
 	return osutilStreamCommand("journalctl", args...)
 }
 
-func MockJournalctl(f func(svcs []string, n int, follow bool) (io.ReadCloser, error)) func() {
+func MockJournalctl(f func(svcs []string, opts LogOptions) (io.ReadCloser, error)) func() {
 	oldJctl := jctl
 	jctl = f
 	return func() {
@@ -169,12 +214,15 @@ type Systemd interface {
 	Stop(service string, timeout time.Duration) error
 	Kill(service, signal, who string) error
 	Restart(service string, timeout time.Duration) error
+	ReloadOrRestart(service string) error
 	Status(units ...string) ([]*UnitStatus, error)
 	IsEnabled(service string) (bool, error)
 	IsActive(service string) (bool, error)
-	LogReader(services []string, n int, follow bool) (io.ReadCloser, error)
+	LogReader(services []string, opts LogOptions) (io.ReadCloser, error)
 	AddMountUnitFile(name, revision, what, where, fstype string) (string, error)
 	RemoveMountUnitFile(baseDir string) error
+	AddSwapUnitFile(what string) (string, error)
+	RemoveSwapUnitFile(what string) error
 	Mask(service string) error
 	Unmask(service string) error
 }
@@ -186,6 +234,9 @@ const (
 	// the default target for systemd units that we generate
 	ServicesTarget = "multi-user.target"
 
+	// the default target for systemd user units that we generate
+	UserServicesTarget = "default.target"
+
 	// the target prerequisite for systemd units we generate
 	PrerequisiteTarget = "network.target"
 
@@ -303,8 +354,8 @@ func (s *systemd) StartNoBlock(serviceNames ...string) error {
 }
 
 // LogReader for the given services
-func (*systemd) LogReader(serviceNames []string, n int, follow bool) (io.ReadCloser, error) {
-	return jctl(serviceNames, n, follow)
+func (*systemd) LogReader(serviceNames []string, opts LogOptions) (io.ReadCloser, error) {
+	return jctl(serviceNames, opts)
 }
 
 var statusregex = regexp.MustCompile(`(?m)^(?:(.+?)=(.*)|(.*))?$`)
@@ -314,6 +365,30 @@ type UnitStatus struct {
 	UnitName string
 	Enabled  bool
 	Active   bool
+
+	// The following are only known for some unit types and versions of
+	// systemd, and are left zero otherwise.
+
+	// SubState is the unit type specific state, e.g. "running" or
+	// "auto-restart" for services.
+	SubState string
+	// NRestarts is the number of automatic restarts of a service.
+	NRestarts int
+	// MainPID is the pid of the main process of a running service.
+	MainPID int
+	// ExitCode describes how the main process of a service last exited,
+	// either "exited", "killed" or "dumped"; ExitStatus is its exit
+	// status or the signal that killed it.
+	ExitCode   string
+	ExitStatus int
+	// Memory is the current memory usage of a service in bytes.
+	Memory uint64
+	// CPUUsage is the CPU time consumed by a service.
+	CPUUsage time.Duration
+	// LastTrigger and NextTrigger are the times a timer last elapsed
+	// and will elapse next.
+	LastTrigger time.Time
+	NextTrigger time.Time
 }
 
 var baseProperties = []string{"Id", "ActiveState", "UnitFileState"}
@@ -327,6 +402,62 @@ var unitProperties = map[string][]string{
 	".mount": extendedProperties,
 }
 
+// optional properties are queried as well, but they are not part of the
+// output of older systemd versions or of all unit types
+var serviceOptionalProperties = []string{"SubState", "NRestarts", "MainPID", "ExecMainCode", "ExecMainStatus", "MemoryCurrent", "CPUUsageNSec"}
+var timerOptionalProperties = []string{"LastTriggerUSec", "NextElapseUSecRealtime"}
+var optionalProperties = map[string]bool{}
+
+// the properties queried for services (and mounts), and for the timers
+// and sockets activating them
+var serviceStatusProperties, activatorStatusProperties []string
+
+func init() {
+	for _, props := range [][]string{serviceOptionalProperties, timerOptionalProperties} {
+		for _, prop := range props {
+			optionalProperties[prop] = true
+		}
+	}
+	serviceStatusProperties = append(append([]string(nil), extendedProperties...), serviceOptionalProperties...)
+	activatorStatusProperties = append(append([]string(nil), baseProperties...), timerOptionalProperties...)
+}
+
+// exitCodes maps the CLD_* codes systemd reports in ExecMainCode.
+var exitCodes = map[string]string{
+	"1": "exited",
+	"2": "killed",
+	"3": "dumped",
+}
+
+// parseUnitCounter parses a counter or resource usage property, which
+// systemd reports as "[not set]" or as the maximum uint64 when unknown.
+func parseUnitCounter(k, v string) (uint64, error) {
+	if v == "[not set]" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get unit status: invalid %s %q in ‘systemctl show’ output", k, v)
+	}
+	if n == math.MaxUint64 {
+		return 0, nil
+	}
+	return n, nil
+}
+
+// parseUnitTimestamp parses a timestamp property, as formatted by
+// systemctl in the local time zone.
+func parseUnitTimestamp(k, v string) (time.Time, error) {
+	if v == "n/a" || v == "0" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("Mon 2006-01-02 15:04:05 MST", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot get unit status: invalid %s %q in ‘systemctl show’ output", k, v)
+	}
+	return t, nil
+}
+
 func (s *systemd) getUnitStatus(properties []string, unitNames []string) ([]*UnitStatus, error) {
 	cmd := make([]string, len(unitNames)+2)
 	cmd[0] = "show"
@@ -379,9 +510,18 @@ func (s *systemd) getUnitStatus(properties []string, unitNames []string) ([]*Uni
 		v := string(bs[2])
 
 		if v == "" {
-			return nil, fmt.Errorf("cannot get unit status: empty field %q in ‘systemctl show’ output", k)
+			if !optionalProperties[k] {
+				return nil, fmt.Errorf("cannot get unit status: empty field %q in ‘systemctl show’ output", k)
+			}
+			// not known for this unit
+			if seen[k] {
+				return nil, fmt.Errorf("cannot get unit status: duplicate field %q in ‘systemctl show’ output", k)
+			}
+			seen[k] = true
+			continue
 		}
 
+		var err error
 		switch k {
 		case "Id":
 			cur.UnitName = v
@@ -393,9 +533,41 @@ func (s *systemd) getUnitStatus(properties []string, unitNames []string) ([]*Uni
 		case "UnitFileState":
 			// "static" means it can't be disabled
 			cur.Enabled = v == "enabled" || v == "static"
+		case "SubState":
+			cur.SubState = v
+		case "NRestarts", "MainPID", "ExecMainStatus":
+			var n int
+			n, err = strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get unit status: invalid %s %q in ‘systemctl show’ output", k, v)
+			}
+			switch k {
+			case "NRestarts":
+				cur.NRestarts = n
+			case "MainPID":
+				cur.MainPID = n
+			case "ExecMainStatus":
+				cur.ExitStatus = n
+			}
+		case "ExecMainCode":
+			// 0 means the main process has not exited yet
+			cur.ExitCode = exitCodes[v]
+		case "MemoryCurrent":
+			cur.Memory, err = parseUnitCounter(k, v)
+		case "CPUUsageNSec":
+			var nsec uint64
+			nsec, err = parseUnitCounter(k, v)
+			cur.CPUUsage = time.Duration(nsec)
+		case "LastTriggerUSec":
+			cur.LastTrigger, err = parseUnitTimestamp(k, v)
+		case "NextElapseUSecRealtime":
+			cur.NextTrigger, err = parseUnitTimestamp(k, v)
 		default:
 			return nil, fmt.Errorf("cannot get unit status: unexpected field %q in ‘systemctl show’ output", k)
 		}
+		if err != nil {
+			return nil, err
+		}
 
 		if seen[k] {
 			return nil, fmt.Errorf("cannot get unit status: duplicate field %q in ‘systemctl show’ output", k)
@@ -432,8 +604,8 @@ func (s *systemd) Status(unitNames ...string) ([]*UnitStatus, error) {
 		units      []string
 		properties []string
 	}{
-		{units: extendedUnits, properties: extendedProperties},
-		{units: limitedUnits, properties: baseProperties},
+		{units: extendedUnits, properties: serviceStatusProperties},
+		{units: limitedUnits, properties: activatorStatusProperties},
 	} {
 		if len(set.units) == 0 {
 			continue
@@ -561,6 +733,16 @@ func (s *systemd) Restart(serviceName string, timeout time.Duration) error {
 	return s.Start(serviceName)
 }
 
+// ReloadOrRestart reloads the service if it supports it, or restarts it
+// otherwise.
+func (s *systemd) ReloadOrRestart(serviceName string) error {
+	if s.mode == GlobalUserMode {
+		panic("cannot call reload-or-restart with GlobalUserMode")
+	}
+	_, err := s.systemctl("reload-or-restart", serviceName)
+	return err
+}
+
 // Error is returned if the systemd action failed
 type Error struct {
 	cmd      []string
@@ -634,6 +816,15 @@ func (l Log) PID() string {
 	return "-"
 }
 
+// Priority is the syslog priority level of the Log, if any; otherwise, "-".
+func (l Log) Priority() string {
+	if prio, ok := l["PRIORITY"]; ok {
+		return prio
+	}
+
+	return "-"
+}
+
 // MountUnitPath returns the path of a {,auto}mount unit
 func MountUnitPath(baseDir string) string {
 	escapedPath := EscapeUnitNamePath(baseDir)
@@ -741,3 +932,79 @@ func (s *systemd) RemoveMountUnitFile(mountedDir string) error {
 
 	return nil
 }
+
+// SwapUnitPath returns the path of the swap unit for the given swap file.
+func SwapUnitPath(what string) string {
+	escapedPath := EscapeUnitNamePath(what)
+	return filepath.Join(dirs.SnapServicesDir, escapedPath+".swap")
+}
+
+// AddSwapUnitFile adds/enables/starts a swap unit for the given swap file.
+func (s *systemd) AddSwapUnitFile(what string) (string, error) {
+	daemonReloadLock.Lock()
+	defer daemonReloadLock.Unlock()
+
+	c := fmt.Sprintf(`[Unit]
+Description=Swap file %s managed by snapd
+
+[Swap]
+What=%s
+
+[Install]
+WantedBy=swap.target
+`, what, what)
+
+	su := SwapUnitPath(what)
+	if err := os.MkdirAll(filepath.Dir(su), 0755); err != nil {
+		return "", err
+	}
+	swapUnitName, err := filepath.Base(su), osutil.AtomicWriteFile(su, []byte(c), 0644, 0)
+	if err != nil {
+		return "", err
+	}
+
+	// we need to do a daemon-reload here to ensure that systemd really
+	// knows about this new swap unit file
+	if err := s.daemonReloadNoLock(); err != nil {
+		return "", err
+	}
+
+	if err := s.Enable(swapUnitName); err != nil {
+		return "", err
+	}
+	if err := s.Start(swapUnitName); err != nil {
+		return "", err
+	}
+
+	return swapUnitName, nil
+}
+
+// RemoveSwapUnitFile stops using the given swap file and removes its
+// swap unit.
+func (s *systemd) RemoveSwapUnitFile(what string) error {
+	daemonReloadLock.Lock()
+	defer daemonReloadLock.Unlock()
+
+	unit := SwapUnitPath(what)
+	if !osutil.FileExists(unit) {
+		return nil
+	}
+
+	// stopping the unit runs swapoff
+	if err := s.Stop(filepath.Base(unit), 5*time.Minute); err != nil {
+		return err
+	}
+	if err := s.Disable(filepath.Base(unit)); err != nil {
+		return err
+	}
+	if err := os.Remove(unit); err != nil {
+		return err
+	}
+	// daemon-reload to ensure that systemd actually really
+	// forgets about this swap unit
+	if err := s.daemonReloadNoLock(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -120,13 +120,13 @@ func (s *SystemdTestSuite) myRun(args ...string) (out []byte, err error) {
 	return out, err
 }
 
-func (s *SystemdTestSuite) myJctl(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+func (s *SystemdTestSuite) myJctl(svcs []string, opts LogOptions) (io.ReadCloser, error) {
 	var err error
 	var out []byte
 
-	s.jns = append(s.jns, strconv.Itoa(n))
+	s.jns = append(s.jns, strconv.Itoa(opts.N))
 	s.jsvcs = append(s.jsvcs, svcs)
-	s.jfollows = append(s.jfollows, follow)
+	s.jfollows = append(s.jfollows, opts.Follow)
 
 	if s.j < len(s.jouts) {
 		out = s.jouts[s.j]
@@ -239,11 +239,103 @@ UnitFileState=disabled
 	})
 	c.Check(s.rep.msgs, IsNil)
 	c.Assert(s.argses, DeepEquals, [][]string{
-		{"show", "--property=Id,ActiveState,UnitFileState,Type", "foo.service", "bar.service", "baz.service"},
-		{"show", "--property=Id,ActiveState,UnitFileState", "some.timer", "other.socket"},
+		{"show", "--property=Id,ActiveState,UnitFileState,Type,SubState,NRestarts,MainPID,ExecMainCode,ExecMainStatus,MemoryCurrent,CPUUsageNSec", "foo.service", "bar.service", "baz.service"},
+		{"show", "--property=Id,ActiveState,UnitFileState,LastTriggerUSec,NextElapseUSecRealtime", "some.timer", "other.socket"},
 	})
 }
 
+func (s *SystemdTestSuite) TestStatusExtended(c *C) {
+	s.outs = [][]byte{
+		[]byte(`
+Type=simple
+Id=foo.service
+ActiveState=active
+UnitFileState=enabled
+SubState=running
+NRestarts=0
+MainPID=1234
+ExecMainCode=0
+ExecMainStatus=0
+MemoryCurrent=1048576
+CPUUsageNSec=1500000000
+
+Type=simple
+Id=bar.service
+ActiveState=activating
+UnitFileState=enabled
+SubState=auto-restart
+NRestarts=7
+MainPID=0
+ExecMainCode=2
+ExecMainStatus=9
+MemoryCurrent=[not set]
+CPUUsageNSec=18446744073709551615
+`[1:]),
+		[]byte(`
+Id=some.timer
+ActiveState=active
+UnitFileState=enabled
+LastTriggerUSec=Sun 2020-09-13 12:26:40 UTC
+NextElapseUSecRealtime=Mon 2020-09-14 12:26:40 UTC
+
+Id=other.timer
+ActiveState=active
+UnitFileState=enabled
+LastTriggerUSec=n/a
+NextElapseUSecRealtime=
+`[1:]),
+	}
+	out, err := New("", SystemMode, s.rep).Status("foo.service", "bar.service", "some.timer", "other.timer")
+	c.Assert(err, IsNil)
+	c.Check(out, DeepEquals, []*UnitStatus{
+		{
+			Daemon:   "simple",
+			UnitName: "foo.service",
+			Active:   true,
+			Enabled:  true,
+			SubState: "running",
+			MainPID:  1234,
+			Memory:   1048576,
+			CPUUsage: 1500 * time.Millisecond,
+		}, {
+			Daemon:     "simple",
+			UnitName:   "bar.service",
+			Enabled:    true,
+			SubState:   "auto-restart",
+			NRestarts:  7,
+			ExitCode:   "killed",
+			ExitStatus: 9,
+		}, {
+			UnitName:    "some.timer",
+			Active:      true,
+			Enabled:     true,
+			LastTrigger: time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC),
+			NextTrigger: time.Date(2020, 9, 14, 12, 26, 40, 0, time.UTC),
+		}, {
+			UnitName: "other.timer",
+			Active:   true,
+			Enabled:  true,
+		},
+	})
+}
+
+func (s *SystemdTestSuite) TestStatusBadExtendedValues(c *C) {
+	for _, t := range []struct {
+		line string
+		err  string
+	}{
+		{"NRestarts=many", `cannot get unit status: invalid NRestarts "many" in ‘systemctl show’ output`},
+		{"MainPID=-", `cannot get unit status: invalid MainPID "-" in ‘systemctl show’ output`},
+		{"MemoryCurrent=1G", `cannot get unit status: invalid MemoryCurrent "1G" in ‘systemctl show’ output`},
+		{"LastTriggerUSec=yesterday", `cannot get unit status: invalid LastTriggerUSec "yesterday" in ‘systemctl show’ output`},
+	} {
+		s.outs = [][]byte{[]byte("Type=simple\nId=foo.service\nActiveState=active\nUnitFileState=enabled\n" + t.line + "\n")}
+		s.i = 0
+		_, err := New("", SystemMode, s.rep).Status("foo.service")
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
 func (s *SystemdTestSuite) TestStatusBadNumberOfValues(c *C) {
 	s.outs = [][]byte{
 		[]byte(`
@@ -425,6 +517,17 @@ func (s *SystemdTestSuite) TestRestart(c *C) {
 	c.Check(s.argses[2], DeepEquals, []string{"start", "foo"})
 }
 
+func (s *SystemdTestSuite) TestReloadOrRestart(c *C) {
+	err := New("", SystemMode, s.rep).ReloadOrRestart("foo")
+	c.Assert(err, IsNil)
+	c.Check(s.argses, DeepEquals, [][]string{{"reload-or-restart", "foo"}})
+
+	s.argses = nil
+	err = New("", UserMode, s.rep).ReloadOrRestart("foo")
+	c.Assert(err, IsNil)
+	c.Check(s.argses, DeepEquals, [][]string{{"--user", "reload-or-restart", "foo"}})
+}
+
 func (s *SystemdTestSuite) TestKill(c *C) {
 	c.Assert(New("", SystemMode, s.rep).Kill("foo", "HUP", ""), IsNil)
 	c.Check(s.argses, DeepEquals, [][]string{{"kill", "foo", "-s", "HUP", "--kill-who=all"}})
@@ -438,7 +541,7 @@ func (s *SystemdTestSuite) TestIsTimeout(c *C) {
 func (s *SystemdTestSuite) TestLogErrJctl(c *C) {
 	s.jerrs = []error{&Timeout{}}
 
-	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, 24, false)
+	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, LogOptions{N: 24})
 	c.Check(err, NotNil)
 	c.Check(reader, IsNil)
 	c.Check(s.jns, DeepEquals, []string{"24"})
@@ -453,7 +556,7 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 `
 	s.jouts = [][]byte{[]byte(expected)}
 
-	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, 24, false)
+	reader, err := New("", SystemMode, s.rep).LogReader([]string{"foo"}, LogOptions{N: 24})
 	c.Check(err, IsNil)
 	logs, err := ioutil.ReadAll(reader)
 	c.Assert(err, IsNil)
@@ -464,6 +567,11 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 	c.Check(s.j, Equals, 1)
 }
 
+func (s *SystemdTestSuite) TestLogPriority(c *C) {
+	c.Check(Log{}.Priority(), Equals, "-")
+	c.Check(Log{"PRIORITY": "6"}.Priority(), Equals, "6")
+}
+
 func (s *SystemdTestSuite) TestLogPID(c *C) {
 	c.Check(Log{}.PID(), Equals, "-")
 	c.Check(Log{"_PID": "99"}.PID(), Equals, "99")
@@ -695,15 +803,35 @@ func (s *SystemdTestSuite) TestJctl(c *C) {
 		return nil, nil
 	})
 
-	_, err = Jctl([]string{"foo", "bar"}, 10, false)
+	_, err = Jctl([]string{"foo", "bar"}, LogOptions{N: 10})
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "-u", "foo", "-u", "bar"})
-	_, err = Jctl([]string{"foo", "bar", "baz"}, 99, true)
+	_, err = Jctl([]string{"foo", "bar", "baz"}, LogOptions{N: 99, Follow: true})
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "99", "-f", "-u", "foo", "-u", "bar", "-u", "baz"})
-	_, err = Jctl([]string{"foo", "bar"}, -1, false)
+	_, err = Jctl([]string{"foo", "bar"}, LogOptions{N: -1})
 	c.Assert(err, IsNil)
 	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "-u", "foo", "-u", "bar"})
+	_, err = Jctl([]string{"foo"}, LogOptions{
+		N:        10,
+		Since:    time.Unix(1600000000, 0),
+		Until:    time.Unix(1600003600, 0),
+		Priority: "warning",
+	})
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "--since=@1600000000", "--until=@1600003600", "--priority=warning", "-u", "foo"})
+	_, err = Jctl([]string{"foo"}, LogOptions{N: -1, Follow: true, Since: time.Unix(1600000000, 0)})
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "--no-tail", "-f", "--since=@1600000000", "-u", "foo"})
+}
+
+func (s *SystemdTestSuite) TestValidateLogPriority(c *C) {
+	for _, prio := range []string{"emerg", "err", "warning", "debug", "0", "3", "7"} {
+		c.Check(ValidateLogPriority(prio), IsNil, Commentf(prio))
+	}
+	for _, prio := range []string{"", "error", "8", "-1", "WARNING"} {
+		c.Check(ValidateLogPriority(prio), ErrorMatches, `invalid log priority ".*", expected one of: emerg, alert, crit, err, warning, notice, info, debug`, Commentf(prio))
+	}
 }
 
 func (s *SystemdTestSuite) TestIsActiveIsInactive(c *C) {
@@ -763,6 +891,61 @@ func (s *SystemdTestSuite) TestRemoveMountUnit(c *C) {
 	})
 }
 
+func (s *SystemdTestSuite) TestAddSwapUnit(c *C) {
+	rootDir := dirs.GlobalRootDir
+
+	swapUnitName, err := New(rootDir, SystemMode, nil).AddSwapUnitFile("/var/tmp/swapfile.swp")
+	c.Assert(err, IsNil)
+	c.Check(swapUnitName, Equals, "var-tmp-swapfile.swp.swap")
+
+	c.Assert(filepath.Join(dirs.SnapServicesDir, swapUnitName), testutil.FileEquals, `[Unit]
+Description=Swap file /var/tmp/swapfile.swp managed by snapd
+
+[Swap]
+What=/var/tmp/swapfile.swp
+
+[Install]
+WantedBy=swap.target
+`)
+
+	c.Assert(s.argses, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", rootDir, "enable", "var-tmp-swapfile.swp.swap"},
+		{"start", "var-tmp-swapfile.swp.swap"},
+	})
+}
+
+func (s *SystemdTestSuite) TestRemoveSwapUnit(c *C) {
+	rootDir := dirs.GlobalRootDir
+
+	swapUnit := SwapUnitPath("/var/tmp/swapfile.swp")
+	c.Assert(os.MkdirAll(filepath.Dir(swapUnit), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(swapUnit, nil, 0644), IsNil)
+
+	s.outs = [][]byte{
+		nil, // for the "stop" itself
+		[]byte("ActiveState=inactive\n"),
+	}
+
+	err := New(rootDir, SystemMode, nil).RemoveSwapUnitFile("/var/tmp/swapfile.swp")
+	c.Assert(err, IsNil)
+	// the file is gone
+	c.Check(osutil.FileExists(swapUnit), Equals, false)
+	// and the unit is stopped, disabled and the daemon reloaded
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"stop", "var-tmp-swapfile.swp.swap"},
+		{"show", "--property=ActiveState", "var-tmp-swapfile.swp.swap"},
+		{"--root", rootDir, "disable", "var-tmp-swapfile.swp.swap"},
+		{"daemon-reload"},
+	})
+}
+
+func (s *SystemdTestSuite) TestRemoveSwapUnitNotThere(c *C) {
+	err := New(dirs.GlobalRootDir, SystemMode, nil).RemoveSwapUnitFile("/var/tmp/swapfile.swp")
+	c.Assert(err, IsNil)
+	c.Check(s.argses, HasLen, 0)
+}
+
 func (s *SystemdTestSuite) TestDaemonReloadMutex(c *C) {
 	rootDir := dirs.GlobalRootDir
 	sysd := New(rootDir, SystemMode, nil)
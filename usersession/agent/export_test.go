@@ -21,12 +21,23 @@ package agent
 
 import (
 	"syscall"
+	"time"
 )
 
 var (
-	SessionInfoCmd = sessionInfoCmd
+	SessionInfoCmd    = sessionInfoCmd
+	ServicesCmd       = servicesCmd
+	ServiceControlCmd = serviceControlCmd
 )
 
+func MockServiceStopTimeout(t time.Duration) (restore func()) {
+	old := serviceStopTimeout
+	serviceStopTimeout = t
+	return func() {
+		serviceStopTimeout = old
+	}
+}
+
 func MockUcred(ucred *syscall.Ucred, err error) (restore func()) {
 	old := sysGetsockoptUcred
 	sysGetsockoptUcred = func(fd, level, opt int) (*syscall.Ucred, error) {
@@ -20,12 +20,23 @@
 package agent
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeout"
 )
 
 var restApi = []*Command{
 	rootCmd,
 	sessionInfoCmd,
+	servicesCmd,
+	serviceControlCmd,
 }
 
 var (
@@ -38,6 +49,17 @@ var (
 		Path: "/v1/session-info",
 		GET:  sessionInfo,
 	}
+
+	servicesCmd = &Command{
+		Path: "/v1/services",
+		GET:  getServices,
+		POST: postServices,
+	}
+
+	serviceControlCmd = &Command{
+		Path: "/v1/service-control",
+		POST: postServiceControl,
+	}
 )
 
 func sessionInfo(c *Command, r *http.Request) Response {
@@ -46,3 +68,241 @@ func sessionInfo(c *Command, r *http.Request) Response {
 	}
 	return SyncResponse(m)
 }
+
+// serviceStopTimeout is the time given to user services to stop before
+// giving up on them.
+var serviceStopTimeout = time.Duration(timeout.DefaultTimeout)
+
+type serviceStatus struct {
+	Unit    string `json:"unit"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+}
+
+// validateUserServices checks that only snap service units are being
+// asked about, the session agent is not a general purpose proxy for the
+// user's systemd instance.
+func validateUserServices(units []string) error {
+	if len(units) == 0 {
+		return fmt.Errorf("no services specified")
+	}
+	for _, unit := range units {
+		if !strings.HasPrefix(unit, "snap.") || !strings.HasSuffix(unit, ".service") {
+			return fmt.Errorf("cannot operate on non-snap service %q", unit)
+		}
+	}
+	return nil
+}
+
+func getServices(c *Command, r *http.Request) Response {
+	units := strutil.CommaSeparatedList(r.URL.Query().Get("names"))
+	if err := validateUserServices(units); err != nil {
+		return BadRequest("%v", err)
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.UserMode, nil)
+	sts, err := sysd.Status(units...)
+	if err != nil {
+		return InternalError("cannot get status of user services: %v", err)
+	}
+	result := make([]serviceStatus, len(sts))
+	for i, st := range sts {
+		result[i] = serviceStatus{
+			Unit:    st.UnitName,
+			Enabled: st.Enabled,
+			Active:  st.Active,
+		}
+	}
+	return SyncResponse(result)
+}
+
+type serviceInstruction struct {
+	Action   string   `json:"action"`
+	Services []string `json:"services"`
+	Enable   bool     `json:"enable,omitempty"`
+	Disable  bool     `json:"disable,omitempty"`
+}
+
+func postServices(c *Command, r *http.Request) Response {
+	var inst serviceInstruction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&inst); err != nil {
+		return BadRequest("cannot decode request body into service instruction: %v", err)
+	}
+	if err := validateUserServices(inst.Services); err != nil {
+		return BadRequest("%v", err)
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.UserMode, nil)
+	var err error
+	switch inst.Action {
+	case "start":
+		if inst.Disable {
+			return BadRequest("cannot disable services while starting them")
+		}
+		if inst.Enable {
+			for _, unit := range inst.Services {
+				if err = sysd.Enable(unit); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil {
+			err = sysd.Start(inst.Services...)
+		}
+	case "stop":
+		if inst.Enable {
+			return BadRequest("cannot enable services while stopping them")
+		}
+		for _, unit := range inst.Services {
+			if inst.Disable {
+				if err = sysd.Disable(unit); err != nil {
+					break
+				}
+			}
+			if err = sysd.Stop(unit, serviceStopTimeout); err != nil {
+				break
+			}
+		}
+	case "restart":
+		if inst.Enable || inst.Disable {
+			return BadRequest("cannot enable or disable services while restarting them")
+		}
+		for _, unit := range inst.Services {
+			if err = sysd.Restart(unit, serviceStopTimeout); err != nil {
+				break
+			}
+		}
+	default:
+		return BadRequest("unknown action %q", inst.Action)
+	}
+	if err != nil {
+		return InternalError("cannot %s user services: %v", inst.Action, err)
+	}
+	return SyncResponse(nil)
+}
+
+type serviceControlInstruction struct {
+	Action   string   `json:"action"`
+	Services []string `json:"services"`
+}
+
+// serviceControlErrors holds the per unit errors of a service control
+// request, start errors are accompanied by the errors of stopping the
+// units that were successfully started before the failure.
+type serviceControlErrors struct {
+	StartErrors  map[string]string `json:"start-errors,omitempty"`
+	StopErrors   map[string]string `json:"stop-errors,omitempty"`
+	ReloadErrors map[string]string `json:"reload-errors,omitempty"`
+}
+
+func serviceControlError(action string, errs *serviceControlErrors) Response {
+	return &resp{
+		Type:   ResponseTypeError,
+		Status: 500,
+		Result: &errorResult{
+			Message: fmt.Sprintf("some user services failed to %s", action),
+			Kind:    errorKindServiceControl,
+			Value:   errs,
+		},
+	}
+}
+
+func serviceStart(inst *serviceControlInstruction, sysd systemd.Systemd) Response {
+	var started []string
+	startErrors := make(map[string]string)
+	for _, unit := range inst.Services {
+		if err := sysd.Start(unit); err != nil {
+			startErrors[unit] = err.Error()
+			break
+		}
+		started = append(started, unit)
+	}
+	if len(startErrors) == 0 {
+		return SyncResponse(nil)
+	}
+
+	// undo the units that were started, in reverse order
+	stopErrors := make(map[string]string)
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := sysd.Stop(started[i], serviceStopTimeout); err != nil {
+			stopErrors[started[i]] = err.Error()
+		}
+	}
+	return serviceControlError("start", &serviceControlErrors{
+		StartErrors: startErrors,
+		StopErrors:  stopErrors,
+	})
+}
+
+func serviceStop(inst *serviceControlInstruction, sysd systemd.Systemd) Response {
+	stopErrors := make(map[string]string)
+	for _, unit := range inst.Services {
+		if err := sysd.Stop(unit, serviceStopTimeout); err != nil {
+			stopErrors[unit] = err.Error()
+		}
+	}
+	if len(stopErrors) == 0 {
+		return SyncResponse(nil)
+	}
+	return serviceControlError("stop", &serviceControlErrors{
+		StopErrors: stopErrors,
+	})
+}
+
+func serviceReload(inst *serviceControlInstruction, sysd systemd.Systemd) Response {
+	reloadErrors := make(map[string]string)
+	for _, unit := range inst.Services {
+		if err := sysd.ReloadOrRestart(unit); err != nil {
+			reloadErrors[unit] = err.Error()
+		}
+	}
+	if len(reloadErrors) == 0 {
+		return SyncResponse(nil)
+	}
+	return serviceControlError("reload", &serviceControlErrors{
+		ReloadErrors: reloadErrors,
+	})
+}
+
+func serviceDaemonReload(inst *serviceControlInstruction, sysd systemd.Systemd) Response {
+	if len(inst.Services) != 0 {
+		return BadRequest("daemon-reload should not be called with any services")
+	}
+	if err := sysd.DaemonReload(); err != nil {
+		return InternalError("cannot reload systemd user instance: %v", err)
+	}
+	return SyncResponse(nil)
+}
+
+var serviceControlActions = map[string]func(*serviceControlInstruction, systemd.Systemd) Response{
+	"start":         serviceStart,
+	"stop":          serviceStop,
+	"reload":        serviceReload,
+	"daemon-reload": serviceDaemonReload,
+}
+
+func postServiceControl(c *Command, r *http.Request) Response {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		return BadRequest("unknown content type: %s", contentType)
+	}
+
+	var inst serviceControlInstruction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&inst); err != nil {
+		return BadRequest("cannot decode request body into service control instruction: %v", err)
+	}
+	impl := serviceControlActions[inst.Action]
+	if impl == nil {
+		return BadRequest("unknown action %q", inst.Action)
+	}
+	if inst.Action != "daemon-reload" {
+		if err := validateUserServices(inst.Services); err != nil {
+			return BadRequest("%v", err)
+		}
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.UserMode, nil)
+	return impl(&inst, sysd)
+}
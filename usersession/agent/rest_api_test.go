@@ -20,29 +20,58 @@
 package agent_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"time"
 
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/usersession/agent"
 )
 
-type restSuite struct{}
+type restSuite struct {
+	testutil.BaseTest
+	sysdLog [][]string
+}
 
 var _ = Suite(&restSuite{})
 
 func (s *restSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
 	dirs.SetRootDir(c.MkDir())
 	xdgRuntimeDir := fmt.Sprintf("%s/%d", dirs.XdgRuntimeDirBase, os.Getuid())
 	c.Assert(os.MkdirAll(xdgRuntimeDir, 0700), IsNil)
+
+	s.sysdLog = nil
+	s.AddCleanup(systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		if cmd[1] == "show" && cmd[2] == "--property=ActiveState" {
+			return []byte("ActiveState=inactive\n"), nil
+		}
+		if cmd[1] == "show" {
+			var out []string
+			for _, unit := range cmd[3:] {
+				out = append(out, fmt.Sprintf("Id=%s\nType=simple\nActiveState=active\nUnitFileState=enabled\n", unit))
+			}
+			return []byte(strings.Join(out, "\n")), nil
+		}
+		return nil, nil
+	}))
+	s.AddCleanup(systemd.MockStopDelays(time.Millisecond, 25*time.Second))
+	s.AddCleanup(agent.MockServiceStopTimeout(time.Second))
 }
 
 func (s *restSuite) TearDownTest(c *C) {
 	dirs.SetRootDir("")
+	s.BaseTest.TearDownTest(c)
 }
 
 type resp struct {
@@ -74,3 +103,262 @@ func (s *restSuite) TestSessionInfo(c *C) {
 		"version": "42b1",
 	})
 }
+
+func (s *restSuite) TestServicesStatus(c *C) {
+	c.Check(agent.ServicesCmd.Path, Equals, "/v1/services")
+
+	req, err := http.NewRequest("GET", "/v1/services?names=snap.foo.svc1.service,snap.bar.svc2.service", nil)
+	c.Assert(err, IsNil)
+	rec := httptest.NewRecorder()
+	agent.ServicesCmd.GET(agent.ServicesCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, DeepEquals, []interface{}{
+		map[string]interface{}{"unit": "snap.foo.svc1.service", "enabled": true, "active": true},
+		map[string]interface{}{"unit": "snap.bar.svc2.service", "enabled": true, "active": true},
+	})
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type,SubState,NRestarts,MainPID,ExecMainCode,ExecMainStatus,MemoryCurrent,CPUUsageNSec", "snap.foo.svc1.service", "snap.bar.svc2.service"},
+	})
+}
+
+func (s *restSuite) TestServicesStatusNonSnapService(c *C) {
+	req, err := http.NewRequest("GET", "/v1/services?names=snap.foo.svc1.service,dbus.service", nil)
+	c.Assert(err, IsNil)
+	rec := httptest.NewRecorder()
+	agent.ServicesCmd.GET(agent.ServicesCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": `cannot operate on non-snap service "dbus.service"`,
+	})
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
+func (s *restSuite) postServices(c *C, body string) (int, resp) {
+	req, err := http.NewRequest("POST", "/v1/services", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	rec := httptest.NewRecorder()
+	agent.ServicesCmd.POST(agent.ServicesCmd, req).ServeHTTP(rec, req)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	return rec.Code, rsp
+}
+
+func (s *restSuite) TestServicesStartEnable(c *C) {
+	code, rsp := s.postServices(c, `{"action": "start", "services": ["snap.foo.svc1.service"], "enable": true}`)
+	c.Check(code, Equals, 200)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "--root", dirs.GlobalRootDir, "enable", "snap.foo.svc1.service"},
+		{"--user", "start", "snap.foo.svc1.service"},
+	})
+}
+
+func (s *restSuite) TestServicesStopDisable(c *C) {
+	code, rsp := s.postServices(c, `{"action": "stop", "services": ["snap.foo.svc1.service"], "disable": true}`)
+	c.Check(code, Equals, 200)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "--root", dirs.GlobalRootDir, "disable", "snap.foo.svc1.service"},
+		{"--user", "stop", "snap.foo.svc1.service"},
+		{"--user", "show", "--property=ActiveState", "snap.foo.svc1.service"},
+	})
+}
+
+func (s *restSuite) TestServicesRestart(c *C) {
+	code, rsp := s.postServices(c, `{"action": "restart", "services": ["snap.foo.svc1.service"]}`)
+	c.Check(code, Equals, 200)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "stop", "snap.foo.svc1.service"},
+		{"--user", "show", "--property=ActiveState", "snap.foo.svc1.service"},
+		{"--user", "start", "snap.foo.svc1.service"},
+	})
+}
+
+func (s *restSuite) TestServicesErrors(c *C) {
+	for _, t := range []struct {
+		body string
+		err  string
+	}{
+		{`junk`, `cannot decode request body into service instruction: .*`},
+		{`{"action": "start"}`, `no services specified`},
+		{`{"action": "start", "services": ["other.service"]}`, `cannot operate on non-snap service "other.service"`},
+		{`{"action": "frobnicate", "services": ["snap.foo.svc1.service"]}`, `unknown action "frobnicate"`},
+		{`{"action": "start", "services": ["snap.foo.svc1.service"], "disable": true}`, `cannot disable services while starting them`},
+		{`{"action": "stop", "services": ["snap.foo.svc1.service"], "enable": true}`, `cannot enable services while stopping them`},
+	} {
+		code, rsp := s.postServices(c, t.body)
+		c.Check(code, Equals, 400, Commentf(t.body))
+		c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+		c.Check(rsp.Result.(map[string]interface{})["message"], Matches, t.err, Commentf(t.body))
+	}
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
+func (s *restSuite) TestServicesFailure(c *C) {
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	code, rsp := s.postServices(c, `{"action": "start", "services": ["snap.foo.svc1.service"]}`)
+	c.Check(code, Equals, 500)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "cannot start user services: boom",
+	})
+}
+
+func (s *restSuite) postServiceControl(c *C, body string) (int, resp) {
+	req, err := http.NewRequest("POST", "/v1/service-control", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	agent.ServiceControlCmd.POST(agent.ServiceControlCmd, req).ServeHTTP(rec, req)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	return rec.Code, rsp
+}
+
+func (s *restSuite) TestServiceControl(c *C) {
+	// the agent.ServiceControl end point only supports POST requests
+	c.Check(agent.ServiceControlCmd.GET, IsNil)
+	c.Check(agent.ServiceControlCmd.PUT, IsNil)
+	c.Check(agent.ServiceControlCmd.DELETE, IsNil)
+	c.Assert(agent.ServiceControlCmd.POST, NotNil)
+
+	c.Check(agent.ServiceControlCmd.Path, Equals, "/v1/service-control")
+}
+
+func (s *restSuite) TestServiceControlDaemonReload(c *C) {
+	code, rsp := s.postServiceControl(c, `{"action": "daemon-reload"}`)
+	c.Check(code, Equals, 200)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "daemon-reload"},
+	})
+}
+
+func (s *restSuite) TestServiceControlStart(c *C) {
+	code, rsp := s.postServiceControl(c, `{"action": "start", "services": ["snap.foo.svc1.service", "snap.bar.svc2.service"]}`)
+	c.Check(code, Equals, 200)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "start", "snap.foo.svc1.service"},
+		{"--user", "start", "snap.bar.svc2.service"},
+	})
+}
+
+func (s *restSuite) TestServiceControlStartFailureUndoes(c *C) {
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		switch {
+		case cmd[1] == "start" && cmd[2] == "snap.bar.svc2.service":
+			return nil, fmt.Errorf("start failure")
+		case cmd[1] == "show":
+			return []byte("ActiveState=inactive\n"), nil
+		}
+		return nil, nil
+	})
+	defer restore()
+
+	code, rsp := s.postServiceControl(c, `{"action": "start", "services": ["snap.foo.svc1.service", "snap.bar.svc2.service", "snap.baz.svc3.service"]}`)
+	c.Check(code, Equals, 500)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "some user services failed to start",
+		"kind":    "service-control",
+		"value": map[string]interface{}{
+			"start-errors": map[string]interface{}{
+				"snap.bar.svc2.service": "start failure",
+			},
+		},
+	})
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "start", "snap.foo.svc1.service"},
+		{"--user", "start", "snap.bar.svc2.service"},
+		{"--user", "stop", "snap.foo.svc1.service"},
+		{"--user", "show", "--property=ActiveState", "snap.foo.svc1.service"},
+	})
+}
+
+func (s *restSuite) TestServiceControlStop(c *C) {
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		switch {
+		case cmd[1] == "stop" && cmd[2] == "snap.foo.svc1.service":
+			return nil, fmt.Errorf("stop failure")
+		case cmd[1] == "show":
+			return []byte("ActiveState=inactive\n"), nil
+		}
+		return nil, nil
+	})
+	defer restore()
+
+	code, rsp := s.postServiceControl(c, `{"action": "stop", "services": ["snap.foo.svc1.service", "snap.bar.svc2.service"]}`)
+	c.Check(code, Equals, 500)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "some user services failed to stop",
+		"kind":    "service-control",
+		"value": map[string]interface{}{
+			"stop-errors": map[string]interface{}{
+				"snap.foo.svc1.service": "stop failure",
+			},
+		},
+	})
+	// all services are attempted
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "stop", "snap.foo.svc1.service"},
+		{"--user", "stop", "snap.bar.svc2.service"},
+		{"--user", "show", "--property=ActiveState", "snap.bar.svc2.service"},
+	})
+}
+
+func (s *restSuite) TestServiceControlReload(c *C) {
+	code, rsp := s.postServiceControl(c, `{"action": "reload", "services": ["snap.foo.svc1.service"]}`)
+	c.Check(code, Equals, 200)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "reload-or-restart", "snap.foo.svc1.service"},
+	})
+}
+
+func (s *restSuite) TestServiceControlErrors(c *C) {
+	for _, t := range []struct {
+		body string
+		err  string
+	}{
+		{`junk`, `cannot decode request body into service control instruction: .*`},
+		{`{"action": "frobnicate"}`, `unknown action "frobnicate"`},
+		{`{"action": "start"}`, `no services specified`},
+		{`{"action": "stop", "services": ["dbus.service"]}`, `cannot operate on non-snap service "dbus.service"`},
+		{`{"action": "daemon-reload", "services": ["snap.foo.svc1.service"]}`, `daemon-reload should not be called with any services`},
+	} {
+		code, rsp := s.postServiceControl(c, t.body)
+		c.Check(code, Equals, 400, Commentf(t.body))
+		c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+		c.Check(rsp.Result.(map[string]interface{})["message"], Matches, t.err, Commentf(t.body))
+	}
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
+func (s *restSuite) TestServiceControlBadContentType(c *C) {
+	req, err := http.NewRequest("POST", "/v1/service-control", bytes.NewBufferString(`{"action": "daemon-reload"}`))
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	agent.ServiceControlCmd.POST(agent.ServiceControlCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+	c.Check(s.sysdLog, HasLen, 0)
+}
@@ -0,0 +1,189 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package client implements clients for the per-user session agents, as
+// served by usersession/agent.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+// doTimeout is the timeout for a single request to the session agent.
+var doTimeout = 60 * time.Second
+
+// Client talks to the session agent of a single user.
+type Client struct {
+	uid    int
+	socket string
+	doer   *http.Client
+}
+
+// New returns a client for the session agent of the current user.
+func New() *Client {
+	return NewForUID(os.Getuid())
+}
+
+// NewForUID returns a client for the session agent of the user with the
+// given uid.
+func NewForUID(uid int) *Client {
+	socket := fmt.Sprintf("%s/%d/snapd-session-agent.socket", dirs.XdgRuntimeDirBase, uid)
+	transport := &http.Transport{
+		Dial: func(_, _ string) (net.Conn, error) {
+			return net.Dial("unix", socket)
+		},
+		DisableKeepAlives: true,
+	}
+	return &Client{
+		uid:    uid,
+		socket: socket,
+		doer:   &http.Client{Transport: transport},
+	}
+}
+
+// UID returns the uid of the user whose session agent the client talks to.
+func (client *Client) UID() int {
+	return client.uid
+}
+
+// ConnectionError is returned when the session agent cannot be reached.
+type ConnectionError struct {
+	Socket string
+	Err    error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("cannot communicate with session agent at %s: %v", e.Socket, e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+type response struct {
+	Type   string          `json:"type"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Error is the error returned by the session agent.
+type Error struct {
+	Message string          `json:"message"`
+	Kind    string          `json:"kind,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (client *Client) doSync(ctx context.Context, method, path string, query url.Values, body interface{}, v interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	u := url.URL{
+		Scheme:   "http",
+		Host:     "localhost",
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	ctx, cancel := context.WithTimeout(ctx, doTimeout)
+	defer cancel()
+	req, err := http.NewRequest(method, u.String(), &reqBody)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := client.doer.Do(req)
+	if err != nil {
+		return &ConnectionError{Socket: client.socket, Err: err}
+	}
+	defer rsp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("cannot decode session agent response: %v", err)
+	}
+	switch r.Type {
+	case "sync":
+		if v != nil {
+			if err := json.Unmarshal(r.Result, v); err != nil {
+				return fmt.Errorf("cannot unmarshal session agent response: %v", err)
+			}
+		}
+		return nil
+	case "error":
+		var e Error
+		if err := json.Unmarshal(r.Result, &e); err != nil || e.Message == "" {
+			return fmt.Errorf("session agent returned error with status %d", rsp.StatusCode)
+		}
+		return &e
+	}
+	return fmt.Errorf("unexpected session agent response type %q", r.Type)
+}
+
+// ServiceStatus describes the state of a user service unit in the
+// session of the user.
+type ServiceStatus struct {
+	Unit    string `json:"unit"`
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+}
+
+// ServicesStatus returns the status of the given user service units.
+func (client *Client) ServicesStatus(ctx context.Context, units []string) ([]*ServiceStatus, error) {
+	q := url.Values{}
+	q.Set("names", strings.Join(units, ","))
+	var sts []*ServiceStatus
+	if err := client.doSync(ctx, "GET", "/v1/services", q, nil, &sts); err != nil {
+		return nil, err
+	}
+	return sts, nil
+}
+
+// ServiceInstruction holds the action to perform on user service units.
+type ServiceInstruction struct {
+	Action   string   `json:"action"`
+	Services []string `json:"services"`
+	Enable   bool     `json:"enable,omitempty"`
+	Disable  bool     `json:"disable,omitempty"`
+}
+
+// ServicesAction asks the session agent to start, stop or restart user
+// service units.
+func (client *Client) ServicesAction(ctx context.Context, inst *ServiceInstruction) error {
+	return client.doSync(ctx, "POST", "/v1/services", nil, inst, nil)
+}
@@ -0,0 +1,127 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/usersession/client"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type clientSuite struct {
+	server  *http.Server
+	handler http.HandlerFunc
+}
+
+var _ = Suite(&clientSuite{})
+
+func (s *clientSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	socket := fmt.Sprintf("%s/%d/snapd-session-agent.socket", dirs.XdgRuntimeDirBase, os.Getuid())
+	c.Assert(os.MkdirAll(filepath.Dir(socket), 0700), IsNil)
+	l, err := net.Listen("unix", socket)
+	c.Assert(err, IsNil)
+
+	s.handler = nil
+	s.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handler(w, r)
+	})}
+	go s.server.Serve(l)
+}
+
+func (s *clientSuite) TearDownTest(c *C) {
+	c.Check(s.server.Close(), IsNil)
+	dirs.SetRootDir("")
+}
+
+func (s *clientSuite) TestServicesStatus(c *C) {
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v1/services")
+		c.Check(r.URL.Query().Get("names"), Equals, "snap.foo.svc1.service,snap.foo.svc2.service")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "sync", "result": [
+{"unit": "snap.foo.svc1.service", "enabled": true, "active": true},
+{"unit": "snap.foo.svc2.service", "enabled": false, "active": false}]}`))
+	}
+
+	sts, err := client.New().ServicesStatus(context.Background(), []string{"snap.foo.svc1.service", "snap.foo.svc2.service"})
+	c.Assert(err, IsNil)
+	c.Check(sts, DeepEquals, []*client.ServiceStatus{
+		{Unit: "snap.foo.svc1.service", Enabled: true, Active: true},
+		{Unit: "snap.foo.svc2.service"},
+	})
+}
+
+func (s *clientSuite) TestServicesAction(c *C) {
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v1/services")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		var inst map[string]interface{}
+		c.Assert(json.Unmarshal(body, &inst), IsNil)
+		c.Check(inst, DeepEquals, map[string]interface{}{
+			"action":   "start",
+			"services": []interface{}{"snap.foo.svc1.service"},
+			"enable":   true,
+		})
+		w.Write([]byte(`{"type": "sync", "result": null}`))
+	}
+
+	err := client.New().ServicesAction(context.Background(), &client.ServiceInstruction{
+		Action:   "start",
+		Services: []string{"snap.foo.svc1.service"},
+		Enable:   true,
+	})
+	c.Check(err, IsNil)
+}
+
+func (s *clientSuite) TestServicesActionError(c *C) {
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+		w.Write([]byte(`{"type": "error", "result": {"message": "cannot start user services: boom"}}`))
+	}
+
+	err := client.New().ServicesAction(context.Background(), &client.ServiceInstruction{
+		Action:   "start",
+		Services: []string{"snap.foo.svc1.service"},
+	})
+	c.Check(err, ErrorMatches, "cannot start user services: boom")
+	c.Check(err, FitsTypeOf, &client.Error{})
+}
+
+func (s *clientSuite) TestNoAgent(c *C) {
+	_, err := client.NewForUID(os.Getuid()+1).ServicesStatus(context.Background(), []string{"snap.foo.svc1.service"})
+	c.Check(err, ErrorMatches, `cannot communicate with session agent at .*/snapd-session-agent.socket: .*`)
+}
@@ -0,0 +1,209 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/xerrors"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+// Sessions talks to the session agents of all the users with a running
+// session, it is meant to be used by snapd running as root.
+type Sessions struct {
+	agents []*Client
+}
+
+// DiscoverSessions finds the session agents listening on their sockets
+// in the runtime directories of the users.
+func DiscoverSessions() (*Sessions, error) {
+	sockets, err := filepath.Glob(filepath.Join(dirs.XdgRuntimeDirBase, "*", "snapd-session-agent.socket"))
+	if err != nil {
+		return nil, err
+	}
+	var uids []int
+	for _, socket := range sockets {
+		uid, err := strconv.Atoi(filepath.Base(filepath.Dir(socket)))
+		if err != nil {
+			// not a runtime directory of a user
+			continue
+		}
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+
+	agents := make([]*Client, len(uids))
+	for i, uid := range uids {
+		agents[i] = NewForUID(uid)
+	}
+	return &Sessions{agents: agents}, nil
+}
+
+// UIDs returns the users whose session agents were discovered.
+func (s *Sessions) UIDs() []int {
+	uids := make([]int, len(s.agents))
+	for i, agent := range s.agents {
+		uids[i] = agent.uid
+	}
+	return uids
+}
+
+// agentGone returns whether the error means that there is no session
+// agent behind the socket anymore, which happens when the session ended
+// without the socket being cleaned up.
+func agentGone(err error) bool {
+	var connErr *ConnectionError
+	if !xerrors.As(err, &connErr) {
+		return false
+	}
+	return xerrors.Is(err, syscall.ECONNREFUSED) || xerrors.Is(err, syscall.ENOENT)
+}
+
+// ServiceFailure describes a failure of a user service in the session of
+// a particular user.
+type ServiceFailure struct {
+	Uid     int
+	Service string
+	Error   string
+}
+
+// serviceControlErrors mirrors the error value of the service-control
+// endpoint of the session agent.
+type serviceControlErrors struct {
+	StartErrors  map[string]string `json:"start-errors"`
+	StopErrors   map[string]string `json:"stop-errors"`
+	ReloadErrors map[string]string `json:"reload-errors"`
+}
+
+func failures(uid int, errs map[string]string) []ServiceFailure {
+	units := make([]string, 0, len(errs))
+	for unit := range errs {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	out := make([]ServiceFailure, len(units))
+	for i, unit := range units {
+		out[i] = ServiceFailure{Uid: uid, Service: unit, Error: errs[unit]}
+	}
+	return out
+}
+
+type agentResult struct {
+	uid  int
+	errs *serviceControlErrors
+	err  error
+}
+
+// serviceControl sends the given action to all the session agents in
+// parallel and collects their results, ordered by uid.
+func (s *Sessions) serviceControl(ctx context.Context, action string, services []string) ([]agentResult, error) {
+	inst := map[string]interface{}{
+		"action":   action,
+		"services": services,
+	}
+	results := make([]agentResult, len(s.agents))
+	var wg sync.WaitGroup
+	for i, agent := range s.agents {
+		wg.Add(1)
+		go func(i int, agent *Client) {
+			defer wg.Done()
+			res := agentResult{uid: agent.uid}
+			err := agent.doSync(ctx, "POST", "/v1/service-control", nil, inst, nil)
+			if e, ok := err.(*Error); ok && e.Kind == "service-control" {
+				res.errs = &serviceControlErrors{}
+				if jerr := json.Unmarshal(e.Value, res.errs); jerr != nil {
+					err = fmt.Errorf("cannot decode service control errors: %v", jerr)
+				} else {
+					err = nil
+				}
+			}
+			if err != nil && !agentGone(err) {
+				res.err = err
+			}
+			results[i] = res
+		}(i, agent)
+	}
+	wg.Wait()
+
+	var errs []string
+	for _, res := range results {
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("uid %d: %v", res.uid, res.err))
+		}
+	}
+	if len(errs) > 0 {
+		return results, fmt.Errorf("cannot %s user services: %s", action, strings.Join(errs, "; "))
+	}
+	return results, nil
+}
+
+// ServicesDaemonReload asks every session agent to reload the systemd
+// user instance of its session, for unit changes to be picked up.
+func (s *Sessions) ServicesDaemonReload(ctx context.Context) error {
+	_, err := s.serviceControl(ctx, "daemon-reload", nil)
+	return err
+}
+
+// ServicesStart starts the given user services in every session. In
+// sessions where some service fails to start the services started before
+// are stopped again, and failures to do so are reported as stop failures.
+func (s *Sessions) ServicesStart(ctx context.Context, services []string) (startFailures, stopFailures []ServiceFailure, err error) {
+	results, err := s.serviceControl(ctx, "start", services)
+	for _, res := range results {
+		if res.errs != nil {
+			startFailures = append(startFailures, failures(res.uid, res.errs.StartErrors)...)
+			stopFailures = append(stopFailures, failures(res.uid, res.errs.StopErrors)...)
+		}
+	}
+	return startFailures, stopFailures, err
+}
+
+// ServicesStop stops the given user services in every session.
+func (s *Sessions) ServicesStop(ctx context.Context, services []string) (stopFailures []ServiceFailure, err error) {
+	results, err := s.serviceControl(ctx, "stop", services)
+	for _, res := range results {
+		if res.errs != nil {
+			stopFailures = append(stopFailures, failures(res.uid, res.errs.StopErrors)...)
+		}
+	}
+	return stopFailures, err
+}
+
+// ServicesReload reloads, or restarts when reloading is not supported,
+// the given user services in every session.
+func (s *Sessions) ServicesReload(ctx context.Context, services []string) (reloadFailures []ServiceFailure, err error) {
+	results, err := s.serviceControl(ctx, "reload", services)
+	for _, res := range results {
+		if res.errs != nil {
+			reloadFailures = append(reloadFailures, failures(res.uid, res.errs.ReloadErrors)...)
+		}
+	}
+	return reloadFailures, err
+}
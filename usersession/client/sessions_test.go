@@ -0,0 +1,182 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/usersession/client"
+)
+
+type sessionsSuite struct {
+	servers  []*http.Server
+	handlers map[int]http.HandlerFunc
+}
+
+var _ = Suite(&sessionsSuite{})
+
+func (s *sessionsSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.servers = nil
+	s.handlers = make(map[int]http.HandlerFunc)
+}
+
+func (s *sessionsSuite) TearDownTest(c *C) {
+	for _, srv := range s.servers {
+		c.Check(srv.Close(), IsNil)
+	}
+	dirs.SetRootDir("")
+}
+
+func (s *sessionsSuite) socket(c *C, uid string) string {
+	socket := filepath.Join(dirs.XdgRuntimeDirBase, uid, "snapd-session-agent.socket")
+	c.Assert(os.MkdirAll(filepath.Dir(socket), 0700), IsNil)
+	return socket
+}
+
+func (s *sessionsSuite) mockAgent(c *C, uid int, handler http.HandlerFunc) {
+	l, err := net.Listen("unix", s.socket(c, fmt.Sprint(uid)))
+	c.Assert(err, IsNil)
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(l)
+	s.servers = append(s.servers, srv)
+}
+
+func (s *sessionsSuite) TestDiscoverSessions(c *C) {
+	s.mockAgent(c, 1000, nil)
+	s.mockAgent(c, 42, nil)
+	// not a user runtime directory
+	s.socket(c, "not-a-uid")
+	c.Assert(ioutil.WriteFile(s.socket(c, "not-a-uid"), nil, 0600), IsNil)
+
+	sessions, err := client.DiscoverSessions()
+	c.Assert(err, IsNil)
+	c.Check(sessions.UIDs(), DeepEquals, []int{42, 1000})
+}
+
+func (s *sessionsSuite) TestServicesDaemonReload(c *C) {
+	var mu sync.Mutex
+	var calls []int
+	for _, uid := range []int{42, 1000} {
+		uid := uid
+		s.mockAgent(c, uid, func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v1/service-control")
+			c.Check(r.Header.Get("Content-Type"), Equals, "application/json")
+			body, err := ioutil.ReadAll(r.Body)
+			c.Assert(err, IsNil)
+			c.Check(string(body), Equals, `{"action":"daemon-reload","services":null}`+"\n")
+			mu.Lock()
+			calls = append(calls, uid)
+			mu.Unlock()
+			fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+		})
+	}
+	// a stale socket of a session that has ended
+	c.Assert(ioutil.WriteFile(s.socket(c, "1001"), nil, 0600), IsNil)
+
+	sessions, err := client.DiscoverSessions()
+	c.Assert(err, IsNil)
+	c.Check(sessions.UIDs(), DeepEquals, []int{42, 1000, 1001})
+	c.Check(sessions.ServicesDaemonReload(context.Background()), IsNil)
+	c.Check(calls, HasLen, 2)
+}
+
+func (s *sessionsSuite) TestServicesStartFailures(c *C) {
+	s.mockAgent(c, 42, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+	s.mockAgent(c, 1000, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+		fmt.Fprintln(w, `{"type": "error", "result": {
+"message": "some user services failed to start",
+"kind": "service-control",
+"value": {
+  "start-errors": {"snap.bar.svc2.service": "start failure"},
+  "stop-errors": {"snap.foo.svc1.service": "stop failure"}
+}}}`)
+	})
+
+	sessions, err := client.DiscoverSessions()
+	c.Assert(err, IsNil)
+	startFailures, stopFailures, err := sessions.ServicesStart(context.Background(), []string{"snap.foo.svc1.service", "snap.bar.svc2.service"})
+	c.Assert(err, IsNil)
+	c.Check(startFailures, DeepEquals, []client.ServiceFailure{
+		{Uid: 1000, Service: "snap.bar.svc2.service", Error: "start failure"},
+	})
+	c.Check(stopFailures, DeepEquals, []client.ServiceFailure{
+		{Uid: 1000, Service: "snap.foo.svc1.service", Error: "stop failure"},
+	})
+}
+
+func (s *sessionsSuite) TestServicesStopFailures(c *C) {
+	for _, uid := range []int{42, 1000} {
+		uid := uid
+		s.mockAgent(c, uid, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(500)
+			fmt.Fprintf(w, `{"type": "error", "result": {
+"message": "some user services failed to stop",
+"kind": "service-control",
+"value": {"stop-errors": {"snap.foo.svc1.service": "failure %d"}}}}`, uid)
+		})
+	}
+
+	sessions, err := client.DiscoverSessions()
+	c.Assert(err, IsNil)
+	stopFailures, err := sessions.ServicesStop(context.Background(), []string{"snap.foo.svc1.service"})
+	c.Assert(err, IsNil)
+	c.Check(stopFailures, DeepEquals, []client.ServiceFailure{
+		{Uid: 42, Service: "snap.foo.svc1.service", Error: "failure 42"},
+		{Uid: 1000, Service: "snap.foo.svc1.service", Error: "failure 1000"},
+	})
+}
+
+func (s *sessionsSuite) TestServicesReloadAgentError(c *C) {
+	s.mockAgent(c, 42, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+	s.mockAgent(c, 1000, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "unknown action \"reload\""}}`)
+	})
+
+	sessions, err := client.DiscoverSessions()
+	c.Assert(err, IsNil)
+	reloadFailures, err := sessions.ServicesReload(context.Background(), []string{"snap.foo.svc1.service"})
+	c.Check(err, ErrorMatches, `cannot reload user services: uid 1000: unknown action "reload"`)
+	c.Check(reloadFailures, HasLen, 0)
+}
+
+func (s *sessionsSuite) TestNoSessions(c *C) {
+	sessions, err := client.DiscoverSessions()
+	c.Assert(err, IsNil)
+	c.Check(sessions.UIDs(), HasLen, 0)
+	c.Check(sessions.ServicesDaemonReload(context.Background()), IsNil)
+}
@@ -0,0 +1,170 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// snapNameFromServiceFile returns the name of the snap owning the given
+// D-Bus activation file, as recorded in its X-Snap key.
+func snapNameFromServiceFile(filename string) (owner string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "X-Snap=") {
+			return strings.TrimPrefix(line, "X-Snap="), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// snapServiceActivationFiles returns the names of the D-Bus activation
+// files in the given directory owned by the given snap.
+func snapServiceActivationFiles(dir, snapName string) (services []string, err error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.service"))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		owner, err := snapNameFromServiceFile(match)
+		if err != nil {
+			return nil, err
+		}
+		if owner == snapName {
+			services = append(services, filepath.Base(match))
+		}
+	}
+	return services, nil
+}
+
+func generateDBusActivationFile(app *snap.AppInfo, busName string, systemBus bool) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[D-BUS Service]\n")
+	fmt.Fprintf(&buf, "Name=%s\n", busName)
+	fmt.Fprintf(&buf, "Exec=%s\n", app.LauncherCommand())
+	fmt.Fprintf(&buf, "AssumedAppArmorLabel=%s\n", app.SecurityTag())
+	if systemBus {
+		fmt.Fprintf(&buf, "User=root\n")
+	}
+	fmt.Fprintf(&buf, "SystemdService=%s\n", app.ServiceName())
+	fmt.Fprintf(&buf, "X-Snap=%s\n", app.Snap.InstanceName())
+	return buf.Bytes()
+}
+
+// ensureDBusActivationFiles synchronizes the D-Bus activation files of the
+// given snap in the given directory with the given content.
+func ensureDBusActivationFiles(dir, snapName string, content map[string]osutil.FileState) error {
+	existing, err := snapServiceActivationFiles(dir, snapName)
+	if err != nil {
+		return err
+	}
+	globs := existing
+	for name := range content {
+		if strutil.ListContains(existing, name) {
+			continue
+		}
+		// refuse to replace activation files of other snaps
+		owner, err := snapNameFromServiceFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			return fmt.Errorf("cannot add D-Bus activation file %q of snap %q: already provided by snap %q", name, snapName, owner)
+		}
+		globs = append(globs, name)
+	}
+	if len(globs) == 0 {
+		return nil
+	}
+	sort.Strings(globs)
+	_, _, err = osutil.EnsureDirStateGlobs(dir, globs, content)
+	return err
+}
+
+// AddSnapDBusActivationFiles puts in place the D-Bus activation files of the
+// services of the snap activated by the bus names of its D-Bus slots.
+func AddSnapDBusActivationFiles(s *snap.Info) error {
+	sessionContent := make(map[string]osutil.FileState)
+	systemContent := make(map[string]osutil.FileState)
+
+	for _, app := range s.Apps {
+		for _, slot := range app.ActivatesOn {
+			var busName, bus string
+			if err := slot.Attr("name", &busName); err != nil {
+				return err
+			}
+			if err := slot.Attr("bus", &bus); err != nil {
+				return err
+			}
+
+			content := sessionContent
+			if bus == "system" {
+				content = systemContent
+			}
+			filename := busName + ".service"
+			content[filename] = &osutil.MemoryFileState{
+				Content: generateDBusActivationFile(app, busName, bus == "system"),
+				Mode:    0644,
+			}
+		}
+	}
+
+	for _, dir := range []string{dirs.SnapDBusSessionServicesDir, dirs.SnapDBusSystemServicesDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	if err := ensureDBusActivationFiles(dirs.SnapDBusSessionServicesDir, s.InstanceName(), sessionContent); err != nil {
+		return err
+	}
+	return ensureDBusActivationFiles(dirs.SnapDBusSystemServicesDir, s.InstanceName(), systemContent)
+}
+
+// RemoveSnapDBusActivationFiles removes the D-Bus activation files of the
+// services of the snap.
+func RemoveSnapDBusActivationFiles(s *snap.Info) error {
+	for _, dir := range []string{dirs.SnapDBusSessionServicesDir, dirs.SnapDBusSystemServicesDir} {
+		if !osutil.IsDirectory(dir) {
+			continue
+		}
+		if err := ensureDBusActivationFiles(dir, s.InstanceName(), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
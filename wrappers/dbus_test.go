@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+type dbusTestSuite struct {
+	testutil.BaseTest
+	tempdir string
+}
+
+var _ = Suite(&dbusTestSuite{})
+
+func (s *dbusTestSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.tempdir = c.MkDir()
+	dirs.SetRootDir(s.tempdir)
+}
+
+func (s *dbusTestSuite) TearDownTest(c *C) {
+	s.BaseTest.TearDownTest(c)
+	dirs.SetRootDir("")
+}
+
+const dbusSnapYaml = `
+name: snapname
+version: 1.0
+slots:
+  dbus-slot:
+    interface: dbus
+    bus: system
+    name: org.example.Foo
+  dbus-session-slot:
+    interface: dbus
+    bus: session
+    name: org.example.Bar
+apps:
+  system-daemon:
+    daemon: simple
+    activates-on: [dbus-slot]
+  session-daemon:
+    daemon: simple
+    daemon-scope: user
+    activates-on: [dbus-session-slot]
+`
+
+const expectedSystemServiceFile = `[D-BUS Service]
+Name=org.example.Foo
+Exec=/usr/bin/snap run snapname.system-daemon
+AssumedAppArmorLabel=snap.snapname.system-daemon
+User=root
+SystemdService=snap.snapname.system-daemon.service
+X-Snap=snapname
+`
+
+const expectedSessionServiceFile = `[D-BUS Service]
+Name=org.example.Bar
+Exec=/usr/bin/snap run snapname.session-daemon
+AssumedAppArmorLabel=snap.snapname.session-daemon
+SystemdService=snap.snapname.session-daemon.service
+X-Snap=snapname
+`
+
+func (s *dbusTestSuite) TestAddSnapDBusActivationFiles(c *C) {
+	info := snaptest.MockSnap(c, dbusSnapYaml, &snap.SideInfo{Revision: snap.R(12)})
+	err := wrappers.AddSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+
+	c.Check(filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Foo.service"), testutil.FileEquals, expectedSystemServiceFile)
+	c.Check(filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Bar.service"), testutil.FileEquals, expectedSessionServiceFile)
+}
+
+func (s *dbusTestSuite) TestAddSnapDBusActivationFilesRemovesStale(c *C) {
+	info := snaptest.MockSnap(c, dbusSnapYaml, &snap.SideInfo{Revision: snap.R(12)})
+	c.Assert(os.MkdirAll(dirs.SnapDBusSystemServicesDir, 0755), IsNil)
+	c.Assert(os.MkdirAll(dirs.SnapDBusSessionServicesDir, 0755), IsNil)
+
+	// a stale activation file of the same snap and one of another snap
+	stale := filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Stale.service")
+	c.Assert(ioutil.WriteFile(stale, []byte("[D-BUS Service]\nX-Snap=snapname\n"), 0644), IsNil)
+	other := filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Other.service")
+	c.Assert(ioutil.WriteFile(other, []byte("[D-BUS Service]\nX-Snap=othersnap\n"), 0644), IsNil)
+
+	err := wrappers.AddSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+
+	c.Check(osutil.FileExists(stale), Equals, false)
+	c.Check(osutil.FileExists(other), Equals, true)
+	c.Check(filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Foo.service"), testutil.FileEquals, expectedSystemServiceFile)
+}
+
+func (s *dbusTestSuite) TestAddSnapDBusActivationFilesConflict(c *C) {
+	info := snaptest.MockSnap(c, dbusSnapYaml, &snap.SideInfo{Revision: snap.R(12)})
+	c.Assert(os.MkdirAll(dirs.SnapDBusSystemServicesDir, 0755), IsNil)
+	conflict := filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Foo.service")
+	c.Assert(ioutil.WriteFile(conflict, []byte("[D-BUS Service]\nX-Snap=othersnap\n"), 0644), IsNil)
+
+	err := wrappers.AddSnapDBusActivationFiles(info)
+	c.Assert(err, ErrorMatches, `cannot add D-Bus activation file "org.example.Foo.service" of snap "snapname": already provided by snap "othersnap"`)
+	c.Check(conflict, testutil.FileEquals, "[D-BUS Service]\nX-Snap=othersnap\n")
+}
+
+func (s *dbusTestSuite) TestRemoveSnapDBusActivationFiles(c *C) {
+	info := snaptest.MockSnap(c, dbusSnapYaml, &snap.SideInfo{Revision: snap.R(12)})
+	c.Assert(wrappers.AddSnapDBusActivationFiles(info), IsNil)
+	other := filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Other.service")
+	c.Assert(ioutil.WriteFile(other, []byte("[D-BUS Service]\nX-Snap=othersnap\n"), 0644), IsNil)
+
+	err := wrappers.RemoveSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Foo.service")), Equals, false)
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapDBusSessionServicesDir, "org.example.Bar.service")), Equals, false)
+	c.Check(osutil.FileExists(other), Equals, true)
+}
+
+func (s *dbusTestSuite) TestRemoveSnapDBusActivationFilesNoDirs(c *C) {
+	info := snaptest.MockSnap(c, dbusSnapYaml, &snap.SideInfo{Revision: snap.R(12)})
+	c.Assert(wrappers.RemoveSnapDBusActivationFiles(info), IsNil)
+}
+
+func (s *dbusTestSuite) TestParallelInstanceAddSnapDBusActivationFiles(c *C) {
+	info := snaptest.MockSnapInstance(c, "snapname_foo", dbusSnapYaml, &snap.SideInfo{Revision: snap.R(12)})
+	err := wrappers.AddSnapDBusActivationFiles(info)
+	c.Assert(err, IsNil)
+
+	c.Check(filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Foo.service"), testutil.FileContains, "X-Snap=snapname_foo\n")
+	c.Check(filepath.Join(dirs.SnapDBusSystemServicesDir, "org.example.Foo.service"), testutil.FileContains, "SystemdService=snap.snapname_foo.system-daemon.service\n")
+}
@@ -50,3 +50,13 @@ func MockKillWait(wait time.Duration) (restore func()) {
 		killWait = oldKillWait
 	}
 }
+
+type UserSessions = userSessions
+
+func MockDiscoverUserSessions(f func() (UserSessions, error)) (restore func()) {
+	old := discoverUserSessions
+	discoverUserSessions = f
+	return func() {
+		discoverUserSessions = old
+	}
+}
@@ -21,10 +21,12 @@ package wrappers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -35,11 +37,13 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/sys"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timeout"
 	"github.com/snapcore/snapd/timeutil"
 	"github.com/snapcore/snapd/timings"
+	"github.com/snapcore/snapd/usersession/client"
 )
 
 type interacter interface {
@@ -57,12 +61,86 @@ func serviceStopTimeout(app *snap.AppInfo) time.Duration {
 	return time.Duration(tout)
 }
 
-func generateSnapServiceFile(app *snap.AppInfo) ([]byte, error) {
+// SnapServiceOptions carries the options affecting how the service units
+// of a snap are generated.
+type SnapServiceOptions struct {
+	// QuotaGroup is the quota group the snap is in, its services then
+	// run in the slice of the group.
+	QuotaGroup *quota.Group
+	// ConnectedServices maps the names of the plugs and slots of the
+	// snap to the services of other snaps on the other side of their
+	// connections, they are used to order the services declaring
+	// before-connected/after-connected.
+	ConnectedServices map[string][]*snap.AppInfo
+}
+
+// userSessions controls the user services in the running user sessions,
+// through their session agents.
+type userSessions interface {
+	ServicesDaemonReload(ctx context.Context) error
+	ServicesStart(ctx context.Context, services []string) (startFailures, stopFailures []client.ServiceFailure, err error)
+	ServicesStop(ctx context.Context, services []string) (stopFailures []client.ServiceFailure, err error)
+}
+
+var discoverUserSessions = func() (userSessions, error) {
+	return client.DiscoverSessions()
+}
+
+// reloadUserSessions makes the systemd instances of the running user
+// sessions pick up the changes to the user service units.
+func reloadUserSessions() error {
+	sessions, err := discoverUserSessions()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	return sessions.ServicesDaemonReload(ctx)
+}
+
+// startUserServices starts the given user services in the running user
+// sessions. The services failing to start in some session are reported but
+// don't make the operation fail, the session may be broken in ways snapd
+// cannot help.
+func startUserServices(services []string, inter interacter) error {
+	sessions, err := discoverUserSessions()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	startFailures, stopFailures, err := sessions.ServicesStart(ctx, services)
+	for _, f := range startFailures {
+		inter.Notify(fmt.Sprintf("Could not start user service %q for uid %d: %s", f.Service, f.Uid, f.Error))
+	}
+	for _, f := range stopFailures {
+		inter.Notify(fmt.Sprintf("While trying to stop previously started user service %q for uid %d: %s", f.Service, f.Uid, f.Error))
+	}
+	return err
+}
+
+// stopUserServices stops the given user services in the running user
+// sessions, waiting for them at most the given timeout.
+func stopUserServices(services []string, tout time.Duration, inter interacter) error {
+	sessions, err := discoverUserSessions()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tout)
+	defer cancel()
+	stopFailures, err := sessions.ServicesStop(ctx, services)
+	for _, f := range stopFailures {
+		inter.Notify(fmt.Sprintf("Could not stop user service %q for uid %d: %s", f.Service, f.Uid, f.Error))
+	}
+	return err
+}
+
+func generateSnapServiceFile(app *snap.AppInfo, opts *SnapServiceOptions) ([]byte, error) {
 	if err := snap.ValidateApp(app); err != nil {
 		return nil, err
 	}
 
-	return genServiceFile(app), nil
+	return genServiceFile(app, opts), nil
 }
 
 func stopService(sysd systemd.Systemd, app *snap.AppInfo, inter interacter) error {
@@ -108,11 +186,21 @@ func StartServices(apps []*snap.AppInfo, inter interacter, tm timings.Measurer)
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
 
 	services := make([]string, 0, len(apps))
+	var userServices []string
 	for _, app := range apps {
 		// they're *supposed* to be all services, but checking doesn't hurt
 		if !app.IsService() {
 			continue
 		}
+		// user services are started by the systemd instances of the
+		// running user sessions, the other sessions start them when
+		// they begin
+		if app.IsUserService() {
+			if len(app.Sockets) == 0 && app.Timer == nil {
+				userServices = append(userServices, app.ServiceName())
+			}
+			continue
+		}
 
 		defer func(app *snap.AppInfo) {
 			if err == nil {
@@ -197,11 +285,20 @@ func StartServices(apps []*snap.AppInfo, inter interacter, tm timings.Measurer)
 		}
 	}
 
+	if len(userServices) > 0 {
+		timings.Run(tm, "start-user-services", "start user services", func(nested timings.Measurer) {
+			err = startUserServices(userServices, inter)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // AddSnapServices adds service units for the applications from the snap which are services.
-func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err error) {
+func AddSnapServices(s *snap.Info, disabledSvcs []string, opts *SnapServiceOptions, inter interacter) (err error) {
 	if s.GetType() == snap.TypeSnapd {
 		return writeSnapdServicesOnCore(s, inter)
 	}
@@ -218,8 +315,11 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err
 	}
 
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+	userGlobalSysd := systemd.New(dirs.GlobalRootDir, systemd.GlobalUserMode, inter)
 	var written []string
+	var writtenSystem, writtenUser bool
 	var enabled []string
+	var enabledUser []string
 	defer func() {
 		if err == nil {
 			return
@@ -229,16 +329,26 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err
 				inter.Notify(fmt.Sprintf("while trying to disable %s due to previous failure: %v", s, e))
 			}
 		}
+		for _, s := range enabledUser {
+			if e := userGlobalSysd.Disable(s); e != nil {
+				inter.Notify(fmt.Sprintf("while trying to disable %s due to previous failure: %v", s, e))
+			}
+		}
 		for _, s := range written {
 			if e := os.Remove(s); e != nil {
 				inter.Notify(fmt.Sprintf("while trying to remove %s due to previous failure: %v", s, e))
 			}
 		}
-		if len(written) > 0 {
+		if writtenSystem {
 			if e := sysd.DaemonReload(); e != nil {
 				inter.Notify(fmt.Sprintf("while trying to perform systemd daemon-reload due to previous failure: %v", e))
 			}
 		}
+		if writtenUser {
+			if e := reloadUserSessions(); e != nil {
+				inter.Notify(fmt.Sprintf("while trying to perform systemd daemon-reload in the user sessions due to previous failure: %v", e))
+			}
+		}
 	}()
 
 	for _, app := range s.Apps {
@@ -246,7 +356,7 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err
 			continue
 		}
 		// Generate service file
-		content, err := generateSnapServiceFile(app)
+		content, err := generateSnapServiceFile(app, opts)
 		if err != nil {
 			return err
 		}
@@ -256,6 +366,11 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err
 			return err
 		}
 		written = append(written, svcFilePath)
+		if app.IsUserService() {
+			writtenUser = true
+		} else {
+			writtenSystem = true
+		}
 
 		// Generate systemd .socket files if needed
 		socketFiles, err := generateSnapSocketFiles(app)
@@ -296,18 +411,91 @@ func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err
 			continue
 		}
 
+		if app.IsUserService() {
+			// enabled for all users, it's started with their next
+			// session and by StartServices in the running ones
+			if err := userGlobalSysd.Enable(svcName); err != nil {
+				return err
+			}
+			enabledUser = append(enabledUser, svcName)
+			continue
+		}
+
 		if err := sysd.Enable(svcName); err != nil {
 			return err
 		}
 		enabled = append(enabled, svcName)
 	}
 
-	if len(written) > 0 {
+	if writtenSystem {
 		if err := sysd.DaemonReload(); err != nil {
 			return err
 		}
 	}
+	if writtenUser {
+		if err := reloadUserSessions(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnsureSnapServices rewrites the system service units of an installed
+// snap with the given options, e.g. after the snap was moved to another
+// quota group, leaving their enabled state alone. It returns the services
+// whose unit changed; they need to be restarted to pick up the change.
+func EnsureSnapServices(s *snap.Info, opts *SnapServiceOptions, inter interacter) (changed []*snap.AppInfo, err error) {
+	svcs := s.Services()
+	sort.Slice(svcs, func(i, j int) bool { return svcs[i].Name < svcs[j].Name })
+	for _, app := range svcs {
+		if app.IsUserService() {
+			// the options only apply to system services
+			continue
+		}
+		content, err := generateSnapServiceFile(app, opts)
+		if err != nil {
+			return nil, err
+		}
+		svcFilePath := app.ServiceFile()
+		os.MkdirAll(filepath.Dir(svcFilePath), 0755)
+		err = osutil.EnsureFileState(svcFilePath, &osutil.MemoryFileState{Content: content, Mode: 0644})
+		if err == osutil.ErrSameState {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, app)
+	}
 
+	if len(changed) > 0 {
+		sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+		if err := sysd.DaemonReload(); err != nil {
+			return nil, err
+		}
+	}
+
+	return changed, nil
+}
+
+// RestartServices restarts the given services if they are active, so that
+// they pick up changes to their units.
+func RestartServices(svcs []*snap.AppInfo, inter interacter) error {
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+
+	for _, app := range svcs {
+		active, err := sysd.IsActive(app.ServiceName())
+		if err != nil {
+			return err
+		}
+		if !active {
+			continue
+		}
+		if err := sysd.Restart(app.ServiceName(), serviceStopTimeout(app)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -316,6 +504,8 @@ func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter int
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
 
 	logger.Debugf("StopServices called for %q, reason: %v", apps, reason)
+	var userServices []string
+	var userStopTimeout time.Duration
 	for _, app := range apps {
 		// Handle the case where service file doesn't exist and don't try to stop it as it will fail.
 		// This can happen with snap try when snap.yaml is modified on the fly and a daemon line is added.
@@ -332,6 +522,15 @@ func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter int
 				continue
 			}
 		}
+		// user services are stopped by the systemd instances of the
+		// running user sessions
+		if app.IsUserService() {
+			userServices = append(userServices, app.ServiceName())
+			if tout := serviceStopTimeout(app); tout > userStopTimeout {
+				userStopTimeout = tout
+			}
+			continue
+		}
 
 		var err error
 		timings.Run(tm, "stop-service", fmt.Sprintf("stop service %q", app.ServiceName()), func(nested timings.Measurer) {
@@ -353,6 +552,17 @@ func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter int
 		}
 	}
 
+	if len(userServices) > 0 {
+		var err error
+		timings.Run(tm, "stop-user-services", "stop user services", func(nested timings.Measurer) {
+			// leave some time for the session agents to answer
+			err = stopUserServices(userServices, userStopTimeout+5*time.Second, inter)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -365,7 +575,9 @@ func ServicesEnableState(s *snap.Info, inter interacter) (map[string]bool, error
 	// systemd state of the snaps
 	snapSvcsState := make(map[string]bool, len(s.Apps))
 	for name, app := range s.Apps {
-		if !app.IsService() {
+		// user services are enabled for all users and can only be
+		// disabled by the users themselves
+		if !app.IsService() || app.IsUserService() {
 			continue
 		}
 		state, err := sysd.IsEnabled(app.ServiceName())
@@ -380,12 +592,26 @@ func ServicesEnableState(s *snap.Info, inter interacter) (map[string]bool, error
 // RemoveSnapServices disables and removes service units for the applications from the snap which are services.
 func RemoveSnapServices(s *snap.Info, inter interacter) error {
 	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+	userGlobalSysd := systemd.New(dirs.GlobalRootDir, systemd.GlobalUserMode, inter)
 	nservices := 0
+	nuserServices := 0
 
 	for _, app := range s.Apps {
 		if !app.IsService() || !osutil.FileExists(app.ServiceFile()) {
 			continue
 		}
+
+		if app.IsUserService() {
+			serviceName := app.ServiceName()
+			if err := userGlobalSysd.Disable(serviceName); err != nil {
+				return err
+			}
+			if err := os.Remove(app.ServiceFile()); err != nil && !os.IsNotExist(err) {
+				logger.Noticef("Failed to remove service file for %q: %v", serviceName, err)
+			}
+			nuserServices++
+			continue
+		}
 		nservices++
 
 		serviceName := filepath.Base(app.ServiceFile())
@@ -431,6 +657,11 @@ func RemoveSnapServices(s *snap.Info, inter interacter) error {
 			return err
 		}
 	}
+	if nuserServices > 0 {
+		if err := reloadUserSessions(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -446,16 +677,51 @@ func genServiceNames(snap *snap.Info, appNames []string) []string {
 	return names
 }
 
-func genServiceFile(appInfo *snap.AppInfo) []byte {
+// genConnectedServiceNames returns the names of the services connected to
+// the given plugs and slots of a service.
+func genConnectedServiceNames(appInfo *snap.AppInfo, plugOrSlotNames []string, opts *SnapServiceOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range plugOrSlotNames {
+		for _, other := range opts.ConnectedServices[name] {
+			if other.Snap.InstanceName() == appInfo.Snap.InstanceName() {
+				continue
+			}
+			if !other.IsService() || other.IsUserService() != appInfo.IsUserService() {
+				continue
+			}
+			if !strutil.ListContains(names, other.ServiceName()) {
+				names = append(names, other.ServiceName())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func genServiceFile(appInfo *snap.AppInfo, opts *SnapServiceOptions) []byte {
 	serviceTemplate := `[Unit]
 # Auto-generated, DO NOT EDIT
 Description=Service for snap application {{.App.Snap.InstanceName}}.{{.App.Name}}
+{{- if .MountUnit}}
 Requires={{.MountUnit}}
 Wants={{.PrerequisiteTarget}}
 After={{.MountUnit}} {{.PrerequisiteTarget}}{{if .After}} {{ stringsJoin .After " " }}{{end}}
+{{- else if .After}}
+After={{ stringsJoin .After " " }}
+{{- end}}
+{{- if .AfterConnected}}
+Wants={{ stringsJoin .AfterConnected " " }}
+After={{ stringsJoin .AfterConnected " " }}
+{{- end}}
 {{- if .Before}}
 Before={{ stringsJoin .Before " "}}
 {{- end}}
+{{- if .BeforeConnected}}
+Before={{ stringsJoin .BeforeConnected " " }}
+{{- end}}
 X-Snappy=yes
 
 [Service]
@@ -465,7 +731,7 @@ Restart={{.Restart}}
 {{- if .App.RestartDelay}}
 RestartSec={{.App.RestartDelay.Seconds}}
 {{- end}}
-WorkingDirectory={{.App.Snap.DataDir}}
+WorkingDirectory={{.WorkingDir}}
 {{- if .App.StopCommand}}
 ExecStop={{.App.LauncherStopCommand}}
 {{- end}}
@@ -497,6 +763,27 @@ KillMode={{.KillMode}}
 {{- if .KillSignal}}
 KillSignal={{.KillSignal}}
 {{- end}}
+{{- if .SliceUnit}}
+Slice={{.SliceUnit}}
+{{- end}}
+{{- if .App.MemoryMax}}
+MemoryMax={{.App.MemoryMax}}
+{{- end}}
+{{- if .App.CPUWeight}}
+CPUWeight={{.App.CPUWeight}}
+{{- end}}
+{{- if .App.IOWeight}}
+IOWeight={{.App.IOWeight}}
+{{- end}}
+{{- if .App.TasksMax}}
+TasksMax={{.App.TasksMax}}
+{{- end}}
+{{- if .App.Nice}}
+Nice={{.App.Nice}}
+{{- end}}
+{{- if .App.OOMScoreAdjust}}
+OOMScoreAdjust={{.App.OOMScoreAdjust}}
+{{- end}}
 {{- if not .App.Sockets}}
 
 [Install]
@@ -542,8 +829,12 @@ WantedBy={{.ServicesTarget}}
 		Remain             string
 		KillMode           string
 		KillSignal         string
+		WorkingDir         string
+		SliceUnit          string
 		Before             []string
 		After              []string
+		BeforeConnected    []string
+		AfterConnected     []string
 
 		Home    string
 		EnvVars string
@@ -559,14 +850,32 @@ WantedBy={{.ServicesTarget}}
 		Remain:             remain,
 		KillMode:           killMode,
 		KillSignal:         appInfo.StopMode.KillSignal(),
+		WorkingDir:         appInfo.Snap.DataDir(),
 
 		Before: genServiceNames(appInfo.Snap, appInfo.Before),
 		After:  genServiceNames(appInfo.Snap, appInfo.After),
 
+		BeforeConnected: genConnectedServiceNames(appInfo, appInfo.BeforeConnected, opts),
+		AfterConnected:  genConnectedServiceNames(appInfo, appInfo.AfterConnected, opts),
+
 		// systemd runs as PID 1 so %h will not work.
 		Home: "/root",
 	}
 
+	if appInfo.IsUserService() {
+		// user units cannot depend on system units like the mount
+		// unit of the snap, and quota groups only apply to system
+		// services
+		wrapperData.MountUnit = ""
+		wrapperData.ServicesTarget = systemd.UserServicesTarget
+		// the data directory of the snap belongs to root, use the one
+		// of the user, which is only created by the first run of the
+		// snap, hence the "-" telling systemd to carry on without it
+		wrapperData.WorkingDir = "-" + appInfo.Snap.UserDataDir("%h")
+	} else if opts != nil && opts.QuotaGroup != nil {
+		wrapperData.SliceUnit = opts.QuotaGroup.SliceFileName()
+	}
+
 	if err := t.Execute(&templateOut, wrapperData); err != nil {
 		// this can never happen, except we forget a variable
 		logger.Panicf("Unable to execute template: %v", err)
@@ -31,6 +31,7 @@ import (
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timeout"
@@ -127,11 +128,114 @@ apps:
 	info.Revision = snap.R(44)
 	app := info.Apps["app"]
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, nil)
 	c.Assert(err, IsNil)
 	c.Check(string(generatedWrapper), Equals, expectedAppService)
 }
 
+func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileWithQuotaGroup(c *C) {
+	yamlText := `
+name: snap
+version: 1.0
+apps:
+    app:
+        command: bin/start
+        daemon: simple
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	grp, err := quota.NewGroup("foo", 1024*1024, 0)
+	c.Assert(err, IsNil)
+	sub, err := grp.NewSubGroup("bar", 1024*1024, 0)
+	c.Assert(err, IsNil)
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, &wrappers.SnapServiceOptions{QuotaGroup: sub})
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), testutil.Contains, "\nType=simple\nSlice=snap.foo-bar.slice\n\n[Install]\n")
+}
+
+func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileWithResourceControls(c *C) {
+	yamlText := `
+name: snap
+version: 1.0
+apps:
+    app:
+        command: bin/start
+        daemon: simple
+        memory-max: 512M
+        cpu-weight: 200
+        io-weight: 50
+        tasks-max: 64
+        nice: 0
+        oom-score-adjust: -100
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, nil)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), testutil.Contains, `
+Type=simple
+MemoryMax=512M
+CPUWeight=200
+IOWeight=50
+TasksMax=64
+Nice=0
+OOMScoreAdjust=-100
+
+[Install]
+`)
+}
+
+func (s *servicesWrapperGenSuite) TestGenerateSnapUserServiceFile(c *C) {
+	yamlText := `
+name: snap
+version: 1.0
+apps:
+    app:
+        command: bin/start
+        daemon: simple
+        daemon-scope: user
+        after: [other]
+    other:
+        command: bin/other
+        daemon: simple
+        daemon-scope: user
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	grp, err := quota.NewGroup("foo", 1024*1024, 0)
+	c.Assert(err, IsNil)
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, &wrappers.SnapServiceOptions{QuotaGroup: grp})
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Equals, `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.app
+After=snap.snap.other.service
+X-Snappy=yes
+
+[Service]
+ExecStart=/usr/bin/snap run snap.app
+SyslogIdentifier=snap.app
+Restart=on-failure
+WorkingDirectory=-%h/snap/snap/44
+TimeoutStopSec=30
+Type=simple
+
+[Install]
+WantedBy=default.target
+`)
+}
+
 func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileWithStartTimeout(c *C) {
 	yamlText := `
 name: snap
@@ -147,7 +251,7 @@ apps:
 	info.Revision = snap.R(44)
 	app := info.Apps["app"]
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, nil)
 	c.Assert(err, IsNil)
 	c.Check(string(generatedWrapper), testutil.Contains, "\nTimeoutStartSec=600\n")
 }
@@ -168,7 +272,7 @@ apps:
 		info.Revision = snap.R(44)
 		app := info.Apps["app"]
 
-		generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+		generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, nil)
 		c.Assert(err, IsNil)
 		wrapperText := string(generatedWrapper)
 		if cond == snap.RestartNever {
@@ -197,7 +301,7 @@ func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileTypeForking(c *C) {
 		Daemon:          "forking",
 	}
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, nil)
 	c.Assert(err, IsNil)
 	c.Assert(string(generatedWrapper), Equals, expectedTypeForkingWrapper)
 }
@@ -218,7 +322,7 @@ func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileIllegalChars(c *C)
 		Daemon:          "simple",
 	}
 
-	_, err := wrappers.GenerateSnapServiceFile(service)
+	_, err := wrappers.GenerateSnapServiceFile(service, nil)
 	c.Assert(err, NotNil)
 }
 
@@ -243,7 +347,7 @@ apps:
 	info.Revision = snap.R(44)
 	app := info.Apps["app"]
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, nil)
 	c.Assert(err, IsNil)
 
 	c.Assert(string(generatedWrapper), Equals, expectedDbusService)
@@ -266,7 +370,7 @@ apps:
 
 	app := info.Apps["app"]
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, nil)
 	c.Assert(err, IsNil)
 
 	c.Assert(string(generatedWrapper), Equals, expectedOneshotService)
@@ -336,7 +440,7 @@ WantedBy=sockets.target
 	sock1Expected := fmt.Sprintf(sock1ExpectedFmt, mountUnitPrefix, mountUnitPrefix, si.DataDir())
 	sock2Expected := fmt.Sprintf(sock2ExpectedFmt, mountUnitPrefix, mountUnitPrefix, si.DataDir())
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, nil)
 	c.Assert(err, IsNil)
 	c.Assert(strings.Contains(string(generatedWrapper), "[Install]"), Equals, false)
 	c.Assert(strings.Contains(string(generatedWrapper), "WantedBy=multi-user.target"), Equals, false)
@@ -427,7 +531,7 @@ WantedBy=multi-user.target
 		c.Logf("tc: %v", tc)
 		service.After = tc.after
 		service.Before = tc.before
-		generatedWrapper, err := wrappers.GenerateSnapServiceFile(service)
+		generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, nil)
 		c.Assert(err, IsNil)
 
 		expectedService := fmt.Sprintf(expectedServiceFmt, mountUnitPrefix, mountUnitPrefix,
@@ -436,6 +540,92 @@ WantedBy=multi-user.target
 	}
 }
 
+func (s *servicesWrapperGenSuite) TestServiceAfterBeforeConnected(c *C) {
+	info := snaptest.MockInfo(c, `
+name: consumer
+version: 1.0
+plugs:
+  db:
+    interface: content
+apps:
+  app:
+    command: bin/app
+    daemon: simple
+    plugs: [db]
+    slots: [feed]
+    after-connected: [db]
+    before-connected: [feed]
+`, &snap.SideInfo{Revision: snap.R(12)})
+	provider := snaptest.MockInfo(c, `
+name: provider
+version: 1.0
+apps:
+  db:
+    command: bin/db
+    daemon: simple
+  db-helper:
+    command: bin/helper
+    daemon: simple
+  user-svc:
+    command: bin/user-svc
+    daemon: simple
+    daemon-scope: user
+  cmd:
+    command: bin/cmd
+`, &snap.SideInfo{Revision: snap.R(3)})
+	reader := snaptest.MockInfo(c, `
+name: reader
+version: 1.0
+apps:
+  read:
+    command: bin/read
+    daemon: simple
+`, &snap.SideInfo{Revision: snap.R(4)})
+	app := info.Apps["app"]
+
+	const expectedServiceFmt = `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application consumer.app
+Requires=%[1]s-consumer-12.mount
+Wants=network.target
+After=%[1]s-consumer-12.mount network.target%[2]s
+X-Snappy=yes
+
+[Service]
+ExecStart=/usr/bin/snap run consumer.app
+SyslogIdentifier=consumer.app
+Restart=on-failure
+WorkingDirectory=/var/snap/consumer/12
+TimeoutStopSec=30
+Type=simple
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	// without connections nothing is added
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app, nil)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(expectedServiceFmt, mountUnitPrefix, ""))
+
+	opts := &wrappers.SnapServiceOptions{
+		ConnectedServices: map[string][]*snap.AppInfo{
+			// non-services and user services are ignored
+			"db": {
+				provider.Apps["db"], provider.Apps["db-helper"],
+				provider.Apps["user-svc"], provider.Apps["cmd"],
+			},
+			"feed": {reader.Apps["read"]},
+		},
+	}
+	generatedWrapper, err = wrappers.GenerateSnapServiceFile(app, opts)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(expectedServiceFmt, mountUnitPrefix, `
+Wants=snap.provider.db-helper.service snap.provider.db.service
+After=snap.provider.db-helper.service snap.provider.db.service
+Before=snap.reader.read.service`))
+}
+
 func (s *servicesWrapperGenSuite) TestServiceTimerUnit(c *C) {
 	const expectedServiceFmt = `[Unit]
 # Auto-generated, DO NOT EDIT
@@ -536,7 +726,7 @@ WantedBy=multi-user.target
 		},
 	}
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, nil)
 	c.Assert(err, IsNil)
 
 	c.Logf("service: \n%v\n", string(generatedWrapper))
@@ -688,7 +878,7 @@ func (s *servicesWrapperGenSuite) TestKillModeSig(c *C) {
 			StopMode: snap.StopModeType(rm),
 		}
 
-		generatedWrapper, err := wrappers.GenerateSnapServiceFile(service)
+		generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, nil)
 		c.Assert(err, IsNil)
 
 		c.Check(string(generatedWrapper), Equals, fmt.Sprintf(`[Unit]
@@ -728,7 +918,7 @@ func (s *servicesWrapperGenSuite) TestRestartDelay(c *C) {
 		RestartDelay: timeout.Timeout(20 * time.Second),
 	}
 
-	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service)
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, nil)
 	c.Assert(err, IsNil)
 
 	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(`[Unit]
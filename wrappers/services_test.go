@@ -20,6 +20,7 @@
 package wrappers_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
@@ -34,12 +35,15 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/progress/progresstest"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
+	"github.com/snapcore/snapd/usersession/client"
 	"github.com/snapcore/snapd/wrappers"
 )
 
@@ -79,7 +83,7 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemove(c *C) {
 	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
 	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 	c.Check(s.sysdLog, DeepEquals, [][]string{
 		{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)},
@@ -114,6 +118,128 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemove(c *C) {
 	c.Check(s.sysdLog[1], DeepEquals, []string{"daemon-reload"})
 }
 
+// fakeUserSessions records the requests to the session agents.
+type fakeUserSessions struct {
+	calls         []string
+	startFailures []client.ServiceFailure
+}
+
+func (f *fakeUserSessions) ServicesDaemonReload(ctx context.Context) error {
+	f.calls = append(f.calls, "daemon-reload")
+	return nil
+}
+
+func (f *fakeUserSessions) ServicesStart(ctx context.Context, services []string) (startFailures, stopFailures []client.ServiceFailure, err error) {
+	f.calls = append(f.calls, "start "+strings.Join(services, " "))
+	return f.startFailures, nil, nil
+}
+
+func (f *fakeUserSessions) ServicesStop(ctx context.Context, services []string) (stopFailures []client.ServiceFailure, err error) {
+	f.calls = append(f.calls, "stop "+strings.Join(services, " "))
+	return nil, nil
+}
+
+func (s *servicesTestSuite) TestAddSnapServicesAndRemoveUserDaemons(c *C) {
+	sessions := &fakeUserSessions{}
+	restore := wrappers.MockDiscoverUserSessions(func() (wrappers.UserSessions, error) {
+		return sessions, nil
+	})
+	defer restore()
+
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+  daemon-scope: user
+`, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
+	userSvcFile := filepath.Join(s.tempdir, "/etc/systemd/user/snap.hello-snap.svc2.service")
+
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+	c.Assert(s.sysdLog, HasLen, 3)
+	c.Check(s.sysdLog, testutil.DeepContains, []string{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)})
+	c.Check(s.sysdLog, testutil.DeepContains, []string{"--user", "--global", "--root", dirs.GlobalRootDir, "enable", filepath.Base(userSvcFile)})
+	c.Check(s.sysdLog[2], DeepEquals, []string{"daemon-reload"})
+	c.Check(userSvcFile, testutil.FileContains, "\nWantedBy=default.target\n")
+	// the running user sessions pick up the new unit
+	c.Check(sessions.calls, DeepEquals, []string{"daemon-reload"})
+
+	// user services are started in the running user sessions
+	s.sysdLog = nil
+	sessions.calls = nil
+	err = wrappers.StartServices(info.Services(), progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "is-enabled", filepath.Base(svcFile)},
+		{"start", filepath.Base(svcFile)},
+	})
+	c.Check(sessions.calls, DeepEquals, []string{"start snap.hello-snap.svc2.service"})
+
+	s.sysdLog = nil
+	sessions.calls = nil
+	err = wrappers.StopServices([]*snap.AppInfo{info.Apps["svc2"]}, "", progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+	c.Check(sessions.calls, DeepEquals, []string{"stop snap.hello-snap.svc2.service"})
+
+	s.sysdLog = nil
+	sessions.calls = nil
+	err = wrappers.RemoveSnapServices(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(svcFile), Equals, false)
+	c.Check(osutil.FileExists(userSvcFile), Equals, false)
+	c.Assert(s.sysdLog, HasLen, 3)
+	c.Check(s.sysdLog, testutil.DeepContains, []string{"--root", dirs.GlobalRootDir, "disable", filepath.Base(svcFile)})
+	c.Check(s.sysdLog, testutil.DeepContains, []string{"--user", "--global", "--root", dirs.GlobalRootDir, "disable", filepath.Base(userSvcFile)})
+	c.Check(s.sysdLog[2], DeepEquals, []string{"daemon-reload"})
+	c.Check(sessions.calls, DeepEquals, []string{"daemon-reload"})
+}
+
+func (s *servicesTestSuite) TestStartUserServicesReportsFailures(c *C) {
+	sessions := &fakeUserSessions{
+		startFailures: []client.ServiceFailure{{Uid: 1000, Service: "snap.hello-snap.svc2.service", Error: "boom"}},
+	}
+	restore := wrappers.MockDiscoverUserSessions(func() (wrappers.UserSessions, error) {
+		return sessions, nil
+	})
+	defer restore()
+
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+  daemon-scope: user
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	inter := &progresstest.Meter{}
+	err := wrappers.StartServices([]*snap.AppInfo{info.Apps["svc2"]}, inter, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(inter.Notices, DeepEquals, []string{`Could not start user service "snap.hello-snap.svc2.service" for uid 1000: boom`})
+}
+
+func (s *servicesTestSuite) TestStopServicesRefreshEndureUserDaemon(c *C) {
+	sessions := &fakeUserSessions{}
+	restore := wrappers.MockDiscoverUserSessions(func() (wrappers.UserSessions, error) {
+		return sessions, nil
+	})
+	defer restore()
+
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+  daemon-scope: user
+  refresh-mode: endure
+`, &snap.SideInfo{Revision: snap.R(12)})
+	c.Assert(wrappers.AddSnapServices(info, nil, nil, progress.Null), IsNil)
+
+	sessions.calls = nil
+	err := wrappers.StopServices([]*snap.AppInfo{info.Apps["svc2"]}, snap.StopReasonRefresh, progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(sessions.calls, HasLen, 0)
+}
+
 var snapdYaml = `name: snapd
 version: 1.0
 type: snapd
@@ -132,7 +258,7 @@ func (s *servicesTestSuite) TestRemoveSnapWithSocketsRemovesSocketsService(c *C)
       listen-stream: $SNAP_COMMON/sock2.socket
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	err = wrappers.StopServices(info.Services(), "", &progress.Null, s.perfTimings)
@@ -172,7 +298,7 @@ apps:
    daemon: forking
 `, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	sysdLog = nil
@@ -338,7 +464,7 @@ func (s *servicesTestSuite) TestAddSnapServicesWithDisabledServices(c *C) {
 	// svc1 will be disabled
 	disabledSvcs := []string{"svc1"}
 
-	err := wrappers.AddSnapServices(info, disabledSvcs, progress.Null)
+	err := wrappers.AddSnapServices(info, disabledSvcs, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	// only svc2 should be enabled
@@ -386,7 +512,7 @@ func (s *servicesTestSuite) TestAddSnapServicesWithDisabledServicesNowApp(c *C)
 
 	svcs := []string{"hello"}
 
-	err := wrappers.AddSnapServices(info, svcs, progress.Null)
+	err := wrappers.AddSnapServices(info, svcs, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	// check the log for the notice
@@ -440,7 +566,7 @@ func (s *servicesTestSuite) TestAddSnapServicesWithDisabledServicesMissing(c *C)
 
 	svcs := []string{"old-disabled-svc"}
 
-	err := wrappers.AddSnapServices(info, svcs, progress.Null)
+	err := wrappers.AddSnapServices(info, svcs, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	// check the log for the notice
@@ -478,7 +604,7 @@ func (s *servicesTestSuite) TestStopServicesWithSockets(c *C) {
       listen-stream: $SNAP_DATA/sock2.socket
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	sysdLog = nil
@@ -549,7 +675,7 @@ func (s *servicesTestSuite) TestAddSnapMultiServicesFailCreateCleanup(c *C) {
   daemon: potato
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, ErrorMatches, ".*potato.*")
 
 	// the services are cleaned up
@@ -614,7 +740,7 @@ func (s *servicesTestSuite) TestAddSnapMultiServicesFailEnableCleanup(c *C) {
   daemon: simple
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, ErrorMatches, "failed")
 
 	// the services are cleaned up
@@ -662,7 +788,7 @@ func (s *servicesTestSuite) TestAddSnapMultiServicesStartFailOnSystemdReloadClea
   daemon: simple
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, ErrorMatches, "failed")
 
 	// the services are cleaned up
@@ -698,7 +824,7 @@ func (s *servicesTestSuite) TestAddSnapSocketFiles(c *C) {
 	sock2File := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.sock2.socket")
 	sock3File := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.sock3.socket")
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	expected := fmt.Sprintf(
@@ -941,7 +1067,7 @@ func (s *servicesTestSuite) TestServiceAfterBefore(c *C) {
 		},
 	}}
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	for _, check := range checks {
@@ -981,7 +1107,7 @@ func (s *servicesTestSuite) TestServiceWatchdog(c *C) {
 `
 	info := snaptest.MockSnap(c, snapYaml, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	content, err := ioutil.ReadFile(filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc2.service"))
@@ -1011,7 +1137,7 @@ apps:
 	info := snaptest.MockSnap(c, surviveYaml, &snap.SideInfo{Revision: snap.R(1)})
 	survivorFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.survive-snap.survivor.service")
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 	c.Check(s.sysdLog, DeepEquals, [][]string{
 		{"--root", dirs.GlobalRootDir, "enable", filepath.Base(survivorFile)},
@@ -1063,7 +1189,7 @@ apps:
 		info := snaptest.MockSnap(c, surviveYaml, &snap.SideInfo{Revision: snap.R(1)})
 
 		s.sysdLog = nil
-		err := wrappers.AddSnapServices(info, nil, progress.Null)
+		err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 		c.Assert(err, IsNil)
 		c.Check(s.sysdLog, DeepEquals, [][]string{
 			{"--root", dirs.GlobalRootDir, "enable", filepath.Base(survivorFile)},
@@ -1175,7 +1301,7 @@ func (s *servicesTestSuite) TestAddRemoveSnapWithTimersAddsRemovesTimerFiles(c *
   timer: 10:00-12:00
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	app := info.Apps["svc2"]
@@ -1222,7 +1348,7 @@ func (s *servicesTestSuite) TestFailedAddSnapCleansUp(c *C) {
 	})
 	defer r()
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, NotNil)
 
 	c.Logf("services dir: %v", dirs.SnapServicesDir)
@@ -1264,7 +1390,7 @@ apps:
 
 	for i, info := range []*snap.Info{onlyServices, onlySockets, onlyTimers} {
 		s.sysdLog = nil
-		err := wrappers.AddSnapServices(info, nil, progress.Null)
+		err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 		c.Assert(err, IsNil)
 		reloads := 0
 		c.Logf("calls: %v", s.sysdLog)
@@ -1305,7 +1431,7 @@ apps:
 	info := snaptest.MockSnap(c, snapYaml, &snap.SideInfo{Revision: snap.R(12)})
 
 	// fix the apps order to make the test stable
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 	c.Assert(s.sysdLog, HasLen, 2, Commentf("len: %v calls: %v", len(s.sysdLog), s.sysdLog))
 	c.Check(s.sysdLog, DeepEquals, [][]string{
@@ -1325,7 +1451,7 @@ func (s *servicesTestSuite) TestServiceRestartDelay(c *C) {
 `
 	info := snaptest.MockSnap(c, snapYaml, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
 	c.Assert(err, IsNil)
 
 	content, err := ioutil.ReadFile(filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc2.service"))
@@ -1336,3 +1462,38 @@ func (s *servicesTestSuite) TestServiceRestartDelay(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(strings.Contains(string(content), "RestartSec="), Equals, false)
 }
+
+func (s *servicesTestSuite) TestEnsureSnapServicesAndRestart(c *C) {
+	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
+
+	err := wrappers.AddSnapServices(info, nil, nil, progress.Null)
+	c.Assert(err, IsNil)
+
+	// nothing changed
+	s.sysdLog = nil
+	changed, err := wrappers.EnsureSnapServices(info, nil, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(changed, HasLen, 0)
+	c.Check(s.sysdLog, HasLen, 0)
+
+	grp, err := quota.NewGroup("foo", 0, 0)
+	c.Assert(err, IsNil)
+	changed, err = wrappers.EnsureSnapServices(info, &wrappers.SnapServiceOptions{QuotaGroup: grp}, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(changed, DeepEquals, []*snap.AppInfo{info.Apps["svc1"]})
+	c.Check(svcFile, testutil.FileContains, "\nSlice=snap.foo.slice\n")
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+
+	s.sysdLog = nil
+	err = wrappers.RestartServices(changed, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "is-active", "snap.hello-snap.svc1.service"},
+		{"stop", "snap.hello-snap.svc1.service"},
+		{"show", "--property=ActiveState", "snap.hello-snap.svc1.service"},
+		{"start", "snap.hello-snap.svc1.service"},
+	})
+}
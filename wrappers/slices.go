@@ -0,0 +1,110 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func generateQuotaSliceFile(grp *quota.Group) []byte {
+	buf := fmt.Sprintf(`[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Slice for snap quota group %s
+Before=slices.target
+X-Snappy=yes
+
+[Slice]
+# Always enable accounting, so that the usage of the group can be queried
+CPUAccounting=true
+MemoryAccounting=true
+`, grp.Name)
+	if grp.CPULimit != 0 {
+		buf += fmt.Sprintf("CPUQuota=%d%%\n", grp.CPULimit)
+	}
+	if grp.MemoryLimit != 0 {
+		// MemoryLimit is the cgroup v1 equivalent of MemoryMax
+		buf += fmt.Sprintf("MemoryMax=%[1]d\nMemoryLimit=%[1]d\n", grp.MemoryLimit)
+	}
+	return []byte(buf)
+}
+
+// EnsureQuotaSlices writes the slice units of the given quota groups.
+func EnsureQuotaSlices(grps []*quota.Group, inter interacter) error {
+	if err := os.MkdirAll(dirs.SnapServicesDir, 0755); err != nil {
+		return err
+	}
+
+	modified := false
+	for _, grp := range grps {
+		path := filepath.Join(dirs.SnapServicesDir, grp.SliceFileName())
+		err := osutil.EnsureFileState(path, &osutil.MemoryFileState{
+			Content: generateQuotaSliceFile(grp),
+			Mode:    0644,
+		})
+		if err == osutil.ErrSameState {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		modified = true
+	}
+
+	if !modified {
+		return nil
+	}
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+	return sysd.DaemonReload()
+}
+
+// RemoveQuotaSlices stops and removes the slice units of the given quota
+// groups. The services of the snaps in the groups must have been moved
+// out of the slices before, stopping a slice stops everything in it.
+func RemoveQuotaSlices(grps []*quota.Group, inter interacter) error {
+	sysd := systemd.New(dirs.GlobalRootDir, systemd.SystemMode, inter)
+
+	removed := 0
+	for _, grp := range grps {
+		path := filepath.Join(dirs.SnapServicesDir, grp.SliceFileName())
+		if !osutil.FileExists(path) {
+			continue
+		}
+		if err := sysd.Stop(grp.SliceFileName(), 5*time.Second); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+	}
+
+	if removed == 0 {
+		return nil
+	}
+	return sysd.DaemonReload()
+}
@@ -0,0 +1,111 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers_test
+
+import (
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+type slicesSuite struct {
+	testutil.BaseTest
+	sysdLog [][]string
+}
+
+var _ = Suite(&slicesSuite{})
+
+func (s *slicesSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.sysdLog = nil
+	s.AddCleanup(systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	}))
+	s.AddCleanup(systemd.MockStopDelays(time.Millisecond, 25*time.Second))
+}
+
+func (s *slicesSuite) TestEnsureAndRemoveQuotaSlices(c *C) {
+	grp, err := quota.NewGroup("foo", 1024*1024*1024, 150)
+	c.Assert(err, IsNil)
+	sub, err := grp.NewSubGroup("bar", 512*1024*1024, 100)
+	c.Assert(err, IsNil)
+	other, err := quota.NewGroup("other", 0, 0)
+	c.Assert(err, IsNil)
+
+	err = wrappers.EnsureQuotaSlices([]*quota.Group{grp, sub, other}, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileEquals, `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Slice for snap quota group foo
+Before=slices.target
+X-Snappy=yes
+
+[Slice]
+# Always enable accounting, so that the usage of the group can be queried
+CPUAccounting=true
+MemoryAccounting=true
+CPUQuota=150%
+MemoryMax=1073741824
+MemoryLimit=1073741824
+`)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo-bar.slice"), testutil.FileContains, "\nDescription=Slice for snap quota group bar\n")
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.other.slice"), Not(testutil.FileContains), "Max=")
+
+	// nothing changed
+	s.sysdLog = nil
+	err = wrappers.EnsureQuotaSlices([]*quota.Group{grp, sub, other}, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+
+	err = wrappers.RemoveQuotaSlices([]*quota.Group{sub, other}, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FilePresent)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo-bar.slice"), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.other.slice"), testutil.FileAbsent)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"stop", "snap.foo-bar.slice"},
+		{"show", "--property=ActiveState", "snap.foo-bar.slice"},
+		{"stop", "snap.other.slice"},
+		{"show", "--property=ActiveState", "snap.other.slice"},
+		{"daemon-reload"},
+	})
+
+	// removing missing slices is a no-op
+	s.sysdLog = nil
+	err = wrappers.RemoveQuotaSlices([]*quota.Group{sub}, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+}